@@ -0,0 +1,71 @@
+package activation
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "1")
+	os.Setenv("LISTEN_FDNAMES", "osb")
+
+	listeners, err := listeners(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, len(listeners); e != a {
+		t.Fatalf("Unexpected number of listeners; expected %v, got %v", e, a)
+	}
+	defer listeners[0].Close()
+	if e, a := l.Addr().String(), listeners[0].Addr().String(); e != a {
+		t.Errorf("Unexpected listener address; expected %v, got %v", e, a)
+	}
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		if _, ok := os.LookupEnv(key); ok {
+			t.Errorf("Expected %v to be unset", key)
+		}
+	}
+}
+
+func TestListenersNotActivated(t *testing.T) {
+	cases := []struct {
+		name string
+		pid  string
+		fds  string
+		err  bool
+	}{
+		{name: "no environment"},
+		{name: "other process", pid: "1", fds: "1"},
+		{name: "invalid fds", pid: strconv.Itoa(os.Getpid()), fds: "many", err: true},
+	}
+
+	for _, tc := range cases {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		if tc.pid != "" {
+			os.Setenv("LISTEN_PID", tc.pid)
+			os.Setenv("LISTEN_FDS", tc.fds)
+		}
+
+		listeners, err := Listeners()
+		if tc.err != (err != nil) {
+			t.Errorf("%v: unexpected error: %v", tc.name, err)
+		}
+		if len(listeners) != 0 {
+			t.Errorf("%v: expected no listeners, got %v", tc.name, listeners)
+		}
+	}
+}
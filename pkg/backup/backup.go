@@ -0,0 +1,178 @@
+// Package backup provides instance backup and restore endpoints as a
+// rest.ExtensionAPI. Creating a backup and restoring one are asynchronous
+// operations run by a jobs.Manager: the endpoints answer 202 with an
+// operation key that platforms poll on the instance's last_operation
+// endpoint like any other asynchronous operation.
+package backup
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// The names of the backup operations. They are used as route names, metric
+// labels and operation types.
+const (
+	OperationCreateBackup = "create_backup"
+	OperationListBackups  = "list_backups"
+	OperationRestore      = "restore_backup"
+)
+
+// AdheresTo identifies the API served by the Extension in the catalog.
+const AdheresTo = "urn:osb-broker-lib:backup:v1"
+
+// varKeyBackupID is the route variable holding the backup ID.
+const varKeyBackupID = "backup_id"
+
+// Backup describes a backup of an instance.
+type Backup struct {
+	// ID identifies the backup among the backups of its instance.
+	ID string `json:"id"`
+	// Created is when the backup was taken.
+	Created time.Time `json:"created"`
+	// Metadata is broker-specific information about the backup.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Interface contains the business logic of backups.
+type Interface interface {
+	// ListBackups returns the backups of an instance.
+	ListBackups(instanceID string, c *broker.RequestContext) ([]Backup, error)
+	// CreateBackup returns the backup about to be taken of an instance and
+	// the Job taking it. Parameters are the JSON body of the request, if
+	// any.
+	CreateBackup(instanceID string, parameters json.RawMessage, c *broker.RequestContext) (*Backup, broker.Job, error)
+	// Restore returns the Job restoring an instance from one of its
+	// backups.
+	Restore(instanceID, backupID string, c *broker.RequestContext) (broker.Job, error)
+}
+
+// Extension serves the backup endpoints of the instances of ServiceIDs:
+//
+//	GET  /v2/service_instances/{instance_id}/backups
+//	POST /v2/service_instances/{instance_id}/backups
+//	POST /v2/service_instances/{instance_id}/backups/{backup_id}/restore
+type Extension struct {
+	// Backups contains the business logic of backups.
+	Backups Interface
+	// Jobs runs the backup and restore Jobs. It must be the APISurface's
+	// job manager, so that their operations can be polled.
+	Jobs *jobs.Manager
+	// ServiceIDs are the IDs of the services whose instances can be backed
+	// up.
+	ServiceIDs []string
+	// DiscoveryURL is the URL of the OpenAPI document advertised in the
+	// catalog.
+	DiscoveryURL string
+}
+
+// createResponse is the body of the response to a backup request.
+type createResponse struct {
+	Operation *osb.OperationKey `json:"operation"`
+	Backup    *Backup           `json:"backup"`
+}
+
+// restoreResponse is the body of the response to a restore request.
+type restoreResponse struct {
+	Operation *osb.OperationKey `json:"operation"`
+}
+
+// listResponse is the body of the response to a list request.
+type listResponse struct {
+	Backups []Backup `json:"backups"`
+}
+
+// API returns the rest.ExtensionAPI to add to the APISurface's
+// ExtensionAPIs.
+func (e *Extension) API() rest.ExtensionAPI {
+	return rest.ExtensionAPI{
+		ExtensionAPI: broker.ExtensionAPI{
+			DiscoveryURL: e.DiscoveryURL,
+			AdheresTo:    AdheresTo,
+		},
+		ServiceIDs: e.ServiceIDs,
+		Operations: []rest.ExtensionOperation{
+			{
+				Name:    OperationListBackups,
+				Method:  "GET",
+				Path:    "/v2/service_instances/{instance_id}/backups",
+				Handler: e.list,
+			},
+			{
+				Name:    OperationCreateBackup,
+				Method:  "POST",
+				Path:    "/v2/service_instances/{instance_id}/backups",
+				Handler: e.create,
+			},
+			{
+				Name:    OperationRestore,
+				Method:  "POST",
+				Path:    "/v2/service_instances/{instance_id}/backups/{backup_id}/restore",
+				Handler: e.restore,
+			},
+		},
+	}
+}
+
+func (e *Extension) list(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+	backups, err := e.Backups.ListBackups(request.InstanceID, c)
+	if err != nil {
+		return nil, err
+	}
+	if backups == nil {
+		backups = []Backup{}
+	}
+	return &rest.ExtensionResponse{Body: &listResponse{Backups: backups}}, nil
+}
+
+func (e *Extension) create(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+	backup, job, err := e.Backups.CreateBackup(request.InstanceID, request.Body, c)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.submit(OperationCreateBackup, request.InstanceID, job)
+	if err != nil {
+		return nil, err
+	}
+	return &rest.ExtensionResponse{
+		StatusCode: http.StatusAccepted,
+		Body:       &createResponse{Operation: key, Backup: backup},
+	}, nil
+}
+
+func (e *Extension) restore(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+	job, err := e.Backups.Restore(request.InstanceID, request.Vars[varKeyBackupID], c)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.submit(OperationRestore, request.InstanceID, job)
+	if err != nil {
+		return nil, err
+	}
+	return &rest.ExtensionResponse{
+		StatusCode: http.StatusAccepted,
+		Body:       &restoreResponse{Operation: key},
+	}, nil
+}
+
+// submit hands job to the job manager, translating a full queue into a 503.
+func (e *Extension) submit(operation, instanceID string, job broker.Job) (*osb.OperationKey, error) {
+	key, err := e.Jobs.Submit(operation, instanceID, "", job)
+	if err == jobs.ErrQueueFull {
+		errorMessage := "QueueFull"
+		description := "The broker has too many operations in progress; retry later."
+		return nil, osb.HTTPStatusCodeError{
+			StatusCode:   http.StatusServiceUnavailable,
+			ErrorMessage: &errorMessage,
+			Description:  &description,
+		}
+	}
+	return key, err
+}
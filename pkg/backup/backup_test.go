@@ -0,0 +1,119 @@
+package backup_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/backup"
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+type fakeBackups struct {
+	backups  []backup.Backup
+	restored chan string
+}
+
+func (f *fakeBackups) ListBackups(instanceID string, c *broker.RequestContext) ([]backup.Backup, error) {
+	return f.backups, nil
+}
+
+func (f *fakeBackups) CreateBackup(instanceID string, parameters json.RawMessage, c *broker.RequestContext) (*backup.Backup, broker.Job, error) {
+	b := backup.Backup{ID: "b1", Created: time.Unix(0, 0).UTC()}
+	return &b, func(ctx context.Context, progress func(string)) error {
+		f.backups = append(f.backups, b)
+		return nil
+	}, nil
+}
+
+func (f *fakeBackups) Restore(instanceID, backupID string, c *broker.RequestContext) (broker.Job, error) {
+	return func(ctx context.Context, progress func(string)) error {
+		f.restored <- backupID
+		return nil
+	}, nil
+}
+
+func TestExtension(t *testing.T) {
+	manager := jobs.NewManager(storage.NewMemory(), 1)
+	defer manager.Close()
+
+	logic := &fakeBackups{restored: make(chan string, 1)}
+	extension := &backup.Extension{Backups: logic, Jobs: manager, ServiceIDs: []string{"db"}}
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+		api.Jobs = manager
+		api.ExtensionAPIs = append(api.ExtensionAPIs, extension.API())
+	})
+
+	do := func(method, path string, out interface{}) int {
+		request, err := http.NewRequest(method, s.URL+path, strings.NewReader(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.15")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	poll := func(key *osb.OperationKey) {
+		for i := 0; i < 100; i++ {
+			response, err := s.Client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "instance", OperationKey: key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.State == osb.StateSucceeded {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Operation %v did not succeed", *key)
+	}
+
+	var created struct {
+		Operation *osb.OperationKey `json:"operation"`
+		Backup    backup.Backup     `json:"backup"`
+	}
+	if e, a := http.StatusAccepted, do("POST", "/v2/service_instances/instance/backups", &created); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if created.Operation == nil || created.Backup.ID != "b1" {
+		t.Fatalf("Unexpected create response: %+v", created)
+	}
+	poll(created.Operation)
+
+	var listed struct {
+		Backups []backup.Backup `json:"backups"`
+	}
+	if e, a := http.StatusOK, do("GET", "/v2/service_instances/instance/backups", &listed); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := 1, len(listed.Backups); e != a {
+		t.Fatalf("Unexpected number of backups; expected %v, got %v", e, a)
+	}
+
+	var restored struct {
+		Operation *osb.OperationKey `json:"operation"`
+	}
+	if e, a := http.StatusAccepted, do("POST", "/v2/service_instances/instance/backups/b1/restore", &restored); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := "b1", <-logic.restored; e != a {
+		t.Errorf("Unexpected backup restored; expected %v, got %v", e, a)
+	}
+	poll(restored.Operation)
+}
@@ -0,0 +1,30 @@
+package broker
+
+import (
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// BindResource is the bind_resource of a bind request, decoded with the
+// field names of the spec. The APISurface passes it to the business logic in
+// the RequestContext of bind requests.
+type BindResource struct {
+	// AppGUID is the GUID of the application the binding is for.
+	AppGUID string `json:"app_guid,omitempty"`
+	// Route is the URL of the route the binding is for.
+	Route string `json:"route,omitempty"`
+	// CredentialClientID is the ID of the OAuth client the credentials are
+	// for.
+	CredentialClientID string `json:"credential_client_id,omitempty"`
+}
+
+// NewAppRequiredError returns the spec's RequiresApp error, for bind
+// requests that must be for an application.
+func NewAppRequiredError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		ErrorMessage: strPtr(osb.AppGUIDRequiredErrorMessage),
+		Description:  strPtr(osb.AppGUIDRequiredErrorDescription),
+	}
+}
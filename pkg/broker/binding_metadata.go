@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"fmt"
+	"time"
+)
+
+// BindingMetadata is the metadata of a binding, telling platforms when its
+// credentials expire so they can rotate them. Timestamps are ISO 8601
+// (RFC 3339) strings in UTC.
+type BindingMetadata struct {
+	// ExpiresAt is when the credentials stop working.
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// RenewBefore is when the platform should start rotating the
+	// credentials. It must not be later than ExpiresAt.
+	RenewBefore string `json:"renew_before,omitempty"`
+}
+
+// NewBindingMetadata returns the metadata of credentials issued at issued
+// and valid for ttl, which platforms should renew renewWindow before they
+// expire. A zero renewWindow leaves RenewBefore unset.
+func NewBindingMetadata(issued time.Time, ttl, renewWindow time.Duration) *BindingMetadata {
+	expires := issued.Add(ttl)
+	m := &BindingMetadata{ExpiresAt: formatTimestamp(expires)}
+	if renewWindow > 0 {
+		m.RenewBefore = formatTimestamp(expires.Add(-renewWindow))
+	}
+	return m
+}
+
+// Validate returns an error if a timestamp is malformed or RenewBefore is
+// later than ExpiresAt.
+func (m *BindingMetadata) Validate() error {
+	expires, err := parseTimestamp("expires_at", m.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	renew, err := parseTimestamp("renew_before", m.RenewBefore)
+	if err != nil {
+		return err
+	}
+	if !expires.IsZero() && renew.After(expires) {
+		return fmt.Errorf("binding metadata renew_before %s is after expires_at %s", m.RenewBefore, m.ExpiresAt)
+	}
+	return nil
+}
+
+// Expired returns whether the credentials have expired at now. Credentials
+// without ExpiresAt never expire.
+func (m *BindingMetadata) Expired(now time.Time) bool {
+	expires, err := parseTimestamp("expires_at", m.ExpiresAt)
+	return err == nil && !expires.IsZero() && !now.Before(expires)
+}
+
+// ShouldRenew returns whether the credentials should be renewed at now:
+// RenewBefore has passed, or they have expired.
+func (m *BindingMetadata) ShouldRenew(now time.Time) bool {
+	renew, err := parseTimestamp("renew_before", m.RenewBefore)
+	if err == nil && !renew.IsZero() && !now.Before(renew) {
+		return true
+	}
+	return m.Expired(now)
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseTimestamp parses the named timestamp, returning the zero time if it
+// is empty.
+func parseTimestamp(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("binding metadata %s %q is not an RFC 3339 timestamp: %v", name, value, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package broker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBindingMetadata(t *testing.T) {
+	issued := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewBindingMetadata(issued, time.Hour, 10*time.Minute)
+
+	if e, a := "2020-01-01T13:00:00Z", m.ExpiresAt; e != a {
+		t.Errorf("Unexpected expires_at; expected %v, got %v", e, a)
+	}
+	if e, a := "2020-01-01T12:50:00Z", m.RenewBefore; e != a {
+		t.Errorf("Unexpected renew_before; expected %v, got %v", e, a)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		expired     bool
+		shouldRenew bool
+	}{
+		{name: "fresh", now: issued},
+		{name: "renew window", now: issued.Add(55 * time.Minute), shouldRenew: true},
+		{name: "expired", now: issued.Add(time.Hour), expired: true, shouldRenew: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.expired, m.Expired(tt.now); e != a {
+				t.Errorf("Unexpected Expired; expected %v, got %v", e, a)
+			}
+			if e, a := tt.shouldRenew, m.ShouldRenew(tt.now); e != a {
+				t.Errorf("Unexpected ShouldRenew; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestBindingMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata BindingMetadata
+		valid    bool
+	}{
+		{name: "empty", valid: true},
+		{name: "expiry only", metadata: BindingMetadata{ExpiresAt: "2020-01-01T13:00:00Z"}, valid: true},
+		{name: "malformed", metadata: BindingMetadata{ExpiresAt: "tomorrow"}},
+		{name: "renew after expiry", metadata: BindingMetadata{ExpiresAt: "2020-01-01T13:00:00Z", RenewBefore: "2020-01-01T14:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.valid, tt.metadata.Validate() == nil; e != a {
+				t.Errorf("Unexpected validity; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package broker
+
+import osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+// CatalogStreamer is implemented by business logic that can produce its
+// catalog one service at a time, for brokers exposing catalogs too large to
+// hold in memory comfortably. When the APISurface streams catalogs, it calls
+// StreamCatalog instead of GetCatalog for business logic implementing it.
+//
+// StreamCatalog must call emit once per service, in order, and stop with
+// emit's error if it returns one. An error returned before the first service
+// is emitted is sent to the platform like a GetCatalog error; once a service
+// has been written the response can only be truncated.
+type CatalogStreamer interface {
+	StreamCatalog(c *RequestContext, emit func(service *osb.Service) error) error
+}
@@ -0,0 +1,38 @@
+package broker
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of r carrying ip as the address of the client
+// that sent it, as resolved by the proxy package for requests forwarded by
+// trusted proxies.
+func WithClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+}
+
+// ClientIP returns the address of the client that sent r: the address set
+// with WithClientIP, or else the host of r.RemoteAddr.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientIP returns the address of the client that sent the request. See
+// ClientIP.
+func (c *RequestContext) ClientIP() string {
+	if c.Request == nil {
+		return ""
+	}
+	return ClientIP(c.Request)
+}
@@ -0,0 +1,26 @@
+package broker
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// CredentialProvider issues binding credentials outside of the business
+// logic, for example dynamic secrets minted by a secret store, and revokes
+// them when the binding is deleted. The APISurface calls Issue after the
+// business logic has synchronously created a binding and merges the issued
+// credentials into the bind response, overriding the business logic's
+// credentials with the same keys; it calls Revoke once the business logic
+// has deleted a binding, or reports it gone. Credentials of bindings created by a Job, or
+// asynchronously by the business logic, are not issued, and neither are
+// credentials of a binding the business logic reports as already existing.
+//
+// Platforms retry bind requests, so Issue may still be called again for a
+// binding that already has credentials when the business logic doesn't
+// report it as existing.
+type CredentialProvider interface {
+	// Issue returns the credentials of a new binding.
+	Issue(request *osb.BindRequest, c *RequestContext) (map[string]interface{}, error)
+	// Revoke revokes the credentials issued for a binding. Revoking
+	// credentials that were never issued is not an error.
+	Revoke(request *osb.UnbindRequest, c *RequestContext) error
+}
@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The protocols of an Endpoint.
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+	ProtocolAll = "all"
+)
+
+// Endpoint is a network endpoint of a binding.
+type Endpoint struct {
+	// Host is a hostname or IP address.
+	Host string `json:"host"`
+	// Ports are ports, such as "443", or inclusive port ranges, such as
+	// "9000-9010".
+	Ports []string `json:"ports"`
+	// Protocol is one of ProtocolTCP, ProtocolUDP or ProtocolAll. It
+	// defaults to ProtocolTCP.
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Validate returns an error if the endpoint has no host or ports, a
+// malformed port, or an unknown protocol.
+func (e *Endpoint) Validate() error {
+	if e.Host == "" {
+		return fmt.Errorf("endpoint has no host")
+	}
+	if len(e.Ports) == 0 {
+		return fmt.Errorf("endpoint %q has no ports", e.Host)
+	}
+	for _, ports := range e.Ports {
+		if err := validatePorts(ports); err != nil {
+			return fmt.Errorf("endpoint %q: %v", e.Host, err)
+		}
+	}
+	switch e.Protocol {
+	case "", ProtocolTCP, ProtocolUDP, ProtocolAll:
+	default:
+		return fmt.Errorf("endpoint %q has unknown protocol %q", e.Host, e.Protocol)
+	}
+	return nil
+}
+
+// ValidateEndpoints returns the error of the first invalid endpoint.
+func ValidateEndpoints(endpoints []Endpoint) error {
+	for i := range endpoints {
+		if err := endpoints[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePorts validates a port or an inclusive port range.
+func validatePorts(ports string) error {
+	parts := strings.SplitN(ports, "-", 2)
+	low, err := parsePort(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid port %q", ports)
+	}
+	if len(parts) == 1 {
+		return nil
+	}
+	high, err := parsePort(parts[1])
+	if err != nil || high < low {
+		return fmt.Errorf("invalid port range %q", ports)
+	}
+	return nil
+}
+
+func parsePort(port string) (int, error) {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return 0, err
+	}
+	if p < 1 || p > 65535 {
+		return 0, fmt.Errorf("port %d out of range", p)
+	}
+	return p, nil
+}
@@ -0,0 +1,27 @@
+package broker
+
+import "testing"
+
+func TestEndpointValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		valid    bool
+	}{
+		{name: "port", endpoint: Endpoint{Host: "db.example.com", Ports: []string{"5432"}}, valid: true},
+		{name: "range", endpoint: Endpoint{Host: "10.0.0.1", Ports: []string{"9000-9010"}, Protocol: ProtocolUDP}, valid: true},
+		{name: "no host", endpoint: Endpoint{Ports: []string{"443"}}},
+		{name: "no ports", endpoint: Endpoint{Host: "db.example.com"}},
+		{name: "port out of range", endpoint: Endpoint{Host: "db.example.com", Ports: []string{"70000"}}},
+		{name: "reversed range", endpoint: Endpoint{Host: "db.example.com", Ports: []string{"9010-9000"}}},
+		{name: "named port", endpoint: Endpoint{Host: "db.example.com", Ports: []string{"https"}}},
+		{name: "unknown protocol", endpoint: Endpoint{Host: "db.example.com", Ports: []string{"443"}, Protocol: "icmp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.valid, tt.endpoint.Validate() == nil; e != a {
+				t.Errorf("Unexpected validity; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
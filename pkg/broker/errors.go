@@ -0,0 +1,37 @@
+package broker
+
+import (
+	"errors"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// Sentinel errors business logic can return, or wrap with fmt.Errorf's %w
+// verb, instead of building osb.HTTPStatusCodeErrors. The APISurface writes
+// them with the status code the OSB spec requires.
+var (
+	// ErrInstanceNotFound is written as a 410 Gone for deprovision and
+	// unbind requests and as a 404 Not Found otherwise.
+	ErrInstanceNotFound = errors.New("service instance not found")
+	// ErrBindingGone is written as a 410 Gone.
+	ErrBindingGone = errors.New("service binding does not exist")
+	// ErrAsyncRequired is written as a 422 Unprocessable Entity with the
+	// AsyncRequired error code. Business logic doesn't need to return it
+	// itself: responses with Async or a Job set answering a request
+	// without accepts_incomplete=true get the same error.
+	ErrAsyncRequired = errors.New(osb.AsyncErrorDescription)
+)
+
+// AsHTTPError returns the first osb.HTTPStatusCodeError, or pointer to one,
+// in err's chain.
+func AsHTTPError(err error) (*osb.HTTPStatusCodeError, bool) {
+	var ptr *osb.HTTPStatusCodeError
+	if errors.As(err, &ptr) && ptr != nil {
+		return ptr, true
+	}
+	var value osb.HTTPStatusCodeError
+	if errors.As(err, &value) {
+		return &value, true
+	}
+	return nil, false
+}
@@ -0,0 +1,58 @@
+package broker
+
+import (
+	"encoding/json"
+)
+
+// ExtensionAPI is an entry of the extension_apis of a service in the
+// catalog, advertising an API beyond the OSB API that platforms can discover
+// and call for instances of the service.
+type ExtensionAPI struct {
+	// DiscoveryURL is the URL of the OpenAPI document describing the API.
+	DiscoveryURL string `json:"discovery_url"`
+	// ServerURL is the URL the API is served at, if it differs from the
+	// one in the OpenAPI document.
+	ServerURL string `json:"server_url,omitempty"`
+	// Credentials are the credentials to call the API with, if they differ
+	// from the broker's.
+	Credentials map[string]interface{} `json:"credentials,omitempty"`
+	// AdheresTo is a URI identifying the specification the API implements.
+	AdheresTo string `json:"adheres_to,omitempty"`
+}
+
+// MarshalJSON encodes the catalog, adding the ExtensionAPIs of each service
+// to its entry.
+func (r CatalogResponse) MarshalJSON() ([]byte, error) {
+	if len(r.ExtensionAPIs) == 0 {
+		return json.Marshal(r.CatalogResponse)
+	}
+
+	services := make([]json.RawMessage, len(r.Services))
+	for i := range r.Services {
+		data, err := marshalServiceWithExtensionAPIs(&r.Services[i], r.ExtensionAPIs[r.Services[i].ID])
+		if err != nil {
+			return nil, err
+		}
+		services[i] = data
+	}
+
+	return json.Marshal(struct {
+		Services []json.RawMessage `json:"services"`
+	}{services})
+}
+
+func marshalServiceWithExtensionAPIs(service interface{}, apis []ExtensionAPI) ([]byte, error) {
+	data, err := json.Marshal(service)
+	if err != nil || len(apis) == 0 {
+		return data, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields["extension_apis"], err = json.Marshal(apis); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
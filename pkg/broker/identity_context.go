@@ -0,0 +1,99 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+type identityKey struct{}
+
+// cachedIdentity is the originating identity header of a request, parsed
+// once by ParseOriginatingIdentity.
+type cachedIdentity struct {
+	header      string
+	originating *osb.OriginatingIdentity
+	err         error
+
+	once        sync.Once
+	identity    Identity
+	identityErr error
+}
+
+// ParseOriginatingIdentity is middleware parsing the originating identity
+// header of every request once and storing the result on the request's
+// context, where OriginatingIdentity and RequestIdentity find it, instead
+// of every handler, hook and authorizer decoding the header again. Install
+// it before the middleware that reads identities.
+func ParseOriginatingIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(osb.OriginatingIdentityHeader)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cached := &cachedIdentity{header: header}
+		cached.originating, cached.err = ParseIdentityHeader(header)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, cached)))
+	})
+}
+
+// cachedIdentityFor returns the parsed identity stored on r, if it still
+// matches r's header; requests adapted to older API versions lose the
+// header, and with it their identity.
+func cachedIdentityFor(r *http.Request) *cachedIdentity {
+	cached, ok := r.Context().Value(identityKey{}).(*cachedIdentity)
+	if !ok || cached.header != r.Header.Get(osb.OriginatingIdentityHeader) {
+		return nil
+	}
+	return cached
+}
+
+// OriginatingIdentity returns the originating identity of r, or nil if it
+// has none. The header is only parsed if ParseOriginatingIdentity didn't
+// already.
+func OriginatingIdentity(r *http.Request) (*osb.OriginatingIdentity, error) {
+	if cached := cachedIdentityFor(r); cached != nil {
+		return cached.originating, cached.err
+	}
+	header := r.Header.Get(osb.OriginatingIdentityHeader)
+	if header == "" {
+		return nil, nil
+	}
+	return ParseIdentityHeader(header)
+}
+
+// RequestIdentity returns the platform specific identity of the user who
+// sent r. It returns an error if r has no valid originating identity. The
+// identity is parsed once per request when ParseOriginatingIdentity ran.
+func RequestIdentity(r *http.Request) (Identity, error) {
+	if cached := cachedIdentityFor(r); cached != nil {
+		cached.once.Do(func() {
+			cached.identity, cached.identityErr = parseRequestIdentity(cached.originating, cached.err)
+		})
+		return cached.identity, cached.identityErr
+	}
+	return parseRequestIdentity(OriginatingIdentity(r))
+}
+
+func parseRequestIdentity(originating *osb.OriginatingIdentity, err error) (Identity, error) {
+	if err != nil {
+		return Identity{}, err
+	}
+	if originating == nil {
+		return Identity{}, fmt.Errorf("no originating identity")
+	}
+	return ParseIdentity(*originating)
+}
+
+// Identity returns the platform specific identity of the user who sent the
+// request. See RequestIdentity.
+func (c *RequestContext) Identity() (Identity, error) {
+	if c.Request == nil {
+		return Identity{}, fmt.Errorf("no originating identity")
+	}
+	return RequestIdentity(c.Request)
+}
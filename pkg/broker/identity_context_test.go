@@ -0,0 +1,112 @@
+package broker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// cfIdentityHeader is the identity of Cloud Foundry user alice.
+const cfIdentityHeader = "cloudfoundry eyJ1c2VyX2lkIjoiYWxpY2UifQ=="
+
+// withParsedIdentity returns r as the handlers behind
+// ParseOriginatingIdentity see it.
+func withParsedIdentity(r *http.Request) *http.Request {
+	var parsed *http.Request
+	ParseOriginatingIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parsed = r
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return parsed
+}
+
+func TestParseOriginatingIdentity(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	r.Header.Set(osb.OriginatingIdentityHeader, cfIdentityHeader)
+	r = withParsedIdentity(r)
+
+	first, err := OriginatingIdentity(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OriginatingIdentity(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("Expected the identity to be parsed once per request")
+	}
+	if e, a := osb.PlatformCloudFoundry, first.Platform; e != a {
+		t.Errorf("Unexpected platform; expected %q, got %q", e, a)
+	}
+
+	identity, err := (&RequestContext{Request: r}).Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.CloudFoundry == nil || identity.CloudFoundry.UserID != "alice" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	// Requests adapted to API versions without identities drop the header.
+	adapted := r.Clone(r.Context())
+	adapted.Header.Del(osb.OriginatingIdentityHeader)
+	if identity, err := OriginatingIdentity(adapted); identity != nil || err != nil {
+		t.Errorf("Expected no identity once the header is removed, got %+v, %v", identity, err)
+	}
+	if _, err := RequestIdentity(adapted); err == nil {
+		t.Error("Expected an error for a request without an identity")
+	}
+}
+
+func TestOriginatingIdentityWithoutMiddleware(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	if identity, err := OriginatingIdentity(r); identity != nil || err != nil {
+		t.Errorf("Expected no identity, got %+v, %v", identity, err)
+	}
+
+	r.Header.Set(osb.OriginatingIdentityHeader, "cloudfoundry")
+	if _, err := OriginatingIdentity(r); err == nil {
+		t.Error("Expected an error for an invalid header")
+	}
+
+	r.Header.Set(osb.OriginatingIdentityHeader, cfIdentityHeader)
+	identity, err := RequestIdentity(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.CloudFoundry == nil || identity.CloudFoundry.UserID != "alice" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+}
+
+// BenchmarkOriginatingIdentity looks up the identity of a last operation
+// poll the way a server with a rate limiter, an authorizer and a lifecycle
+// hook does: four times per request.
+func BenchmarkOriginatingIdentity(b *testing.B) {
+	const lookups = 4
+	r := httptest.NewRequest(http.MethodGet, "/v2/service_instances/instance/last_operation", nil)
+	r.Header.Set(osb.OriginatingIdentityHeader, cfIdentityHeader)
+
+	lookup := func(b *testing.B, r *http.Request) {
+		for i := 0; i < lookups; i++ {
+			if _, err := RequestIdentity(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			lookup(b, r)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			lookup(b, withParsedIdentity(r))
+		}
+	})
+}
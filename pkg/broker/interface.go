@@ -1,8 +1,10 @@
 package broker
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 )
 
@@ -213,7 +215,77 @@ type Interface interface {
 // - a response writer, in case fine-grained control over the response is required
 // - the original http request, in case access is required (to get special
 //   request headers, for example)
+//
+// Writer should be treated as read-only: the APISurface writes the status
+// code and body after the business logic returns, so headers set directly on
+// Writer race with it. Use SetResponseHeader to add headers to the response.
 type RequestContext struct {
 	Writer  http.ResponseWriter
 	Request *http.Request
+	// BindResource is the bind_resource of a bind request. It is nil for
+	// other requests, or if the platform sent none.
+	BindResource *BindResource
+	// PreviousValues is the previous_values of an update request. It is nil
+	// for other requests, or if the platform sent none. See DiffUpdate.
+	PreviousValues *PreviousValues
+	// MaintenanceInfo is the maintenance_info of an update request, the
+	// version the instance should be upgraded to. It is nil for other
+	// requests, or if the platform sent none.
+	MaintenanceInfo *MaintenanceInfo
+
+	responseHeader http.Header
+	warnings       []string
+}
+
+// Context returns the context of the request, which is done when the
+// platform closes the connection or the request times out. Business logic
+// should pass it to the calls it makes, so that it stops working on requests
+// nobody will read the response of.
+func (c *RequestContext) Context() context.Context {
+	if c == nil || c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// Vars returns the variables of the route the request was served by, such
+// as instance_id and binding_id, so that extensions and hooks don't need to
+// parse the URL. The map is a copy; it is empty for requests that weren't
+// routed by the server's router.
+func (c *RequestContext) Vars() map[string]string {
+	vars := map[string]string{}
+	if c == nil || c.Request == nil {
+		return vars
+	}
+	for name, value := range mux.Vars(c.Request) {
+		vars[name] = value
+	}
+	return vars
+}
+
+// SetResponseHeader sets a header on the response to this request. The
+// header is applied by the APISurface when it writes the response, including
+// error responses.
+func (c *RequestContext) SetResponseHeader(key, value string) {
+	if c.responseHeader == nil {
+		c.responseHeader = http.Header{}
+	}
+	c.responseHeader.Set(key, value)
+}
+
+// AddWarning records a non-fatal warning about the request, such as the
+// deprecation of the plan it uses. Warnings are logged by the APISurface and,
+// if it has a WarningHeader, sent to the platform with the response.
+func (c *RequestContext) AddWarning(warning string) {
+	c.warnings = append(c.warnings, warning)
+}
+
+// Warnings returns the warnings added with AddWarning.
+func (c *RequestContext) Warnings() []string {
+	return c.warnings
+}
+
+// ResponseHeader returns the headers set with SetResponseHeader.
+func (c *RequestContext) ResponseHeader() http.Header {
+	return c.responseHeader
 }
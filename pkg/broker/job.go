@@ -0,0 +1,32 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+)
+
+// Job is asynchronous work run by the APISurface's job manager. Business
+// logic returns a Job in the Job field of a provision, update, deprovision or
+// bind response instead of managing the asynchronous operation itself; the
+// job manager assigns the operation key, runs the Job and answers last
+// operation requests for it.
+//
+// The context is cancelled when the job manager is closed. The progress func
+// sets the description surfaced verbatim in last operation responses while
+// the Job is running; ProgressDescription formats descriptions with a
+// completion percentage. A nil error completes the operation successfully; otherwise
+// the operation fails and the error is used as its description.
+type Job func(ctx context.Context, progress func(description string)) error
+
+// ProgressDescription returns a progress description for a step of a Job
+// with its completion percentage, such as "creating database 40%". The
+// percentage is clamped between 0 and 100.
+func ProgressDescription(step string, percent int) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return fmt.Sprintf("%s %d%%", step, percent)
+}
@@ -0,0 +1,23 @@
+package broker
+
+import "testing"
+
+func TestProgressDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     string
+		percent  int
+		expected string
+	}{
+		{name: "in range", step: "creating database", percent: 40, expected: "creating database 40%"},
+		{name: "negative", step: "starting", percent: -5, expected: "starting 0%"},
+		{name: "over 100", step: "finishing", percent: 120, expected: "finishing 100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.expected, ProgressDescription(tt.step, tt.percent); e != a {
+				t.Errorf("Unexpected description; expected %q, got %q", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// RequestIdentityHeader is the header platforms implementing OSB 2.15 set to
+// identify a request across the platform's and the broker's logs.
+const RequestIdentityHeader = "X-Broker-API-Request-Identity"
+
+// RequestIDHeader is the conventional request ID header used when the
+// platform sends no RequestIdentityHeader.
+const RequestIDHeader = "X-Request-Id"
+
+// Logger writes glog lines prefixed with the request ID, operation and
+// instance and binding IDs of a request, so the lines logged by business
+// logic can be correlated with the library's own. Its zero value logs
+// without a prefix.
+type Logger struct {
+	prefix   string
+	disabled bool
+}
+
+// NewRequestLogger returns the Logger of r. The operation is the name of
+// the route r matched.
+func NewRequestLogger(r *http.Request) Logger {
+	var fields []string
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+
+	requestID := r.Header.Get(RequestIdentityHeader)
+	if requestID == "" {
+		requestID = r.Header.Get(RequestIDHeader)
+	}
+	add("request_id", requestID)
+	if route := mux.CurrentRoute(r); route != nil {
+		add("operation", route.GetName())
+	}
+	vars := mux.Vars(r)
+	add("instance_id", vars[osb.VarKeyInstanceID])
+	add("binding_id", vars[osb.VarKeyBindingID])
+
+	if len(fields) == 0 {
+		return Logger{}
+	}
+	return Logger{prefix: "[" + strings.Join(fields, " ") + "] "}
+}
+
+// Logger returns the Logger of the request. See NewRequestLogger.
+func (c *RequestContext) Logger() Logger {
+	if c.Request == nil {
+		return Logger{}
+	}
+	return NewRequestLogger(c.Request)
+}
+
+// V returns a Logger that only logs if glog's verbosity is at least level.
+func (l Logger) V(level glog.Level) Logger {
+	l.disabled = l.disabled || !bool(glog.V(level))
+	return l
+}
+
+// Infof logs to the INFO log.
+func (l Logger) Infof(format string, args ...interface{}) {
+	if !l.disabled {
+		glog.InfoDepth(1, l.prefix+fmt.Sprintf(format, args...))
+	}
+}
+
+// Warningf logs to the WARNING and INFO logs.
+func (l Logger) Warningf(format string, args ...interface{}) {
+	if !l.disabled {
+		glog.WarningDepth(1, l.prefix+fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf logs to the ERROR, WARNING and INFO logs.
+func (l Logger) Errorf(format string, args ...interface{}) {
+	if !l.disabled {
+		glog.ErrorDepth(1, l.prefix+fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestNewRequestLogger(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		header   map[string]string
+		expected string
+	}{
+		{
+			name:     "binding",
+			path:     "/v2/service_instances/foo/service_bindings/bar",
+			header:   map[string]string{RequestIdentityHeader: "platform-id", RequestIDHeader: "proxy-id"},
+			expected: "[request_id=platform-id operation=bind instance_id=foo binding_id=bar] ",
+		},
+		{
+			name:     "request ID fallback",
+			path:     "/v2/service_instances/foo",
+			header:   map[string]string{RequestIDHeader: "proxy-id"},
+			expected: "[request_id=proxy-id operation=provision instance_id=foo] ",
+		},
+		{
+			name:     "unrouted",
+			path:     "/healthz",
+			expected: "",
+		},
+	}
+
+	for _, tc := range cases {
+		var logger Logger
+		router := mux.NewRouter()
+		capture := func(w http.ResponseWriter, r *http.Request) {
+			logger = (&RequestContext{Request: r}).Logger()
+		}
+		router.HandleFunc("/v2/service_instances/{instance_id}", capture).Name("provision")
+		router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", capture).Name("bind")
+		router.NotFoundHandler = http.HandlerFunc(capture)
+
+		r := httptest.NewRequest(http.MethodPut, tc.path, nil)
+		for k, v := range tc.header {
+			r.Header.Set(k, v)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		if e, a := tc.expected, logger.prefix; e != a {
+			t.Errorf("%v: unexpected prefix; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// operationKeySeparator separates the fields of a formatted OperationKey.
+const operationKeySeparator = ":"
+
+// OperationKey is a structured value for the opaque operation field of
+// asynchronous responses. It is formatted as "<type>:<token>" or, if it has
+// an expiry, "<type>:<token>:<unix seconds>", so that brokers can tell which
+// kind of operation a poll refers to and reject stale keys.
+type OperationKey struct {
+	// Type is the kind of operation, for example "provision". It must not
+	// be empty or contain a colon.
+	Type string
+	// Token identifies the operation. It must not be empty or contain a
+	// colon.
+	Token string
+	// Expires is when the key stops being valid. The zero value means the
+	// key never expires.
+	Expires time.Time
+}
+
+// NewOperationKey returns an OperationKey of the given type with a random
+// token. If ttl is positive, the key expires ttl from now.
+func NewOperationKey(operationType string, ttl time.Duration) (*OperationKey, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating operation token: %v", err)
+	}
+
+	key := &OperationKey{
+		Type:  operationType,
+		Token: hex.EncodeToString(token),
+	}
+	if ttl > 0 {
+		key.Expires = time.Now().Add(ttl).Truncate(time.Second)
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParseOperationKey parses an operation key formatted by OperationKey.String.
+func ParseOperationKey(s string) (*OperationKey, error) {
+	parts := strings.Split(s, operationKeySeparator)
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("invalid operation key %q: expected type:token[:expiry]", s)
+	}
+
+	key := &OperationKey{
+		Type:  parts[0],
+		Token: parts[1],
+	}
+	if len(parts) == 3 {
+		expires, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation key %q: invalid expiry: %v", s, err)
+		}
+		key.Expires = time.Unix(expires, 0)
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParseOSBOperationKey parses the operation key sent by a platform in a last
+// operation request. It returns an error if the key is nil.
+func ParseOSBOperationKey(key *osb.OperationKey) (*OperationKey, error) {
+	if key == nil {
+		return nil, fmt.Errorf("no operation key in request")
+	}
+	return ParseOperationKey(string(*key))
+}
+
+// Validate returns an error if the key can't be formatted unambiguously.
+func (k *OperationKey) Validate() error {
+	if k.Type == "" || strings.Contains(k.Type, operationKeySeparator) {
+		return fmt.Errorf("invalid operation key type %q", k.Type)
+	}
+	if k.Token == "" || strings.Contains(k.Token, operationKeySeparator) {
+		return fmt.Errorf("invalid operation key token %q", k.Token)
+	}
+	return nil
+}
+
+// Expired returns whether the key has expired at the given time.
+func (k *OperationKey) Expired(now time.Time) bool {
+	return !k.Expires.IsZero() && now.After(k.Expires)
+}
+
+// String formats the key.
+func (k *OperationKey) String() string {
+	s := k.Type + operationKeySeparator + k.Token
+	if !k.Expires.IsZero() {
+		s += operationKeySeparator + strconv.FormatInt(k.Expires.Unix(), 10)
+	}
+	return s
+}
+
+// OSB returns the key as the value of the operation field of an OSB
+// response.
+func (k *OperationKey) OSB() *osb.OperationKey {
+	key := osb.OperationKey(k.String())
+	return &key
+}
@@ -0,0 +1,90 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestOperationKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{name: "no expiry"},
+		{name: "expiry", ttl: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewOperationKey("provision", tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := ParseOSBOperationKey(key.OSB())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e, a := key.String(), parsed.String(); e != a {
+				t.Errorf("Unexpected key; expected %v, got %v", e, a)
+			}
+			if parsed.Expired(time.Now()) {
+				t.Error("Expected key not to have expired")
+			}
+			if e, a := tt.ttl > 0, parsed.Expired(time.Now().Add(2*time.Hour)); e != a {
+				t.Errorf("Unexpected expiry; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseOperationKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		want    *OperationKey
+		wantErr bool
+	}{
+		{
+			name: "type and token",
+			key:  "bind:abc",
+			want: &OperationKey{Type: "bind", Token: "abc"},
+		},
+		{
+			name: "with expiry",
+			key:  "bind:abc:60",
+			want: &OperationKey{Type: "bind", Token: "abc", Expires: time.Unix(60, 0)},
+		},
+		{name: "no token", key: "bind", wantErr: true},
+		{name: "empty type", key: ":abc", wantErr: true},
+		{name: "empty token", key: "bind:", wantErr: true},
+		{name: "invalid expiry", key: "bind:abc:tomorrow", wantErr: true},
+		{name: "too many fields", key: "bind:abc:60:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOperationKey(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e, a := tt.want.String(), got.String(); e != a {
+				t.Errorf("Unexpected key; expected %v, got %v", e, a)
+			}
+		})
+	}
+
+	if _, err := ParseOSBOperationKey(nil); err == nil {
+		t.Error("Expected an error for a nil key")
+	}
+	key := osb.OperationKey("bind:abc")
+	if _, err := ParseOSBOperationKey(&key); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package broker
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// Purger is an optional interface of business logic that can forget a
+// service instance whose backend resources are already gone. When a
+// deprovision request carries purge=true, the APISurface calls Purge instead
+// of Deprovision. Purge should remove the broker's records of the instance
+// and its bindings without attempting to clean up the backend. Purge
+// requests to business logic that doesn't implement Purger fail with a 501.
+//
+// Platforms never send purge requests; they are meant for operators
+// cleaning up after backends destroyed out of band.
+type Purger interface {
+	Purge(request *osb.DeprovisionRequest, c *RequestContext) (*DeprovisionResponse, error)
+}
@@ -0,0 +1,22 @@
+package broker
+
+// ReloadAware is an optional interface of business logic that applies
+// configuration reloaded while the broker is running, for example after a
+// SIGHUP. Reload is called after the library has applied the settings it
+// owns; an error is reported to whoever triggered the reload and leaves the
+// business logic's previous configuration in place.
+type ReloadAware interface {
+	Reload(c *ReloadConfig) error
+}
+
+// ReloadConfig is the reloaded configuration passed to ReloadAware business
+// logic.
+type ReloadConfig struct {
+	// LogLevel is the glog verbosity.
+	LogLevel int
+	// Maintenance is whether the broker is in read-only mode.
+	Maintenance bool
+	// Catalog is the catalog read from the configured catalog file, or nil
+	// when no catalog file is configured.
+	Catalog *CatalogResponse
+}
@@ -0,0 +1,98 @@
+package broker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// The permissions a service can require in the requires field of its catalog
+// entry.
+const (
+	RequiresSyslogDrain     = "syslog_drain"
+	RequiresRouteForwarding = "route_forwarding"
+	RequiresVolumeMount     = "volume_mount"
+)
+
+// Requires returns whether the service requires the given permission.
+func Requires(service *osb.Service, permission string) bool {
+	for _, p := range service.Requires {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteURL returns the URL of the route a route service is bound to, or an
+// empty string if the binding is not for a route.
+func RouteURL(request *osb.BindRequest) string {
+	if request.BindResource == nil || request.BindResource.Route == nil {
+		return ""
+	}
+	return *request.BindResource.Route
+}
+
+// ValidateRouteService returns an error if the service is a route service,
+// one requiring route_forwarding, without a bindable plan.
+func ValidateRouteService(service *osb.Service) error {
+	if !Requires(service, RequiresRouteForwarding) {
+		return nil
+	}
+	if service.Bindable {
+		return nil
+	}
+	for _, plan := range service.Plans {
+		if plan.Bindable != nil && *plan.Bindable {
+			return nil
+		}
+	}
+	return fmt.Errorf("route service %q has no bindable plan", service.ID)
+}
+
+// ValidateRouteBinding returns a 400 error if a bind request for an instance
+// of the service binds a route while the service doesn't require
+// route_forwarding, or binds a malformed route; and a 422 error if the
+// service is a route service and no route is bound.
+func ValidateRouteBinding(service *osb.Service, request *osb.BindRequest) error {
+	route := RouteURL(request)
+	routeService := Requires(service, RequiresRouteForwarding)
+
+	switch {
+	case route == "" && routeService:
+		return osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusUnprocessableEntity,
+			Description: strPtr(fmt.Sprintf("Service %q is a route service and can only be bound to a route.", service.ID)),
+		}
+	case route != "" && !routeService:
+		return osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr(fmt.Sprintf("Service %q does not require route_forwarding and can't be bound to a route.", service.ID)),
+		}
+	case route != "":
+		if !validRoute(route) {
+			return osb.HTTPStatusCodeError{
+				StatusCode:  http.StatusBadRequest,
+				Description: strPtr(fmt.Sprintf("Invalid route %q.", route)),
+			}
+		}
+	}
+	return nil
+}
+
+// validRoute returns whether route is a URL with a host. Platforms usually
+// send routes without a scheme, such as app.example.com/path.
+func validRoute(route string) bool {
+	if !strings.Contains(route, "://") {
+		route = "//" + route
+	}
+	u, err := url.Parse(route)
+	return err == nil && u.Host != ""
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,79 @@
+package broker
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestValidateRouteBinding(t *testing.T) {
+	routeService := &osb.Service{ID: "router", Requires: []string{RequiresRouteForwarding}, Bindable: true}
+	plain := &osb.Service{ID: "db", Bindable: true}
+	bindTo := func(route string) *osb.BindRequest {
+		request := &osb.BindRequest{}
+		if route != "" {
+			request.BindResource = &osb.BindResource{Route: &route}
+		}
+		return request
+	}
+
+	tests := []struct {
+		name     string
+		service  *osb.Service
+		request  *osb.BindRequest
+		expected int
+	}{
+		{name: "route service bound to route", service: routeService, request: bindTo("app.example.com/path")},
+		{name: "route with scheme", service: routeService, request: bindTo("https://app.example.com")},
+		{name: "route service without route", service: routeService, request: bindTo(""), expected: http.StatusUnprocessableEntity},
+		{name: "plain service bound to route", service: plain, request: bindTo("app.example.com"), expected: http.StatusBadRequest},
+		{name: "malformed route", service: routeService, request: bindTo("/path"), expected: http.StatusBadRequest},
+		{name: "plain service", service: plain, request: bindTo("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRouteBinding(tt.service, tt.request)
+			status := 0
+			if httpErr, ok := osb.IsHTTPError(err); ok {
+				status = httpErr.StatusCode
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if e, a := tt.expected, status; e != a {
+				t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestRouteURL(t *testing.T) {
+	if e, a := "", RouteURL(&osb.BindRequest{}); e != a {
+		t.Errorf("Unexpected route; expected %q, got %q", e, a)
+	}
+	route := "app.example.com"
+	if e, a := route, RouteURL(&osb.BindRequest{BindResource: &osb.BindResource{Route: &route}}); e != a {
+		t.Errorf("Unexpected route; expected %q, got %q", e, a)
+	}
+}
+
+func TestValidateRouteService(t *testing.T) {
+	bindable := true
+	tests := []struct {
+		name    string
+		service *osb.Service
+		valid   bool
+	}{
+		{name: "not a route service", service: &osb.Service{ID: "db"}, valid: true},
+		{name: "bindable", service: &osb.Service{ID: "router", Requires: []string{RequiresRouteForwarding}, Bindable: true}, valid: true},
+		{name: "bindable plan", service: &osb.Service{ID: "router", Requires: []string{RequiresRouteForwarding}, Plans: []osb.Plan{{Bindable: &bindable}}}, valid: true},
+		{name: "not bindable", service: &osb.Service{ID: "router", Requires: []string{RequiresRouteForwarding}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.valid, ValidateRouteService(tt.service) == nil; e != a {
+				t.Errorf("Unexpected validity; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
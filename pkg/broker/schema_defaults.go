@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"encoding/json"
+)
+
+// ApplyParameterDefaults returns parameters with the defaults declared by a
+// plan's JSON schema filled in: every property of the schema with a default
+// that is missing from parameters is set to a copy of the default, at any
+// depth of nested objects present in parameters. schema is usually the
+// Parameters of an osb.InputParameters. parameters may be nil; it is
+// modified in place otherwise.
+func ApplyParameterDefaults(schema interface{}, parameters map[string]interface{}) map[string]interface{} {
+	object := schemaObject(schema)
+	if object == nil {
+		return parameters
+	}
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+	applyDefaults(object, parameters)
+	if len(parameters) == 0 {
+		return nil
+	}
+	return parameters
+}
+
+func applyDefaults(schema map[string]interface{}, parameters map[string]interface{}) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, property := range properties {
+		propertySchema, ok := property.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, present := parameters[name]
+		if !present {
+			def, ok := propertySchema["default"]
+			if !ok {
+				continue
+			}
+			value = copyJSON(def)
+			parameters[name] = value
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			applyDefaults(propertySchema, nested)
+		}
+	}
+}
+
+// schemaObject returns schema as a decoded JSON object, or nil if it isn't
+// one.
+func schemaObject(schema interface{}) map[string]interface{} {
+	if object, ok := schema.(map[string]interface{}); ok {
+		return object
+	}
+	if schema == nil {
+		return nil
+	}
+	object, _ := copyJSON(schema).(map[string]interface{})
+	return object
+}
+
+// copyJSON returns a deep copy of v as a decoded JSON value.
+func copyJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var copied interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil
+	}
+	return copied
+}
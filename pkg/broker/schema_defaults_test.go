@@ -0,0 +1,94 @@
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+	"$schema": "http://json-schema.org/draft-04/schema#",
+	"type": "object",
+	"properties": {
+		"size": {"type": "string", "default": "small"},
+		"replicas": {"type": "integer", "default": 1},
+		"tags": {"type": "array", "default": ["a"]},
+		"backup": {
+			"type": "object",
+			"properties": {
+				"enabled": {"type": "boolean", "default": true},
+				"retention": {"type": "string"}
+			}
+		},
+		"network": {
+			"type": "object",
+			"default": {"public": false},
+			"properties": {
+				"cidr": {"type": "string", "default": "10.0.0.0/16"}
+			}
+		}
+	}
+}`
+
+func TestApplyParameterDefaults(t *testing.T) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		schema     interface{}
+		parameters string
+		expected   string
+	}{
+		{
+			name:       "no parameters",
+			schema:     schema,
+			parameters: `null`,
+			expected:   `{"size":"small","replicas":1,"tags":["a"],"network":{"public":false,"cidr":"10.0.0.0/16"}}`,
+		},
+		{
+			name:       "explicit values win",
+			schema:     schema,
+			parameters: `{"size":"large","replicas":0,"backup":{},"network":{"cidr":"192.168.0.0/24"}}`,
+			expected:   `{"size":"large","replicas":0,"tags":["a"],"backup":{"enabled":true},"network":{"cidr":"192.168.0.0/24"}}`,
+		},
+		{
+			name:       "no schema",
+			parameters: `{"size":"large"}`,
+			expected:   `{"size":"large"}`,
+		},
+		{
+			name:       "schema without defaults",
+			schema:     map[string]interface{}{"type": "object"},
+			parameters: `null`,
+			expected:   `null`,
+		},
+	}
+
+	for _, tc := range cases {
+		var parameters, expected map[string]interface{}
+		json.Unmarshal([]byte(tc.parameters), &parameters)
+		json.Unmarshal([]byte(tc.expected), &expected)
+
+		if e, a := expected, ApplyParameterDefaults(tc.schema, parameters); !reflect.DeepEqual(e, a) {
+			t.Errorf("%v: unexpected parameters; expected %v, got %v", tc.name, e, a)
+		}
+	}
+}
+
+func TestApplyParameterDefaultsCopiesDefaults(t *testing.T) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	parameters := ApplyParameterDefaults(schema, nil)
+	parameters["tags"].([]interface{})[0] = "changed"
+
+	parameters = ApplyParameterDefaults(schema, nil)
+	if e, a := "a", parameters["tags"].([]interface{})[0]; e != a {
+		t.Errorf("Expected the schema's default to be left untouched; expected %v, got %v", e, a)
+	}
+}
@@ -1,10 +1,18 @@
 package broker
 
-import osb "github.com/pmorie/go-open-service-broker-client/v2"
+import (
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
 
 // CatalogResponse is sent as the response to a catalog requests.
 type CatalogResponse struct {
 	osb.CatalogResponse
+
+	// ExtensionAPIs are the extension_apis advertised in the catalog, by
+	// service ID.
+	ExtensionAPIs map[string][]ExtensionAPI `json:"-"`
 }
 
 // ProvisionResponse is sent as the response to a provision call.
@@ -15,21 +23,38 @@ type ProvisionResponse struct {
 	// and the requested parameters are identical to the existing
 	// Service Instance.
 	Exists bool `json:"-"`
+
+	// Job, if set, is run asynchronously by the APISurface's job manager,
+	// which answers the request with 202 and an operation key.
+	Job Job `json:"-"`
 }
 
 // UpdateInstanceResponse is sent as the response to a update call.
 type UpdateInstanceResponse struct {
 	osb.UpdateInstanceResponse
+
+	// Job, if set, is run asynchronously by the APISurface's job manager,
+	// which answers the request with 202 and an operation key.
+	Job Job `json:"-"`
 }
 
 // DeprovisionResponse is sent as the response to a deprovision call.
 type DeprovisionResponse struct {
 	osb.DeprovisionResponse
+
+	// Job, if set, is run asynchronously by the APISurface's job manager,
+	// which answers the request with 202 and an operation key.
+	Job Job `json:"-"`
 }
 
 // LastOperationResponse is sent as the response to a last operation call.
 type LastOperationResponse struct {
 	osb.LastOperationResponse
+
+	// RetryAfter, if set while the operation is in progress, suggests how
+	// long the platform should wait before polling again. It is sent in the
+	// Retry-After header.
+	RetryAfter time.Duration `json:"-"`
 }
 
 // BindResponse is sent as the response to a bind call.
@@ -40,11 +65,31 @@ type BindResponse struct {
 	// and the requested parameters are identical to the existing
 	// Service Binding.
 	Exists bool `json:"-"`
+
+	// Metadata, if set, tells the platform when the binding's credentials
+	// expire.
+	Metadata *BindingMetadata `json:"metadata,omitempty"`
+
+	// Endpoints are the network endpoints the application needs to reach
+	// to use the binding, so platforms can configure network policy.
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+
+	// Job, if set, is run asynchronously by the APISurface's job manager,
+	// which answers the request with 202 and an operation key.
+	Job Job `json:"-"`
 }
 
 // GetBinding is sent as the response to a get binding call.
 type GetBindingResponse struct {
 	osb.GetBindingResponse
+
+	// Metadata, if set, tells the platform when the binding's credentials
+	// expire.
+	Metadata *BindingMetadata `json:"metadata,omitempty"`
+
+	// Endpoints are the network endpoints the application needs to reach
+	// to use the binding, so platforms can configure network policy.
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
 }
 
 // UnbindResponse is sent as the response to a bind call.
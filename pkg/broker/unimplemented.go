@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"fmt"
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// Unimplemented can be embedded in business logic that implements only part
+// of Interface. Every method except ValidateBrokerAPIVersion returns a 501
+// Not Implemented error naming the operation, so a minimal broker only has
+// to implement GetCatalog, Provision and Deprovision:
+//
+//	type logic struct {
+//		broker.Unimplemented
+//	}
+//
+// ValidateBrokerAPIVersion accepts every version.
+type Unimplemented struct{}
+
+var _ Interface = Unimplemented{}
+
+// NotSupportedErrorMessage is the error code of the errors returned by
+// Unimplemented.
+const NotSupportedErrorMessage = "NotSupported"
+
+// NewNotSupportedError returns the OSB error for an operation the broker
+// does not support.
+func NewNotSupportedError(operation string) error {
+	description := fmt.Sprintf("The broker does not support %s.", operation)
+	errorMessage := NotSupportedErrorMessage
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusNotImplemented,
+		ErrorMessage: &errorMessage,
+		Description:  &description,
+	}
+}
+
+// ValidateBrokerAPIVersion accepts every API version.
+func (Unimplemented) ValidateBrokerAPIVersion(version string) error {
+	return nil
+}
+
+// GetCatalog returns a not supported error.
+func (Unimplemented) GetCatalog(c *RequestContext) (*CatalogResponse, error) {
+	return nil, NewNotSupportedError("fetching the catalog")
+}
+
+// Provision returns a not supported error.
+func (Unimplemented) Provision(request *osb.ProvisionRequest, c *RequestContext) (*ProvisionResponse, error) {
+	return nil, NewNotSupportedError("provisioning")
+}
+
+// Deprovision returns a not supported error.
+func (Unimplemented) Deprovision(request *osb.DeprovisionRequest, c *RequestContext) (*DeprovisionResponse, error) {
+	return nil, NewNotSupportedError("deprovisioning")
+}
+
+// LastOperation returns a not supported error.
+func (Unimplemented) LastOperation(request *osb.LastOperationRequest, c *RequestContext) (*LastOperationResponse, error) {
+	return nil, NewNotSupportedError("asynchronous instance operations")
+}
+
+// Bind returns a not supported error.
+func (Unimplemented) Bind(request *osb.BindRequest, c *RequestContext) (*BindResponse, error) {
+	return nil, NewNotSupportedError("binding")
+}
+
+// GetBinding returns a not supported error.
+func (Unimplemented) GetBinding(request *osb.GetBindingRequest, c *RequestContext) (*GetBindingResponse, error) {
+	return nil, NewNotSupportedError("fetching bindings")
+}
+
+// BindingLastOperation returns a not supported error.
+func (Unimplemented) BindingLastOperation(request *osb.BindingLastOperationRequest, c *RequestContext) (*LastOperationResponse, error) {
+	return nil, NewNotSupportedError("asynchronous binding operations")
+}
+
+// Unbind returns a not supported error.
+func (Unimplemented) Unbind(request *osb.UnbindRequest, c *RequestContext) (*UnbindResponse, error) {
+	return nil, NewNotSupportedError("unbinding")
+}
+
+// Update returns a not supported error.
+func (Unimplemented) Update(request *osb.UpdateInstanceRequest, c *RequestContext) (*UpdateInstanceResponse, error) {
+	return nil, NewNotSupportedError("updating instances")
+}
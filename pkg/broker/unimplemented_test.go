@@ -0,0 +1,44 @@
+package broker_test
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+type minimalBroker struct {
+	broker.Unimplemented
+}
+
+func (minimalBroker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+	return &broker.CatalogResponse{}, nil
+}
+
+func TestUnimplemented(t *testing.T) {
+	s := brokertest.NewServer(t, minimalBroker{})
+
+	if _, err := s.Client.GetCatalog(); err != nil {
+		t.Fatalf("Expected the implemented GetCatalog to succeed, got %v", err)
+	}
+
+	_, err := s.Client.Bind(&osb.BindRequest{
+		InstanceID: "instance",
+		BindingID:  "binding",
+		ServiceID:  "service",
+		PlanID:     "plan",
+	})
+	httpErr, ok := osb.IsHTTPError(err)
+	if !ok {
+		t.Fatalf("Expected an HTTP error, got %v", err)
+	}
+	if e, a := http.StatusNotImplemented, httpErr.StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if httpErr.ErrorMessage == nil || *httpErr.ErrorMessage != broker.NotSupportedErrorMessage {
+		t.Errorf("Unexpected error message; expected %v, got %v", broker.NotSupportedErrorMessage, httpErr.ErrorMessage)
+	}
+}
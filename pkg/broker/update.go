@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"reflect"
+	"sort"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// MaintenanceInfo is the maintenance_info of a plan or service instance,
+// identifying the version of the software the instance runs.
+type MaintenanceInfo struct {
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PreviousValues is the previous_values of an update request, the state of
+// the instance before the update, decoded with all the fields of the spec.
+// The APISurface passes it to the business logic in the RequestContext of
+// update requests.
+type PreviousValues struct {
+	// ServiceID is the ID of the instance's service.
+	ServiceID string `json:"service_id,omitempty"`
+	// PlanID is the ID of the instance's plan before the update.
+	PlanID string `json:"plan_id,omitempty"`
+	// OrganizationID is the ID of the instance's organization. Platforms
+	// send it in the context instead.
+	OrganizationID string `json:"organization_id,omitempty"`
+	// SpaceID is the ID of the instance's space. Platforms send it in the
+	// context instead.
+	SpaceID string `json:"space_id,omitempty"`
+	// MaintenanceInfo is the instance's maintenance_info before the
+	// update.
+	MaintenanceInfo *MaintenanceInfo `json:"maintenance_info,omitempty"`
+}
+
+// UpdateDiff is what an update request changes.
+type UpdateDiff struct {
+	// PlanChanged is whether the request moves the instance to another
+	// plan. PreviousPlanID is empty if the platform didn't send it.
+	PlanChanged    bool
+	PreviousPlanID string
+	PlanID         string
+	// Parameters are the names of the parameters the request sets, sorted.
+	Parameters []string
+	// MaintenanceInfoChanged is whether the request upgrades the instance
+	// to another maintenance_info.
+	MaintenanceInfoChanged  bool
+	PreviousMaintenanceInfo *MaintenanceInfo
+	MaintenanceInfo         *MaintenanceInfo
+}
+
+// Changed returns whether the update changes anything.
+func (d *UpdateDiff) Changed() bool {
+	return d.PlanChanged || len(d.Parameters) > 0 || d.MaintenanceInfoChanged
+}
+
+// DiffUpdate returns what request changes, comparing it to the previous
+// values and maintenance_info the APISurface passed in c. Platforms only send
+// the plan and the parameters being changed, so a plan is considered
+// changed whenever it differs from the previous plan or the previous plan is
+// unknown.
+func DiffUpdate(request *osb.UpdateInstanceRequest, c *RequestContext) *UpdateDiff {
+	diff := &UpdateDiff{}
+
+	previous := c.PreviousValues
+	if previous == nil && request.PreviousValues != nil {
+		previous = &PreviousValues{PlanID: request.PreviousValues.PlanID}
+	}
+	if previous != nil {
+		diff.PreviousPlanID = previous.PlanID
+		diff.PreviousMaintenanceInfo = previous.MaintenanceInfo
+	}
+
+	diff.PlanID = diff.PreviousPlanID
+	if request.PlanID != nil && *request.PlanID != "" {
+		diff.PlanID = *request.PlanID
+		diff.PlanChanged = diff.PlanID != diff.PreviousPlanID
+	}
+
+	for name := range request.Parameters {
+		diff.Parameters = append(diff.Parameters, name)
+	}
+	sort.Strings(diff.Parameters)
+
+	diff.MaintenanceInfo = c.MaintenanceInfo
+	if diff.MaintenanceInfo != nil {
+		diff.MaintenanceInfoChanged = !reflect.DeepEqual(diff.MaintenanceInfo, diff.PreviousMaintenanceInfo)
+	}
+
+	return diff
+}
@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestDiffUpdate(t *testing.T) {
+	large := "large"
+	small := "small"
+	v1 := &MaintenanceInfo{Version: "1.0.0"}
+	v2 := &MaintenanceInfo{Version: "2.0.0"}
+
+	cases := []struct {
+		name     string
+		request  *osb.UpdateInstanceRequest
+		c        *RequestContext
+		expected *UpdateDiff
+		changed  bool
+	}{
+		{
+			name:     "plan change",
+			request:  &osb.UpdateInstanceRequest{PlanID: &large},
+			c:        &RequestContext{PreviousValues: &PreviousValues{PlanID: "small"}},
+			expected: &UpdateDiff{PlanChanged: true, PreviousPlanID: "small", PlanID: "large"},
+			changed:  true,
+		},
+		{
+			name:     "same plan",
+			request:  &osb.UpdateInstanceRequest{PlanID: &small},
+			c:        &RequestContext{PreviousValues: &PreviousValues{PlanID: "small"}},
+			expected: &UpdateDiff{PreviousPlanID: "small", PlanID: "small"},
+		},
+		{
+			name:     "unknown previous plan",
+			request:  &osb.UpdateInstanceRequest{PlanID: &large},
+			c:        &RequestContext{},
+			expected: &UpdateDiff{PlanChanged: true, PlanID: "large"},
+			changed:  true,
+		},
+		{
+			name:     "client previous values",
+			request:  &osb.UpdateInstanceRequest{PreviousValues: &osb.PreviousValues{PlanID: "small"}, Parameters: map[string]interface{}{"b": 1, "a": 2}},
+			c:        &RequestContext{},
+			expected: &UpdateDiff{PreviousPlanID: "small", PlanID: "small", Parameters: []string{"a", "b"}},
+			changed:  true,
+		},
+		{
+			name:     "upgrade",
+			request:  &osb.UpdateInstanceRequest{},
+			c:        &RequestContext{PreviousValues: &PreviousValues{MaintenanceInfo: v1}, MaintenanceInfo: v2},
+			expected: &UpdateDiff{MaintenanceInfoChanged: true, PreviousMaintenanceInfo: v1, MaintenanceInfo: v2},
+			changed:  true,
+		},
+		{
+			name:     "same maintenance info",
+			request:  &osb.UpdateInstanceRequest{},
+			c:        &RequestContext{PreviousValues: &PreviousValues{MaintenanceInfo: v1}, MaintenanceInfo: &MaintenanceInfo{Version: "1.0.0"}},
+			expected: &UpdateDiff{PreviousMaintenanceInfo: v1, MaintenanceInfo: &MaintenanceInfo{Version: "1.0.0"}},
+		},
+	}
+
+	for _, tc := range cases {
+		diff := DiffUpdate(tc.request, tc.c)
+		if e, a := tc.expected, diff; !reflect.DeepEqual(e, a) {
+			t.Errorf("%v: unexpected diff; expected %+v, got %+v", tc.name, e, a)
+		}
+		if e, a := tc.changed, diff.Changed(); e != a {
+			t.Errorf("%v: unexpected Changed; expected %v, got %v", tc.name, e, a)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package broker
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 )
@@ -42,6 +44,30 @@ func parseCloudFoundryIdentity(o osb.OriginatingIdentity) (*CloudFoundryUserInfo
 	return &c, nil
 }
 
+// ParseIdentityHeader - parses the value of the originating identity header,
+// which is made of the platform name and the base64 encoded identity value
+// separated by whitespace
+func ParseIdentityHeader(header string) (*osb.OriginatingIdentity, error) {
+	identitySlice := strings.Fields(header)
+	if len(identitySlice) != 2 {
+		return nil, fmt.Errorf("invalid originating identity header")
+	}
+	// Base64 decode the value string so the value is passed as valid JSON.
+	// Platforms are expected to pad the value, but unpadded values are
+	// accepted too.
+	val, err := base64.StdEncoding.DecodeString(identitySlice[1])
+	if err != nil {
+		val, err = base64.RawStdEncoding.DecodeString(identitySlice[1])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding for value of originating identity header")
+	}
+	return &osb.OriginatingIdentity{
+		Platform: identitySlice[0],
+		Value:    string(val),
+	}, nil
+}
+
 // ParseIdentity - retrieve the identity union type
 func ParseIdentity(o osb.OriginatingIdentity) (Identity, error) {
 	identity := Identity{Platform: o.Platform}
@@ -0,0 +1,33 @@
+package broker
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// ProvisionValidator is an optional interface of business logic that can
+// check a provision request without creating anything, for platforms'
+// pre-flight checks. When a provision request carries validate_only=true,
+// the APISurface checks the service and plan against the catalog and calls
+// ValidateProvision instead of Provision. Errors are written to the platform
+// as for Provision; osb.HTTPStatusCodeErrors such as a 422 describe why the
+// request would fail.
+type ProvisionValidator interface {
+	ValidateProvision(request *osb.ProvisionRequest, c *RequestContext) (*ValidateProvisionResponse, error)
+}
+
+// ValidateProvisionResponse describes what a provision request would do.
+type ValidateProvisionResponse struct {
+	// Valid is set by the APISurface once the request passed validation.
+	Valid bool `json:"valid"`
+	// ServiceID and PlanID are the service and plan of the request.
+	ServiceID string `json:"service_id"`
+	PlanID    string `json:"plan_id"`
+	// Async is whether the instance would be provisioned asynchronously.
+	Async bool `json:"async,omitempty"`
+	// Exists is whether an identical instance already exists.
+	Exists bool `json:"exists,omitempty"`
+	// Warnings are problems that wouldn't fail the request.
+	Warnings []string `json:"warnings,omitempty"`
+	// Details are broker-specific details of what would be provisioned.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+// The modes of a VolumeMount.
+const (
+	VolumeModeReadOnly  = "r"
+	VolumeModeReadWrite = "rw"
+)
+
+// VolumeDeviceTypeShared is the only device type defined by the spec: a
+// volume shared by every instance of the application.
+const VolumeDeviceTypeShared = "shared"
+
+// VolumeMount is an entry of the volume_mounts of a bind response. It can be
+// added to the VolumeMounts of a BindResponse as is.
+type VolumeMount struct {
+	// Driver is the name of the volume driver plugin.
+	Driver string `json:"driver"`
+	// ContainerDir is the absolute path the volume is mounted at.
+	ContainerDir string `json:"container_dir"`
+	// Mode is VolumeModeReadOnly or VolumeModeReadWrite.
+	Mode string `json:"mode"`
+	// DeviceType is VolumeDeviceTypeShared.
+	DeviceType string `json:"device_type"`
+	// Device describes the volume.
+	Device VolumeDevice `json:"device"`
+}
+
+// VolumeDevice is the device of a VolumeMount.
+type VolumeDevice struct {
+	// VolumeID identifies the volume for the driver.
+	VolumeID string `json:"volume_id"`
+	// MountConfig is driver-specific configuration.
+	MountConfig map[string]interface{} `json:"mount_config,omitempty"`
+}
+
+// Validate returns an error if a required field is missing or a field has
+// a value the spec doesn't allow.
+func (m *VolumeMount) Validate() error {
+	switch {
+	case m.Driver == "":
+		return fmt.Errorf("volume mount has no driver")
+	case m.ContainerDir == "":
+		return fmt.Errorf("volume mount has no container_dir")
+	case !path.IsAbs(m.ContainerDir):
+		return fmt.Errorf("volume mount container_dir %q is not absolute", m.ContainerDir)
+	case m.Mode != VolumeModeReadOnly && m.Mode != VolumeModeReadWrite:
+		return fmt.Errorf("volume mount %q has mode %q; expected %q or %q", m.ContainerDir, m.Mode, VolumeModeReadOnly, VolumeModeReadWrite)
+	case m.DeviceType != VolumeDeviceTypeShared:
+		return fmt.Errorf("volume mount %q has device_type %q; expected %q", m.ContainerDir, m.DeviceType, VolumeDeviceTypeShared)
+	case m.Device.VolumeID == "":
+		return fmt.Errorf("volume mount %q has no device volume_id", m.ContainerDir)
+	}
+	return nil
+}
+
+// ValidateVolumeMounts returns an error if an entry of the volume_mounts of
+// a bind response isn't a valid VolumeMount. Entries may be VolumeMounts or
+// any value encoding to one in JSON.
+func ValidateVolumeMounts(mounts []interface{}) error {
+	for i, entry := range mounts {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("volume mount %d: %v", i, err)
+		}
+		mount := &VolumeMount{}
+		if err := json.Unmarshal(data, mount); err != nil {
+			return fmt.Errorf("volume mount %d is malformed: %v", i, err)
+		}
+		if err := mount.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package broker
+
+import "testing"
+
+func TestValidateVolumeMounts(t *testing.T) {
+	valid := VolumeMount{
+		Driver:       "nfsv3driver",
+		ContainerDir: "/data",
+		Mode:         VolumeModeReadWrite,
+		DeviceType:   VolumeDeviceTypeShared,
+		Device:       VolumeDevice{VolumeID: "volume"},
+	}
+	with := func(f func(m *VolumeMount)) VolumeMount {
+		m := valid
+		f(&m)
+		return m
+	}
+
+	tests := []struct {
+		name  string
+		mount interface{}
+		valid bool
+	}{
+		{name: "typed", mount: valid, valid: true},
+		{name: "untyped", mount: map[string]interface{}{
+			"driver":        "nfsv3driver",
+			"container_dir": "/data",
+			"mode":          "r",
+			"device_type":   "shared",
+			"device":        map[string]interface{}{"volume_id": "volume"},
+		}, valid: true},
+		{name: "no driver", mount: with(func(m *VolumeMount) { m.Driver = "" })},
+		{name: "relative dir", mount: with(func(m *VolumeMount) { m.ContainerDir = "data" })},
+		{name: "bad mode", mount: with(func(m *VolumeMount) { m.Mode = "read-write" })},
+		{name: "bad device type", mount: with(func(m *VolumeMount) { m.DeviceType = "exclusive" })},
+		{name: "no volume id", mount: with(func(m *VolumeMount) { m.Device.VolumeID = "" })},
+		{name: "not an object", mount: "nfs://server/data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e, a := tt.valid, ValidateVolumeMounts([]interface{}{tt.mount}) == nil; e != a {
+				t.Errorf("Unexpected validity; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+// Package memory provides an in-memory implementation of broker.Interface.
+// It keeps its catalog, instances and bindings in memory and can simulate
+// asynchronous operations, which makes it a runnable starting point for new
+// brokers and a realistic fixture for tests.
+package memory
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// Instance is a service instance stored by the Broker.
+type Instance struct {
+	ID         string
+	ServiceID  string
+	PlanID     string
+	Parameters map[string]interface{}
+	Context    map[string]interface{}
+
+	operation *operation
+}
+
+// Binding is a service binding stored by the Broker.
+type Binding struct {
+	ID          string
+	InstanceID  string
+	ServiceID   string
+	PlanID      string
+	Parameters  map[string]interface{}
+	Credentials map[string]interface{}
+}
+
+// operation is a simulated asynchronous operation on an instance.
+type operation struct {
+	key         osb.OperationKey
+	deprovision bool
+	completeAt  time.Time
+}
+
+// Broker is an in-memory broker.Interface. When AsyncDelay is set,
+// provision, update and deprovision requests that accept incomplete
+// operations are answered asynchronously and complete after AsyncDelay;
+// requests that don't are rejected with the spec's AsyncRequired error.
+//
+// The zero value is not usable; use New.
+type Broker struct {
+	// Services is the catalog served by the broker.
+	Services []osb.Service
+	// AsyncDelay is the duration of simulated asynchronous operations. Zero
+	// makes every operation synchronous.
+	AsyncDelay time.Duration
+
+	mutex     sync.Mutex
+	instances map[string]*Instance
+	bindings  map[string]*Binding
+	now       func() time.Time
+}
+
+var _ broker.Interface = &Broker{}
+
+// New returns a Broker serving the given services.
+func New(services []osb.Service) *Broker {
+	return &Broker{
+		Services:  services,
+		instances: map[string]*Instance{},
+		bindings:  map[string]*Binding{},
+	}
+}
+
+// ValidateBrokerAPIVersion accepts any non-empty API version.
+func (b *Broker) ValidateBrokerAPIVersion(version string) error {
+	if version == "" {
+		return fmt.Errorf("missing %s header", osb.APIVersionHeader)
+	}
+	return nil
+}
+
+// GetCatalog returns the broker's services.
+func (b *Broker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+	response := &broker.CatalogResponse{}
+	response.Services = b.Services
+	return response, nil
+}
+
+// Provision creates an instance of a service in memory.
+func (b *Broker) Provision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.planExists(request.ServiceID, request.PlanID) {
+		return nil, badRequest(fmt.Sprintf("unknown service %q or plan %q", request.ServiceID, request.PlanID))
+	}
+
+	instance := &Instance{
+		ID:         request.InstanceID,
+		ServiceID:  request.ServiceID,
+		PlanID:     request.PlanID,
+		Parameters: request.Parameters,
+		Context:    request.Context,
+	}
+
+	response := &broker.ProvisionResponse{}
+	if existing, ok := b.instances[request.InstanceID]; ok {
+		if existing.operation != nil && !b.completed(existing.operation) {
+			return nil, unprocessable("ConcurrencyError", "Another operation for this service instance is in progress.")
+		}
+		if !sameInstance(existing, instance) {
+			return nil, conflict("instance already exists with different attributes")
+		}
+		response.Exists = true
+		return response, nil
+	}
+
+	if b.AsyncDelay > 0 {
+		if !request.AcceptsIncomplete {
+			return nil, asyncRequired()
+		}
+		instance.operation = b.startOperation(false)
+		response.Async = true
+		response.OperationKey = &instance.operation.key
+	}
+
+	b.instances[request.InstanceID] = instance
+	return response, nil
+}
+
+// Deprovision deletes an instance and its bindings.
+func (b *Broker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instance, ok := b.instances[request.InstanceID]
+	if !ok {
+		return nil, gone()
+	}
+	if instance.operation != nil && !b.completed(instance.operation) {
+		return nil, unprocessable("ConcurrencyError", "Another operation for this service instance is in progress.")
+	}
+
+	response := &broker.DeprovisionResponse{}
+	if b.AsyncDelay > 0 {
+		if !request.AcceptsIncomplete {
+			return nil, asyncRequired()
+		}
+		instance.operation = b.startOperation(true)
+		response.Async = true
+		response.OperationKey = &instance.operation.key
+		return response, nil
+	}
+
+	b.deleteInstance(request.InstanceID)
+	return response, nil
+}
+
+// LastOperation reports the state of the simulated operation on an instance.
+func (b *Broker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instance, ok := b.instances[request.InstanceID]
+	if !ok {
+		return nil, gone()
+	}
+
+	response := &broker.LastOperationResponse{}
+	response.State = osb.StateSucceeded
+
+	op := instance.operation
+	if op == nil {
+		return response, nil
+	}
+	if !b.completed(op) {
+		response.State = osb.StateInProgress
+		return response, nil
+	}
+	if op.deprovision {
+		b.deleteInstance(request.InstanceID)
+		return nil, gone()
+	}
+	instance.operation = nil
+	return response, nil
+}
+
+// Bind creates a binding with generated credentials.
+func (b *Broker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instance, ok := b.instances[request.InstanceID]
+	if !ok || (instance.operation != nil && !b.completed(instance.operation)) {
+		return nil, unprocessable("", fmt.Sprintf("instance %q does not exist or is not ready", request.InstanceID))
+	}
+
+	binding := &Binding{
+		ID:         request.BindingID,
+		InstanceID: request.InstanceID,
+		ServiceID:  request.ServiceID,
+		PlanID:     request.PlanID,
+		Parameters: request.Parameters,
+	}
+
+	response := &broker.BindResponse{}
+	key := bindingKey(request.InstanceID, request.BindingID)
+	if existing, ok := b.bindings[key]; ok {
+		binding.Credentials = existing.Credentials
+		if !reflect.DeepEqual(existing, binding) {
+			return nil, conflict("binding already exists with different attributes")
+		}
+		response.Exists = true
+		response.Credentials = existing.Credentials
+		return response, nil
+	}
+
+	binding.Credentials = map[string]interface{}{
+		"username": "user-" + request.BindingID,
+		"password": randomString(),
+	}
+	b.bindings[key] = binding
+
+	response.Credentials = binding.Credentials
+	return response, nil
+}
+
+// GetBinding returns a stored binding.
+func (b *Broker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	binding, ok := b.bindings[bindingKey(request.InstanceID, request.BindingID)]
+	if !ok {
+		return nil, notFound()
+	}
+
+	response := &broker.GetBindingResponse{}
+	response.Credentials = binding.Credentials
+	response.Parameters = binding.Parameters
+	return response, nil
+}
+
+// BindingLastOperation reports bindings as succeeded, since bindings are
+// always created synchronously.
+func (b *Broker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.bindings[bindingKey(request.InstanceID, request.BindingID)]; !ok {
+		return nil, gone()
+	}
+
+	response := &broker.LastOperationResponse{}
+	response.State = osb.StateSucceeded
+	return response, nil
+}
+
+// Unbind deletes a binding.
+func (b *Broker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key := bindingKey(request.InstanceID, request.BindingID)
+	if _, ok := b.bindings[key]; !ok {
+		return nil, gone()
+	}
+	delete(b.bindings, key)
+
+	return &broker.UnbindResponse{}, nil
+}
+
+// Update changes the plan and parameters of an instance.
+func (b *Broker) Update(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instance, ok := b.instances[request.InstanceID]
+	if !ok {
+		return nil, unprocessable("", fmt.Sprintf("instance %q does not exist", request.InstanceID))
+	}
+	if instance.operation != nil && !b.completed(instance.operation) {
+		return nil, unprocessable("ConcurrencyError", "Another operation for this service instance is in progress.")
+	}
+	if request.PlanID != nil && !b.planExists(instance.ServiceID, *request.PlanID) {
+		return nil, badRequest(fmt.Sprintf("unknown plan %q", *request.PlanID))
+	}
+	if b.AsyncDelay > 0 && !request.AcceptsIncomplete {
+		return nil, asyncRequired()
+	}
+
+	if request.PlanID != nil {
+		instance.PlanID = *request.PlanID
+	}
+	if request.Parameters != nil {
+		instance.Parameters = request.Parameters
+	}
+
+	response := &broker.UpdateInstanceResponse{}
+	if b.AsyncDelay > 0 {
+		instance.operation = b.startOperation(false)
+		response.Async = true
+		response.OperationKey = &instance.operation.key
+	}
+
+	return response, nil
+}
+
+// Instance returns a copy of the stored instance with the given ID.
+func (b *Broker) Instance(id string) (Instance, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instance, ok := b.instances[id]
+	if !ok {
+		return Instance{}, false
+	}
+	return *instance, true
+}
+
+// Binding returns a copy of the stored binding with the given IDs.
+func (b *Broker) Binding(instanceID, bindingID string) (Binding, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	binding, ok := b.bindings[bindingKey(instanceID, bindingID)]
+	if !ok {
+		return Binding{}, false
+	}
+	return *binding, true
+}
+
+func (b *Broker) planExists(serviceID, planID string) bool {
+	for _, service := range b.Services {
+		if service.ID != serviceID {
+			continue
+		}
+		for _, plan := range service.Plans {
+			if plan.ID == planID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *Broker) startOperation(deprovision bool) *operation {
+	return &operation{
+		key:         osb.OperationKey(randomString()),
+		deprovision: deprovision,
+		completeAt:  b.clock().Add(b.AsyncDelay),
+	}
+}
+
+func (b *Broker) completed(op *operation) bool {
+	return !b.clock().Before(op.completeAt)
+}
+
+func (b *Broker) deleteInstance(id string) {
+	delete(b.instances, id)
+	for key, binding := range b.bindings {
+		if binding.InstanceID == id {
+			delete(b.bindings, key)
+		}
+	}
+}
+
+func (b *Broker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func sameInstance(a, b *Instance) bool {
+	return a.ServiceID == b.ServiceID &&
+		a.PlanID == b.PlanID &&
+		reflect.DeepEqual(a.Parameters, b.Parameters)
+}
+
+func bindingKey(instanceID, bindingID string) string {
+	return instanceID + "/" + bindingID
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func badRequest(description string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusBadRequest,
+		Description: &description,
+	}
+}
+
+func conflict(description string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusConflict,
+		Description: &description,
+	}
+}
+
+func gone() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode: http.StatusGone,
+	}
+}
+
+func notFound() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+func unprocessable(errorMessage, description string) error {
+	err := osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusUnprocessableEntity,
+		Description: &description,
+	}
+	if errorMessage != "" {
+		err.ErrorMessage = &errorMessage
+	}
+	return err
+}
+
+func asyncRequired() error {
+	return unprocessable(osb.AsyncErrorMessage, osb.AsyncErrorDescription)
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+func testServices() []osb.Service {
+	return []osb.Service{
+		{
+			ID:          "service-1",
+			Name:        "example",
+			Description: "An example service",
+			Bindable:    true,
+			Plans: []osb.Plan{
+				{ID: "small", Name: "small", Description: "A small plan"},
+				{ID: "large", Name: "large", Description: "A large plan"},
+			},
+		},
+	}
+}
+
+func TestSyncLifecycle(t *testing.T) {
+	b := New(testServices())
+	client := brokertest.NewServer(t, b).Client
+
+	catalog, err := client.GetCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := testServices(), catalog.Services; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Unexpected catalog; expected %+v, got %+v", e, a)
+	}
+
+	provision := &osb.ProvisionRequest{
+		InstanceID:       "i1",
+		ServiceID:        "service-1",
+		PlanID:           "small",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	}
+	if _, err := client.ProvisionInstance(provision); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ProvisionInstance(provision); err != nil {
+		t.Fatalf("Expected identical provision to succeed, got %v", err)
+	}
+
+	conflicting := *provision
+	conflicting.PlanID = "large"
+	if _, err := client.ProvisionInstance(&conflicting); !osb.IsConflictError(err) {
+		t.Fatalf("Expected conflict, got %v", err)
+	}
+
+	bind, err := client.Bind(&osb.BindRequest{
+		InstanceID: "i1",
+		BindingID:  "b1",
+		ServiceID:  "service-1",
+		PlanID:     "small",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bind.Credentials["password"] == "" {
+		t.Fatal("Expected generated credentials")
+	}
+
+	binding, err := client.GetBinding(&osb.GetBindingRequest{InstanceID: "i1", BindingID: "b1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := bind.Credentials, binding.Credentials; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Unexpected credentials; expected %v, got %v", e, a)
+	}
+
+	if _, err := client.Unbind(&osb.UnbindRequest{InstanceID: "i1", BindingID: "b1", ServiceID: "service-1", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.DeprovisionInstance(&osb.DeprovisionRequest{InstanceID: "i1", ServiceID: "service-1", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Instance("i1"); ok {
+		t.Fatal("Expected instance to be deleted")
+	}
+}
+
+func TestAsyncProvision(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(testServices())
+	b.AsyncDelay = time.Minute
+	b.now = func() time.Time { return now }
+
+	client := brokertest.NewServer(t, b).Client
+
+	provision := &osb.ProvisionRequest{
+		InstanceID:       "i1",
+		ServiceID:        "service-1",
+		PlanID:           "small",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	}
+	if _, err := client.ProvisionInstance(provision); !osb.IsAsyncRequiredError(err) {
+		t.Fatalf("Expected AsyncRequired, got %v", err)
+	}
+
+	provision.AcceptsIncomplete = true
+	response, err := client.ProvisionInstance(provision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Async {
+		t.Fatal("Expected an asynchronous response")
+	}
+
+	poll := &osb.LastOperationRequest{InstanceID: "i1", OperationKey: response.OperationKey}
+	lastOp, err := client.PollLastOperation(poll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := osb.StateInProgress, lastOp.State; e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+
+	now = now.Add(time.Minute)
+	lastOp, err = client.PollLastOperation(poll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := osb.StateSucceeded, lastOp.State; e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+}
+
+func TestAsyncOperationConflicts(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(testServices())
+	b.AsyncDelay = time.Minute
+	b.now = func() time.Time { return now }
+
+	client := brokertest.NewServer(t, b).Client
+
+	if _, err := client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:        "i1",
+		ServiceID:         "service-1",
+		PlanID:            "small",
+		OrganizationGUID:  "org",
+		SpaceGUID:         "space",
+		AcceptsIncomplete: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deprovision := &osb.DeprovisionRequest{InstanceID: "i1", ServiceID: "service-1", PlanID: "small", AcceptsIncomplete: true}
+	_, err := client.DeprovisionInstance(deprovision)
+	if httpErr, ok := osb.IsHTTPError(err); !ok || httpErr.ErrorMessage == nil || *httpErr.ErrorMessage != "ConcurrencyError" {
+		t.Fatalf("Expected ConcurrencyError deprovisioning during provision, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	large := "large"
+	if _, err := client.UpdateInstance(&osb.UpdateInstanceRequest{InstanceID: "i1", ServiceID: "service-1", PlanID: &large}); !osb.IsAsyncRequiredError(err) {
+		t.Fatalf("Expected AsyncRequired, got %v", err)
+	}
+	instance, _ := b.Instance("i1")
+	if e, a := "small", instance.PlanID; e != a {
+		t.Fatalf("Expected rejected update to leave the plan unchanged; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,87 @@
+package brokertest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// OSBError is the body of an OSB error response.
+type OSBError struct {
+	// Error is the error code, such as AsyncRequired. It is empty for
+	// errors without one.
+	Error string `json:"error,omitempty"`
+	// Description is the human readable description of the error.
+	Description string `json:"description,omitempty"`
+}
+
+// AssertOSBError reads the body of resp and fails the test unless it is an
+// OSB error response with status code wantStatus and error code wantError.
+// An empty wantError asserts the response has no error code. It returns the
+// decoded error, so tests can check the description. The body is closed.
+func AssertOSBError(t testing.TB, resp *http.Response, wantStatus int, wantError string) *OSBError {
+	t.Helper()
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading error response: %v", err)
+	}
+	return assertOSBError(t, resp.StatusCode, resp.Header, body, wantStatus, wantError)
+}
+
+// AssertOSBError fails the test unless r is an OSB error response with
+// status code wantStatus and error code wantError. See AssertOSBError.
+func (r *Response) AssertOSBError(t testing.TB, wantStatus int, wantError string) *OSBError {
+	t.Helper()
+	return assertOSBError(t, r.StatusCode, r.Header, r.Body, wantStatus, wantError)
+}
+
+// AssertClientError fails the test unless err, returned by an osb.Client,
+// reports an OSB error response with status code wantStatus and error code
+// wantError. See AssertOSBError.
+func AssertClientError(t testing.TB, err error, wantStatus int, wantError string) *OSBError {
+	t.Helper()
+
+	httpErr, ok := osb.IsHTTPError(err)
+	if !ok {
+		t.Fatalf("expected an OSB error response with status %d, got %v", wantStatus, err)
+	}
+	actual := &OSBError{}
+	if httpErr.ErrorMessage != nil {
+		actual.Error = *httpErr.ErrorMessage
+	}
+	if httpErr.Description != nil {
+		actual.Description = *httpErr.Description
+	}
+	if e, a := wantStatus, httpErr.StatusCode; e != a {
+		t.Errorf("unexpected status code; expected %d, got %d (%+v)", e, a, actual)
+	}
+	if e, a := wantError, actual.Error; e != a {
+		t.Errorf("unexpected error code; expected %q, got %q", e, a)
+	}
+	return actual
+}
+
+func assertOSBError(t testing.TB, status int, header http.Header, body []byte, wantStatus int, wantError string) *OSBError {
+	t.Helper()
+
+	if e, a := wantStatus, status; e != a {
+		t.Errorf("unexpected status code; expected %d, got %d: %s", e, a, body)
+	}
+	if e, a := "application/json", header.Get("Content-Type"); e != a {
+		t.Errorf("unexpected content type; expected %q, got %q", e, a)
+	}
+
+	actual := &OSBError{}
+	if err := json.Unmarshal(body, actual); err != nil {
+		t.Fatalf("decoding error response %q: %v", body, err)
+	}
+	if e, a := wantError, actual.Error; e != a {
+		t.Errorf("unexpected error code; expected %q, got %q", e, a)
+	}
+	return actual
+}
@@ -0,0 +1,71 @@
+package brokertest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// recordingTB records failed assertions instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, format)
+}
+
+func TestAssertOSBError(t *testing.T) {
+	s := NewServer(t, &FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return nil, broker.ErrAsyncRequired
+		},
+	})
+
+	_, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	})
+	actual := AssertClientError(t, err, http.StatusUnprocessableEntity, osb.AsyncErrorMessage)
+	if e, a := osb.AsyncErrorDescription, actual.Description; e != a {
+		t.Errorf("Unexpected description; expected %q, got %q", e, a)
+	}
+	s.LastResponse().AssertOSBError(t, http.StatusUnprocessableEntity, osb.AsyncErrorMessage)
+
+	request, _ := http.NewRequest(http.MethodPut, s.URL+"/v2/service_instances/instance", strings.NewReader(`{"service_id": "service", "plan_id": "plan", "organization_guid": "org", "space_guid": "space"}`))
+	request.Header.Set(osb.APIVersionHeader, "2.13")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertOSBError(t, resp, http.StatusUnprocessableEntity, osb.AsyncErrorMessage)
+
+	cases := []struct {
+		name       string
+		wantStatus int
+		wantError  string
+		failures   int
+	}{
+		{name: "match", wantStatus: http.StatusUnprocessableEntity, wantError: osb.AsyncErrorMessage},
+		{name: "wrong status", wantStatus: http.StatusConflict, wantError: osb.AsyncErrorMessage, failures: 1},
+		{name: "wrong error", wantStatus: http.StatusUnprocessableEntity, wantError: "ConcurrencyError", failures: 1},
+		{name: "no error code expected", wantStatus: http.StatusBadRequest, failures: 2},
+	}
+	for _, tc := range cases {
+		tb := &recordingTB{TB: t}
+		s.LastResponse().AssertOSBError(tb, tc.wantStatus, tc.wantError)
+		if e, a := tc.failures, len(tb.failures); e != a {
+			t.Errorf("%v: expected %v failures, got %v", tc.name, e, tb.failures)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package brokertest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// DelayedBroker decorates a broker.Interface so that provision, update,
+// deprovision and bind requests accepting incomplete operations complete
+// asynchronously after Delay. The wrapped business logic runs immediately.
+// HTTP errors, such as validation failures and conflicts, are returned
+// synchronously as a real broker would; any other outcome is only revealed
+// through last operation polls, which report "in progress" until Delay has
+// elapsed and then "succeeded", or "failed" with the error's message. This makes it easy to integration-test the
+// polling behavior of platforms.
+type DelayedBroker struct {
+	broker.Interface
+
+	// Delay is how long operations stay in progress.
+	Delay time.Duration
+
+	mutex      sync.Mutex
+	operations map[string]*delayedOperation
+	now        func() time.Time
+}
+
+type delayedOperation struct {
+	completeAt time.Time
+	err        error
+}
+
+var _ broker.Interface = &DelayedBroker{}
+
+// NewDelayedBroker returns a DelayedBroker wrapping logic.
+func NewDelayedBroker(logic broker.Interface, delay time.Duration) *DelayedBroker {
+	return &DelayedBroker{
+		Interface: logic,
+		Delay:     delay,
+	}
+}
+
+// Provision runs the wrapped Provision and, if the request accepts
+// incomplete operations and the instance didn't already exist, reports it as
+// asynchronous.
+func (b *DelayedBroker) Provision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+	response, err := b.Interface.Provision(request, c)
+	if !deferred(request.AcceptsIncomplete, err) || (response != nil && response.Exists) {
+		return response, err
+	}
+	if response == nil {
+		response = &broker.ProvisionResponse{}
+	}
+	response.Async = true
+	response.OperationKey = b.start(rest.InstanceOperationKey(request.InstanceID), err)
+	return response, nil
+}
+
+// Update runs the wrapped Update and, if the request accepts incomplete
+// operations, reports it as asynchronous.
+func (b *DelayedBroker) Update(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+	response, err := b.Interface.Update(request, c)
+	if !deferred(request.AcceptsIncomplete, err) {
+		return response, err
+	}
+	if response == nil {
+		response = &broker.UpdateInstanceResponse{}
+	}
+	response.Async = true
+	response.OperationKey = b.start(rest.InstanceOperationKey(request.InstanceID), err)
+	return response, nil
+}
+
+// Deprovision runs the wrapped Deprovision and, if the request accepts
+// incomplete operations, reports it as asynchronous.
+func (b *DelayedBroker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+	response, err := b.Interface.Deprovision(request, c)
+	if !deferred(request.AcceptsIncomplete, err) {
+		return response, err
+	}
+	if response == nil {
+		response = &broker.DeprovisionResponse{}
+	}
+	response.Async = true
+	response.OperationKey = b.start(rest.InstanceOperationKey(request.InstanceID), err)
+	return response, nil
+}
+
+// Bind runs the wrapped Bind and, if the request accepts incomplete
+// operations and the binding didn't already exist, reports it as
+// asynchronous.
+func (b *DelayedBroker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+	response, err := b.Interface.Bind(request, c)
+	if !deferred(request.AcceptsIncomplete, err) || (response != nil && response.Exists) {
+		return response, err
+	}
+	if response == nil {
+		response = &broker.BindResponse{}
+	}
+	response.Async = true
+	response.OperationKey = b.start(rest.BindingOperationKey(request.InstanceID, request.BindingID), err)
+	return response, nil
+}
+
+// LastOperation reports the state of a delayed operation on an instance, or
+// delegates to the wrapped LastOperation if there is none.
+func (b *DelayedBroker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	if response, ok := b.poll(rest.InstanceOperationKey(request.InstanceID)); ok {
+		return response, nil
+	}
+	return b.Interface.LastOperation(request, c)
+}
+
+// BindingLastOperation reports the state of a delayed operation on a
+// binding, or delegates to the wrapped BindingLastOperation if there is
+// none.
+func (b *DelayedBroker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	if response, ok := b.poll(rest.BindingOperationKey(request.InstanceID, request.BindingID)); ok {
+		return response, nil
+	}
+	return b.Interface.BindingLastOperation(request, c)
+}
+
+// deferred returns whether the outcome of a request should be reported
+// asynchronously: the request must accept incomplete operations and must not
+// have failed with an HTTP error.
+func deferred(acceptsIncomplete bool, err error) bool {
+	if !acceptsIncomplete {
+		return false
+	}
+	_, isHTTPError := osb.IsHTTPError(err)
+	return !isHTTPError
+}
+
+func (b *DelayedBroker) start(key string, err error) *osb.OperationKey {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.operations == nil {
+		b.operations = map[string]*delayedOperation{}
+	}
+	completeAt := b.clock().Add(b.Delay)
+	b.operations[key] = &delayedOperation{completeAt: completeAt, err: err}
+
+	operationKey := osb.OperationKey(fmt.Sprintf("delayed-%d", completeAt.UnixNano()))
+	return &operationKey
+}
+
+func (b *DelayedBroker) poll(key string) (*broker.LastOperationResponse, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	op, ok := b.operations[key]
+	if !ok {
+		return nil, false
+	}
+
+	response := &broker.LastOperationResponse{}
+	switch {
+	case b.clock().Before(op.completeAt):
+		response.State = osb.StateInProgress
+	case op.err != nil:
+		response.State = osb.StateFailed
+		description := op.err.Error()
+		response.Description = &description
+		delete(b.operations, key)
+	default:
+		response.State = osb.StateSucceeded
+		delete(b.operations, key)
+	}
+	return response, true
+}
+
+func (b *DelayedBroker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,85 @@
+package brokertest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+func TestDelayedBroker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewDelayedBroker(&FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			return nil, errors.New("no capacity")
+		},
+	}, time.Minute)
+	b.now = func() time.Time { return now }
+
+	provision, err := b.Provision(&osb.ProvisionRequest{InstanceID: "i1", AcceptsIncomplete: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !provision.Async || provision.OperationKey == nil {
+		t.Fatalf("Expected an asynchronous response with an operation key, got %+v", provision)
+	}
+
+	if _, err := b.Bind(&osb.BindRequest{InstanceID: "i1", BindingID: "b1", AcceptsIncomplete: true}, nil); err != nil {
+		t.Fatalf("Expected the bind error to be deferred, got %v", err)
+	}
+
+	poll := func(state osb.LastOperationState) *broker.LastOperationResponse {
+		response, err := b.LastOperation(&osb.LastOperationRequest{InstanceID: "i1"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, a := state, response.State; e != a {
+			t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+		}
+		return response
+	}
+
+	poll(osb.StateInProgress)
+	now = now.Add(time.Minute)
+	poll(osb.StateSucceeded)
+
+	bindResponse, err := b.BindingLastOperation(&osb.BindingLastOperationRequest{InstanceID: "i1", BindingID: "b1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bindResponse.State != osb.StateFailed || *bindResponse.Description != "no capacity" {
+		t.Fatalf("Expected the bind to fail with its error, got %+v", bindResponse)
+	}
+}
+
+func TestDelayedBrokerSynchronous(t *testing.T) {
+	b := NewDelayedBroker(&FakeBroker{}, time.Minute)
+
+	response, err := b.Provision(&osb.ProvisionRequest{InstanceID: "i1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Async {
+		t.Fatal("Expected requests not accepting incomplete operations to stay synchronous")
+	}
+}
+
+func TestDelayedBrokerHTTPError(t *testing.T) {
+	b := NewDelayedBroker(&FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusConflict}
+		},
+	}, time.Minute)
+
+	_, err := b.Provision(&osb.ProvisionRequest{InstanceID: "i1", AcceptsIncomplete: true}, nil)
+	if !osb.IsConflictError(err) {
+		t.Fatalf("Expected the conflict to be returned synchronously, got %v", err)
+	}
+	if _, err := b.LastOperation(&osb.LastOperationRequest{InstanceID: "i1"}, nil); err != nil {
+		t.Fatalf("Expected no delayed operation to be started, got %v", err)
+	}
+}
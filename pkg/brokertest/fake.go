@@ -0,0 +1,118 @@
+// Package brokertest provides utilities for testing brokers built with this
+// library and code that consumes broker.Interface.
+package brokertest
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// FakeBroker is a broker.Interface whose methods delegate to the
+// corresponding function fields. Methods whose function field is nil behave
+// as a broker that accepts every request:
+//
+// - ValidateBrokerAPIVersion accepts any version
+// - GetCatalog returns an empty catalog
+// - LastOperation and BindingLastOperation report a succeeded operation
+// - the other operations succeed synchronously with an empty response
+type FakeBroker struct {
+	ValidateBrokerAPIVersionFunc func(version string) error
+	GetCatalogFunc               func(c *broker.RequestContext) (*broker.CatalogResponse, error)
+	ProvisionFunc                func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error)
+	DeprovisionFunc              func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error)
+	LastOperationFunc            func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
+	BindFunc                     func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error)
+	UnbindFunc                   func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error)
+	UpdateFunc                   func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error)
+	GetBindingFunc               func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error)
+	BindingLastOperationFunc     func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
+}
+
+var _ broker.Interface = &FakeBroker{}
+
+// ValidateBrokerAPIVersion calls ValidateBrokerAPIVersionFunc if set.
+func (b *FakeBroker) ValidateBrokerAPIVersion(version string) error {
+	if b.ValidateBrokerAPIVersionFunc == nil {
+		return nil
+	}
+	return b.ValidateBrokerAPIVersionFunc(version)
+}
+
+// GetCatalog calls GetCatalogFunc if set.
+func (b *FakeBroker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+	if b.GetCatalogFunc == nil {
+		return &broker.CatalogResponse{}, nil
+	}
+	return b.GetCatalogFunc(c)
+}
+
+// Provision calls ProvisionFunc if set.
+func (b *FakeBroker) Provision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+	if b.ProvisionFunc == nil {
+		return &broker.ProvisionResponse{}, nil
+	}
+	return b.ProvisionFunc(request, c)
+}
+
+// Deprovision calls DeprovisionFunc if set.
+func (b *FakeBroker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+	if b.DeprovisionFunc == nil {
+		return &broker.DeprovisionResponse{}, nil
+	}
+	return b.DeprovisionFunc(request, c)
+}
+
+// LastOperation calls LastOperationFunc if set.
+func (b *FakeBroker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	if b.LastOperationFunc == nil {
+		return succeeded(), nil
+	}
+	return b.LastOperationFunc(request, c)
+}
+
+// Bind calls BindFunc if set.
+func (b *FakeBroker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+	if b.BindFunc == nil {
+		return &broker.BindResponse{}, nil
+	}
+	return b.BindFunc(request, c)
+}
+
+// Unbind calls UnbindFunc if set.
+func (b *FakeBroker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+	if b.UnbindFunc == nil {
+		return &broker.UnbindResponse{}, nil
+	}
+	return b.UnbindFunc(request, c)
+}
+
+// Update calls UpdateFunc if set.
+func (b *FakeBroker) Update(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+	if b.UpdateFunc == nil {
+		return &broker.UpdateInstanceResponse{}, nil
+	}
+	return b.UpdateFunc(request, c)
+}
+
+// GetBinding calls GetBindingFunc if set.
+func (b *FakeBroker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+	if b.GetBindingFunc == nil {
+		return &broker.GetBindingResponse{}, nil
+	}
+	return b.GetBindingFunc(request, c)
+}
+
+// BindingLastOperation calls BindingLastOperationFunc if set.
+func (b *FakeBroker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	if b.BindingLastOperationFunc == nil {
+		return succeeded(), nil
+	}
+	return b.BindingLastOperationFunc(request, c)
+}
+
+func succeeded() *broker.LastOperationResponse {
+	response := &broker.LastOperationResponse{}
+	response.State = osb.StateSucceeded
+	return response
+}
@@ -0,0 +1,40 @@
+package brokertest
+
+import (
+	"errors"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+func TestFakeBrokerDefaults(t *testing.T) {
+	b := &FakeBroker{}
+
+	if err := b.ValidateBrokerAPIVersion("2.13"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := b.Provision(&osb.ProvisionRequest{}, nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	response, err := b.LastOperation(&osb.LastOperationRequest{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e, a := osb.StateSucceeded, response.State; e != a {
+		t.Errorf("Unexpected state; expected %v, got %v", e, a)
+	}
+}
+
+func TestFakeBrokerOverrides(t *testing.T) {
+	b := &FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return nil, errors.New("oops")
+		},
+	}
+
+	if _, err := b.Provision(&osb.ProvisionRequest{}, nil); err == nil {
+		t.Error("Expected the override to be called")
+	}
+}
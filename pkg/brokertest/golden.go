@@ -0,0 +1,127 @@
+package brokertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// GoldenRequest is the serialized form of a request in a golden file.
+type GoldenRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// GoldenResponse is the serialized form of a response in a golden file.
+type GoldenResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// GoldenExchange is the content of a golden file: a request and the
+// known-good response to it.
+type GoldenExchange struct {
+	Request  GoldenRequest  `json:"request"`
+	Response GoldenResponse `json:"response"`
+}
+
+// Golden records request/response exchanges with a handler into golden
+// files and replays them, verifying the handler's responses byte-for-byte.
+// Golden files are named after the operation they exercise and stored in
+// Dir with a ".golden" extension.
+type Golden struct {
+	// Dir is the directory holding the golden files, usually under
+	// testdata.
+	Dir string
+	// Update makes Assert record new golden files instead of replaying
+	// them. It is typically bound to a test flag.
+	Update bool
+}
+
+// Assert records the exchange for request into the named golden file if
+// Update is set, and replays the golden file otherwise.
+func (g *Golden) Assert(t testing.TB, handler http.Handler, name string, request GoldenRequest) {
+	if g.Update {
+		g.Record(t, handler, name, request)
+		return
+	}
+	g.Replay(t, handler, name)
+}
+
+// Record serves request with handler and writes the exchange to the named
+// golden file.
+func (g *Golden) Record(t testing.TB, handler http.Handler, name string, request GoldenRequest) {
+	exchange := GoldenExchange{
+		Request:  request,
+		Response: serveGolden(handler, request),
+	}
+
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		t.Fatalf("marshaling golden exchange %q: %v", name, err)
+	}
+
+	if err := os.MkdirAll(g.Dir, 0755); err != nil {
+		t.Fatalf("creating golden directory: %v", err)
+	}
+	if err := ioutil.WriteFile(g.path(name), append(data, '\n'), 0644); err != nil {
+		t.Fatalf("writing golden file %q: %v", name, err)
+	}
+}
+
+// Replay serves the request recorded in the named golden file with handler
+// and fails the test if the response differs from the recorded one.
+func (g *Golden) Replay(t testing.TB, handler http.Handler, name string) {
+	data, err := ioutil.ReadFile(g.path(name))
+	if err != nil {
+		t.Fatalf("reading golden file %q: %v", name, err)
+	}
+
+	exchange := GoldenExchange{}
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		t.Fatalf("unmarshaling golden file %q: %v", name, err)
+	}
+
+	actual := serveGolden(handler, exchange.Request)
+	if e, a := exchange.Response.StatusCode, actual.StatusCode; e != a {
+		t.Errorf("%s: unexpected status code; expected %v, got %v", name, e, a)
+	}
+	if e, a := exchange.Response.Header, actual.Header; !reflect.DeepEqual(e, a) {
+		t.Errorf("%s: unexpected headers; expected %v, got %v", name, e, a)
+	}
+	if e, a := exchange.Response.Body, actual.Body; e != a {
+		t.Errorf("%s: unexpected body\n\nexpected: %s\n\ngot: %s", name, e, a)
+	}
+}
+
+func (g *Golden) path(name string) string {
+	return filepath.Join(g.Dir, name+".golden")
+}
+
+func serveGolden(handler http.Handler, request GoldenRequest) GoldenResponse {
+	r := httptest.NewRequest(request.Method, request.URL, bytes.NewBufferString(request.Body))
+	for k, v := range request.Header {
+		r.Header[k] = v
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	response := GoldenResponse{
+		StatusCode: rec.Code,
+		Body:       rec.Body.String(),
+	}
+	if len(rec.Header()) > 0 {
+		response.Header = rec.Header()
+	}
+	return response
+}
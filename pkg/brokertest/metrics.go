@@ -0,0 +1,90 @@
+package brokertest
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+)
+
+// Metrics is a metrics collector whose values can be read directly, so
+// tests can assert that handlers recorded the expected metrics without
+// scraping a Prometheus registry. Pass its OSBMetricsCollector to
+// rest.NewAPISurface, or use Server.Metrics.
+type Metrics struct {
+	*metrics.OSBMetricsCollector
+}
+
+// Observations are the request and response sizes recorded for an
+// operation.
+type Observations struct {
+	// Requests and Responses are the number of request and response
+	// bodies observed.
+	Requests  uint64
+	Responses uint64
+	// RequestBytes and ResponseBytes are the total size of the observed
+	// bodies.
+	RequestBytes  float64
+	ResponseBytes float64
+}
+
+// NewMetrics returns Metrics around a new collector.
+func NewMetrics() *Metrics {
+	return &Metrics{OSBMetricsCollector: metrics.New()}
+}
+
+// CountFor returns the number of requests for operation, such as
+// rest.OperationProvision.
+func (m *Metrics) CountFor(operation string) int {
+	return int(counterValue(m.Actions, operation))
+}
+
+// ErrorsFor returns the number of error responses for operation with the
+// given error class; see metrics.OSBMetricsCollector.Errors.
+func (m *Metrics) ErrorsFor(operation, class string) int {
+	return int(counterValue(m.Errors, operation, class))
+}
+
+// ObservationsFor returns the request and response sizes recorded for
+// operation.
+func (m *Metrics) ObservationsFor(operation string) Observations {
+	requests := histogramValue(m.RequestSize, operation)
+	responses := histogramValue(m.ResponseSize, operation)
+	return Observations{
+		Requests:      requests.GetSampleCount(),
+		Responses:     responses.GetSampleCount(),
+		RequestBytes:  requests.GetSampleSum(),
+		ResponseBytes: responses.GetSampleSum(),
+	}
+}
+
+// counterValue returns the value of the counter of vec with the given
+// labels, or zero if it was never incremented.
+func counterValue(vec *prom.CounterVec, labels ...string) float64 {
+	counter, err := vec.GetMetricWithLabelValues(labels...)
+	if err != nil {
+		return 0
+	}
+	metric := &dto.Metric{}
+	if err := counter.Write(metric); err != nil {
+		return 0
+	}
+	return metric.Counter.GetValue()
+}
+
+// histogramValue returns the histogram of vec with the given labels.
+func histogramValue(vec *prom.HistogramVec, labels ...string) *dto.Histogram {
+	observer, err := vec.GetMetricWithLabelValues(labels...)
+	if err != nil {
+		return nil
+	}
+	histogram, ok := observer.(prom.Histogram)
+	if !ok {
+		return nil
+	}
+	metric := &dto.Metric{}
+	if err := histogram.Write(metric); err != nil {
+		return nil
+	}
+	return metric.Histogram
+}
@@ -0,0 +1,60 @@
+package brokertest
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestMetrics(t *testing.T) {
+	s := NewServer(t, &FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			if request.InstanceID == "busy" {
+				errorMessage := "ConcurrencyError"
+				return nil, osb.HTTPStatusCodeError{
+					StatusCode:   http.StatusUnprocessableEntity,
+					ErrorMessage: &errorMessage,
+				}
+			}
+			return &broker.ProvisionResponse{}, nil
+		},
+	})
+
+	for _, instanceID := range []string{"instance", "busy"} {
+		s.Client.ProvisionInstance(&osb.ProvisionRequest{
+			InstanceID:       instanceID,
+			ServiceID:        "service",
+			PlanID:           "plan",
+			OrganizationGUID: "org",
+			SpaceGUID:        "space",
+		})
+	}
+
+	if e, a := 2, s.Metrics.CountFor(rest.OperationProvision); e != a {
+		t.Errorf("Unexpected provision count; expected %v, got %v", e, a)
+	}
+	if e, a := 0, s.Metrics.CountFor(rest.OperationBind); e != a {
+		t.Errorf("Unexpected bind count; expected %v, got %v", e, a)
+	}
+	if e, a := 1, s.Metrics.ErrorsFor(rest.OperationProvision, "ConcurrencyError"); e != a {
+		t.Errorf("Unexpected error count; expected %v, got %v", e, a)
+	}
+
+	observations := s.Metrics.ObservationsFor(rest.OperationProvision)
+	if e, a := uint64(2), observations.Requests; e != a {
+		t.Errorf("Unexpected request observations; expected %v, got %v", e, a)
+	}
+	if e, a := uint64(2), observations.Responses; e != a {
+		t.Errorf("Unexpected response observations; expected %v, got %v", e, a)
+	}
+	if observations.RequestBytes == 0 || observations.ResponseBytes == 0 {
+		t.Errorf("Expected sizes to be observed, got %+v", observations)
+	}
+	if e, a := (Observations{}), s.Metrics.ObservationsFor(rest.OperationBind); e != a {
+		t.Errorf("Unexpected bind observations; expected %+v, got %+v", e, a)
+	}
+}
@@ -0,0 +1,166 @@
+package brokertest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+)
+
+// Response is a raw HTTP response written by a Server.
+type Response struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Server is an httptest.Server running the full router of this library
+// around a broker.Interface, together with an OSB client configured to talk
+// to it.
+type Server struct {
+	*httptest.Server
+
+	// Client is an OSB client pointed at the server. Alpha features are
+	// enabled and the latest API version is used.
+	Client osb.Client
+	// API is the APISurface serving the broker.
+	API *rest.APISurface
+	// BrokerServer is the server built around API.
+	BrokerServer *server.Server
+	// Registry is the Prometheus registry the server's metrics are
+	// registered with.
+	Registry *prom.Registry
+	// Metrics reads the metrics of API.
+	Metrics *Metrics
+
+	mutex     sync.Mutex
+	responses []Response
+}
+
+// NewServer starts a Server for the given business logic. Options are
+// applied to the APISurface before the router is built. The server is closed
+// when the test completes.
+func NewServer(t testing.TB, logic broker.Interface, options ...func(*rest.APISurface)) *Server {
+	reg := prom.NewRegistry()
+	osbMetrics := metrics.New()
+	reg.MustRegister(osbMetrics)
+
+	api, err := rest.NewAPISurface(logic, osbMetrics)
+	if err != nil {
+		t.Fatalf("creating APISurface: %v", err)
+	}
+	for _, option := range options {
+		option(api)
+	}
+
+	s := &Server{
+		API:          api,
+		BrokerServer: server.New(api, reg),
+		Registry:     reg,
+		Metrics:      &Metrics{OSBMetricsCollector: api.Metrics},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.record))
+	t.Cleanup(s.Close)
+
+	config := osb.DefaultClientConfiguration()
+	config.URL = s.URL
+	config.EnableAlphaFeatures = true
+
+	s.Client, err = osb.NewClient(config)
+	if err != nil {
+		t.Fatalf("creating OSB client: %v", err)
+	}
+
+	return s
+}
+
+// Responses returns the raw responses written by the server so far, in
+// order.
+func (s *Server) Responses() []Response {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	responses := make([]Response, len(s.responses))
+	copy(responses, s.responses)
+	return responses
+}
+
+// LastResponse returns the last raw response written by the server. It
+// returns nil if no request was served yet.
+func (s *Server) LastResponse() *Response {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.responses) == 0 {
+		return nil
+	}
+	response := s.responses[len(s.responses)-1]
+	return &response
+}
+
+// record serves a request with the router and records the response. Writes
+// and flushes are passed through as they happen, so streamed responses reach
+// the client incrementally.
+func (s *Server) record(w http.ResponseWriter, r *http.Request) {
+	capture := &responseCapture{ResponseWriter: w}
+	s.BrokerServer.Router.ServeHTTP(capture, r)
+	if capture.statusCode == 0 {
+		capture.WriteHeader(http.StatusOK)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses = append(s.responses, Response{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		StatusCode: capture.statusCode,
+		Header:     capture.header,
+		Body:       bytes.TrimSpace(capture.body.Bytes()),
+	})
+}
+
+// responseCapture is an http.ResponseWriter that keeps a copy of what is
+// written through it.
+type responseCapture struct {
+	http.ResponseWriter
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	if c.statusCode != 0 {
+		return
+	}
+	c.statusCode = code
+	c.header = http.Header{}
+	for k, v := range c.ResponseWriter.Header() {
+		c.header[k] = append([]string(nil), v...)
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	if c.statusCode == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+func (c *responseCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
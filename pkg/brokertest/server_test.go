@@ -0,0 +1,50 @@
+package brokertest
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+func TestNewServer(t *testing.T) {
+	s := NewServer(t, &FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			response := &broker.ProvisionResponse{}
+			response.Async = true
+			return response, nil
+		},
+	})
+
+	if s.LastResponse() != nil {
+		t.Fatal("Expected no responses before any request")
+	}
+
+	response, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:        "i1",
+		ServiceID:         "s1",
+		PlanID:            "p1",
+		OrganizationGUID:  "org",
+		SpaceGUID:         "space",
+		AcceptsIncomplete: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Async {
+		t.Error("Expected an asynchronous response")
+	}
+
+	raw := s.LastResponse()
+	if e, a := http.StatusAccepted, raw.StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := "/v2/service_instances/i1", raw.Path; e != a {
+		t.Errorf("Unexpected path; expected %v, got %v", e, a)
+	}
+	if e, a := `{"async":true}`, string(raw.Body); e != a {
+		t.Errorf("Unexpected body; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,496 @@
+// Package cfregister registers a broker with a Cloud Foundry Cloud
+// Controller, so brokers deployed to or alongside Cloud Foundry can register
+// themselves on deploy instead of an operator running cf create-service-broker
+// and cf enable-service-access:
+//
+//	r := &cfregister.Registrar{
+//		APIURL:         "https://api.sys.example.com",
+//		ClientID:       "broker-registrar",
+//		ClientSecret:   secret,
+//		BrokerName:     "db-broker",
+//		BrokerURL:      "https://db-broker.apps.example.com",
+//		BrokerUsername: "broker",
+//		BrokerPassword: password,
+//		Orgs:           []string{"data-team"},
+//	}
+//	err := r.Register(ctx)
+//
+// It talks to the v3 Cloud Controller API over plain HTTP and authenticates
+// with UAA, using either a client with the cloud_controller.admin authority
+// or an admin user.
+package cfregister
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultClientID is the UAA client used with the password grant when
+// ClientID is not set; it is the client the cf CLI uses.
+const DefaultClientID = "cf"
+
+// defaultJobPollInterval is the default JobPollInterval.
+const defaultJobPollInterval = 2 * time.Second
+
+// Registrar registers a broker with a Cloud Controller and enables access to
+// its plans.
+type Registrar struct {
+	// APIURL is the base URL of the Cloud Controller API, such as
+	// https://api.sys.example.com.
+	APIURL string
+	// UAAURL is the base URL of the UAA tokens are requested from. It
+	// defaults to the login server advertised by the Cloud Controller.
+	UAAURL string
+	// ClientID and ClientSecret authenticate to UAA. Without Username,
+	// they are used with the client credentials grant, and the client
+	// needs the cloud_controller.admin authority.
+	ClientID     string
+	ClientSecret string
+	// Username and Password, if set, authenticate as an admin user with the
+	// password grant. ClientID defaults to DefaultClientID.
+	Username string
+	Password string
+
+	// BrokerName is the name the broker is registered under. A broker
+	// already registered under this name is updated instead.
+	BrokerName string
+	// BrokerURL is the URL the Cloud Controller reaches the broker at.
+	BrokerURL string
+	// BrokerUsername and BrokerPassword are the basic auth credentials the
+	// Cloud Controller sends to the broker.
+	BrokerUsername string
+	BrokerPassword string
+
+	// Orgs are the names of the organizations access to every plan of the
+	// broker is enabled for.
+	Orgs []string
+	// Public enables access to every plan of the broker for all
+	// organizations. Without Public or Orgs, access is left unchanged.
+	Public bool
+
+	// JobPollInterval is how often the asynchronous jobs registering the
+	// broker are polled. It defaults to 2 seconds.
+	JobPollInterval time.Duration
+	// Client is the HTTP client used to talk to the Cloud Controller and
+	// UAA. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Validate returns an error if the Registrar is missing settings.
+func (r *Registrar) Validate() error {
+	switch {
+	case r.APIURL == "":
+		return errors.New("cfregister: APIURL is required")
+	case r.BrokerName == "" || r.BrokerURL == "":
+		return errors.New("cfregister: BrokerName and BrokerURL are required")
+	case r.Username == "" && r.ClientID == "":
+		return errors.New("cfregister: either ClientID or Username is required")
+	case r.Public && len(r.Orgs) > 0:
+		return errors.New("cfregister: Public and Orgs are mutually exclusive")
+	}
+	return nil
+}
+
+// Register creates the broker, or updates it if a broker called BrokerName
+// is already registered, waits for the Cloud Controller to fetch its
+// catalog and then enables access to its plans. The broker must be
+// reachable at BrokerURL. Register is idempotent, so it can run on every
+// start.
+func (r *Registrar) Register(ctx context.Context) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	token, err := r.token(ctx)
+	if err != nil {
+		return err
+	}
+	c := &ccClient{r: r, token: token}
+
+	guid, err := c.brokerGUID(ctx)
+	if err != nil {
+		return err
+	}
+	if guid == "" {
+		if err := c.createBroker(ctx); err != nil {
+			return err
+		}
+		if guid, err = c.brokerGUID(ctx); err != nil {
+			return err
+		}
+		if guid == "" {
+			return fmt.Errorf("cfregister: broker %q not found after creating it", r.BrokerName)
+		}
+	} else if err := c.updateBroker(ctx, guid); err != nil {
+		return err
+	}
+
+	return c.enableAccess(ctx, guid)
+}
+
+// tokenResponse is the subset of a UAA token response used by Register.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// token requests an access token from UAA.
+func (r *Registrar) token(ctx context.Context) (string, error) {
+	uaaURL := r.UAAURL
+	if uaaURL == "" {
+		var err error
+		if uaaURL, err = r.loginURL(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	form := url.Values{}
+	clientID, clientSecret := r.ClientID, r.ClientSecret
+	if r.Username != "" {
+		if clientID == "" {
+			clientID = DefaultClientID
+		}
+		form.Set("grant_type", "password")
+		form.Set("username", r.Username)
+		form.Set("password", r.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(uaaURL, "/")+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cfregister: requesting a token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError("requesting a token", resp)
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("cfregister: decoding token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("cfregister: UAA returned no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// rootResponse is the subset of the Cloud Controller root document used to
+// find UAA.
+type rootResponse struct {
+	Links struct {
+		Login *link `json:"login"`
+		UAA   *link `json:"uaa"`
+	} `json:"links"`
+}
+
+type link struct {
+	Href string `json:"href"`
+}
+
+// loginURL returns the URL of the login server advertised by the Cloud
+// Controller, falling back to UAA.
+func (r *Registrar) loginURL(ctx context.Context) (string, error) {
+	var root rootResponse
+	c := &ccClient{r: r}
+	if _, err := c.do(ctx, http.MethodGet, r.apiURL("/"), nil, &root); err != nil {
+		return "", err
+	}
+	switch {
+	case root.Links.Login != nil && root.Links.Login.Href != "":
+		return root.Links.Login.Href, nil
+	case root.Links.UAA != nil && root.Links.UAA.Href != "":
+		return root.Links.UAA.Href, nil
+	}
+	return "", errors.New("cfregister: the Cloud Controller advertises no login server; set UAAURL")
+}
+
+func (r *Registrar) apiURL(path string) string {
+	return strings.TrimSuffix(r.APIURL, "/") + path
+}
+
+func (r *Registrar) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+// ccClient makes authenticated Cloud Controller requests.
+type ccClient struct {
+	r     *Registrar
+	token string
+}
+
+// resource is the subset of a Cloud Controller resource used by Register.
+type resource struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+// resourceList is a page of Cloud Controller resources.
+type resourceList struct {
+	Pagination struct {
+		Next *link `json:"next"`
+	} `json:"pagination"`
+	Resources []resource `json:"resources"`
+}
+
+// job is the subset of a Cloud Controller job used by Register.
+type job struct {
+	State  string `json:"state"`
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// brokerRequest is the body of a create or update service broker request.
+type brokerRequest struct {
+	Name           string         `json:"name"`
+	URL            string         `json:"url"`
+	Authentication authentication `json:"authentication"`
+}
+
+type authentication struct {
+	Type        string            `json:"type"`
+	Credentials map[string]string `json:"credentials"`
+}
+
+// visibilityRequest is the body of a service plan visibility request.
+type visibilityRequest struct {
+	Type          string         `json:"type"`
+	Organizations []organization `json:"organizations,omitempty"`
+}
+
+type organization struct {
+	GUID string `json:"guid"`
+}
+
+// do sends a JSON request and decodes the response into out, if not nil.
+// Responses other than 2xx are returned as errors.
+func (c *ccClient) do(ctx context.Context, method, u string, in, out interface{}) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.r.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cfregister: %s %s: %v", method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, responseError(method+" "+req.URL.Path, resp)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("cfregister: decoding %s %s: %v", method, req.URL.Path, err)
+		}
+	}
+	return resp, nil
+}
+
+// list returns every resource listed at path, following pagination.
+func (c *ccClient) list(ctx context.Context, path string) ([]resource, error) {
+	var resources []resource
+	for u := c.r.apiURL(path); u != ""; {
+		var page resourceList
+		if _, err := c.do(ctx, http.MethodGet, u, nil, &page); err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Resources...)
+		u = ""
+		if page.Pagination.Next != nil {
+			u = page.Pagination.Next.Href
+		}
+	}
+	return resources, nil
+}
+
+// brokerGUID returns the GUID of the broker called BrokerName, or "" if
+// there is none.
+func (c *ccClient) brokerGUID(ctx context.Context) (string, error) {
+	brokers, err := c.list(ctx, "/v3/service_brokers?names="+url.QueryEscape(c.r.BrokerName))
+	if err != nil {
+		return "", err
+	}
+	for _, b := range brokers {
+		if b.Name == c.r.BrokerName {
+			return b.GUID, nil
+		}
+	}
+	return "", nil
+}
+
+func (c *ccClient) brokerRequest() *brokerRequest {
+	return &brokerRequest{
+		Name: c.r.BrokerName,
+		URL:  c.r.BrokerURL,
+		Authentication: authentication{
+			Type: "basic",
+			Credentials: map[string]string{
+				"username": c.r.BrokerUsername,
+				"password": c.r.BrokerPassword,
+			},
+		},
+	}
+}
+
+// createBroker registers the broker and waits for the Cloud Controller to
+// fetch its catalog.
+func (c *ccClient) createBroker(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodPost, c.r.apiURL("/v3/service_brokers"), c.brokerRequest(), nil)
+	if err != nil {
+		return err
+	}
+	return c.wait(ctx, resp)
+}
+
+// updateBroker updates the URL and credentials of the broker, which makes
+// the Cloud Controller fetch its catalog again, and waits for it.
+func (c *ccClient) updateBroker(ctx context.Context, guid string) error {
+	resp, err := c.do(ctx, http.MethodPatch, c.r.apiURL("/v3/service_brokers/"+guid), c.brokerRequest(), nil)
+	if err != nil {
+		return err
+	}
+	return c.wait(ctx, resp)
+}
+
+// wait polls the job an asynchronous response points at until it is done.
+// Synchronous responses have no job.
+func (c *ccClient) wait(ctx context.Context, resp *http.Response) error {
+	location := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusAccepted || location == "" {
+		return nil
+	}
+
+	interval := c.r.JobPollInterval
+	if interval == 0 {
+		interval = defaultJobPollInterval
+	}
+	for {
+		var j job
+		if _, err := c.do(ctx, http.MethodGet, location, nil, &j); err != nil {
+			return err
+		}
+		switch j.State {
+		case "COMPLETE":
+			return nil
+		case "FAILED":
+			var details []string
+			for _, e := range j.Errors {
+				details = append(details, e.Detail)
+			}
+			return fmt.Errorf("cfregister: registering broker %q failed: %s", c.r.BrokerName, strings.Join(details, "; "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// enableAccess enables access to every plan of the broker for Orgs, or for
+// all organizations when Public is set.
+func (c *ccClient) enableAccess(ctx context.Context, brokerGUID string) error {
+	if !c.r.Public && len(c.r.Orgs) == 0 {
+		return nil
+	}
+
+	visibility := &visibilityRequest{Type: "public"}
+	if !c.r.Public {
+		orgs, err := c.orgGUIDs(ctx)
+		if err != nil {
+			return err
+		}
+		visibility = &visibilityRequest{Type: "organization"}
+		for _, guid := range orgs {
+			visibility.Organizations = append(visibility.Organizations, organization{GUID: guid})
+		}
+	}
+
+	plans, err := c.list(ctx, "/v3/service_plans?service_broker_guids="+url.QueryEscape(brokerGUID))
+	if err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		// POST adds organizations to the plan's visibility, keeping those
+		// enabled by hand; PATCH replaces it, as making a plan public does.
+		method := http.MethodPost
+		if c.r.Public {
+			method = http.MethodPatch
+		}
+		if _, err := c.do(ctx, method, c.r.apiURL("/v3/service_plans/"+plan.GUID+"/visibility"), visibility, nil); err != nil {
+			return fmt.Errorf("cfregister: enabling access to plan %q: %v", plan.Name, err)
+		}
+	}
+	return nil
+}
+
+// orgGUIDs returns the GUIDs of Orgs.
+func (c *ccClient) orgGUIDs(ctx context.Context) ([]string, error) {
+	names := make([]string, len(c.r.Orgs))
+	for i, name := range c.r.Orgs {
+		names[i] = url.QueryEscape(name)
+	}
+	orgs, err := c.list(ctx, "/v3/organizations?names="+strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	guids := map[string]string{}
+	for _, org := range orgs {
+		guids[org.Name] = org.GUID
+	}
+	var result []string
+	for _, name := range c.r.Orgs {
+		guid, ok := guids[name]
+		if !ok {
+			return nil, fmt.Errorf("cfregister: organization %q not found", name)
+		}
+		result = append(result, guid)
+	}
+	return result, nil
+}
+
+// responseError returns an error describing an unexpected response.
+func responseError(action string, resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("cfregister: %s: unexpected status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,181 @@
+package cfregister
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCC is a fake Cloud Controller and UAA.
+type fakeCC struct {
+	mutex    sync.Mutex
+	server   *httptest.Server
+	brokers  []resource
+	requests []string
+	// visibilities records the visibility requests by plan GUID.
+	visibilities map[string]visibilityRequest
+	// jobPolls is how many times the job is polled before it completes.
+	jobPolls int
+}
+
+func newFakeCC(t *testing.T) *fakeCC {
+	f := &fakeCC{visibilities: map[string]visibilityRequest{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeCC) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	reply := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	if r.URL.Path == "/" {
+		reply(map[string]interface{}{"links": map[string]interface{}{"login": map[string]string{"href": f.server.URL + "/login"}}})
+		return
+	}
+	if r.URL.Path == "/login/oauth/token" {
+		if id, secret, _ := r.BasicAuth(); id != "registrar" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reply(tokenResponse{AccessToken: "token", TokenType: "bearer"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v3/service_brokers":
+		var list resourceList
+		for _, b := range f.brokers {
+			if b.Name == r.URL.Query().Get("names") {
+				list.Resources = append(list.Resources, b)
+			}
+		}
+		reply(list)
+	case r.Method == http.MethodPost && r.URL.Path == "/v3/service_brokers":
+		var request brokerRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.brokers = append(f.brokers, resource{GUID: "broker-guid", Name: request.Name})
+		w.Header().Set("Location", f.server.URL+"/v3/jobs/job-guid")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPatch && r.URL.Path == "/v3/service_brokers/broker-guid":
+		w.Header().Set("Location", f.server.URL+"/v3/jobs/job-guid")
+		w.WriteHeader(http.StatusAccepted)
+	case r.URL.Path == "/v3/jobs/job-guid":
+		f.jobPolls--
+		if f.jobPolls > 0 {
+			reply(job{State: "PROCESSING"})
+			return
+		}
+		reply(job{State: "COMPLETE"})
+	case r.URL.Path == "/v3/organizations":
+		reply(resourceList{Resources: []resource{{GUID: "org-guid", Name: "data-team"}}})
+	case r.URL.Path == "/v3/service_plans":
+		if r.URL.Query().Get("service_broker_guids") != "broker-guid" {
+			reply(resourceList{})
+			return
+		}
+		reply(resourceList{Resources: []resource{{GUID: "small-guid", Name: "small"}, {GUID: "large-guid", Name: "large"}}})
+	case r.Method == http.MethodPost && (r.URL.Path == "/v3/service_plans/small-guid/visibility" || r.URL.Path == "/v3/service_plans/large-guid/visibility"):
+		var request visibilityRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.visibilities[r.URL.Path] = request
+		reply(request)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	cc := newFakeCC(t)
+	cc.jobPolls = 2
+	r := &Registrar{
+		APIURL:          cc.server.URL,
+		ClientID:        "registrar",
+		ClientSecret:    "secret",
+		BrokerName:      "db-broker",
+		BrokerURL:       "https://db-broker.example.com",
+		Orgs:            []string{"data-team"},
+		JobPollInterval: time.Millisecond,
+	}
+
+	if err := r.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GET /",
+		"POST /login/oauth/token",
+		"GET /v3/service_brokers",
+		"POST /v3/service_brokers",
+		"GET /v3/jobs/job-guid",
+		"GET /v3/jobs/job-guid",
+		"GET /v3/service_brokers",
+		"GET /v3/organizations",
+		"GET /v3/service_plans",
+		"POST /v3/service_plans/small-guid/visibility",
+		"POST /v3/service_plans/large-guid/visibility",
+	}
+	if e, a := expected, cc.requests; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected requests; expected %v, got %v", e, a)
+	}
+	visibility := visibilityRequest{Type: "organization", Organizations: []organization{{GUID: "org-guid"}}}
+	if e, a := visibility, cc.visibilities["/v3/service_plans/small-guid/visibility"]; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected visibility; expected %v, got %v", e, a)
+	}
+
+	cc.requests = nil
+	r.Orgs = nil
+	if err := r.Register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		"GET /",
+		"POST /login/oauth/token",
+		"GET /v3/service_brokers",
+		"PATCH /v3/service_brokers/broker-guid",
+		"GET /v3/jobs/job-guid",
+	}
+	if e, a := expected, cc.requests; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected a registered broker to be updated; expected requests %v, got %v", e, a)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	cc := newFakeCC(t)
+	cases := []struct {
+		name      string
+		registrar *Registrar
+	}{
+		{
+			name:      "invalid",
+			registrar: &Registrar{APIURL: cc.server.URL, ClientID: "registrar"},
+		},
+		{
+			name:      "unauthorized",
+			registrar: &Registrar{APIURL: cc.server.URL, ClientID: "registrar", ClientSecret: "wrong", BrokerName: "b", BrokerURL: "https://b"},
+		},
+		{
+			name:      "unknown org",
+			registrar: &Registrar{APIURL: cc.server.URL, ClientID: "registrar", ClientSecret: "secret", BrokerName: "b", BrokerURL: "https://b", Orgs: []string{"other"}},
+		},
+	}
+	for _, tc := range cases {
+		if err := tc.registrar.Register(context.Background()); err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		}
+	}
+}
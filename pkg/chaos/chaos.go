@@ -0,0 +1,166 @@
+// Package chaos provides fault injection middleware for resilience testing.
+// It lets platform integrators check how their service catalog copes with a
+// misbehaving broker by injecting errors, latency and malformed responses
+// into the OSB operations of a server. Fault injection is never enabled by
+// default and must not be used in production.
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+)
+
+// AllOperations is the key of the Fault applied to OSB operations without a
+// Fault of their own. Unnamed routes, such as /metrics and /healthz, are never
+// affected.
+const AllOperations = "*"
+
+// Fault describes the faults injected into one operation.
+type Fault struct {
+	// Latency is added before the request is handled.
+	Latency time.Duration
+	// ErrorRate is the fraction of requests, between 0 and 1, answered with
+	// an error instead of being handled.
+	ErrorRate float64
+	// ErrorStatusCode is the status code of injected errors. It defaults to
+	// 500.
+	ErrorStatusCode int
+	// MalformedRate is the fraction of requests, between 0 and 1, answered
+	// with a 200 and a body that is not valid JSON.
+	MalformedRate float64
+}
+
+// Injector injects faults into the operations of a server. Operations are
+// identified by the names of their routes, which are the rest.Operation*
+// constants for the OSB API.
+type Injector struct {
+	// Enabled must be set for any fault to be injected.
+	Enabled bool
+	// Faults holds the faults to inject, keyed by operation name or
+	// AllOperations.
+	Faults map[string]Fault
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+	sleep func(time.Duration)
+}
+
+// New returns a disabled Injector for the given faults.
+func New(faults map[string]Fault) *Injector {
+	return &Injector{
+		Faults: faults,
+	}
+}
+
+// Middleware returns a handler that injects the configured faults before
+// calling next. It is meant to be installed on a server's router with
+// Router.Use.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !i.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		operation := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			operation = route.GetName()
+		}
+		if operation == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fault, ok := i.Faults[operation]
+		if !ok {
+			fault, ok = i.Faults[AllOperations]
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if fault.Latency > 0 {
+			glog.V(4).Infof("chaos: delaying %q by %v", operation, fault.Latency)
+			if !i.doSleep(r.Context(), fault.Latency) {
+				return
+			}
+		}
+
+		if i.roll(fault.ErrorRate) {
+			glog.V(4).Infof("chaos: injecting error into %q", operation)
+			writeInjectedError(w, fault.ErrorStatusCode)
+			return
+		}
+
+		if i.roll(fault.MalformedRate) {
+			glog.V(4).Infof("chaos: injecting malformed response into %q", operation)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"malformed":`))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// roll returns true with the given probability.
+func (i *Injector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.rand == nil {
+		i.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return i.rand.Float64() < probability
+}
+
+// doSleep waits for d, returning false if the request was cancelled first.
+func (i *Injector) doSleep(ctx context.Context, d time.Duration) bool {
+	if i.sleep != nil {
+		i.sleep(d)
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func writeInjectedError(w http.ResponseWriter, code int) {
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	type e struct {
+		ErrorMessage string `json:"error"`
+		Description  string `json:"description"`
+	}
+	data, err := json.Marshal(&e{
+		ErrorMessage: "InjectedFault",
+		Description:  "This error was injected by the broker's fault injection mode.",
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
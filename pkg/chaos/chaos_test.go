@@ -0,0 +1,85 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newRouter(i *Injector) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/catalog", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"services":[]}`))
+	}).Name("get_catalog")
+	router.HandleFunc("/v2/service_instances/{instance_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Name("provision")
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Use(i.Middleware)
+	return router
+}
+
+func serve(router *mux.Router, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestInjectorDisabledByDefault(t *testing.T) {
+	i := New(map[string]Fault{AllOperations: {ErrorRate: 1}})
+	router := newRouter(i)
+
+	if e, a := http.StatusOK, serve(router, "GET", "/v2/catalog").Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+}
+
+func TestInjectorFaults(t *testing.T) {
+	var slept time.Duration
+	i := New(map[string]Fault{
+		"provision":   {ErrorRate: 1, ErrorStatusCode: http.StatusBadGateway, Latency: time.Second},
+		AllOperations: {MalformedRate: 1},
+	})
+	i.Enabled = true
+	i.sleep = func(d time.Duration) { slept += d }
+	router := newRouter(i)
+
+	rec := serve(router, "PUT", "/v2/service_instances/i1")
+	if e, a := http.StatusBadGateway, rec.Code; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := time.Second, slept; e != a {
+		t.Errorf("Unexpected latency; expected %v, got %v", e, a)
+	}
+
+	rec = serve(router, "GET", "/v2/catalog")
+	if e, a := `{"malformed":`, rec.Body.String(); e != a {
+		t.Errorf("Unexpected body; expected %v, got %v", e, a)
+	}
+
+	rec = serve(router, "GET", "/healthz")
+	if e, a := http.StatusOK, rec.Code; e != a {
+		t.Errorf("Expected unnamed routes to be unaffected; expected %v, got %v", e, a)
+	}
+}
+
+func TestInjectorLatencyCancelled(t *testing.T) {
+	i := New(map[string]Fault{AllOperations: {Latency: time.Hour}})
+	i.Enabled = true
+	router := newRouter(i)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/catalog", nil).WithContext(ctx))
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected a cancelled request not to be handled, got %q", rec.Body.String())
+	}
+}
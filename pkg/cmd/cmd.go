@@ -0,0 +1,361 @@
+// Package cmd bootstraps broker binaries: it registers the standard flags,
+// builds the server around the business logic and runs it until the process
+// is signaled, so that a broker's main function only has to provide its
+// business logic:
+//
+//	func main() {
+//		cmd.Main(func(o *cmd.Options) (broker.Interface, error) {
+//			return NewBusinessLogic()
+//		})
+//	}
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pmorie/osb-broker-lib/pkg/activation"
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/cfregister"
+	"github.com/pmorie/osb-broker-lib/pkg/deadline"
+	"github.com/pmorie/osb-broker-lib/pkg/k8sregister"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/proxy"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+)
+
+// DefaultPort is the port served when --port is not set.
+const DefaultPort = 8443
+
+// TokenAuthenticator authenticates the bearer tokens platforms send, for
+// example with a Kubernetes TokenReview.
+type TokenAuthenticator interface {
+	// Authenticate returns whether token is valid.
+	Authenticate(ctx context.Context, token string) (bool, error)
+}
+
+// Options are the standard options of a broker binary.
+type Options struct {
+	// Port is the port to listen on.
+	Port int
+	// TLSCert and TLSKey are the base64 encoded TLS certificate and key.
+	TLSCert string
+	TLSKey  string
+	// TLSCertFile and TLSKeyFile are the paths of the TLS certificate and
+	// key; they take precedence over TLSCert and TLSKey.
+	TLSCertFile string
+	TLSKeyFile  string
+	// Insecure serves plain HTTP instead of HTTPS.
+	Insecure bool
+	// AuthenticateK8SToken rejects OSB requests whose bearer token isn't
+	// accepted by Authenticator with a 401.
+	AuthenticateK8SToken bool
+	// TrustedProxies are the networks of the proxies trusted to report the
+	// client address in the Forwarded and X-Forwarded-For headers. See
+	// package proxy.
+	TrustedProxies []string
+	// ReusePort listens with SO_REUSEPORT, so a replacement process can
+	// start listening before this one drains. See server.Server.
+	ReusePort bool
+	// ProxyProtocol reads the PROXY protocol header sent by TCP load
+	// balancers. When TrustedProxies is set, only those peers may send one.
+	ProxyProtocol bool
+	// EnableCORS answers CORS preflight requests.
+	EnableCORS bool
+	// PrettyJSON indents JSON responses and sorts their keys. It is meant
+	// for development.
+	PrettyJSON bool
+	// DeadlineHeader, if set, is the header platforms or gateways send the
+	// deadline of a request in; the business logic's context expires at
+	// it. See package deadline.
+	DeadlineHeader string
+	// The timeouts, MaxHeaderBytes, DisableHTTP2 and EnableH2C are passed
+	// to the server; see server.Server.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	MaxHeaderBytes    int
+	DisableHTTP2      bool
+	EnableH2C         bool
+	// Authenticator authenticates tokens when AuthenticateK8SToken is set.
+	// It is not a flag; set it in the func passed to Main.
+	Authenticator TokenAuthenticator
+	// Configure, if set, is called with the APISurface before the server
+	// is built. It is not a flag; set it in the func passed to Main.
+	Configure func(api *rest.APISurface) error
+	// CFRegistrar, if set, registers the broker with a Cloud Foundry Cloud
+	// Controller once the server is started, retrying until it succeeds.
+	// It is not a flag; set it in the func passed to Main.
+	CFRegistrar *cfregister.Registrar
+	// K8SRegistrar, if set, registers the broker with the Kubernetes
+	// Service Catalog once the server is started, retrying until it
+	// succeeds. It is not a flag; set it in the func passed to Main.
+	K8SRegistrar *k8sregister.Registrar
+}
+
+// AddFlags registers the options as flags of fs. The glog flags, such as
+// --logtostderr, are registered on flag.CommandLine by glog itself.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.Port, "port", DefaultPort, "the port to listen on")
+	fs.StringVar(&o.TLSCert, "tlsCert", "", "the base64 encoded TLS certificate")
+	fs.StringVar(&o.TLSKey, "tlsKey", "", "the base64 encoded TLS key")
+	fs.StringVar(&o.TLSCertFile, "tls-cert-file", "", "the path of the TLS certificate")
+	fs.StringVar(&o.TLSKeyFile, "tls-private-key-file", "", "the path of the TLS key")
+	fs.BoolVar(&o.Insecure, "insecure", false, "serve plain HTTP instead of HTTPS")
+	fs.BoolVar(&o.AuthenticateK8SToken, "authenticate-k8s-token", false, "authenticate the bearer tokens of OSB requests")
+	fs.BoolVar(&o.EnableH2C, "h2c", false, "also serve HTTP/2 without TLS")
+	fs.BoolVar(&o.ReusePort, "reuse-port", false, "listen with SO_REUSEPORT for zero-downtime restarts")
+	fs.BoolVar(&o.ProxyProtocol, "proxy-protocol", false, "read PROXY protocol headers sent by TCP load balancers")
+	fs.BoolVar(&o.PrettyJSON, "pretty-json", false, "indent JSON responses and sort their keys, for development")
+	fs.StringVar(&o.DeadlineHeader, "deadline-header", "", "the header holding the deadline of OSB requests, such as "+deadline.DefaultHeader)
+	fs.Var((*listValue)(&o.TrustedProxies), "trusted-proxies", "comma separated networks of trusted proxies")
+}
+
+// listValue is a flag.Value holding a comma separated list.
+type listValue []string
+
+func (l *listValue) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the options are inconsistent.
+func (o *Options) Validate() error {
+	if o.Port <= 0 || o.Port > 65535 {
+		return fmt.Errorf("invalid port %d", o.Port)
+	}
+	if !o.Insecure {
+		files := o.TLSCertFile != "" && o.TLSKeyFile != ""
+		inline := o.TLSCert != "" && o.TLSKey != ""
+		if !files && !inline {
+			return errors.New("a TLS certificate and key are required unless --insecure is set")
+		}
+	}
+	if o.AuthenticateK8SToken && o.Authenticator == nil {
+		return errors.New("--authenticate-k8s-token requires an Authenticator")
+	}
+	if o.CFRegistrar != nil {
+		if err := o.CFRegistrar.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.K8SRegistrar != nil {
+		if err := o.K8SRegistrar.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Main registers the standard flags on flag.CommandLine, parses them, builds
+// the business logic with newLogic and serves it until the process receives
+// SIGINT or SIGTERM. It exits the process with status 1 on error.
+func Main(newLogic func(o *Options) (broker.Interface, error)) {
+	o := &Options{}
+	o.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	if err := run(o, newLogic); err != nil {
+		glog.Errorf("%v", err)
+		glog.Flush()
+		os.Exit(1)
+	}
+	glog.Flush()
+}
+
+func run(o *Options, newLogic func(o *Options) (broker.Interface, error)) error {
+	logic, err := newLogic(o)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cancelOnSignal(ctx, cancel)
+
+	return Run(ctx, o, logic)
+}
+
+// cancelOnSignal calls cancel when the process receives SIGINT or SIGTERM.
+func cancelOnSignal(ctx context.Context, cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case s := <-signals:
+		glog.Infof("Received %v, shutting down", s)
+		cancel()
+	case <-ctx.Done():
+	}
+}
+
+// NewServer builds the server for logic according to o.
+func NewServer(o *Options, logic broker.Interface) (*server.Server, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	reg := prom.NewRegistry()
+	osbMetrics := metrics.New()
+	reg.MustRegister(osbMetrics)
+
+	api, err := rest.NewAPISurface(logic, osbMetrics)
+	if err != nil {
+		return nil, err
+	}
+	api.EnableCORS = o.EnableCORS
+	api.PrettyJSON = o.PrettyJSON
+	if o.Configure != nil {
+		if err := o.Configure(api); err != nil {
+			return nil, err
+		}
+	}
+
+	s := server.New(api, reg)
+	if len(o.TrustedProxies) > 0 {
+		trusted, err := proxy.ParseTrusted(o.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		s.Router.Use(trusted.Middleware)
+		s.ProxyProtocolTrusted = trusted
+	}
+	s.ProxyProtocol = o.ProxyProtocol
+	s.ReusePort = o.ReusePort
+	s.ReadTimeout = o.ReadTimeout
+	s.ReadHeaderTimeout = o.ReadHeaderTimeout
+	s.WriteTimeout = o.WriteTimeout
+	s.IdleTimeout = o.IdleTimeout
+	s.ShutdownTimeout = o.ShutdownTimeout
+	s.MaxHeaderBytes = o.MaxHeaderBytes
+	s.DisableHTTP2 = o.DisableHTTP2
+	s.EnableH2C = o.EnableH2C
+	if o.AuthenticateK8SToken {
+		s.UseOSBMiddleware(authenticate(o.Authenticator))
+	}
+	if o.DeadlineHeader != "" {
+		s.EnableRequestDeadlines(&deadline.Deadlines{Header: o.DeadlineHeader})
+	}
+	return s, nil
+}
+
+// Run serves logic according to o until ctx is done. When the process is
+// socket activated by systemd, the server accepts connections on the first
+// socket passed instead of listening on Port.
+func Run(ctx context.Context, o *Options, logic broker.Interface) error {
+	s, err := NewServer(o, logic)
+	if err != nil {
+		return err
+	}
+
+	listeners, err := activation.Listeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) > 0 {
+		for _, l := range listeners[1:] {
+			glog.Warningf("Ignoring socket activated listener %v", l.Addr())
+			l.Close()
+		}
+		s.Listener = listeners[0]
+	}
+
+	if o.CFRegistrar != nil {
+		go register(ctx, "the Cloud Controller", o.CFRegistrar)
+	}
+	if o.K8SRegistrar != nil {
+		go register(ctx, "the Service Catalog", o.K8SRegistrar)
+	}
+
+	addr := fmt.Sprintf(":%d", o.Port)
+	switch {
+	case o.Insecure:
+		err = s.Run(ctx, addr)
+	case o.TLSCertFile != "" && o.TLSKeyFile != "":
+		err = s.RunTLSWithTLSFiles(ctx, addr, o.TLSCertFile, o.TLSKeyFile)
+	default:
+		err = s.RunTLS(ctx, addr, o.TLSCert, o.TLSKey)
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// registerRetryInterval is how long register waits between attempts.
+var registerRetryInterval = 10 * time.Second
+
+// registrar registers the broker with a platform.
+type registrar interface {
+	Register(ctx context.Context) error
+}
+
+// register registers the broker with r until it succeeds or ctx is done.
+// Platforms fetch the catalog while registering, so the first attempts may
+// fail until the server is listening and reachable.
+func register(ctx context.Context, platform string, r registrar) {
+	for {
+		err := r.Register(ctx)
+		if err == nil {
+			glog.Infof("Registered the broker with %s", platform)
+			return
+		}
+		glog.Warningf("Error registering the broker with %s, retrying in %v: %v", platform, registerRetryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(registerRetryInterval):
+		}
+	}
+}
+
+// authenticate returns middleware rejecting requests whose bearer token
+// isn't accepted by a with a 401.
+func authenticate(a TokenAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ok, err := a.Authenticate(r.Context(), strings.TrimPrefix(auth, "Bearer "))
+			if err != nil {
+				glog.Errorf("Error authenticating token: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
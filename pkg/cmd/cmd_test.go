@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+type staticAuthenticator string
+
+func (a staticAuthenticator) Authenticate(ctx context.Context, token string) (bool, error) {
+	return token == string(a), nil
+}
+
+func TestOptionsFlags(t *testing.T) {
+	fs := flag.NewFlagSet("broker", flag.ContinueOnError)
+	o := &Options{}
+	o.AddFlags(fs)
+	if err := fs.Parse([]string{"--port", "8080", "--insecure", "--proxy-protocol", "--reuse-port", "--authenticate-k8s-token", "--trusted-proxies", "10.0.0.0/8, fd00::/8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := 8080, o.Port; e != a {
+		t.Errorf("Unexpected port; expected %v, got %v", e, a)
+	}
+	if e, a := []string{"10.0.0.0/8", "fd00::/8"}, o.TrustedProxies; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected trusted proxies; expected %v, got %v", e, a)
+	}
+	if !o.Insecure || !o.AuthenticateK8SToken || !o.ProxyProtocol || !o.ReusePort {
+		t.Errorf("Expected the boolean flags to be set, got %+v", o)
+	}
+	if err := o.Validate(); err == nil {
+		t.Error("Expected an error without an Authenticator")
+	}
+	o.Authenticator = staticAuthenticator("token")
+	if err := o.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestOptionsValidateTLS(t *testing.T) {
+	o := &Options{Port: DefaultPort}
+	if err := o.Validate(); err == nil {
+		t.Error("Expected an error without TLS material or --insecure")
+	}
+	o.TLSCertFile, o.TLSKeyFile = "tls.crt", "tls.key"
+	if err := o.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewServerAuthenticates(t *testing.T) {
+	s, err := NewServer(&Options{
+		Port:                 DefaultPort,
+		Insecure:             true,
+		AuthenticateK8SToken: true,
+		Authenticator:        staticAuthenticator("token"),
+	}, &brokertest.FakeBroker{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, token string) int {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("X-Broker-API-Version", "2.13")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if e, a := http.StatusUnauthorized, get("/v2/catalog", ""); e != a {
+		t.Errorf("Unexpected status code without a token; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusUnauthorized, get("/v2/catalog", "wrong"); e != a {
+		t.Errorf("Unexpected status code with a wrong token; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusOK, get("/v2/catalog", "token"); e != a {
+		t.Errorf("Unexpected status code with a valid token; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusOK, get("/healthz", ""); e != a {
+		t.Errorf("Expected health checks not to be authenticated; expected %v, got %v", e, a)
+	}
+}
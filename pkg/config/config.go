@@ -0,0 +1,406 @@
+// Package config loads the options of a broker binary from an optional YAML
+// file and from environment variables, which take precedence, so brokers
+// deployed with Helm or plain Kubernetes manifests can be configured without
+// flags.
+//
+// Every option has a file key and an environment variable:
+//
+//	port: 8443                       OSB_PORT
+//	insecure: false                  OSB_INSECURE
+//	tls:
+//	  cert: <base64>                 OSB_TLS_CERT
+//	  key: <base64>                  OSB_TLS_KEY
+//	  cert_file: /etc/tls/tls.crt    OSB_TLS_CERT_FILE
+//	  key_file: /etc/tls/tls.key     OSB_TLS_KEY_FILE
+//	cors:
+//	  enabled: false                 OSB_CORS_ENABLED
+//	auth:
+//	  k8s_token: false               OSB_AUTH_K8S_TOKEN
+//	timeouts:
+//	  read: 30s                      OSB_TIMEOUTS_READ
+//	  read_header: 10s               OSB_TIMEOUTS_READ_HEADER
+//	  write: 30s                     OSB_TIMEOUTS_WRITE
+//	  idle: 2m                       OSB_TIMEOUTS_IDLE
+//	  shutdown: 3s                   OSB_TIMEOUTS_SHUTDOWN
+//	trusted_proxies: 10.0.0.0/8,fd00::/8  OSB_TRUSTED_PROXIES
+//	proxy_protocol: false            OSB_PROXY_PROTOCOL
+//	reuse_port: false                OSB_REUSE_PORT
+//	max_header_bytes: 65536          OSB_MAX_HEADER_BYTES
+//	http2:
+//	  disabled: false                OSB_HTTP2_DISABLED
+//	  h2c: false                     OSB_HTTP2_H2C
+//	features:
+//	  <name>: true                   OSB_FEATURES_<NAME>
+//	log:
+//	  level: 4                       OSB_LOG_LEVEL
+//	ratelimit:
+//	  rate: 10                       OSB_RATELIMIT_RATE
+//	  burst: 20                      OSB_RATELIMIT_BURST
+//	maintenance: false               OSB_MAINTENANCE
+//	catalog_file: /etc/catalog.json  OSB_CATALOG_FILE
+//	debug:
+//	  pretty_json: false             OSB_DEBUG_PRETTY_JSON
+//
+// The log, ratelimit, maintenance and catalog_file options can be reloaded
+// while the broker is running; see package reload.
+//
+// Only the subset of YAML needed for this layout is supported: nested
+// mappings of scalars, comments and quoted strings.
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/cmd"
+	"github.com/pmorie/osb-broker-lib/pkg/proxy"
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+)
+
+// EnvPrefix prefixes the environment variables read by Load.
+const EnvPrefix = "OSB_"
+
+// Config is the configuration of a broker binary.
+type Config struct {
+	// Port is the port to listen on. It defaults to cmd.DefaultPort.
+	Port int
+	// Insecure serves plain HTTP instead of HTTPS.
+	Insecure bool
+	// TLSCert and TLSKey are the base64 encoded TLS certificate and key.
+	TLSCert string
+	TLSKey  string
+	// TLSCertFile and TLSKeyFile are the paths of the TLS certificate and
+	// key.
+	TLSCertFile string
+	TLSKeyFile  string
+	// EnableCORS answers CORS preflight requests.
+	EnableCORS bool
+	// TrustedProxies are the networks of the trusted proxies.
+	TrustedProxies []string
+	// ProxyProtocol reads PROXY protocol headers.
+	ProxyProtocol bool
+	// ReusePort listens with SO_REUSEPORT.
+	ReusePort bool
+	// AuthenticateK8SToken authenticates the bearer tokens of OSB requests.
+	AuthenticateK8SToken bool
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout, IdleTimeout and
+	// ShutdownTimeout are the server timeouts.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	// MaxHeaderBytes bounds the size of request headers.
+	MaxHeaderBytes int
+	// DisableHTTP2 serves HTTP/1.1 only and EnableH2C also serves HTTP/2
+	// without TLS.
+	DisableHTTP2 bool
+	EnableH2C    bool
+	// Features holds the feature flags, keyed by lower case name.
+	Features map[string]bool
+	// LogLevel is the glog verbosity.
+	LogLevel int
+	// RateLimit and RateBurst are the per-principal rate limit; a zero
+	// RateLimit disables rate limiting.
+	RateLimit float64
+	RateBurst int
+	// Maintenance puts the broker in read-only mode.
+	Maintenance bool
+	// CatalogFile is the path of a JSON catalog passed to business logic
+	// implementing broker.ReloadAware.
+	CatalogFile string
+	// PrettyJSON indents JSON responses and sorts their keys.
+	PrettyJSON bool
+}
+
+// Enabled returns whether the feature flag name is set.
+func (c *Config) Enabled(name string) bool {
+	return c.Features[strings.ToLower(name)]
+}
+
+// Validate returns an error if the configuration is inconsistent.
+func (c *Config) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("config: invalid port %d", c.Port)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("config: tls.cert_file and tls.key_file must be set together")
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("config: tls.cert and tls.key must be set together")
+	}
+	if !c.Insecure && c.TLSCertFile == "" && c.TLSCert == "" {
+		return fmt.Errorf("config: a TLS certificate and key are required unless insecure is set")
+	}
+	if c.MaxHeaderBytes < 0 {
+		return fmt.Errorf("config: max_header_bytes must not be negative")
+	}
+	if c.DisableHTTP2 && c.EnableH2C {
+		return fmt.Errorf("config: http2.h2c requires HTTP/2")
+	}
+	if c.LogLevel < 0 {
+		return fmt.Errorf("config: log.level must not be negative")
+	}
+	if c.RateLimit < 0 || c.RateBurst < 0 {
+		return fmt.Errorf("config: ratelimit.rate and ratelimit.burst must not be negative")
+	}
+	if c.RateLimit > 0 && c.RateBurst == 0 {
+		return fmt.Errorf("config: ratelimit.burst is required with ratelimit.rate")
+	}
+	for key, d := range map[string]time.Duration{
+		"timeouts.read":        c.ReadTimeout,
+		"timeouts.read_header": c.ReadHeaderTimeout,
+		"timeouts.write":       c.WriteTimeout,
+		"timeouts.idle":        c.IdleTimeout,
+		"timeouts.shutdown":    c.ShutdownTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("config: %s must not be negative", key)
+		}
+	}
+	return nil
+}
+
+// ApplyTo copies the configuration to the options of a broker binary.
+func (c *Config) ApplyTo(o *cmd.Options) {
+	o.Port = c.Port
+	o.Insecure = c.Insecure
+	o.TLSCert = c.TLSCert
+	o.TLSKey = c.TLSKey
+	o.TLSCertFile = c.TLSCertFile
+	o.TLSKeyFile = c.TLSKeyFile
+	o.EnableCORS = c.EnableCORS
+	o.TrustedProxies = c.TrustedProxies
+	o.ProxyProtocol = c.ProxyProtocol
+	o.ReusePort = c.ReusePort
+	o.AuthenticateK8SToken = c.AuthenticateK8SToken
+	o.ReadTimeout = c.ReadTimeout
+	o.ReadHeaderTimeout = c.ReadHeaderTimeout
+	o.WriteTimeout = c.WriteTimeout
+	o.IdleTimeout = c.IdleTimeout
+	o.ShutdownTimeout = c.ShutdownTimeout
+	o.MaxHeaderBytes = c.MaxHeaderBytes
+	o.DisableHTTP2 = c.DisableHTTP2
+	o.EnableH2C = c.EnableH2C
+	o.PrettyJSON = c.PrettyJSON
+}
+
+// Sanitized returns the options keyed by file key, for admin endpoints
+// reporting the effective configuration. Durations are formatted as in the
+// file, feature flags are keyed "features.<name>" and the inline TLS key is
+// replaced by redact.Placeholder.
+func (c *Config) Sanitized() map[string]interface{} {
+	values := map[string]interface{}{
+		"port":                 c.Port,
+		"insecure":             c.Insecure,
+		"tls.cert":             c.TLSCert,
+		"tls.key":              c.TLSKey,
+		"tls.cert_file":        c.TLSCertFile,
+		"tls.key_file":         c.TLSKeyFile,
+		"cors.enabled":         c.EnableCORS,
+		"auth.k8s_token":       c.AuthenticateK8SToken,
+		"trusted_proxies":      strings.Join(c.TrustedProxies, ","),
+		"proxy_protocol":       c.ProxyProtocol,
+		"reuse_port":           c.ReusePort,
+		"timeouts.read":        c.ReadTimeout.String(),
+		"timeouts.read_header": c.ReadHeaderTimeout.String(),
+		"timeouts.write":       c.WriteTimeout.String(),
+		"timeouts.idle":        c.IdleTimeout.String(),
+		"timeouts.shutdown":    c.ShutdownTimeout.String(),
+		"max_header_bytes":     c.MaxHeaderBytes,
+		"http2.disabled":       c.DisableHTTP2,
+		"http2.h2c":            c.EnableH2C,
+		"log.level":            c.LogLevel,
+		"ratelimit.rate":       c.RateLimit,
+		"ratelimit.burst":      c.RateBurst,
+		"maintenance":          c.Maintenance,
+		"catalog_file":         c.CatalogFile,
+		"debug.pretty_json":    c.PrettyJSON,
+	}
+	if c.TLSKey != "" {
+		values["tls.key"] = redact.Placeholder
+	}
+	for name, enabled := range c.Features {
+		values["features."+name] = enabled
+	}
+	return values
+}
+
+// Load reads the YAML file at path, if path isn't empty, then overrides its
+// values with the environment and validates the result.
+func Load(path string) (*Config, error) {
+	var file io.Reader
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		file = f
+	}
+	return Parse(file, os.Environ())
+}
+
+// Parse is Load reading the file from file, which may be nil, and the
+// environment from environ, a list of KEY=value strings.
+func Parse(file io.Reader, environ []string) (*Config, error) {
+	values := map[string]string{}
+	if file != nil {
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		values, err = parseYAML(string(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], EnvPrefix) {
+			continue
+		}
+		key, ok := envKey(kv[len(EnvPrefix):i])
+		if !ok {
+			continue
+		}
+		values[key] = kv[i+1:]
+	}
+
+	c := &Config{
+		Port:     cmd.DefaultPort,
+		Features: map[string]bool{},
+	}
+	for key, value := range values {
+		if err := c.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// keys are the file keys of the options other than feature flags.
+var keys = []string{
+	"port",
+	"insecure",
+	"tls.cert",
+	"tls.key",
+	"tls.cert_file",
+	"tls.key_file",
+	"cors.enabled",
+	"auth.k8s_token",
+	"trusted_proxies",
+	"proxy_protocol",
+	"reuse_port",
+	"timeouts.read",
+	"timeouts.read_header",
+	"timeouts.write",
+	"timeouts.idle",
+	"timeouts.shutdown",
+	"max_header_bytes",
+	"http2.disabled",
+	"http2.h2c",
+	"log.level",
+	"ratelimit.rate",
+	"ratelimit.burst",
+	"maintenance",
+	"catalog_file",
+	"debug.pretty_json",
+}
+
+// envKey returns the file key of the environment variable name, stripped
+// of EnvPrefix.
+func envKey(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "features_") {
+		return "features." + strings.TrimPrefix(name, "features_"), true
+	}
+	for _, key := range keys {
+		if strings.Replace(key, ".", "_", -1) == name {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// set sets the option with the file key key.
+func (c *Config) set(key, value string) error {
+	var err error
+	switch key {
+	case "port":
+		c.Port, err = strconv.Atoi(value)
+	case "insecure":
+		c.Insecure, err = strconv.ParseBool(value)
+	case "tls.cert":
+		c.TLSCert = value
+	case "tls.key":
+		c.TLSKey = value
+	case "tls.cert_file":
+		c.TLSCertFile = value
+	case "tls.key_file":
+		c.TLSKeyFile = value
+	case "cors.enabled":
+		c.EnableCORS, err = strconv.ParseBool(value)
+	case "auth.k8s_token":
+		c.AuthenticateK8SToken, err = strconv.ParseBool(value)
+	case "trusted_proxies":
+		c.TrustedProxies = nil
+		for _, network := range strings.Split(value, ",") {
+			if network = strings.TrimSpace(network); network != "" {
+				c.TrustedProxies = append(c.TrustedProxies, network)
+			}
+		}
+		_, err = proxy.ParseTrusted(c.TrustedProxies)
+	case "proxy_protocol":
+		c.ProxyProtocol, err = strconv.ParseBool(value)
+	case "reuse_port":
+		c.ReusePort, err = strconv.ParseBool(value)
+	case "timeouts.read":
+		c.ReadTimeout, err = time.ParseDuration(value)
+	case "timeouts.read_header":
+		c.ReadHeaderTimeout, err = time.ParseDuration(value)
+	case "timeouts.write":
+		c.WriteTimeout, err = time.ParseDuration(value)
+	case "timeouts.idle":
+		c.IdleTimeout, err = time.ParseDuration(value)
+	case "max_header_bytes":
+		c.MaxHeaderBytes, err = strconv.Atoi(value)
+	case "http2.disabled":
+		c.DisableHTTP2, err = strconv.ParseBool(value)
+	case "http2.h2c":
+		c.EnableH2C, err = strconv.ParseBool(value)
+	case "timeouts.shutdown":
+		c.ShutdownTimeout, err = time.ParseDuration(value)
+	case "log.level":
+		c.LogLevel, err = strconv.Atoi(value)
+	case "ratelimit.rate":
+		c.RateLimit, err = strconv.ParseFloat(value, 64)
+	case "ratelimit.burst":
+		c.RateBurst, err = strconv.Atoi(value)
+	case "maintenance":
+		c.Maintenance, err = strconv.ParseBool(value)
+	case "debug.pretty_json":
+		c.PrettyJSON, err = strconv.ParseBool(value)
+	case "catalog_file":
+		c.CatalogFile = value
+	default:
+		if !strings.HasPrefix(key, "features.") {
+			return fmt.Errorf("config: unknown key %q", key)
+		}
+		var enabled bool
+		enabled, err = strconv.ParseBool(value)
+		c.Features[strings.ToLower(strings.TrimPrefix(key, "features."))] = enabled
+	}
+	if err != nil {
+		return fmt.Errorf("config: invalid %s %q: %v", key, value, err)
+	}
+	return nil
+}
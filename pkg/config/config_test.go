@@ -0,0 +1,154 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/cmd"
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+)
+
+const testFile = `
+# Broker configuration
+port: 8080
+tls:
+  cert_file: /etc/tls/tls.crt  # mounted from a secret
+  key_file: "/etc/tls/tls.key"
+cors:
+  enabled: true
+trusted_proxies: 10.0.0.0/8, 192.168.1.10
+timeouts:
+  read: 30s
+  idle: 2m
+http2:
+  h2c: true
+features:
+  Backups: true
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(testFile), []string{
+		"OSB_PORT=9090",
+		"OSB_TIMEOUTS_WRITE=1m",
+		"OSB_FEATURES_SHARING=true",
+		"OSB_DEBUG_PRETTY_JSON=true",
+		"HOME=/root",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Config{
+		Port:           9090,
+		TLSCertFile:    "/etc/tls/tls.crt",
+		TLSKeyFile:     "/etc/tls/tls.key",
+		EnableCORS:     true,
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10"},
+		ReadTimeout:    30 * time.Second,
+		IdleTimeout:    2 * time.Minute,
+		EnableH2C:      true,
+		WriteTimeout:   time.Minute,
+		PrettyJSON:     true,
+		Features: map[string]bool{
+			"backups": true,
+			"sharing": true,
+		},
+	}
+	if !reflect.DeepEqual(e, c) {
+		t.Errorf("Unexpected config; expected %+v, got %+v", e, c)
+	}
+	if !c.Enabled("Sharing") {
+		t.Error("Expected the sharing feature to be enabled")
+	}
+
+	o := &cmd.Options{}
+	c.ApplyTo(o)
+	if e, a := "/etc/tls/tls.key", o.TLSKeyFile; e != a {
+		t.Errorf("Unexpected TLS key file; expected %v, got %v", e, a)
+	}
+	if err := o.Validate(); err != nil {
+		t.Errorf("Unexpected error validating options: %v", err)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	c, err := Parse(nil, []string{"OSB_INSECURE=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := cmd.DefaultPort, c.Port; e != a {
+		t.Errorf("Unexpected port; expected %v, got %v", e, a)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		environ []string
+	}{
+		{name: "no TLS", file: "port: 8080"},
+		{name: "unknown key", file: "insecure: true\nprot: 8080"},
+		{name: "invalid port", environ: []string{"OSB_INSECURE=true", "OSB_PORT=http"}},
+		{name: "invalid duration", file: "insecure: true\ntimeouts:\n  read: 30"},
+		{name: "negative duration", file: "insecure: true\ntimeouts:\n  read: -1s"},
+		{name: "half TLS", file: "tls:\n  cert_file: tls.crt"},
+		{name: "invalid proxy", file: "insecure: true\ntrusted_proxies: proxy.local"},
+		{name: "h2c without HTTP/2", file: "insecure: true\nhttp2:\n  disabled: true\n  h2c: true"},
+		{name: "list", file: "insecure: true\nfeatures:\n  - backups"},
+		{name: "bad indentation", file: "insecure: true\ntls:\n    cert: a\n  key: b"},
+		{name: "no value", file: "insecure"},
+	}
+
+	for _, tc := range cases {
+		var file *strings.Reader
+		if tc.file != "" {
+			file = strings.NewReader(tc.file)
+		}
+		var err error
+		if file != nil {
+			_, err = Parse(file, tc.environ)
+		} else {
+			_, err = Parse(nil, tc.environ)
+		}
+		if err == nil {
+			t.Errorf("%v: expected an error", tc.name)
+		}
+	}
+}
+
+func TestSanitized(t *testing.T) {
+	c := &Config{
+		Port:           8443,
+		TLSCert:        "cert",
+		TLSKey:         "key",
+		TrustedProxies: []string{"10.0.0.0/8", "fd00::/8"},
+		ReadTimeout:    30 * time.Second,
+		Features:       map[string]bool{"beta": true},
+	}
+	values := c.Sanitized()
+
+	cases := []struct {
+		key      string
+		expected interface{}
+	}{
+		{key: "port", expected: 8443},
+		{key: "tls.cert", expected: "cert"},
+		{key: "tls.key", expected: redact.Placeholder},
+		{key: "trusted_proxies", expected: "10.0.0.0/8,fd00::/8"},
+		{key: "timeouts.read", expected: "30s"},
+		{key: "features.beta", expected: true},
+	}
+	for _, tc := range cases {
+		if e, a := tc.expected, values[tc.key]; e != a {
+			t.Errorf("%v: expected %v, got %v", tc.key, e, a)
+		}
+	}
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			t.Errorf("Missing key %v", key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses a document of nested mappings of scalars into values
+// keyed by the dotted path of their keys.
+func parseYAML(doc string) (map[string]string, error) {
+	type level struct {
+		indent int
+		prefix string
+	}
+	values := map[string]string{}
+	// stack holds the enclosing mappings; the document is the first.
+	stack := []level{{indent: 0}}
+	// pending is the key of the previous line when it opened a mapping.
+	pending := ""
+
+	for n, line := range strings.Split(doc, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		if strings.TrimSpace(line) == "" || line == "---" {
+			continue
+		}
+		if strings.Contains(line, "\t") {
+			return nil, fmt.Errorf("config: line %d: tabs are not allowed", n+1)
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		if pending != "" {
+			if indent <= stack[len(stack)-1].indent {
+				return nil, fmt.Errorf("config: line %d: expected a nested mapping", n+1)
+			}
+			stack = append(stack, level{indent: indent, prefix: pending + "."})
+			pending = ""
+		}
+		for indent < stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		if indent != stack[len(stack)-1].indent {
+			return nil, fmt.Errorf("config: line %d: inconsistent indentation", n+1)
+		}
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			return nil, fmt.Errorf("config: line %d: lists are not supported", n+1)
+		}
+
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("config: line %d: expected key: value", n+1)
+		}
+		key := stack[len(stack)-1].prefix + strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if value == "" {
+			pending = key
+			continue
+		}
+		value, err := unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("config: line %d: %v", n+1, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// stripComment removes a trailing comment from line, ignoring # within
+// quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote returns the scalar value, unquoted if it is quoted.
+func unquote(value string) (string, error) {
+	switch value[0] {
+	case '"':
+		return strconv.Unquote(value)
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,314 @@
+// Package contract drives every OSB operation through the official Go OSB
+// client against a running broker, checking that the broker speaks the API
+// the way the client, and the platforms built on it, expect: status codes,
+// headers, idempotent retries and asynchronous operation polling.
+//
+// This library runs it against its own server on every change. Brokers can
+// run it against themselves in their integration tests:
+//
+//	func TestContract(t *testing.T) {
+//		s := brokertest.NewServer(t, NewBusinessLogic())
+//		contract.Run(t, contract.Fixture{
+//			Client:    s.Client,
+//			ServiceID: "db",
+//			PlanID:    "small",
+//		})
+//	}
+package contract
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+const (
+	defaultPollInterval = 100 * time.Millisecond
+	defaultTimeout      = 30 * time.Second
+)
+
+// Fixture describes the broker under test.
+type Fixture struct {
+	// Client talks to the broker under test.
+	Client osb.Client
+	// ServiceID and PlanID are the bindable plan instances are provisioned
+	// from. Both must be in the catalog.
+	ServiceID string
+	PlanID    string
+	// UpdatePlanID, if set, is a plan instances are updated to.
+	UpdatePlanID string
+	// Parameters are passed to provision and bind requests.
+	Parameters map[string]interface{}
+	// AsyncBindings sends bind and unbind requests accepting incomplete
+	// operations and checks get binding. The client must have alpha
+	// features enabled.
+	AsyncBindings bool
+	// PollInterval is how often asynchronous operations are polled. It
+	// defaults to 100ms.
+	PollInterval time.Duration
+	// Timeout bounds how long asynchronous operations are polled. It
+	// defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Run runs the contract as subtests of t. Every run provisions, updates,
+// binds, unbinds and deprovisions a fresh instance, so it can run against a
+// shared broker; the subtests depend on each other and stop at the first
+// failure.
+func Run(t *testing.T, f Fixture) {
+	t.Helper()
+	if f.PollInterval == 0 {
+		f.PollInterval = defaultPollInterval
+	}
+	if f.Timeout == 0 {
+		f.Timeout = defaultTimeout
+	}
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	instanceID, bindingID := "contract-instance-"+suffix, "contract-binding-"+suffix
+
+	steps := []struct {
+		name string
+		run  func(t *testing.T, f *Fixture, instanceID, bindingID string)
+	}{
+		{"catalog", testCatalog},
+		{"provision", testProvision},
+		{"update", testUpdate},
+		{"bind", testBind},
+		{"unbind", testUnbind},
+		{"deprovision", testDeprovision},
+	}
+	for _, step := range steps {
+		if !t.Run(step.name, func(t *testing.T) { step.run(t, &f, instanceID, bindingID) }) {
+			return
+		}
+	}
+}
+
+func testCatalog(t *testing.T, f *Fixture, _, _ string) {
+	catalog, err := f.Client.GetCatalog()
+	if err != nil {
+		t.Fatalf("getting the catalog: %v", err)
+	}
+
+	for _, service := range catalog.Services {
+		if service.ID != f.ServiceID {
+			continue
+		}
+		for _, plan := range service.Plans {
+			if plan.ID == f.PlanID {
+				return
+			}
+		}
+	}
+	t.Fatalf("plan %q of service %q not found in the catalog", f.PlanID, f.ServiceID)
+}
+
+func (f *Fixture) provisionRequest(instanceID string) *osb.ProvisionRequest {
+	return &osb.ProvisionRequest{
+		InstanceID:        instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         f.ServiceID,
+		PlanID:            f.PlanID,
+		OrganizationGUID:  "contract-org",
+		SpaceGUID:         "contract-space",
+		Parameters:        f.Parameters,
+	}
+}
+
+func testProvision(t *testing.T, f *Fixture, instanceID, _ string) {
+	response, err := f.Client.ProvisionInstance(f.provisionRequest(instanceID))
+	if err != nil {
+		t.Fatalf("provisioning: %v", err)
+	}
+	if response.Async {
+		f.waitForInstance(t, instanceID, response.OperationKey, false)
+	}
+
+	// A retry of a completed provision must succeed without creating
+	// anything; the spec answers it with 200 rather than 201 or 202.
+	retry, err := f.Client.ProvisionInstance(f.provisionRequest(instanceID))
+	if err != nil {
+		t.Fatalf("retrying the provision: %v", err)
+	}
+	if retry.Async {
+		t.Error("expected a retry of a completed provision to be synchronous")
+	}
+
+	conflicting := f.provisionRequest(instanceID)
+	conflicting.OrganizationGUID = "other-org"
+	conflicting.Parameters = map[string]interface{}{"contract": "conflict"}
+	if _, err := f.Client.ProvisionInstance(conflicting); !isStatus(err, http.StatusConflict) {
+		t.Errorf("expected provisioning an existing instance with different attributes to get a 409, got %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, f *Fixture, instanceID, _ string) {
+	if f.UpdatePlanID == "" {
+		t.Skip("no UpdatePlanID")
+	}
+
+	response, err := f.Client.UpdateInstance(&osb.UpdateInstanceRequest{
+		InstanceID:        instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         f.ServiceID,
+		PlanID:            &f.UpdatePlanID,
+	})
+	if err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+	if response.Async {
+		f.waitForInstance(t, instanceID, response.OperationKey, false)
+	}
+	f.PlanID = f.UpdatePlanID
+}
+
+func (f *Fixture) bindRequest(instanceID, bindingID string) *osb.BindRequest {
+	appGUID := "contract-app"
+	return &osb.BindRequest{
+		BindingID:         bindingID,
+		InstanceID:        instanceID,
+		AcceptsIncomplete: f.AsyncBindings,
+		ServiceID:         f.ServiceID,
+		PlanID:            f.PlanID,
+		BindResource:      &osb.BindResource{AppGUID: &appGUID},
+		Parameters:        f.Parameters,
+	}
+}
+
+func testBind(t *testing.T, f *Fixture, instanceID, bindingID string) {
+	response, err := f.Client.Bind(f.bindRequest(instanceID, bindingID))
+	if err != nil {
+		t.Fatalf("binding: %v", err)
+	}
+	if response.Async {
+		if !f.AsyncBindings {
+			t.Fatal("got an asynchronous binding for a request that doesn't accept one")
+		}
+		f.waitForBinding(t, instanceID, bindingID, response.OperationKey, false)
+	}
+
+	if _, err := f.Client.Bind(f.bindRequest(instanceID, bindingID)); err != nil {
+		t.Errorf("retrying the bind: %v", err)
+	}
+
+	if !f.AsyncBindings {
+		return
+	}
+	binding, err := f.Client.GetBinding(&osb.GetBindingRequest{InstanceID: instanceID, BindingID: bindingID})
+	if err != nil {
+		t.Fatalf("getting the binding: %v", err)
+	}
+	if !response.Async && len(response.Credentials) > 0 && len(binding.Credentials) == 0 {
+		t.Error("expected get binding to return the binding's credentials")
+	}
+}
+
+func testUnbind(t *testing.T, f *Fixture, instanceID, bindingID string) {
+	request := &osb.UnbindRequest{
+		InstanceID:        instanceID,
+		BindingID:         bindingID,
+		AcceptsIncomplete: f.AsyncBindings,
+		ServiceID:         f.ServiceID,
+		PlanID:            f.PlanID,
+	}
+	response, err := f.Client.Unbind(request)
+	if err != nil {
+		t.Fatalf("unbinding: %v", err)
+	}
+	if response.Async {
+		f.waitForBinding(t, instanceID, bindingID, response.OperationKey, true)
+	}
+
+	// The client treats the 410 Gone of a repeated unbind as success.
+	if _, err := f.Client.Unbind(request); err != nil {
+		t.Errorf("expected unbinding a missing binding to get a 410, got %v", err)
+	}
+}
+
+func testDeprovision(t *testing.T, f *Fixture, instanceID, _ string) {
+	request := &osb.DeprovisionRequest{
+		InstanceID:        instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         f.ServiceID,
+		PlanID:            f.PlanID,
+	}
+	response, err := f.Client.DeprovisionInstance(request)
+	if err != nil {
+		t.Fatalf("deprovisioning: %v", err)
+	}
+	if response.Async {
+		f.waitForInstance(t, instanceID, response.OperationKey, true)
+	}
+
+	// The client treats the 410 Gone of a repeated deprovision as success.
+	if _, err := f.Client.DeprovisionInstance(request); err != nil {
+		t.Errorf("expected deprovisioning a missing instance to get a 410, got %v", err)
+	}
+}
+
+// waitForInstance polls the last operation of an instance until it
+// succeeds. Deletions also succeed when the instance is gone.
+func (f *Fixture) waitForInstance(t *testing.T, instanceID string, key *osb.OperationKey, deleting bool) {
+	t.Helper()
+	f.wait(t, deleting, func() (*osb.LastOperationResponse, error) {
+		return f.Client.PollLastOperation(&osb.LastOperationRequest{
+			InstanceID:   instanceID,
+			ServiceID:    &f.ServiceID,
+			PlanID:       &f.PlanID,
+			OperationKey: key,
+		})
+	})
+}
+
+// waitForBinding polls the last operation of a binding until it succeeds.
+// Deletions also succeed when the binding is gone.
+func (f *Fixture) waitForBinding(t *testing.T, instanceID, bindingID string, key *osb.OperationKey, deleting bool) {
+	t.Helper()
+	f.wait(t, deleting, func() (*osb.LastOperationResponse, error) {
+		return f.Client.PollBindingLastOperation(&osb.BindingLastOperationRequest{
+			InstanceID:   instanceID,
+			BindingID:    bindingID,
+			ServiceID:    &f.ServiceID,
+			PlanID:       &f.PlanID,
+			OperationKey: key,
+		})
+	})
+}
+
+func (f *Fixture) wait(t *testing.T, deleting bool, poll func() (*osb.LastOperationResponse, error)) {
+	t.Helper()
+	for deadline := time.Now().Add(f.Timeout); ; time.Sleep(f.PollInterval) {
+		response, err := poll()
+		switch {
+		case err != nil && deleting && osb.IsGoneError(err):
+			return
+		case err != nil:
+			t.Fatalf("polling the last operation: %v", err)
+		case response.State == osb.StateSucceeded:
+			return
+		case response.State == osb.StateFailed:
+			t.Fatalf("operation failed: %v", describe(response.Description))
+		case response.State != osb.StateInProgress:
+			t.Fatalf("unexpected last operation state %q", response.State)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("operation still in progress after %v", f.Timeout)
+		}
+	}
+}
+
+func isStatus(err error, status int) bool {
+	httpErr, ok := osb.IsHTTPError(err)
+	return ok && httpErr.StatusCode == status
+}
+
+func describe(description *string) string {
+	if description == nil {
+		return "no description"
+	}
+	return *description
+}
@@ -0,0 +1,56 @@
+package contract_test
+
+import (
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokers/memory"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/contract"
+)
+
+func services() []osb.Service {
+	return []osb.Service{
+		{
+			ID:          "db",
+			Name:        "db",
+			Description: "A database",
+			Bindable:    true,
+			Plans: []osb.Plan{
+				{ID: "small", Name: "small", Description: "A small database"},
+				{ID: "large", Name: "large", Description: "A large database"},
+			},
+		},
+	}
+}
+
+func TestContract(t *testing.T) {
+	asyncBroker := memory.New(services())
+	asyncBroker.AsyncDelay = 20 * time.Millisecond
+
+	cases := []struct {
+		name          string
+		logic         broker.Interface
+		asyncBindings bool
+	}{
+		{name: "sync", logic: memory.New(services())},
+		{name: "async", logic: asyncBroker},
+		{name: "async bindings", logic: brokertest.NewDelayedBroker(memory.New(services()), 20*time.Millisecond), asyncBindings: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := brokertest.NewServer(t, tc.logic)
+			contract.Run(t, contract.Fixture{
+				Client:        s.Client,
+				ServiceID:     "db",
+				PlanID:        "small",
+				UpdatePlanID:  "large",
+				AsyncBindings: tc.asyncBindings,
+				PollInterval:  5 * time.Millisecond,
+			})
+		})
+	}
+}
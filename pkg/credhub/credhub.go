@@ -0,0 +1,139 @@
+// Package credhub delivers binding credentials by reference through
+// CredHub, as Cloud Foundry platforms expect: the credentials are stored in
+// CredHub and the bind response only carries a credhub-ref pointing at
+// them, which the platform resolves when the application starts.
+package credhub
+
+import (
+	"fmt"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// RefKey is the key of the credentials block of a bind response carrying
+// credentials by reference.
+const RefKey = "credhub-ref"
+
+// Client is the subset of the CredHub API used to store credentials.
+// Implementations typically wrap the CredHub client library.
+type Client interface {
+	// SetJSON stores value as the JSON credential called name,
+	// overwriting any previous value.
+	SetJSON(name string, value map[string]interface{}) error
+	// AddPermission grants actor the operations, such as "read", on the
+	// credential called name.
+	AddPermission(name, actor string, operations []string) error
+	// Delete deletes the credential called name. Deleting a credential
+	// that doesn't exist is not an error.
+	Delete(name string) error
+}
+
+// CredentialName returns the CredHub name of the credentials of a binding,
+// /c/<broker>/<instance>/<binding>/credentials. Names are scoped by instance
+// rather than by service, as the Cloud Foundry convention has it, because
+// get binding requests don't carry the service ID.
+func CredentialName(brokerName, instanceID, bindingID string) string {
+	return fmt.Sprintf("/c/%s/%s/%s/credentials", brokerName, instanceID, bindingID)
+}
+
+// Ref returns the credentials block referencing the credential called name.
+func Ref(name string) map[string]interface{} {
+	return map[string]interface{}{RefKey: name}
+}
+
+// IsRef returns whether credentials are a reference rather than the
+// credentials themselves.
+func IsRef(credentials map[string]interface{}) bool {
+	_, ok := credentials[RefKey]
+	return ok && len(credentials) == 1
+}
+
+// Broker decorates a broker.Interface so that the credentials of its
+// bindings are stored in CredHub and replaced by a reference in bind and get
+// binding responses. Read access is granted to the application the binding
+// is for. Credentials are deleted from CredHub on unbind.
+type Broker struct {
+	broker.Interface
+
+	// Client stores the credentials.
+	Client Client
+	// BrokerName is the broker's name in credential names.
+	BrokerName string
+}
+
+var _ broker.Interface = &Broker{}
+
+// Bind runs the wrapped Bind and stores the credentials it returns.
+func (b *Broker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+	response, err := b.Interface.Bind(request, c)
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	response.Credentials, err = b.store(request.InstanceID, request.BindingID, appGUID(request), response.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetBinding runs the wrapped GetBinding and stores the credentials it
+// returns, which asynchronous bindings only reveal once they complete.
+func (b *Broker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+	response, err := b.Interface.GetBinding(request, c)
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	response.Credentials, err = b.store(request.InstanceID, request.BindingID, "", response.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Unbind deletes the stored credentials and runs the wrapped Unbind.
+func (b *Broker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+	response, err := b.Interface.Unbind(request, c)
+	if err != nil {
+		return response, err
+	}
+
+	if err := b.Client.Delete(CredentialName(b.BrokerName, request.InstanceID, request.BindingID)); err != nil {
+		return nil, fmt.Errorf("deleting credentials of binding %q from CredHub: %v", request.BindingID, err)
+	}
+	return response, nil
+}
+
+// store stores credentials in CredHub and returns the reference to return
+// instead. Credentials that are empty or already a reference are returned
+// as is.
+func (b *Broker) store(instanceID, bindingID, appGUID string, credentials map[string]interface{}) (map[string]interface{}, error) {
+	if len(credentials) == 0 || IsRef(credentials) {
+		return credentials, nil
+	}
+
+	name := CredentialName(b.BrokerName, instanceID, bindingID)
+	if err := b.Client.SetJSON(name, credentials); err != nil {
+		return nil, fmt.Errorf("storing credentials of binding %q in CredHub: %v", bindingID, err)
+	}
+	if appGUID != "" {
+		if err := b.Client.AddPermission(name, "mtls-app:"+appGUID, []string{"read"}); err != nil {
+			return nil, fmt.Errorf("granting application %q access to the credentials of binding %q: %v", appGUID, bindingID, err)
+		}
+	}
+	return Ref(name), nil
+}
+
+// appGUID returns the GUID of the application a binding is for, if any.
+func appGUID(request *osb.BindRequest) string {
+	if request.BindResource != nil && request.BindResource.AppGUID != nil {
+		return *request.BindResource.AppGUID
+	}
+	if request.AppGUID != nil {
+		return *request.AppGUID
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package credhub
+
+import (
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+type fakeClient struct {
+	credentials map[string]map[string]interface{}
+	permissions map[string][]string
+}
+
+func (f *fakeClient) SetJSON(name string, value map[string]interface{}) error {
+	f.credentials[name] = value
+	return nil
+}
+
+func (f *fakeClient) AddPermission(name, actor string, operations []string) error {
+	f.permissions[name] = append(f.permissions[name], actor)
+	return nil
+}
+
+func (f *fakeClient) Delete(name string) error {
+	delete(f.credentials, name)
+	return nil
+}
+
+func TestBroker(t *testing.T) {
+	client := &fakeClient{
+		credentials: map[string]map[string]interface{}{},
+		permissions: map[string][]string{},
+	}
+	secret := map[string]interface{}{"password": "hunter2"}
+	b := &Broker{
+		Interface: &brokertest.FakeBroker{
+			BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+				response := &broker.BindResponse{}
+				response.Credentials = secret
+				return response, nil
+			},
+		},
+		Client:     client,
+		BrokerName: "db-broker",
+	}
+
+	appGUID := "app"
+	response, err := b.Bind(&osb.BindRequest{
+		InstanceID:   "instance",
+		BindingID:    "binding",
+		BindResource: &osb.BindResource{AppGUID: &appGUID},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "/c/db-broker/instance/binding/credentials"
+	if e, a := Ref(name), response.Credentials; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected credentials; expected %v, got %v", e, a)
+	}
+	if e, a := secret, client.credentials[name]; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected stored credentials; expected %v, got %v", e, a)
+	}
+	if e, a := []string{"mtls-app:app"}, client.permissions[name]; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected permissions; expected %v, got %v", e, a)
+	}
+
+	if _, err := b.Unbind(&osb.UnbindRequest{InstanceID: "instance", BindingID: "binding"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.credentials[name]; ok {
+		t.Error("Expected the credentials to be deleted on unbind")
+	}
+}
+
+func TestIsRef(t *testing.T) {
+	cases := []struct {
+		credentials map[string]interface{}
+		expected    bool
+	}{
+		{credentials: Ref("/c/broker/instance/binding/credentials"), expected: true},
+		{credentials: map[string]interface{}{"password": "hunter2"}},
+		{credentials: map[string]interface{}{RefKey: "/c/x", "password": "hunter2"}},
+		{},
+	}
+	for _, tc := range cases {
+		if e, a := tc.expected, IsRef(tc.credentials); e != a {
+			t.Errorf("IsRef(%v): expected %v, got %v", tc.credentials, e, a)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package dashboard supports SSO-protected service dashboards following the
+// Cloud Foundry dashboard client convention: services declare an OAuth
+// client in the dashboard_client field of their catalog entry, which the
+// platform registers with its authorization server, and the dashboard
+// verifies the tokens issued to that client.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// ContextKey is the field of a provision request's context in which
+// platforms that register dashboard clients themselves pass the client
+// credentials to the broker.
+const ContextKey = "dashboard_client"
+
+// ServiceClient returns the dashboard client declared by a service of the
+// catalog, or nil if the service doesn't declare one.
+func ServiceClient(catalog *broker.CatalogResponse, serviceID string) *osb.DashboardClient {
+	for i := range catalog.Services {
+		if catalog.Services[i].ID == serviceID {
+			return catalog.Services[i].DashboardClient
+		}
+	}
+	return nil
+}
+
+// ValidateClient returns an error if the dashboard client is missing its ID,
+// secret or an absolute redirect URI.
+func ValidateClient(client *osb.DashboardClient) error {
+	switch {
+	case client.ID == "":
+		return fmt.Errorf("dashboard client has no id")
+	case client.Secret == "":
+		return fmt.Errorf("dashboard client %q has no secret", client.ID)
+	}
+	redirect, err := url.Parse(client.RedirectURI)
+	if err != nil {
+		return fmt.Errorf("dashboard client %q has an invalid redirect_uri: %v", client.ID, err)
+	}
+	if !redirect.IsAbs() {
+		return fmt.Errorf("dashboard client %q needs an absolute redirect_uri, got %q", client.ID, client.RedirectURI)
+	}
+	return nil
+}
+
+// ProvisionClient returns the dashboard client credentials the platform sent
+// in the context of a provision request, or nil if it sent none. It returns
+// a 400 error if the credentials are malformed.
+func ProvisionClient(request *osb.ProvisionRequest) (*osb.DashboardClient, error) {
+	raw, ok := request.Context[ContextKey]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	client := &osb.DashboardClient{}
+	if err := json.Unmarshal(data, client); err != nil {
+		return nil, badRequest(err)
+	}
+	if err := ValidateClient(client); err != nil {
+		return nil, badRequest(err)
+	}
+	return client, nil
+}
+
+func badRequest(err error) error {
+	description := fmt.Sprintf("Invalid %s in context: %v", ContextKey, err)
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusBadRequest,
+		Description: &description,
+	}
+}
+
+// TokenVerifier verifies the bearer token of a dashboard request, for
+// example by introspecting it with the authorization server or checking its
+// signature, and returns its claims. It should check that the token was
+// issued to the dashboard client and grants access to the instance.
+type TokenVerifier func(r *http.Request, instanceID, token string) (claims map[string]interface{}, err error)
+
+type claimsKey struct{}
+
+// Claims returns the claims of the token a request to a protected dashboard
+// was verified with.
+func Claims(r *http.Request) map[string]interface{} {
+	claims, _ := r.Context().Value(claimsKey{}).(map[string]interface{})
+	return claims
+}
+
+// Protect returns middleware that rejects dashboard requests without a
+// bearer token accepted by verify with a 401. The instance ID passed to
+// verify is the instance_id route variable, if any. It can be installed on
+// the router serving the dashboard with Router.Use.
+func Protect(verify TokenVerifier) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") {
+				unauthorized(w, "missing bearer token")
+				return
+			}
+
+			claims, err := verify(r, mux.Vars(r)[osb.VarKeyInstanceID], strings.TrimPrefix(auth, "Bearer "))
+			if err != nil {
+				unauthorized(w, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, reason string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, reason))
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,109 @@
+package dashboard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+func TestServiceClient(t *testing.T) {
+	client := &osb.DashboardClient{ID: "client", Secret: "secret", RedirectURI: "https://dashboard.example.com"}
+	catalog := &broker.CatalogResponse{}
+	catalog.Services = []osb.Service{{ID: "plain"}, {ID: "sso", DashboardClient: client}}
+
+	if ServiceClient(catalog, "plain") != nil {
+		t.Error("Expected no dashboard client for plain")
+	}
+	if e, a := client, ServiceClient(catalog, "sso"); e != a {
+		t.Errorf("Unexpected dashboard client; expected %v, got %v", e, a)
+	}
+}
+
+func TestProvisionClient(t *testing.T) {
+	cases := []struct {
+		name    string
+		context map[string]interface{}
+		id      string
+		err     bool
+	}{
+		{name: "none", context: map[string]interface{}{"platform": "cloudfoundry"}},
+		{
+			name: "valid",
+			context: map[string]interface{}{ContextKey: map[string]interface{}{
+				"id": "client", "secret": "secret", "redirect_uri": "https://dashboard.example.com",
+			}},
+			id: "client",
+		},
+		{
+			name: "relative redirect",
+			context: map[string]interface{}{ContextKey: map[string]interface{}{
+				"id": "client", "secret": "secret", "redirect_uri": "/callback",
+			}},
+			err: true,
+		},
+		{name: "malformed", context: map[string]interface{}{ContextKey: "client"}, err: true},
+	}
+	for _, tc := range cases {
+		client, err := ProvisionClient(&osb.ProvisionRequest{Context: tc.context})
+		if e, a := tc.err, err != nil; e != a {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if err != nil {
+			if httpErr, ok := osb.IsHTTPError(err); !ok || httpErr.StatusCode != http.StatusBadRequest {
+				t.Errorf("%v: expected a 400, got %v", tc.name, err)
+			}
+			continue
+		}
+		id := ""
+		if client != nil {
+			id = client.ID
+		}
+		if e, a := tc.id, id; e != a {
+			t.Errorf("%v: unexpected client id; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
+
+func TestProtect(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/dashboard/{instance_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Claims(r)["user"].(string)))
+	})
+	router.Use(Protect(func(r *http.Request, instanceID, token string) (map[string]interface{}, error) {
+		if token != "good" || instanceID != "instance" {
+			return nil, errors.New("access denied")
+		}
+		return map[string]interface{}{"user": "alice"}, nil
+	}))
+
+	get := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/dashboard/instance", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if e, a := http.StatusUnauthorized, get("").Code; e != a {
+		t.Errorf("Unexpected status code without a token; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusUnauthorized, get("bad").Code; e != a {
+		t.Errorf("Unexpected status code with a bad token; expected %v, got %v", e, a)
+	}
+	w := get("good")
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status code with a good token; expected %v, got %v", e, a)
+	}
+	if e, a := "alice", w.Body.String(); e != a {
+		t.Errorf("Expected the claims to reach the handler; expected %v, got %v", e, a)
+	}
+}
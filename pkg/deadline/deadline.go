@@ -0,0 +1,153 @@
+// Package deadline applies the deadlines platforms and gateways send with
+// OSB requests to the context of the request, so that business logic
+// passing RequestContext.Context to its calls stops working on requests the
+// platform has already given up on.
+package deadline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// DefaultHeader is the header read when Deadlines.Header is not set.
+const DefaultHeader = "X-Broker-Request-Timeout"
+
+// Deadlines reads the deadline of a request from a header. The header holds
+// either a timeout relative to the arrival of the request, as a number of
+// Units or a Go duration such as "30s", or an absolute RFC 3339 time.
+// Requests without the header, or with an invalid one, keep the context they
+// arrived with.
+type Deadlines struct {
+	// Header is the header holding the deadline. It defaults to
+	// DefaultHeader; gateways injecting their own, such as Envoy's
+	// X-Envoy-Expected-Rq-Timeout-Ms, can be used instead.
+	Header string
+	// Unit is the unit of timeouts sent as bare numbers. It defaults to
+	// seconds; use time.Millisecond for Envoy's header.
+	Unit time.Duration
+	// Margin is subtracted from the deadline, so that the broker gives up
+	// early enough for its answer to reach the platform in time.
+	Margin time.Duration
+	// Max, if set, caps the timeout of a request, protecting the broker
+	// from platforms asking it to work for hours.
+	Max time.Duration
+
+	now func() time.Time
+}
+
+// Deadline returns the deadline r carries, if any.
+func (d *Deadlines) Deadline(r *http.Request) (time.Time, bool) {
+	header := d.Header
+	if header == "" {
+		header = DefaultHeader
+	}
+	value := strings.TrimSpace(r.Header.Get(header))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	now := d.clock()
+	deadline, err := d.parse(value, now)
+	if err != nil {
+		broker.NewRequestLogger(r).Infof("Ignoring invalid %s header %q: %v", header, value, err)
+		return time.Time{}, false
+	}
+	if d.Max > 0 && deadline.Sub(now) > d.Max {
+		deadline = now.Add(d.Max)
+	}
+	return deadline.Add(-d.Margin), true
+}
+
+func (d *Deadlines) parse(value string, now time.Time) (time.Time, error) {
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		unit := d.Unit
+		if unit == 0 {
+			unit = time.Second
+		}
+		return now.Add(time.Duration(n * float64(unit))), nil
+	}
+	if timeout, err := time.ParseDuration(value); err == nil {
+		return now.Add(timeout), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (d *Deadlines) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// Middleware returns a handler that runs requests carrying a deadline with
+// a context expiring at it. If the deadline passes before the handler
+// answered, the platform gets a 504 Gateway Timeout instead of an empty
+// response. It can be installed on a server's router with Router.Use.
+func (d *Deadlines) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := d.Deadline(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		recorder := &writeRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		// The handler writes nothing for requests whose context is done;
+		// answer them unless the platform closed the connection.
+		if !recorder.written && ctx.Err() == context.DeadlineExceeded && r.Context().Err() == nil {
+			writeDeadlineExceeded(w)
+		}
+	})
+}
+
+// writeRecorder records whether a response was started.
+type writeRecorder struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeRecorder) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writeRecorder) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *writeRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.written = true
+		f.Flush()
+	}
+}
+
+func writeDeadlineExceeded(w http.ResponseWriter) {
+	type e struct {
+		ErrorMessage string `json:"error"`
+		Description  string `json:"description"`
+	}
+	data, err := json.Marshal(&e{
+		ErrorMessage: "DeadlineExceeded",
+		Description:  "The request did not complete before the deadline set by the platform.",
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	w.Write(data)
+}
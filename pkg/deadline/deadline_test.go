@@ -0,0 +1,99 @@
+package deadline
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDeadline(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		deadlines Deadlines
+		header    string
+		value     string
+		expected  time.Time
+		ok        bool
+	}{
+		{
+			name:  "no header",
+			value: "",
+		},
+		{
+			name:     "seconds",
+			value:    "30",
+			expected: now.Add(30 * time.Second),
+			ok:       true,
+		},
+		{
+			name:     "fractional seconds",
+			value:    "1.5",
+			expected: now.Add(1500 * time.Millisecond),
+			ok:       true,
+		},
+		{
+			name:     "duration",
+			value:    "2m",
+			expected: now.Add(2 * time.Minute),
+			ok:       true,
+		},
+		{
+			name:     "absolute",
+			value:    "2018-03-01T12:00:10Z",
+			expected: now.Add(10 * time.Second),
+			ok:       true,
+		},
+		{
+			name:      "custom header and unit",
+			deadlines: Deadlines{Header: "X-Envoy-Expected-Rq-Timeout-Ms", Unit: time.Millisecond},
+			header:    "X-Envoy-Expected-Rq-Timeout-Ms",
+			value:     "250",
+			expected:  now.Add(250 * time.Millisecond),
+			ok:        true,
+		},
+		{
+			name:      "margin",
+			deadlines: Deadlines{Margin: time.Second},
+			value:     "30",
+			expected:  now.Add(29 * time.Second),
+			ok:        true,
+		},
+		{
+			name:      "max",
+			deadlines: Deadlines{Max: time.Minute},
+			value:     "3600",
+			expected:  now.Add(time.Minute),
+			ok:        true,
+		},
+		{
+			name:  "invalid",
+			value: "soon",
+		},
+	}
+
+	for _, tc := range cases {
+		header := tc.header
+		if header == "" {
+			header = DefaultHeader
+		}
+		r, err := http.NewRequest(http.MethodPut, "/v2/service_instances/instance", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.value != "" {
+			r.Header.Set(header, tc.value)
+		}
+
+		d := tc.deadlines
+		d.now = func() time.Time { return now }
+		deadline, ok := d.Deadline(r)
+		if e, a := tc.ok, ok; e != a {
+			t.Errorf("%v: unexpected ok; expected %v, got %v", tc.name, e, a)
+			continue
+		}
+		if e, a := tc.expected, deadline; !e.Equal(a) {
+			t.Errorf("%v: unexpected deadline; expected %v, got %v", tc.name, e, a)
+		}
+	}
+}
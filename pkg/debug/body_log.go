@@ -0,0 +1,62 @@
+package debug
+
+import (
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// DefaultBodyLogLevel is the default BodyLogger.Level.
+	DefaultBodyLogLevel glog.Level = 8
+	// DefaultBodyLogMaxBytes is the default BodyLogger.MaxBodyBytes.
+	DefaultBodyLogMaxBytes = 64 << 10
+)
+
+// BodyLogger logs the request and response bodies of OSB operations,
+// redacted, while the glog verbosity is at least Level, to troubleshoot
+// schema mismatches with platforms. Bodies are only captured while logging
+// is on, so the verbosity can be raised at runtime, with the -v flag's
+// Value or the reload package, without restarting or slowing the broker
+// down otherwise.
+type BodyLogger struct {
+	// Level is the glog verbosity logging bodies. It defaults to
+	// DefaultBodyLogLevel.
+	Level glog.Level
+	// MaxBodyBytes is the length of request and response bodies logged.
+	// Only that much of a body is captured, the rest is passed on as it
+	// is read or written; longer bodies can't be redacted and are logged
+	// as redact.Placeholder. It defaults to DefaultBodyLogMaxBytes.
+	MaxBodyBytes int
+}
+
+// Middleware logs the bodies of the requests served by next. Install it with
+// server.Server.UseOSBMiddleware.
+func (l *BodyLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		level := l.Level
+		if level == 0 {
+			level = DefaultBodyLogLevel
+		}
+		if !glog.V(level) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		max := l.MaxBodyBytes
+		if max <= 0 {
+			max = DefaultBodyLogMaxBytes
+		}
+		body := captureRequestBody(r, max)
+		capture := newResponseCapture(w, max)
+		next.ServeHTTP(capture, r)
+
+		operation := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			operation = route.GetName()
+		}
+		glog.Infof("%s %s %s request=%s response=%d %s", operation, r.Method, r.URL.RequestURI(),
+			capturedBody(body, max), capture.statusCode, capturedBody(&capture.body, max))
+	})
+}
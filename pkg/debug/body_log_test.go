@@ -0,0 +1,44 @@
+package debug
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLoggerPassesBodiesThrough(t *testing.T) {
+	v := flag.Lookup("v").Value.String()
+	defer flag.Set("v", v)
+
+	for _, level := range []string{"0", "8"} {
+		flag.Set("v", level)
+
+		var received string
+		h := (&BodyLogger{MaxBodyBytes: 16}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			received = string(body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"credentials":{"password":"hunter2"}}`))
+		}))
+
+		r := httptest.NewRequest("PUT", "/v2/service_instances/1/service_bindings/2", strings.NewReader(`{"service_id":"svc"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if e, a := `{"service_id":"svc"}`, received; e != a {
+			t.Errorf("v=%v: unexpected request body; expected %v, got %v", level, e, a)
+		}
+		if e, a := http.StatusCreated, w.Code; e != a {
+			t.Errorf("v=%v: unexpected status code; expected %v, got %v", level, e, a)
+		}
+		if e, a := `{"credentials":{"password":"hunter2"}}`, w.Body.String(); e != a {
+			t.Errorf("v=%v: unexpected response body; expected %v, got %v", level, e, a)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package debug keeps the most recent OSB requests served by a broker in
+// memory and exposes them on an authenticated endpoint, to diagnose platform
+// integration issues without enabling full debug logging. Bodies are
+// redacted and truncated before being kept. LogLevel changes the log
+// verbosity of a running broker for the same purpose.
+package debug
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+)
+
+const (
+	// Path is the path the Handler is meant to be served at.
+	Path = "/debug/requests"
+	// DefaultSize is the default number of requests kept.
+	DefaultSize = 100
+	// DefaultMaxBodyBytes is the default length bodies are truncated to.
+	DefaultMaxBodyBytes = 2048
+
+	truncated = "...(truncated)"
+)
+
+// Entry is a request kept by a Buffer.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Operation    string    `json:"operation,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	InstanceID   string    `json:"instance_id,omitempty"`
+	BindingID    string    `json:"binding_id,omitempty"`
+	StatusCode   int       `json:"status_code"`
+	Duration     string    `json:"duration"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+}
+
+// Buffer is a ring buffer of the most recent requests served through its
+// Middleware.
+type Buffer struct {
+	// Size is the number of requests kept. It defaults to DefaultSize.
+	Size int
+	// MaxBodyBytes is the length of request and response bodies kept.
+	// Only that much of a body is captured; longer bodies can't be
+	// redacted and are kept as redact.Placeholder. It defaults to
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int
+	// Token is the bearer token required by Handler. Handler rejects every
+	// request while it is empty.
+	Token string
+
+	mutex   sync.Mutex
+	entries []Entry
+	next    int
+}
+
+// Middleware keeps the requests served by next. Install it with
+// server.Server.UseOSBMiddleware so only OSB requests are kept.
+func (b *Buffer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		max := b.MaxBodyBytes
+		if max <= 0 {
+			max = DefaultMaxBodyBytes
+		}
+		body := captureRequestBody(r, max)
+		capture := newResponseCapture(w, max)
+		next.ServeHTTP(capture, r)
+
+		vars := mux.Vars(r)
+		entry := Entry{
+			Time:         start.UTC(),
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			InstanceID:   vars["instance_id"],
+			BindingID:    vars["binding_id"],
+			StatusCode:   capture.statusCode,
+			Duration:     time.Since(start).String(),
+			RequestBody:  b.body(body),
+			ResponseBody: b.body(&capture.body),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			entry.Operation = route.GetName()
+		}
+		b.add(entry)
+	})
+}
+
+// Entries returns the requests kept, most recent first.
+func (b *Buffer) Entries() []Entry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries := make([]Entry, 0, len(b.entries))
+	for i := 1; i <= len(b.entries); i++ {
+		entries = append(entries, b.entries[(b.next-i+len(b.entries))%len(b.entries)])
+	}
+	return entries
+}
+
+// Handler returns the endpoint serving the requests kept, most recent first,
+// to GET requests bearing Token. The limit query parameter bounds the
+// number of requests returned.
+func (b *Buffer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !b.authorized(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		entries := b.Entries()
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			if n < len(entries) {
+				entries = entries[:n]
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	})
+}
+
+func (b *Buffer) add(entry Entry) {
+	size := b.Size
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.entries) < size {
+		b.entries = append(b.entries, entry)
+		b.next = len(b.entries) % size
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % size
+}
+
+// body returns the redacted and truncated form of a captured body.
+func (b *Buffer) body(captured *limitedBuffer) string {
+	max := b.MaxBodyBytes
+	if max <= 0 {
+		max = DefaultMaxBodyBytes
+	}
+	return capturedBody(captured, max)
+}
+
+// capturedBody returns the redacted form of a body captured up to max
+// bytes, truncated to max.
+func capturedBody(captured *limitedBuffer, max int) string {
+	s := string(redact.JSON(captured.Bytes()))
+	if len(s) > max {
+		s = s[:max] + truncated
+	} else if captured.truncated {
+		s += truncated
+	}
+	return s
+}
+
+func (b *Buffer) authorized(r *http.Request) bool {
+	return bearerAuthorized(r, b.Token)
+}
+
+// bearerAuthorized returns whether r bears token. No request is authorized
+// by an empty token.
+func bearerAuthorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	bearer := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
+
+// limitedBuffer keeps the first max bytes written to it, and drops the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if room := b.max - b.Len(); len(data) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(data[:room])
+		}
+		return len(data), nil
+	}
+	return b.Buffer.Write(data)
+}
+
+// captureRequestBody replaces the body of r with one handing the body to the
+// handler as it reads it, keeping a copy of its first max bytes.
+func captureRequestBody(r *http.Request, max int) *limitedBuffer {
+	captured := &limitedBuffer{max: max}
+	if r.Body != nil {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(r.Body, captured), r.Body}
+	}
+	return captured
+}
+
+// responseCapture is a ResponseWriter that keeps a copy of the status code
+// and the first bytes of the body written through it, passing the body on
+// as it is written.
+type responseCapture struct {
+	http.ResponseWriter
+	statusCode int
+	body       limitedBuffer
+}
+
+func newResponseCapture(w http.ResponseWriter, max int) *responseCapture {
+	return &responseCapture{ResponseWriter: w, statusCode: http.StatusOK, body: limitedBuffer{max: max}}
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	c.statusCode = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+// Flush forwards to the underlying ResponseWriter so that streamed responses
+// are not held back.
+func (c *responseCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
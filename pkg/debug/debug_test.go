@@ -0,0 +1,137 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/debug"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+)
+
+func TestBuffer(t *testing.T) {
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{
+		ValidateBrokerAPIVersionFunc: func(string) error { return nil },
+		BindFunc: func(req *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			return &broker.BindResponse{
+				BindResponse: osb.BindResponse{Credentials: map[string]interface{}{"password": "hunter2"}},
+			}, nil
+		},
+	}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := server.New(api, prom.NewRegistry())
+	buffer := &debug.Buffer{Size: 2, MaxBodyBytes: 64, Token: "secret"}
+	s.UseOSBMiddleware(buffer.Middleware)
+	s.Router.Handle(debug.Path, buffer.Handler())
+
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf(`{"service_id":"svc","plan_id":"plan","parameters":{"padding":%q}}`, strings.Repeat("x", 100))
+		r := httptest.NewRequest("PUT", fmt.Sprintf("/v2/service_instances/instance-%d/service_bindings/binding", i), strings.NewReader(body))
+		r.Header.Set(osb.APIVersionHeader, "2.13")
+		s.Router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	r := httptest.NewRequest("GET", debug.Path, nil)
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+	if e, a := http.StatusUnauthorized, w.Code; e != a {
+		t.Errorf("Unexpected status code without a token; expected %v, got %v", e, a)
+	}
+
+	r = httptest.NewRequest("GET", debug.Path, nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	var entries []debug.Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(entries); e != a {
+		t.Fatalf("Unexpected number of entries; expected %v, got %v", e, a)
+	}
+	latest := entries[0]
+	if e, a := "instance-2", latest.InstanceID; e != a {
+		t.Errorf("Unexpected instance ID of the latest entry; expected %v, got %v", e, a)
+	}
+	if e, a := "instance-1", entries[1].InstanceID; e != a {
+		t.Errorf("Unexpected instance ID of the previous entry; expected %v, got %v", e, a)
+	}
+	if e, a := rest.OperationBind, latest.Operation; e != a {
+		t.Errorf("Unexpected operation; expected %v, got %v", e, a)
+	}
+	if e, a := "binding", latest.BindingID; e != a {
+		t.Errorf("Unexpected binding ID; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusCreated, latest.StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if !strings.HasSuffix(latest.RequestBody, "...(truncated)") {
+		t.Errorf("Expected the request body to be truncated, got %q", latest.RequestBody)
+	}
+	if strings.Contains(latest.ResponseBody, "hunter2") || !strings.Contains(latest.ResponseBody, redact.Placeholder) {
+		t.Errorf("Expected the credentials to be redacted, got %q", latest.ResponseBody)
+	}
+
+	r = httptest.NewRequest("GET", debug.Path+"?limit=1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, len(entries); e != a {
+		t.Errorf("Unexpected number of entries with a limit; expected %v, got %v", e, a)
+	}
+}
+
+func TestBufferCapturesBodyPrefix(t *testing.T) {
+	buffer := &debug.Buffer{MaxBodyBytes: 16}
+	large := strings.Repeat("x", 1<<20)
+	var read int
+	handler := buffer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		read = len(body)
+		w.Write([]byte(large))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(large)))
+	if e, a := len(large), read; e != a {
+		t.Errorf("Unexpected length of the request body read; expected %v, got %v", e, a)
+	}
+	if e, a := len(large), w.Body.Len(); e != a {
+		t.Errorf("Unexpected length of the response body written; expected %v, got %v", e, a)
+	}
+
+	entries := buffer.Entries()
+	if e, a := 1, len(entries); e != a {
+		t.Fatalf("Unexpected number of entries; expected %v, got %v", e, a)
+	}
+	// A body cut short isn't valid JSON, so it can't be redacted.
+	expected := `"` + redact.Placeholder + `"...(truncated)`
+	if e, a := expected, entries[0].RequestBody; e != a {
+		t.Errorf("Unexpected request body; expected %q, got %q", e, a)
+	}
+	if e, a := expected, entries[0].ResponseBody; e != a {
+		t.Errorf("Unexpected response body; expected %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,177 @@
+package debug
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// LogLevelPath is the path the LogLevel handler is meant to be served at.
+const LogLevelPath = "/admin/log-level"
+
+// LogLevel changes the glog verbosity of a running broker, so operators can
+// turn on verbose OSB request logging during an incident without a restart.
+// A change can be temporary: the previous verbosity is restored once its
+// duration has elapsed.
+type LogLevel struct {
+	// Token is the bearer token required by Handler. Handler rejects every
+	// request while it is empty.
+	Token string
+
+	mutex    sync.Mutex
+	revert   *time.Timer
+	previous int
+	revertAt time.Time
+	// generation counts the calls of Set, so a restore that fired while
+	// a later Set held the mutex knows it was cancelled.
+	generation uint64
+}
+
+// logLevelState is the body of the responses of the LogLevel handler.
+type logLevelState struct {
+	Level    int        `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// logLevelRequest is the body of a request changing the verbosity.
+type logLevelRequest struct {
+	Level *int `json:"level"`
+	// Duration, a Go duration such as "15m", makes the change temporary.
+	Duration string `json:"duration,omitempty"`
+}
+
+// Set sets the glog verbosity to level. A positive duration restores the
+// current verbosity once it has elapsed; a later Set cancels the pending
+// restore, keeping the verbosity from before the temporary change as the
+// one to restore if it is temporary too.
+func (l *LogLevel) Set(level int, duration time.Duration) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current, err := logLevel()
+	if err != nil {
+		return err
+	}
+	// A restore that has fired but not yet run is pending still: it
+	// clears revert once it runs, and gives up as generation changed.
+	pending := l.revert != nil
+	if pending {
+		l.revert.Stop()
+	} else {
+		l.previous = current
+	}
+	l.generation++
+	l.revert = nil
+	l.revertAt = time.Time{}
+
+	if err := setLogLevel(level); err != nil {
+		return err
+	}
+	glog.Infof("Log level set to %d", level)
+
+	if duration > 0 {
+		previous, generation := l.previous, l.generation
+		l.revertAt = time.Now().Add(duration)
+		l.revert = time.AfterFunc(duration, func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			if l.generation != generation {
+				return
+			}
+			if err := setLogLevel(previous); err != nil {
+				glog.Errorf("Error restoring log level %d: %v", previous, err)
+				return
+			}
+			l.revert = nil
+			l.revertAt = time.Time{}
+			glog.Infof("Log level restored to %d", previous)
+		})
+	}
+	return nil
+}
+
+func (l *LogLevel) state() (logLevelState, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	level, err := logLevel()
+	if err != nil {
+		return logLevelState{}, err
+	}
+	state := logLevelState{Level: level}
+	if !l.revertAt.IsZero() {
+		revertAt := l.revertAt.UTC()
+		state.RevertAt = &revertAt
+	}
+	return state, nil
+}
+
+// Handler returns the endpoint reporting the verbosity on GET and changing
+// it on PUT, with a body such as {"level": 4, "duration": "15m"}, to
+// requests bearing Token. Both answer with the verbosity in effect.
+func (l *LogLevel) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !bearerAuthorized(r, l.Token) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			request := &logLevelRequest{}
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Level == nil || *request.Level < 0 {
+				http.Error(w, `expected a body such as {"level": 4, "duration": "15m"}`, http.StatusBadRequest)
+				return
+			}
+			var duration time.Duration
+			if request.Duration != "" {
+				var err error
+				if duration, err = time.ParseDuration(request.Duration); err != nil || duration < 0 {
+					http.Error(w, "invalid duration", http.StatusBadRequest)
+					return
+				}
+			}
+			if err := l.Set(*request.Level, duration); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		state, err := l.state()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	})
+}
+
+// logLevel returns the glog verbosity, which glog only exposes as a flag.
+func logLevel() (int, error) {
+	v := flag.Lookup("v")
+	if v == nil {
+		return 0, fmt.Errorf("debug: glog verbosity flag not registered")
+	}
+	return strconv.Atoi(v.Value.String())
+}
+
+// setLogLevel sets the glog verbosity.
+func setLogLevel(level int) error {
+	v := flag.Lookup("v")
+	if v == nil {
+		return fmt.Errorf("debug: glog verbosity flag not registered")
+	}
+	return v.Value.Set(strconv.Itoa(level))
+}
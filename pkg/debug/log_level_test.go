@@ -0,0 +1,119 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/debug"
+)
+
+func TestLogLevel(t *testing.T) {
+	v := flag.Lookup("v")
+	original := v.Value.String()
+	defer v.Value.Set(original)
+	v.Value.Set("2")
+
+	level := &debug.LogLevel{Token: "secret"}
+	handler := level.Handler()
+	do := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, debug.LogLevelPath, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	levelOf := func(w *httptest.ResponseRecorder) int {
+		var state struct {
+			Level int `json:"level"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+			t.Fatalf("Unexpected body %q: %v", w.Body.String(), err)
+		}
+		return state.Level
+	}
+
+	cases := []struct {
+		name, method, token, body string
+		status                    int
+	}{
+		{name: "no token", method: http.MethodGet, status: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPut, token: "guess", body: `{"level": 9}`, status: http.StatusUnauthorized},
+		{name: "no level", method: http.MethodPut, token: "secret", body: `{}`, status: http.StatusBadRequest},
+		{name: "negative level", method: http.MethodPut, token: "secret", body: `{"level": -1}`, status: http.StatusBadRequest},
+		{name: "invalid duration", method: http.MethodPut, token: "secret", body: `{"level": 9, "duration": "soon"}`, status: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodDelete, token: "secret", status: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		if e, a := tc.status, do(tc.method, tc.token, tc.body).Code; e != a {
+			t.Errorf("%v: unexpected status; expected %v, got %v", tc.name, e, a)
+		}
+	}
+	if e, a := "2", v.Value.String(); e != a {
+		t.Fatalf("Rejected requests changed the log level; expected %v, got %v", e, a)
+	}
+
+	w := do(http.MethodGet, "secret", "")
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status; expected %v, got %v", e, a)
+	}
+	if e, a := 2, levelOf(w); e != a {
+		t.Errorf("Unexpected level; expected %v, got %v", e, a)
+	}
+
+	w = do(http.MethodPut, "secret", `{"level": 5}`)
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status; expected %v, got %v", e, a)
+	}
+	if e, a := 5, levelOf(w); e != a {
+		t.Errorf("Unexpected level; expected %v, got %v", e, a)
+	}
+	if e, a := "5", v.Value.String(); e != a {
+		t.Errorf("Unexpected verbosity flag; expected %v, got %v", e, a)
+	}
+
+	// A temporary change restores the level it replaced, even when it
+	// replaces another temporary change.
+	if err := level.Set(7, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	w = do(http.MethodPut, "secret", `{"level": 8, "duration": "20ms"}`)
+	if e, a := 8, levelOf(w); e != a {
+		t.Errorf("Unexpected level; expected %v, got %v", e, a)
+	}
+	if !strings.Contains(w.Body.String(), "revert_at") {
+		t.Errorf("Expected the restore time in %q", w.Body.String())
+	}
+	// The verbosity flag is only read under the handler's lock while a
+	// restore may be running.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if a := levelOf(do(http.MethodGet, "secret", "")); a == 5 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Log level not restored; expected 5, got %v", a)
+		}
+	}
+	if strings.Contains(do(http.MethodGet, "secret", "").Body.String(), "revert_at") {
+		t.Errorf("Expected no restore time once the level was restored")
+	}
+
+	// A permanent change cancels a restore that fired while it was made.
+	for i := 0; i < 100; i++ {
+		if err := level.Set(9, time.Nanosecond); err != nil {
+			t.Fatal(err)
+		}
+		if err := level.Set(6, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if e, a := 6, levelOf(do(http.MethodGet, "secret", "")); e != a {
+		t.Errorf("Unexpected level after cancelled restores; expected %v, got %v", e, a)
+	}
+}
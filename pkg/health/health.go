@@ -0,0 +1,158 @@
+// Package health provides aggregated readiness checks for the named
+// dependencies of a broker, such as databases, cloud APIs and message queues.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the timeout applied to checks registered without one.
+const DefaultTimeout = 5 * time.Second
+
+// CheckFunc checks the health of a single dependency of the broker, such as
+// a database, a cloud API or a message queue. A CheckFunc should return
+// promptly once the given context is done.
+type CheckFunc func(ctx context.Context) error
+
+// check is a named CheckFunc with a timeout.
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Checker aggregates the named checks registered by a broker and reports
+// their combined status. The zero value is not usable; use New.
+type Checker struct {
+	mutex  sync.RWMutex
+	checks []check
+}
+
+// New returns a new Checker without any registered checks. A Checker without
+// checks always reports itself as healthy.
+func New() *Checker {
+	return &Checker{}
+}
+
+// Register adds a named check to the Checker. If timeout is zero or
+// negative, DefaultTimeout is used. Registering a check with the name of an
+// existing check replaces it.
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.checks {
+		if c.checks[i].name == name {
+			c.checks[i] = check{name: name, timeout: timeout, fn: fn}
+			return
+		}
+	}
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name          string `json:"name"`
+	Healthy       bool   `json:"healthy"`
+	Error         string `json:"error,omitempty"`
+	LatencyMillis int64  `json:"latency_ms"`
+}
+
+// Summary is the outcome of running all the checks of a Checker.
+type Summary struct {
+	Healthy bool     `json:"healthy"`
+	Checks  []Result `json:"checks"`
+}
+
+// Run runs all registered checks concurrently and returns a Summary of their
+// results, in registration order. The Summary is healthy only if every check
+// succeeded within its timeout.
+func (c *Checker) Run(ctx context.Context) *Summary {
+	c.mutex.RLock()
+	checks := make([]check, len(c.checks))
+	copy(checks, c.checks)
+	c.mutex.RUnlock()
+
+	summary := &Summary{
+		Healthy: true,
+		Checks:  make([]Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			summary.Checks[i] = runCheck(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range summary.Checks {
+		if !r.Healthy {
+			summary.Healthy = false
+		}
+	}
+
+	return summary
+}
+
+// runCheck runs a single check, bounding it by the check's timeout.
+func runCheck(ctx context.Context, ch check) Result {
+	ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ch.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %v", ch.timeout)
+	}
+
+	result := Result{
+		Name:          ch.name,
+		Healthy:       err == nil,
+		LatencyMillis: int64(time.Since(start) / time.Millisecond),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// ServeHTTP runs all registered checks and writes their Summary as JSON. The
+// response status is 200 if all checks are healthy and 503 otherwise.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	summary := c.Run(r.Context())
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if !summary.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	cases := []struct {
+		name          string
+		checks        map[string]CheckFunc
+		timeout       time.Duration
+		expectHealthy bool
+	}{
+		{
+			name:          "no checks",
+			expectHealthy: true,
+		},
+		{
+			name: "all healthy",
+			checks: map[string]CheckFunc{
+				"database": func(context.Context) error { return nil },
+				"queue":    func(context.Context) error { return nil },
+			},
+			expectHealthy: true,
+		},
+		{
+			name: "one failing",
+			checks: map[string]CheckFunc{
+				"database": func(context.Context) error { return nil },
+				"cloud":    func(context.Context) error { return errors.New("unreachable") },
+			},
+			expectHealthy: false,
+		},
+		{
+			name: "timeout",
+			checks: map[string]CheckFunc{
+				"slow": func(ctx context.Context) error {
+					<-ctx.Done()
+					time.Sleep(time.Second)
+					return nil
+				},
+			},
+			timeout:       10 * time.Millisecond,
+			expectHealthy: false,
+		},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			for name, fn := range tc.checks {
+				c.Register(name, tc.timeout, fn)
+			}
+
+			summary := c.Run(context.Background())
+			if e, a := tc.expectHealthy, summary.Healthy; e != a {
+				t.Errorf("Unexpected health; expected %v, got %v: %+v", e, a, summary)
+			}
+			if e, a := len(tc.checks), len(summary.Checks); e != a {
+				t.Errorf("Unexpected number of results; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestCheckerServeHTTP(t *testing.T) {
+	c := New()
+	c.Register("database", 0, func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/readiness", nil))
+	if e, a := http.StatusOK, rec.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	c.Register("database", 0, func(context.Context) error { return errors.New("down") })
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/readiness", nil))
+	if e, a := http.StatusServiceUnavailable, rec.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	summary := &Summary{}
+	if err := json.Unmarshal(rec.Body.Bytes(), summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Checks) != 1 || summary.Checks[0].Name != "database" || summary.Checks[0].Error != "down" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+const (
+	// DefaultCleanupWindow is how long a Cleaner leaves an orphaned
+	// instance to the platform when Window is not set.
+	DefaultCleanupWindow = 24 * time.Hour
+	// DefaultCleanupInterval is how often a Cleaner scans the store when
+	// Interval is not set.
+	DefaultCleanupInterval = 10 * time.Minute
+
+	// provisionOperation is the type of the operations submitted for
+	// provision requests by the APISurface.
+	provisionOperation = "provision"
+)
+
+// Cleaner finds instances likely to have been orphaned by the platform and
+// hands them to the business logic for cleanup, so that their resources
+// don't leak. An instance is orphaned when its latest operation is a
+// provision that, Window after its last update, either failed or was never
+// polled to completion by the platform.
+type Cleaner struct {
+	// Manager holds the store of operations to scan.
+	Manager *Manager
+	// Cleanup releases the resources of the instance an orphaned provision
+	// operation created. Once it succeeds, the operation is deleted from
+	// the store; on error, it is retried on the next scan.
+	Cleanup func(op *storage.Operation) error
+	// Window is how long after its last update an orphaned provision is
+	// left to the platform, which may still poll or deprovision it. It
+	// defaults to DefaultCleanupWindow.
+	Window time.Duration
+	// Interval is how often the store is scanned. It defaults to
+	// DefaultCleanupInterval.
+	Interval time.Duration
+
+	now func() time.Time
+}
+
+// Run cleans up orphaned instances every Interval until ctx is done.
+func (c *Cleaner) Run(ctx context.Context) {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultCleanupInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Clean(); err != nil {
+			glog.Errorf("Error cleaning up orphaned instances: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Clean scans the store once and cleans up the orphaned instances found.
+func (c *Cleaner) Clean() error {
+	ops, err := c.Manager.Store.ListOperations()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+	window := c.Window
+	if window <= 0 {
+		window = DefaultCleanupWindow
+	}
+
+	for _, op := range ops {
+		if !orphaned(op) || now.Sub(op.Updated) < window {
+			continue
+		}
+		// A later operation on the instance, such as a deprovision,
+		// means the platform is still managing it.
+		latest, err := c.Manager.Store.LatestOperation(op.InstanceID, "")
+		if err != nil {
+			return err
+		}
+		if latest.Key != op.Key {
+			continue
+		}
+
+		glog.Infof("Cleaning up orphaned instance %q (operation %q, state %q)", op.InstanceID, op.Key, op.State)
+		if err := c.Cleanup(op); err != nil {
+			glog.Errorf("Error cleaning up orphaned instance %q: %v", op.InstanceID, err)
+			continue
+		}
+		if err := c.Manager.Store.DeleteOperation(op.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orphaned returns whether a finished operation may have left an orphaned
+// instance behind.
+func orphaned(op *storage.Operation) bool {
+	if op.Type != provisionOperation || op.BindingID != "" {
+		return false
+	}
+	switch op.State {
+	case osb.StateFailed:
+		return true
+	case osb.StateSucceeded:
+		return op.Observed.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+func TestCleaner(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	defer m.Close()
+
+	start := time.Unix(1000000, 0)
+	for _, op := range []*storage.Operation{
+		{Key: "failed", Type: "provision", InstanceID: "failed", State: osb.StateFailed},
+		{Key: "unobserved", Type: "provision", InstanceID: "unobserved", State: osb.StateSucceeded},
+		{Key: "observed", Type: "provision", InstanceID: "observed", State: osb.StateSucceeded, Observed: start},
+		{Key: "in-progress", Type: "provision", InstanceID: "in-progress", State: osb.StateInProgress},
+		{Key: "deprovisioned-1", Type: "provision", InstanceID: "deprovisioned", State: osb.StateFailed},
+		{Key: "deprovisioned-2", Type: "deprovision", InstanceID: "deprovisioned", State: osb.StateSucceeded, Created: start.Add(time.Second)},
+		{Key: "erroring", Type: "provision", InstanceID: "erroring", State: osb.StateFailed},
+	} {
+		op.Updated = start
+		if err := store.PutOperation(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := start
+	cleaned := map[string]bool{}
+	c := &Cleaner{
+		Manager: m,
+		Window:  time.Hour,
+		Cleanup: func(op *storage.Operation) error {
+			if op.InstanceID == "erroring" {
+				return errors.New("cloud unavailable")
+			}
+			cleaned[op.InstanceID] = true
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	if err := c.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cleaned) != 0 {
+		t.Fatalf("Expected nothing to be cleaned up within the window, got %v", cleaned)
+	}
+
+	now = start.Add(time.Hour)
+	if err := c.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := map[string]bool{"failed": true, "unobserved": true}, cleaned; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Unexpected instances cleaned up; expected %v, got %v", e, a)
+	}
+	if _, err := store.GetOperation("failed"); err != storage.ErrNotFound {
+		t.Errorf("Expected a cleaned up operation to be deleted, got %v", err)
+	}
+	if _, err := store.GetOperation("erroring"); err != nil {
+		t.Errorf("Expected a failed cleanup to be retried later, got %v", err)
+	}
+}
+
+func TestLastOperationMarksObserved(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	defer m.Close()
+
+	store.PutOperation(&storage.Operation{Key: "op", InstanceID: "instance", State: osb.StateSucceeded})
+	if _, err := m.LastOperation(nil, "instance", ""); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := store.GetOperation("op")
+	if op.Observed.IsZero() {
+		t.Error("Expected polling a terminal state to mark the operation as observed")
+	}
+}
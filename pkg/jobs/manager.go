@@ -0,0 +1,390 @@
+// Package jobs provides a manager for asynchronous OSB operations. Business
+// logic hands the manager a broker.Job; the manager assigns it an operation
+// key, persists its state in a storage.OperationStore, runs it on a pool of
+// workers and answers last operation requests for it.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+const (
+	// DefaultWorkers is the number of workers used when Manager.Workers is
+	// not set.
+	DefaultWorkers = 4
+	// DefaultQueueDepth is the queue depth used when Manager.QueueDepth is
+	// not set.
+	DefaultQueueDepth = 1024
+	// DefaultQueueName is the queue label used in metrics when
+	// Manager.Name is not set.
+	DefaultQueueName = "default"
+	// DefaultRetention is how long finished operations are kept when
+	// Manager.Retention is not set.
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// ErrQueueFull is returned by Submit when QueueDepth jobs are already
+// waiting for a worker.
+var ErrQueueFull = errors.New("jobs: queue is full")
+
+// Manager runs asynchronous jobs and tracks their state.
+type Manager struct {
+	// Store persists the state of operations.
+	Store storage.OperationStore
+	// Workers is the number of jobs run concurrently. It defaults to
+	// DefaultWorkers.
+	Workers int
+	// QueueDepth is the number of accepted jobs that may wait for a
+	// worker. Further jobs are rejected with ErrQueueFull until a worker
+	// frees up. It defaults to DefaultQueueDepth.
+	QueueDepth int
+	// RetryAfter is the delay platforms are asked to wait before retrying
+	// a request rejected because the queue was full. Zero lets the
+	// APISurface pick its default.
+	RetryAfter time.Duration
+	// Name labels the Manager's metrics. It defaults to
+	// DefaultQueueName.
+	Name string
+	// Retention is how long succeeded and failed operations are kept after
+	// their last update, so that platforms can still poll them, before
+	// Prune deletes them. It defaults to DefaultRetention; a negative
+	// value keeps them forever.
+	Retention time.Duration
+	// Metrics, if set, receives the queue depth, busy workers and rejected
+	// jobs of the Manager.
+	Metrics *metrics.OSBMetricsCollector
+
+	startOnce sync.Once
+	queue     chan *task
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	now       func() time.Time
+	busy      int64
+
+	// active holds the keys of the operations queued or running in this
+	// process.
+	activeMutex sync.Mutex
+	active      map[string]bool
+}
+
+// task is a submitted job and its operation.
+type task struct {
+	mutex sync.Mutex
+	op    *storage.Operation
+	job   broker.Job
+}
+
+// NewManager returns a Manager persisting operations in store and running
+// jobs on the given number of workers.
+func NewManager(store storage.OperationStore, workers int) *Manager {
+	return &Manager{
+		Store:   store,
+		Workers: workers,
+	}
+}
+
+// start launches the workers the first time the Manager is used.
+func (m *Manager) start() {
+	m.startOnce.Do(func() {
+		if m.now == nil {
+			m.now = time.Now
+		}
+		workers := m.Workers
+		if workers <= 0 {
+			workers = DefaultWorkers
+		}
+
+		m.active = map[string]bool{}
+		depth := m.QueueDepth
+		if depth <= 0 {
+			depth = DefaultQueueDepth
+		}
+		if m.Name == "" {
+			m.Name = DefaultQueueName
+		}
+
+		m.queue = make(chan *task, depth)
+		m.ctx, m.cancel = context.WithCancel(context.Background())
+		for i := 0; i < workers; i++ {
+			m.wg.Add(1)
+			go m.work()
+		}
+	})
+}
+
+// Close cancels the context of running jobs and waits for the workers to
+// exit. Jobs still queued are left in progress in the Store.
+func (m *Manager) Close() {
+	m.start()
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Submit records a new in-progress operation of the given type for an
+// instance, or for a binding if bindingID is set, and queues job to run it.
+// It returns the operation key to send to the platform, or ErrQueueFull if
+// the job can't be queued.
+func (m *Manager) Submit(operationType, instanceID, bindingID string, job broker.Job) (*osb.OperationKey, error) {
+	m.start()
+
+	key, err := broker.NewOperationKey(operationType, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	op := &storage.Operation{
+		Key:        key.String(),
+		Type:       operationType,
+		InstanceID: instanceID,
+		BindingID:  bindingID,
+		State:      osb.StateInProgress,
+		Attempts:   1,
+		Created:    now,
+		Updated:    now,
+	}
+	if err := m.Store.PutOperation(op); err != nil {
+		return nil, err
+	}
+
+	if err := m.enqueue(&task{op: op, job: job}); err != nil {
+		if deleteErr := m.Store.DeleteOperation(op.Key); deleteErr != nil {
+			glog.Errorf("Error deleting rejected operation %q: %v", op.Key, deleteErr)
+		}
+		return nil, err
+	}
+	return key.OSB(), nil
+}
+
+// resume queues job to run an operation that was left in progress, for
+// example by a previous process, counting a new attempt.
+func (m *Manager) resume(op *storage.Operation, job broker.Job) error {
+	m.start()
+
+	previous := *op
+	op.Attempts++
+	op.Updated = m.now()
+	if err := m.Store.PutOperation(op); err != nil {
+		return err
+	}
+
+	if err := m.enqueue(&task{op: op, job: job}); err != nil {
+		*op = previous
+		if putErr := m.Store.PutOperation(op); putErr != nil {
+			glog.Errorf("Error restoring operation %q: %v", op.Key, putErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) enqueue(t *task) error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	m.setActive(t.op.Key, true)
+	select {
+	case m.queue <- t:
+		m.recordQueue()
+		return nil
+	default:
+		m.setActive(t.op.Key, false)
+		if m.Metrics != nil {
+			m.Metrics.JobsRejected.WithLabelValues(m.Name).Inc()
+		}
+		return ErrQueueFull
+	}
+}
+
+// recordQueue updates the Manager's metrics.
+func (m *Manager) recordQueue() {
+	if m.Metrics == nil {
+		return
+	}
+	m.Metrics.JobQueueDepth.WithLabelValues(m.Name).Set(float64(len(m.queue)))
+	m.Metrics.JobWorkersBusy.WithLabelValues(m.Name).Set(float64(atomic.LoadInt64(&m.busy)))
+}
+
+func (m *Manager) setActive(key string, active bool) {
+	m.activeMutex.Lock()
+	defer m.activeMutex.Unlock()
+	if active {
+		m.active[key] = true
+	} else {
+		delete(m.active, key)
+	}
+}
+
+// ActiveOperations returns the number of operations queued or running in
+// this process.
+func (m *Manager) ActiveOperations() int {
+	m.activeMutex.Lock()
+	defer m.activeMutex.Unlock()
+	return len(m.active)
+}
+
+// isActive returns whether the operation is queued or running in this
+// process.
+func (m *Manager) isActive(key string) bool {
+	m.activeMutex.Lock()
+	defer m.activeMutex.Unlock()
+	return m.active[key]
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case t := <-m.queue:
+			if m.ctx.Err() != nil {
+				// Closed while the task was queued: leave it in
+				// progress for a reconciler to pick up.
+				m.setActive(t.op.Key, false)
+				return
+			}
+			atomic.AddInt64(&m.busy, 1)
+			m.recordQueue()
+			m.run(t)
+			atomic.AddInt64(&m.busy, -1)
+			m.recordQueue()
+		}
+	}
+}
+
+// run runs a task's job and records its outcome. A job that panics fails
+// its operation; a job interrupted by Close leaves its operation in progress
+// for a reconciler to pick up.
+func (m *Manager) run(t *task) {
+	defer m.setActive(t.op.Key, false)
+	defer func() {
+		if p := recover(); p != nil {
+			glog.Errorf("Job for operation %q panicked: %v\n%s", t.op.Key, p, debug.Stack())
+			m.update(t, osb.StateFailed, "The operation failed unexpectedly.")
+		}
+	}()
+
+	err := t.job(m.ctx, func(description string) {
+		m.update(t, osb.StateInProgress, description)
+	})
+
+	if err != nil && m.ctx.Err() != nil && errors.Is(err, m.ctx.Err()) {
+		glog.Infof("Job for operation %q interrupted by shutdown", t.op.Key)
+		return
+	}
+	if err != nil {
+		description := err.Error()
+		if httpErr, ok := broker.AsHTTPError(err); ok && httpErr.Description != nil {
+			description = *httpErr.Description
+		}
+		m.update(t, osb.StateFailed, description)
+		return
+	}
+	m.update(t, osb.StateSucceeded, "")
+}
+
+func (m *Manager) update(t *task, state osb.LastOperationState, description string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.op.State = state
+	t.op.Description = description
+	t.op.Updated = m.now()
+	if err := m.Store.PutOperation(t.op); err != nil {
+		glog.Errorf("Error storing state of operation %q: %v", t.op.Key, err)
+	}
+}
+
+// LastOperation returns the state of an operation run by the Manager. The
+// operation is looked up by key if the platform sent one; otherwise the most
+// recent operation on the instance, or on the binding if bindingID is set,
+// is used. The first time a terminal state is returned, the operation is
+// marked as observed. It returns storage.ErrNotFound if the Manager doesn't know the
+// operation, in which case the business logic should be asked instead.
+func (m *Manager) LastOperation(key *osb.OperationKey, instanceID, bindingID string) (*broker.LastOperationResponse, error) {
+	op, err := m.findOperation(key, instanceID, bindingID)
+	if err != nil {
+		return nil, err
+	}
+	if op.State != osb.StateInProgress && op.Observed.IsZero() {
+		m.start()
+		op.Observed = m.now()
+		if err := m.Store.PutOperation(op); err != nil {
+			glog.Errorf("Error recording observation of operation %q: %v", op.Key, err)
+		}
+	}
+
+	response := &broker.LastOperationResponse{}
+	response.State = op.State
+	if op.Description != "" {
+		response.Description = &op.Description
+	}
+	return response, nil
+}
+
+func (m *Manager) findOperation(key *osb.OperationKey, instanceID, bindingID string) (*storage.Operation, error) {
+	if key != nil {
+		op, err := m.Store.GetOperation(string(*key))
+		if err != nil {
+			return nil, err
+		}
+		if op.InstanceID != instanceID || op.BindingID != bindingID {
+			return nil, storage.ErrNotFound
+		}
+		return op, nil
+	}
+
+	return m.Store.LatestOperation(instanceID, bindingID)
+}
+
+// expired returns whether a finished operation is past its retention.
+func (m *Manager) expired(op *storage.Operation, now time.Time) bool {
+	if op.State == osb.StateInProgress {
+		return false
+	}
+	retention := m.Retention
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+	return retention > 0 && now.Sub(op.Updated) > retention
+}
+
+// Prune deletes the finished operations past their retention from the Store
+// and returns how many were deleted. A Reconciler prunes on every scan;
+// programs running a Manager without one should call Prune periodically.
+func (m *Manager) Prune() (int, error) {
+	m.start()
+
+	ops, err := m.Store.ListOperations()
+	if err != nil {
+		return 0, err
+	}
+
+	now := m.now()
+	pruned := 0
+	for _, op := range ops {
+		if !m.expired(op, now) {
+			continue
+		}
+		if err := m.Store.DeleteOperation(op.Key); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
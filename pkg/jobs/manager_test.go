@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+// waitForState polls m until the operation reaches the given state.
+func waitForState(t *testing.T, m *Manager, key *osb.OperationKey, instanceID string, state osb.LastOperationState) *broker.LastOperationResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response, err := m.LastOperation(key, instanceID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.State == state {
+			return response
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for state %q; last state %q", state, response.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager(storage.NewMemory(), 1)
+	defer m.Close()
+
+	release := make(chan struct{})
+	key, err := m.Submit("provision", "instance", "", func(ctx context.Context, progress func(string)) error {
+		progress("creating database")
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := waitForState(t, m, key, "instance", osb.StateInProgress)
+	for response.Description == nil {
+		time.Sleep(10 * time.Millisecond)
+		response = waitForState(t, m, key, "instance", osb.StateInProgress)
+	}
+	if e, a := "creating database", *response.Description; e != a {
+		t.Errorf("Unexpected description; expected %q, got %q", e, a)
+	}
+
+	close(release)
+	waitForState(t, m, key, "instance", osb.StateSucceeded)
+
+	// Without a key, the latest operation on the instance is reported.
+	_, err = m.Submit("update", "instance", "", func(ctx context.Context, progress func(string)) error {
+		return errors.New("quota exceeded")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = waitForState(t, m, nil, "instance", osb.StateFailed)
+	if response.Description == nil || *response.Description != "quota exceeded" {
+		t.Errorf("Unexpected description: %v", response.Description)
+	}
+
+	if _, err := m.LastOperation(nil, "other", ""); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an unknown instance, got %v", err)
+	}
+	if _, err := m.LastOperation(key, "other", ""); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a key of another instance, got %v", err)
+	}
+}
+
+// metricValue returns the value of a gauge or counter.
+func metricValue(t *testing.T, m prom.Metric) float64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	if err := m.Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if metric.Gauge != nil {
+		return metric.Gauge.GetValue()
+	}
+	return metric.Counter.GetValue()
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	m.QueueDepth = 1
+	m.Metrics = metrics.New()
+	defer m.Close()
+
+	running := make(chan struct{}, 2)
+	block := func(ctx context.Context, progress func(string)) error {
+		running <- struct{}{}
+		<-ctx.Done()
+		return nil
+	}
+
+	// The first job occupies the only worker, the second the only queue
+	// slot.
+	if _, err := m.Submit("provision", "a", "", block); err != nil {
+		t.Fatal(err)
+	}
+	<-running
+	if _, err := m.Submit("provision", "b", "", block); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Submit("provision", "c", "", block); err != ErrQueueFull {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	if _, err := m.LastOperation(nil, "c", ""); err != storage.ErrNotFound {
+		t.Errorf("Expected the rejected operation not to be stored, got %v", err)
+	}
+
+	if e, a := 1.0, metricValue(t, m.Metrics.JobsRejected.WithLabelValues(DefaultQueueName)); e != a {
+		t.Errorf("Unexpected rejected jobs; expected %v, got %v", e, a)
+	}
+	if e, a := 1.0, metricValue(t, m.Metrics.JobQueueDepth.WithLabelValues(DefaultQueueName)); e != a {
+		t.Errorf("Unexpected queue depth; expected %v, got %v", e, a)
+	}
+}
+
+func TestManagerJobPanic(t *testing.T) {
+	m := NewManager(storage.NewMemory(), 1)
+	defer m.Close()
+
+	key, err := m.Submit("provision", "instance", "", func(ctx context.Context, progress func(string)) error {
+		panic("nil map")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, m, key, "instance", osb.StateFailed)
+
+	// The worker survives the panic.
+	key, err = m.Submit("update", "instance", "", func(ctx context.Context, progress func(string)) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, m, key, "instance", osb.StateSucceeded)
+}
+
+func TestManagerCloseInterruptsJob(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+
+	running := make(chan struct{})
+	key, err := m.Submit("provision", "instance", "", func(ctx context.Context, progress func(string)) error {
+		close(running)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-running
+	m.Close()
+
+	op, err := store.GetOperation(string(*key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := osb.StateInProgress, op.State; e != a {
+		t.Errorf("Expected an interrupted operation to stay in progress; expected %v, got %v", e, a)
+	}
+}
+
+func TestManagerPrune(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	m.Retention = time.Hour
+	m.now = func() time.Time { return now }
+	defer m.Close()
+
+	for _, op := range []*storage.Operation{
+		{Key: "old-succeeded", State: osb.StateSucceeded, Updated: now.Add(-2 * time.Hour)},
+		{Key: "old-in-progress", State: osb.StateInProgress, Updated: now.Add(-2 * time.Hour)},
+		{Key: "recent-failed", State: osb.StateFailed, Updated: now.Add(-time.Minute)},
+	} {
+		if err := store.PutOperation(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := m.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, pruned; e != a {
+		t.Errorf("Unexpected number of pruned operations; expected %v, got %v", e, a)
+	}
+	if _, err := store.GetOperation("old-succeeded"); err != storage.ErrNotFound {
+		t.Errorf("Expected the expired operation to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+const (
+	// DefaultReconcileInterval is how often a Reconciler scans the store
+	// when Interval is not set.
+	DefaultReconcileInterval = 30 * time.Second
+	// DefaultMaxAttempts is the number of times an operation is started
+	// before a Reconciler gives up on it, when MaxAttempts is not set.
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the delay before the first re-drive of an
+	// operation when Backoff is not set.
+	DefaultBackoff = 10 * time.Second
+
+	// reconcileLockTTL bounds how long a Reconciler holds the lock of an
+	// operation while deciding to re-drive it.
+	reconcileLockTTL = time.Minute
+	// reconcileLockWait is how long a Reconciler waits for the lock of an
+	// operation held by another process before moving on.
+	reconcileLockWait = time.Second
+)
+
+// Reconciler re-drives the operations left in progress in a Manager's store
+// that no worker of the Manager is running, typically because the process
+// running them exited. Each re-drive is delayed by an exponential backoff
+// from the operation's last update; once an operation has been started
+// MaxAttempts times, it is marked as failed. Finished operations past the
+// Manager's Retention are pruned along the way.
+type Reconciler struct {
+	// Manager runs the re-driven operations.
+	Manager *Manager
+	// Resume returns the Job continuing an operation. It is supplied by
+	// the business logic, which must make its jobs safe to run again.
+	Resume func(op *storage.Operation) (broker.Job, error)
+	// Interval is how often the store is scanned. It defaults to
+	// DefaultReconcileInterval.
+	Interval time.Duration
+	// MaxAttempts is the number of times an operation is started before
+	// it is marked as failed. It defaults to DefaultMaxAttempts.
+	MaxAttempts int
+	// Backoff is the delay before the first re-drive of an operation;
+	// it doubles with every attempt. It defaults to DefaultBackoff.
+	Backoff time.Duration
+	// Locker, if set, is used to make sure that only one of several
+	// broker processes sharing the Manager's store re-drives a given
+	// operation.
+	Locker storage.Locker
+
+	now func() time.Time
+}
+
+// Run reconciles the store every Interval until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultReconcileInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Reconcile(); err != nil {
+			glog.Errorf("Error reconciling operations: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile scans the store once, re-driving or failing the operations that
+// are due and deleting the finished operations past the Manager's
+// retention. If the Manager's queue fills up, the scan stops and ErrQueueFull
+// is returned; the remaining operations are picked up by the next scan.
+func (r *Reconciler) Reconcile() error {
+	ops, err := r.Manager.Store.ListOperations()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+
+	for i, op := range ops {
+		if r.Manager.expired(op, now) {
+			if err := r.Manager.Store.DeleteOperation(op.Key); err != nil {
+				return err
+			}
+			continue
+		}
+		if !r.due(op, now) {
+			continue
+		}
+
+		if err := r.redrive(op, now); err != nil {
+			if err == ErrQueueFull {
+				// The rejection is counted in the Manager's metrics;
+				// the remaining operations are retried on the next
+				// scan.
+				glog.Warningf("Job queue full; deferring operation %q and %d other operations to the next scan", op.Key, len(ops)-i-1)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// due returns whether an operation should be re-driven or failed.
+func (r *Reconciler) due(op *storage.Operation, now time.Time) bool {
+	if op.State != osb.StateInProgress || r.Manager.isActive(op.Key) {
+		return false
+	}
+	return !now.Before(op.Updated.Add(r.backoff(op.Attempts)))
+}
+
+// redrive fails an operation out of attempts, or queues it again. With a
+// Locker, the operation is read again under its lock, since another process
+// may have re-driven it since the scan.
+func (r *Reconciler) redrive(op *storage.Operation, now time.Time) error {
+	if r.Locker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileLockWait)
+		unlock, err := r.Locker.Lock(ctx, "reconcile/"+op.Key, reconcileLockTTL)
+		cancel()
+		if err != nil {
+			glog.V(4).Infof("Skipping operation %q locked by another process: %v", op.Key, err)
+			return nil
+		}
+		defer func() {
+			if err := unlock(); err != nil {
+				glog.Warningf("Error unlocking operation %q: %v", op.Key, err)
+			}
+		}()
+
+		op, err = r.Manager.Store.GetOperation(op.Key)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !r.due(op, now) {
+			return nil
+		}
+	}
+
+	if op.Attempts >= r.maxAttempts() {
+		op.State = osb.StateFailed
+		op.Description = fmt.Sprintf("Gave up after %d attempts", op.Attempts)
+		op.Updated = now
+		return r.Manager.Store.PutOperation(op)
+	}
+
+	job, err := r.Resume(op)
+	if err != nil {
+		glog.Errorf("Error resuming operation %q: %v", op.Key, err)
+		return nil
+	}
+	glog.Infof("Re-driving operation %q (attempt %d)", op.Key, op.Attempts+1)
+	return r.Manager.resume(op, job)
+}
+
+func (r *Reconciler) maxAttempts() int {
+	if r.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return r.MaxAttempts
+}
+
+// backoff returns the delay after the given number of attempts before an
+// operation is re-driven.
+func (r *Reconciler) backoff(attempts int) time.Duration {
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
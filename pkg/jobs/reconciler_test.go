@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+func TestReconciler(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	defer m.Close()
+
+	// An operation left in progress by a previous process.
+	start := time.Unix(1000, 0)
+	store.PutOperation(&storage.Operation{
+		Key:        "provision:orphan",
+		Type:       "provision",
+		InstanceID: "instance",
+		State:      osb.StateInProgress,
+		Attempts:   1,
+		Created:    start,
+		Updated:    start,
+	})
+
+	now := start
+	resumed := make(chan struct{}, 10)
+	r := &Reconciler{
+		Manager:     m,
+		MaxAttempts: 2,
+		Backoff:     time.Minute,
+		Resume: func(op *storage.Operation) (broker.Job, error) {
+			return func(ctx context.Context, progress func(string)) error {
+				resumed <- struct{}{}
+				<-ctx.Done()
+				return ctx.Err()
+			}, nil
+		},
+		now: func() time.Time { return now },
+	}
+	m.start()
+	m.now = func() time.Time { return now }
+
+	// Not due before the backoff has elapsed.
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 0 {
+		t.Fatal("Expected the operation not to be re-driven before its backoff")
+	}
+
+	now = start.Add(time.Minute)
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-resumed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the operation to be re-driven")
+	}
+
+	// A running operation is left alone.
+	now = now.Add(time.Hour)
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := store.GetOperation("provision:orphan")
+	if e, a := 2, op.Attempts; e != a {
+		t.Fatalf("Unexpected attempts; expected %v, got %v", e, a)
+	}
+
+	// Once it's orphaned again, MaxAttempts has been reached.
+	m.setActive("provision:orphan", false)
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	op, _ = store.GetOperation("provision:orphan")
+	if e, a := osb.StateFailed, op.State; e != a {
+		t.Errorf("Unexpected state; expected %v, got %v", e, a)
+	}
+}
+
+func TestReconcilerQueueFull(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	m.QueueDepth = 1
+	m.Metrics = metrics.New()
+	defer m.Close()
+
+	running := make(chan struct{}, 1)
+	block := func(ctx context.Context, progress func(string)) error {
+		select {
+		case running <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if _, err := m.Submit("provision", "busy", "", block); err != nil {
+		t.Fatal(err)
+	}
+	<-running
+
+	start := time.Unix(1000, 0)
+	for _, key := range []string{"provision:a", "provision:b"} {
+		store.PutOperation(&storage.Operation{
+			Key:        key,
+			Type:       "provision",
+			InstanceID: key,
+			State:      osb.StateInProgress,
+			Attempts:   1,
+			Created:    start,
+			Updated:    start,
+		})
+	}
+
+	r := &Reconciler{
+		Manager: m,
+		Backoff: time.Second,
+		Resume: func(op *storage.Operation) (broker.Job, error) {
+			return block, nil
+		},
+		now: func() time.Time { return start.Add(time.Hour) },
+	}
+
+	// The first orphan takes the only queue slot; the second is deferred.
+	if err := r.Reconcile(); err != ErrQueueFull {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	if e, a := 1.0, metricValue(t, m.Metrics.JobsRejected.WithLabelValues(DefaultQueueName)); e != a {
+		t.Errorf("Unexpected rejected jobs; expected %v, got %v", e, a)
+	}
+	op, _ := store.GetOperation("provision:b")
+	if e, a := 1, op.Attempts; e != a {
+		t.Errorf("Expected the deferred operation's attempts to be unchanged; expected %v, got %v", e, a)
+	}
+}
+
+// heldLocker is a storage.Locker whose locks are always held by another
+// process.
+type heldLocker struct{}
+
+func (heldLocker) Lock(ctx context.Context, key string, ttl time.Duration) (func() error, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestReconcilerLocked(t *testing.T) {
+	store := storage.NewMemory()
+	m := NewManager(store, 1)
+	defer m.Close()
+
+	start := time.Unix(1000, 0)
+	store.PutOperation(&storage.Operation{
+		Key:      "provision:orphan",
+		State:    osb.StateInProgress,
+		Attempts: 1,
+		Created:  start,
+		Updated:  start,
+	})
+
+	r := &Reconciler{
+		Manager: m,
+		Locker:  heldLocker{},
+		Resume: func(op *storage.Operation) (broker.Job, error) {
+			t.Fatal("Expected an operation locked by another process not to be re-driven")
+			return nil, nil
+		},
+		now: func() time.Time { return start.Add(time.Hour) },
+	}
+	if err := r.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,245 @@
+// Package k8sregister registers a broker with the Kubernetes Service Catalog
+// by creating or updating the ClusterServiceBroker, or the namespaced
+// ServiceBroker, pointing at it, so brokers deployed to Kubernetes can
+// register themselves on deploy instead of an operator applying the
+// resource by hand. See package cfregister for Cloud Foundry.
+//
+// It talks to the API server over plain HTTP; the service account needs
+// patch permissions on clusterservicebrokers or servicebrokers in the
+// servicecatalog.k8s.io group.
+package k8sregister
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// apiVersion is the API version of the broker resources.
+	apiVersion = "servicecatalog.k8s.io/v1beta1"
+	// fieldManager is the field manager of the server-side applies.
+	fieldManager = "osb-broker-lib"
+)
+
+// Auth types of the broker's credentials secret.
+const (
+	// AuthBasic is a secret holding username and password keys.
+	AuthBasic = "basic"
+	// AuthBearer is a secret holding a token key.
+	AuthBearer = "bearer"
+)
+
+// Registrar creates or updates the broker resource pointing at a broker.
+type Registrar struct {
+	// URL is the base URL of the Kubernetes API server.
+	URL string
+	// Token is the bearer token used to authenticate to the API server.
+	Token string
+	// Client is the HTTP client used to talk to the API server. It
+	// defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Name is the name of the broker resource.
+	Name string
+	// Namespace, if set, registers a namespaced ServiceBroker in it
+	// instead of a ClusterServiceBroker.
+	Namespace string
+	// BrokerURL is the URL the Service Catalog reaches the broker at, such
+	// as https://db-broker.brokers.svc.
+	BrokerURL string
+	// AuthType is the type of the credentials in AuthSecretName, AuthBasic
+	// or AuthBearer. It defaults to AuthBasic.
+	AuthType string
+	// AuthSecretName is the name of the secret holding the credentials the
+	// Service Catalog sends to the broker. Without it, the broker is
+	// registered without credentials.
+	AuthSecretName string
+	// AuthSecretNamespace is the namespace of AuthSecretName for a
+	// ClusterServiceBroker. Namespaced ServiceBrokers use their own
+	// namespace.
+	AuthSecretNamespace string
+	// CABundle is the PEM encoded CA bundle used to verify the broker's
+	// certificate.
+	CABundle []byte
+	// InsecureSkipTLSVerify disables verifying the broker's certificate.
+	InsecureSkipTLSVerify bool
+}
+
+// NewInClusterRegistrar returns a Registrar using the service account of the
+// pod it runs in. Its namespace is used as AuthSecretNamespace.
+func NewInClusterRegistrar(name, brokerURL string) (*Registrar, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &Registrar{
+		URL:   "https://" + net.JoinHostPort(host, port),
+		Token: strings.TrimSpace(string(token)),
+		Client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		Name:                name,
+		BrokerURL:           brokerURL,
+		AuthSecretNamespace: strings.TrimSpace(string(namespace)),
+	}, nil
+}
+
+// Validate returns an error if the Registrar is missing settings.
+func (r *Registrar) Validate() error {
+	switch {
+	case r.URL == "":
+		return errors.New("k8sregister: URL is required")
+	case r.Name == "" || r.BrokerURL == "":
+		return errors.New("k8sregister: Name and BrokerURL are required")
+	case r.AuthType != "" && r.AuthType != AuthBasic && r.AuthType != AuthBearer:
+		return fmt.Errorf("k8sregister: unknown AuthType %q", r.AuthType)
+	case r.AuthSecretName != "" && r.Namespace == "" && r.AuthSecretNamespace == "":
+		return errors.New("k8sregister: AuthSecretNamespace is required for a ClusterServiceBroker")
+	}
+	return nil
+}
+
+// brokerResource is the subset of a ClusterServiceBroker or ServiceBroker
+// set by Register.
+type brokerResource struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Metadata   brokerMetadata `json:"metadata"`
+	Spec       brokerSpec     `json:"spec"`
+}
+
+type brokerMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type brokerSpec struct {
+	URL                   string    `json:"url"`
+	AuthInfo              *authInfo `json:"authInfo,omitempty"`
+	CABundle              []byte    `json:"caBundle,omitempty"`
+	InsecureSkipTLSVerify bool      `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+type authInfo struct {
+	Basic  *authSecret `json:"basic,omitempty"`
+	Bearer *authSecret `json:"bearer,omitempty"`
+}
+
+type authSecret struct {
+	SecretRef secretRef `json:"secretRef"`
+}
+
+type secretRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// resource returns the broker resource Register applies.
+func (r *Registrar) resource() *brokerResource {
+	resource := &brokerResource{
+		APIVersion: apiVersion,
+		Kind:       "ClusterServiceBroker",
+		Metadata:   brokerMetadata{Name: r.Name},
+		Spec: brokerSpec{
+			URL:                   r.BrokerURL,
+			CABundle:              r.CABundle,
+			InsecureSkipTLSVerify: r.InsecureSkipTLSVerify,
+		},
+	}
+	if r.Namespace != "" {
+		resource.Kind = "ServiceBroker"
+		resource.Metadata.Namespace = r.Namespace
+	}
+
+	if r.AuthSecretName != "" {
+		secret := &authSecret{SecretRef: secretRef{Name: r.AuthSecretName}}
+		if r.Namespace == "" {
+			secret.SecretRef.Namespace = r.AuthSecretNamespace
+		}
+		resource.Spec.AuthInfo = &authInfo{Basic: secret}
+		if r.AuthType == AuthBearer {
+			resource.Spec.AuthInfo = &authInfo{Bearer: secret}
+		}
+	}
+	return resource
+}
+
+// resourceURL returns the URL of the broker resource.
+func (r *Registrar) resourceURL() string {
+	base := strings.TrimSuffix(r.URL, "/") + "/apis/" + apiVersion
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s/namespaces/%s/servicebrokers/%s", base, url.PathEscape(r.Namespace), url.PathEscape(r.Name))
+	}
+	return fmt.Sprintf("%s/clusterservicebrokers/%s", base, url.PathEscape(r.Name))
+}
+
+// Register creates the broker resource, or updates it if it exists. The
+// resource is written with a server-side apply, so it is created or updated
+// in a single request and fields set by others, such as relist settings,
+// are left alone. Register is idempotent, so it can run on every start.
+func (r *Registrar) Register(ctx context.Context) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(r.resource())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPatch, r.resourceURL()+"?fieldManager="+fieldManager+"&force=true", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/apply-patch+yaml")
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("k8sregister: applying broker %q: %v", r.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("k8sregister: applying broker %q: unexpected status %d: %s", r.Name, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package k8sregister
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	cases := []struct {
+		name      string
+		registrar Registrar
+		path      string
+		expected  brokerResource
+	}{
+		{
+			name:      "cluster broker",
+			registrar: Registrar{Name: "db", BrokerURL: "https://db.brokers.svc", AuthSecretName: "db-auth", AuthSecretNamespace: "brokers"},
+			path:      "/apis/servicecatalog.k8s.io/v1beta1/clusterservicebrokers/db",
+			expected: brokerResource{
+				APIVersion: apiVersion,
+				Kind:       "ClusterServiceBroker",
+				Metadata:   brokerMetadata{Name: "db"},
+				Spec: brokerSpec{
+					URL:      "https://db.brokers.svc",
+					AuthInfo: &authInfo{Basic: &authSecret{SecretRef: secretRef{Name: "db-auth", Namespace: "brokers"}}},
+				},
+			},
+		},
+		{
+			name:      "namespaced broker",
+			registrar: Registrar{Name: "db", Namespace: "team", BrokerURL: "https://db.brokers.svc", AuthType: AuthBearer, AuthSecretName: "db-auth"},
+			path:      "/apis/servicecatalog.k8s.io/v1beta1/namespaces/team/servicebrokers/db",
+			expected: brokerResource{
+				APIVersion: apiVersion,
+				Kind:       "ServiceBroker",
+				Metadata:   brokerMetadata{Name: "db", Namespace: "team"},
+				Spec: brokerSpec{
+					URL:      "https://db.brokers.svc",
+					AuthInfo: &authInfo{Bearer: &authSecret{SecretRef: secretRef{Name: "db-auth"}}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		var path, method, contentType string
+		var applied brokerResource
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, method, contentType = r.URL.Path, r.Method, r.Header.Get("Content-Type")
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&applied)
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		tc.registrar.URL = server.URL
+		tc.registrar.Token = "token"
+		if err := tc.registrar.Register(context.Background()); err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.name, err)
+		}
+		server.Close()
+
+		if e, a := tc.path, path; e != a {
+			t.Errorf("%v: unexpected path; expected %v, got %v", tc.name, e, a)
+		}
+		if method != http.MethodPatch || contentType != "application/apply-patch+yaml" {
+			t.Errorf("%v: expected a server-side apply, got %v %v", tc.name, method, contentType)
+		}
+		if e, a := tc.expected, applied; !reflect.DeepEqual(e, a) {
+			t.Errorf("%v: unexpected resource; expected %+v, got %+v", tc.name, e, a)
+		}
+	}
+}
+
+func TestRegisterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	r := &Registrar{URL: server.URL, Name: "db", BrokerURL: "https://db.brokers.svc"}
+	if err := r.Register(context.Background()); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name      string
+		registrar Registrar
+		valid     bool
+	}{
+		{name: "valid", registrar: Registrar{URL: "https://k8s", Name: "db", BrokerURL: "https://db"}, valid: true},
+		{name: "no name", registrar: Registrar{URL: "https://k8s", BrokerURL: "https://db"}},
+		{name: "unknown auth", registrar: Registrar{URL: "https://k8s", Name: "db", BrokerURL: "https://db", AuthType: "digest"}},
+		{name: "cluster secret without namespace", registrar: Registrar{URL: "https://k8s", Name: "db", BrokerURL: "https://db", AuthSecretName: "auth"}},
+		{name: "namespaced secret", registrar: Registrar{URL: "https://k8s", Name: "db", Namespace: "team", BrokerURL: "https://db", AuthSecretName: "auth"}, valid: true},
+	}
+	for _, tc := range cases {
+		if e, a := tc.valid, tc.registrar.Validate() == nil; e != a {
+			t.Errorf("%v: expected valid to be %v", tc.name, e)
+		}
+	}
+}
@@ -4,13 +4,73 @@ import (
 	prom "github.com/prometheus/client_golang/prometheus"
 )
 
-const actionsMetricName = "osb_actions_total"
+const (
+	actionsMetricName               = "osb_actions_total"
+	concurrentOperationsMetricName  = "osb_concurrent_operations"
+	concurrencySaturationMetricName = "osb_concurrency_saturation"
+	circuitBreakerStateMetricName   = "osb_circuit_breaker_state"
+	backpressureRejectedMetricName  = "osb_backpressure_rejected_total"
+	jobQueueDepthMetricName         = "osb_job_queue_depth"
+	jobWorkersBusyMetricName        = "osb_job_workers_busy"
+	jobsRejectedMetricName          = "osb_jobs_rejected_total"
+	errorsMetricName                = "osb_errors_total"
+	requestSizeMetricName           = "osb_request_size_bytes"
+	responseSizeMetricName          = "osb_response_size_bytes"
+	instancesMetricName             = "osb_instances"
+	bindingsMetricName              = "osb_bindings"
+	abandonedMetricName             = "osb_requests_abandoned_total"
+	responseWriteFailuresMetricName = "osb_response_write_failures_total"
+)
 
 // OSBMetricsCollector - action counter
 type OSBMetricsCollector struct {
 	Actions *prom.CounterVec
+	// ConcurrentOperations - business logic invocations currently admitted by
+	// the concurrency limiter, by operation
+	ConcurrentOperations *prom.GaugeVec
+	// ConcurrencySaturation - fraction of the overall concurrency limit in use
+	ConcurrencySaturation prom.Gauge
+	// CircuitBreakerState - state of the circuit breaker by operation: 0 for
+	// closed, 1 for half-open and 2 for open
+	CircuitBreakerState *prom.GaugeVec
+	// BackpressureRejected - requests rejected because the backpressure
+	// queue was full, by operation
+	BackpressureRejected *prom.CounterVec
+	// JobQueueDepth - asynchronous jobs waiting for a worker, by queue
+	JobQueueDepth *prom.GaugeVec
+	// JobWorkersBusy - workers running an asynchronous job, by queue
+	JobWorkersBusy *prom.GaugeVec
+	// JobsRejected - asynchronous jobs rejected because their queue was
+	// full, by queue
+	JobsRejected *prom.CounterVec
+	// Errors - error responses by operation and error class: the OSB error
+	// code, such as AsyncRequired, or validation_failed, internal or the
+	// status text for errors without one
+	Errors *prom.CounterVec
+	// RequestSize - size of the request bodies of the OSB API, by operation
+	RequestSize *prom.HistogramVec
+	// ResponseSize - size of the response bodies of the OSB API, by
+	// operation
+	ResponseSize *prom.HistogramVec
+	// Instances - provisioned service instances, by service and plan. It is
+	// maintained by rest.InventoryHook.
+	Instances *prom.GaugeVec
+	// Bindings - active bindings, by service and plan. It is maintained by
+	// rest.InventoryHook.
+	Bindings *prom.GaugeVec
+	// Abandoned - requests the platform gave up on, by closing the
+	// connection or timing out, before the response was written, by
+	// operation
+	Abandoned *prom.CounterVec
+	// ResponseWriteFailures - responses that could not be written in full
+	// to the platform, by operation
+	ResponseWriteFailures *prom.CounterVec
 }
 
+// sizeBuckets are the buckets of the size histograms, from 64 bytes to
+// 4 MiB.
+var sizeBuckets = prom.ExponentialBuckets(64, 4, 9)
+
 // New - constructs a metrics collector with an action counter
 func New() *OSBMetricsCollector {
 	return &OSBMetricsCollector{
@@ -18,15 +78,101 @@ func New() *OSBMetricsCollector {
 			Name: actionsMetricName,
 			Help: "Total amount of actions requested.",
 		}, []string{"action"}),
+		ConcurrentOperations: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: concurrentOperationsMetricName,
+			Help: "Number of business logic invocations in progress.",
+		}, []string{"operation"}),
+		ConcurrencySaturation: prom.NewGauge(prom.GaugeOpts{
+			Name: concurrencySaturationMetricName,
+			Help: "Fraction of the overall concurrent operations limit in use.",
+		}),
+		CircuitBreakerState: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: circuitBreakerStateMetricName,
+			Help: "State of the circuit breaker (0 closed, 1 half-open, 2 open).",
+		}, []string{"operation"}),
+		BackpressureRejected: prom.NewCounterVec(prom.CounterOpts{
+			Name: backpressureRejectedMetricName,
+			Help: "Total amount of requests rejected because the backpressure queue was full.",
+		}, []string{"operation"}),
+		JobQueueDepth: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: jobQueueDepthMetricName,
+			Help: "Number of asynchronous jobs waiting for a worker.",
+		}, []string{"queue"}),
+		JobWorkersBusy: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: jobWorkersBusyMetricName,
+			Help: "Number of workers running an asynchronous job.",
+		}, []string{"queue"}),
+		JobsRejected: prom.NewCounterVec(prom.CounterOpts{
+			Name: jobsRejectedMetricName,
+			Help: "Total amount of asynchronous jobs rejected because their queue was full.",
+		}, []string{"queue"}),
+		Errors: prom.NewCounterVec(prom.CounterOpts{
+			Name: errorsMetricName,
+			Help: "Total amount of error responses by error class.",
+		}, []string{"operation", "class"}),
+		RequestSize: prom.NewHistogramVec(prom.HistogramOpts{
+			Name:    requestSizeMetricName,
+			Help:    "Size of OSB API request bodies in bytes.",
+			Buckets: sizeBuckets,
+		}, []string{"operation"}),
+		ResponseSize: prom.NewHistogramVec(prom.HistogramOpts{
+			Name:    responseSizeMetricName,
+			Help:    "Size of OSB API response bodies in bytes.",
+			Buckets: sizeBuckets,
+		}, []string{"operation"}),
+		Instances: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: instancesMetricName,
+			Help: "Number of provisioned service instances.",
+		}, []string{"service", "plan"}),
+		Bindings: prom.NewGaugeVec(prom.GaugeOpts{
+			Name: bindingsMetricName,
+			Help: "Number of active service bindings.",
+		}, []string{"service", "plan"}),
+		Abandoned: prom.NewCounterVec(prom.CounterOpts{
+			Name: abandonedMetricName,
+			Help: "Total amount of requests abandoned by the client before the response was written.",
+		}, []string{"operation"}),
+		ResponseWriteFailures: prom.NewCounterVec(prom.CounterOpts{
+			Name: responseWriteFailuresMetricName,
+			Help: "Total amount of responses that failed to be written to the client.",
+		}, []string{"operation"}),
 	}
 }
 
 // Describe returns all descriptions of the collector.
 func (c *OSBMetricsCollector) Describe(ch chan<- *prom.Desc) {
 	c.Actions.Describe(ch)
+	c.ConcurrentOperations.Describe(ch)
+	c.ConcurrencySaturation.Describe(ch)
+	c.CircuitBreakerState.Describe(ch)
+	c.BackpressureRejected.Describe(ch)
+	c.JobQueueDepth.Describe(ch)
+	c.JobWorkersBusy.Describe(ch)
+	c.JobsRejected.Describe(ch)
+	c.Errors.Describe(ch)
+	c.RequestSize.Describe(ch)
+	c.ResponseSize.Describe(ch)
+	c.Instances.Describe(ch)
+	c.Bindings.Describe(ch)
+	c.Abandoned.Describe(ch)
+	c.ResponseWriteFailures.Describe(ch)
 }
 
 // Collect returns the current state of all metrics of the collector.
 func (c *OSBMetricsCollector) Collect(ch chan<- prom.Metric) {
 	c.Actions.Collect(ch)
+	c.ConcurrentOperations.Collect(ch)
+	c.ConcurrencySaturation.Collect(ch)
+	c.CircuitBreakerState.Collect(ch)
+	c.BackpressureRejected.Collect(ch)
+	c.JobQueueDepth.Collect(ch)
+	c.JobWorkersBusy.Collect(ch)
+	c.JobsRejected.Collect(ch)
+	c.Errors.Collect(ch)
+	c.RequestSize.Collect(ch)
+	c.ResponseSize.Collect(ch)
+	c.Instances.Collect(ch)
+	c.Bindings.Collect(ch)
+	c.Abandoned.Collect(ch)
+	c.ResponseWriteFailures.Collect(ch)
 }
@@ -0,0 +1,13 @@
+// Package mocks contains counterfeiter fakes of the public interfaces of this
+// library, including the optional interfaces business logic implements next
+// to broker.Interface, so that code consuming them can be tested without
+// hand-written stubs. Each fake records the arguments of every call and lets
+// tests configure return values per call or replace a method with a stub.
+package mocks
+
+//go:generate counterfeiter -o fake_interface.go ../broker Interface
+//go:generate counterfeiter -o fake_catalog_streamer.go ../broker CatalogStreamer
+//go:generate counterfeiter -o fake_credential_provider.go ../broker CredentialProvider
+//go:generate counterfeiter -o fake_provision_validator.go ../broker ProvisionValidator
+//go:generate counterfeiter -o fake_purger.go ../broker Purger
+//go:generate counterfeiter -o fake_reload_aware.go ../broker ReloadAware
@@ -0,0 +1,201 @@
+// Package offering serves single service offerings of the catalog, with
+// their plans, as a rest.ExtensionAPI, so platforms and dashboards
+// integrating with huge catalogs can fetch the service they need instead of
+// downloading the whole catalog:
+//
+//	GET /v2/catalog/services/{service_id}
+//
+// Services are served from a Cache of the catalog, which is rendered once
+// and indexed by service ID.
+package offering
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// OperationGetService is the name of the operation fetching a service. It
+// is used as route name and metric label.
+const OperationGetService = "get_service"
+
+// AdheresTo identifies the API served by the Extension in the catalog.
+const AdheresTo = "urn:osb-broker-lib:offering:v1"
+
+// varKeyServiceID is the route variable holding the service ID.
+const varKeyServiceID = "service_id"
+
+// Cache holds the catalog rendered by service, so single services can be
+// served without asking the business logic for the whole catalog on every
+// request. The catalog is shared by all requests: it must not vary with the
+// platform or user making the request.
+type Cache struct {
+	// Catalog returns the catalog to cache, typically the Catalog method
+	// of the APISurface, so services are served as they appear in the
+	// catalog.
+	Catalog func(c *broker.RequestContext) (*broker.CatalogResponse, error)
+	// TTL is how long the catalog is cached. Zero caches it until
+	// Invalidate is called, which suits catalogs that only change on
+	// reload.
+	TTL time.Duration
+
+	mutex    sync.Mutex
+	services map[string]*service
+	fetched  time.Time
+	now      func() time.Time
+}
+
+// service is a service of the catalog rendered as in the catalog.
+type service struct {
+	data json.RawMessage
+	etag string
+}
+
+// Invalidate drops the cached catalog, so the next request fetches it
+// again. Call it once the catalog changed, for example when the broker's
+// configuration is reloaded.
+func (c *Cache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.services = nil
+}
+
+// Service returns the service with the given ID rendered as in the catalog,
+// including its plans and extension_apis, and its entity tag. It returns a
+// 404 error if the catalog has no such service.
+func (c *Cache) Service(rc *broker.RequestContext, serviceID string) (json.RawMessage, string, error) {
+	services, err := c.load(rc)
+	if err != nil {
+		return nil, "", err
+	}
+	s, ok := services[serviceID]
+	if !ok {
+		description := fmt.Sprintf("The catalog has no service %q.", serviceID)
+		return nil, "", osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusNotFound,
+			Description: &description,
+		}
+	}
+	return s.data, s.etag, nil
+}
+
+// load returns the cached services, fetching the catalog if the cache is
+// empty or expired. Requests wait for a fetch in progress rather than
+// fetching the catalog again.
+func (c *Cache) load(rc *broker.RequestContext) (map[string]*service, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.clock()
+	if c.services != nil && (c.TTL <= 0 || now.Sub(c.fetched) < c.TTL) {
+		return c.services, nil
+	}
+
+	catalog, err := c.Catalog(rc)
+	if err != nil {
+		return nil, err
+	}
+	services, err := index(catalog)
+	if err != nil {
+		return nil, err
+	}
+	c.services, c.fetched = services, now
+	return services, nil
+}
+
+func (c *Cache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// index renders the services of catalog as the catalog does.
+func index(catalog *broker.CatalogResponse) (map[string]*service, error) {
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		return nil, err
+	}
+	var rendered struct {
+		Services []json.RawMessage `json:"services"`
+	}
+	if err := json.Unmarshal(data, &rendered); err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*service, len(rendered.Services))
+	for i, data := range rendered.Services {
+		services[catalog.Services[i].ID] = &service{
+			data: data,
+			etag: fmt.Sprintf(`"%x"`, sha256.Sum256(data)),
+		}
+	}
+	return services, nil
+}
+
+// Extension serves the services of the catalog from Cache.
+type Extension struct {
+	// Cache holds the catalog the services are served from.
+	Cache *Cache
+	// ServiceIDs are the IDs of the services advertising the API in the
+	// catalog. The API serves every service of the catalog regardless.
+	ServiceIDs []string
+	// DiscoveryURL is the URL of the OpenAPI document advertised in the
+	// catalog.
+	DiscoveryURL string
+}
+
+// API returns the rest.ExtensionAPI to add to the APISurface's
+// ExtensionAPIs.
+func (e *Extension) API() rest.ExtensionAPI {
+	return rest.ExtensionAPI{
+		ExtensionAPI: broker.ExtensionAPI{
+			DiscoveryURL: e.DiscoveryURL,
+			AdheresTo:    AdheresTo,
+		},
+		ServiceIDs: e.ServiceIDs,
+		Operations: []rest.ExtensionOperation{
+			{
+				Name:    OperationGetService,
+				Method:  "GET",
+				Path:    "/v2/catalog/services/{service_id}",
+				Handler: e.get,
+			},
+		},
+	}
+}
+
+// get answers with the service, or with 304 Not Modified if the platform
+// already has it.
+func (e *Extension) get(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+	data, etag, err := e.Cache.Service(c, request.Vars[varKeyServiceID])
+	if err != nil {
+		return nil, err
+	}
+	c.SetResponseHeader("ETag", etag)
+	if c.Request != nil && etagMatches(c.Request.Header.Get("If-None-Match"), etag) {
+		return &rest.ExtensionResponse{StatusCode: http.StatusNotModified}, nil
+	}
+	return &rest.ExtensionResponse{Body: data}, nil
+}
+
+// etagMatches returns whether the If-None-Match header value lists etag,
+// comparing weakly as RFC 7232 requires.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
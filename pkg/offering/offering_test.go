@@ -0,0 +1,142 @@
+package offering_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/offering"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestExtension(t *testing.T) {
+	fetches := 0
+	plan := "small"
+	cache := &offering.Cache{}
+	extension := &offering.Extension{Cache: cache, ServiceIDs: []string{"db"}, DiscoveryURL: "https://broker/openapi.json"}
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			fetches++
+			return &broker.CatalogResponse{
+				CatalogResponse: osb.CatalogResponse{Services: []osb.Service{
+					{ID: "db", Name: "database", Plans: []osb.Plan{{ID: plan, Name: plan}}},
+					{ID: "mq", Name: "queue"},
+				}},
+			}, nil
+		},
+	}, func(api *rest.APISurface) {
+		cache.Catalog = api.Catalog
+		api.ExtensionAPIs = append(api.ExtensionAPIs, extension.API())
+	})
+
+	get := func(serviceID, etag string) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/v2/catalog/services/"+serviceID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(osb.APIVersionHeader, "2.15")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]interface{}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Fatalf("Unexpected body %q: %v", data, err)
+			}
+		}
+		return resp, body
+	}
+
+	resp, body := get("db", "")
+	if e, a := http.StatusOK, resp.StatusCode; e != a {
+		t.Fatalf("Unexpected status; expected %v, got %v", e, a)
+	}
+	if e, a := "database", body["name"]; e != a {
+		t.Errorf("Unexpected service name; expected %v, got %v", e, a)
+	}
+	if plans, _ := body["plans"].([]interface{}); len(plans) != 1 {
+		t.Errorf("Unexpected plans %v", body["plans"])
+	}
+	if apis, _ := body["extension_apis"].([]interface{}); len(apis) != 1 {
+		t.Errorf("Expected the service to advertise the extension, got %v", body["extension_apis"])
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	resp, body = get("mq", "")
+	if e, a := "queue", body["name"]; e != a {
+		t.Errorf("Unexpected service name; expected %v, got %v", e, a)
+	}
+	if _, ok := body["extension_apis"]; ok {
+		t.Errorf("Unexpected extension_apis for a service not advertising the extension: %v", body["extension_apis"])
+	}
+
+	resp, _ = get("db", etag)
+	if e, a := http.StatusNotModified, resp.StatusCode; e != a {
+		t.Errorf("Unexpected status with a matching ETag; expected %v, got %v", e, a)
+	}
+
+	resp, body = get("unknown", "")
+	if e, a := http.StatusNotFound, resp.StatusCode; e != a {
+		t.Errorf("Unexpected status for an unknown service; expected %v, got %v", e, a)
+	}
+	if e, a := `The catalog has no service "unknown".`, body["description"]; e != a {
+		t.Errorf("Unexpected description; expected %v, got %v", e, a)
+	}
+
+	if e, a := 1, fetches; e != a {
+		t.Errorf("Unexpected number of catalog fetches; expected %v, got %v", e, a)
+	}
+
+	plan = "large"
+	cache.Invalidate()
+	resp, body = get("db", etag)
+	if e, a := http.StatusOK, resp.StatusCode; e != a {
+		t.Errorf("Unexpected status after the catalog changed; expected %v, got %v", e, a)
+	}
+	if e, a := 2, fetches; e != a {
+		t.Errorf("Unexpected number of catalog fetches after invalidation; expected %v, got %v", e, a)
+	}
+	if resp.Header.Get("ETag") == etag {
+		t.Error("Expected the ETag to change with the service")
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	fail := true
+	cache := &offering.Cache{
+		Catalog: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			if fail {
+				return nil, errors.New("backend down")
+			}
+			return &broker.CatalogResponse{
+				CatalogResponse: osb.CatalogResponse{Services: []osb.Service{{ID: "db", Name: "database"}}},
+			}, nil
+		},
+	}
+
+	if _, _, err := cache.Service(&broker.RequestContext{}, "db"); err == nil {
+		t.Fatal("Expected the catalog error")
+	}
+	fail = false
+	if _, _, err := cache.Service(&broker.RequestContext{}, "db"); err != nil {
+		t.Errorf("Expected a failed fetch not to be cached, got %v", err)
+	}
+}
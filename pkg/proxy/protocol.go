@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHeaderTimeout is the default Listener.HeaderTimeout.
+	DefaultHeaderTimeout = 10 * time.Second
+
+	// maxV1HeaderLength is the longest PROXY protocol v1 header, including
+	// its CRLF.
+	maxV1HeaderLength = 107
+	// v2HeaderLength is the length of the fixed part of a v2 header.
+	v2HeaderLength = 16
+)
+
+// v2Signature starts every PROXY protocol v2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// Listener reads the PROXY protocol v1 or v2 header that TCP load balancers
+// send at the start of each connection and reports the address it carries
+// as the connection's RemoteAddr. Connections without a header are served
+// with the peer's address. The header is read when the connection is first
+// read from or asked for its address, so a slow peer doesn't block Accept.
+type Listener struct {
+	net.Listener
+	// Trusted, if set, restricts the peers allowed to send a header;
+	// connections from other peers sending one are closed.
+	Trusted *Trusted
+	// HeaderTimeout bounds reading the header. It defaults to
+	// DefaultHeaderTimeout.
+	HeaderTimeout time.Duration
+}
+
+// Accept waits for and returns the next connection.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, listener: l, reader: bufio.NewReader(c)}, nil
+}
+
+// conn is a connection whose PROXY protocol header is read lazily.
+type conn struct {
+	net.Conn
+	listener *Listener
+	reader   *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	return c.remoteAddr
+}
+
+// readHeader reads the PROXY protocol header, if any. An invalid header, or
+// a header from an untrusted peer, closes the connection.
+func (c *conn) readHeader() {
+	c.remoteAddr = c.Conn.RemoteAddr()
+
+	timeout := c.listener.HeaderTimeout
+	if timeout == 0 {
+		timeout = DefaultHeaderTimeout
+	}
+	c.Conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	addr, err := readProxyHeader(c.reader)
+	if err == nil && addr != nil && c.listener.Trusted != nil && !c.trustedPeer() {
+		err = fmt.Errorf("proxy: PROXY header from untrusted peer %v", c.remoteAddr)
+	}
+	if err != nil {
+		c.err = err
+		c.Conn.Close()
+		return
+	}
+	if addr != nil {
+		c.remoteAddr = addr
+	}
+}
+
+func (c *conn) trustedPeer() bool {
+	tcp, ok := c.Conn.RemoteAddr().(*net.TCPAddr)
+	return ok && c.listener.Trusted.Contains(tcp.IP)
+}
+
+// readProxyHeader consumes the PROXY protocol header at the start of r and
+// returns the source address it carries. It returns a nil address without
+// consuming anything if r doesn't start with a header, and a nil address if
+// the header carries none, as for v1 UNKNOWN and v2 LOCAL headers.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case 'P':
+		if prefix, err := r.Peek(6); err != nil || string(prefix) != "PROXY " {
+			return nil, nil
+		}
+		return readV1Header(r)
+	case '\r':
+		if signature, err := r.Peek(len(v2Signature)); err != nil || !bytes.Equal(signature, v2Signature) {
+			return nil, nil
+		}
+		return readV2Header(r)
+	}
+	return nil, nil
+}
+
+// readV1Header reads a header such as
+// "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readV1Header(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1HeaderLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			return parseV1Header(strings.TrimSuffix(string(line), "\r\n"))
+		}
+	}
+	return nil, errors.New("proxy: PROXY v1 header too long")
+}
+
+func parseV1Header(line string) (net.Addr, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("proxy: invalid PROXY v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("proxy: invalid PROXY v1 header %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2Header reads a binary v2 header, ignoring its TLVs.
+func readV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, v2HeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	version, command := header[12]>>4, header[12]&0x0f
+	family := header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+	if version != 2 || command > 1 {
+		return nil, fmt.Errorf("proxy: invalid PROXY v2 version and command %#x", header[12])
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	// A LOCAL command is sent by the proxy itself, e.g. for health checks.
+	if command == 0 {
+		return nil, nil
+	}
+
+	var ipLength int
+	switch family {
+	case 0x11: // TCP over IPv4
+		ipLength = net.IPv4len
+	case 0x21: // TCP over IPv6
+		ipLength = net.IPv6len
+	default:
+		return nil, nil
+	}
+	if length < 2*ipLength+4 {
+		return nil, fmt.Errorf("proxy: PROXY v2 address block too short")
+	}
+	ip := net.IP(payload[:ipLength])
+	port := binary.BigEndian.Uint16(payload[2*ipLength:])
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
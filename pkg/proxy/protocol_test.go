@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func v2Header(command byte, src net.IP, port uint16) string {
+	var addresses []byte
+	family := byte(0x11)
+	if src.To4() == nil {
+		family = 0x21
+		addresses = append(append(addresses, src.To16()...), net.IPv6loopback...)
+	} else {
+		addresses = append(append(addresses, src.To4()...), 127, 0, 0, 1)
+	}
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports, port)
+	binary.BigEndian.PutUint16(ports[2:], 443)
+	addresses = append(addresses, ports...)
+	// A TLV that must be skipped.
+	addresses = append(addresses, 0x04, 0x00, 0x01, 0xff)
+
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(addresses)))
+	return string(append(header, addresses...))
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		err      bool
+		// untouched is set when the input must be left unconsumed.
+		untouched bool
+	}{
+		{name: "none", input: "GET / HTTP/1.1\r\n", untouched: true},
+		{name: "PUT", input: "PUT /v2/service_instances/1 HTTP/1.1\r\n", untouched: true},
+		{name: "v1 TCP4", input: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nGET", expected: "192.0.2.1:56324"},
+		{name: "v1 TCP6", input: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nGET", expected: "[2001:db8::1]:56324"},
+		{name: "v1 UNKNOWN", input: "PROXY UNKNOWN\r\nGET"},
+		{name: "v1 mismatched family", input: "PROXY TCP4 2001:db8::1 2001:db8::2 56324 443\r\n", err: true},
+		{name: "v1 invalid port", input: "PROXY TCP4 192.0.2.1 198.51.100.1 http 443\r\n", err: true},
+		{name: "v1 too long", input: "PROXY " + strings.Repeat("x", 200), err: true},
+		{name: "v2 IPv4", input: v2Header(1, net.ParseIP("192.0.2.1"), 56324) + "GET", expected: "192.0.2.1:56324"},
+		{name: "v2 IPv6", input: v2Header(1, net.ParseIP("2001:db8::1"), 56324) + "GET", expected: "[2001:db8::1]:56324"},
+		{name: "v2 LOCAL", input: v2Header(0, net.ParseIP("192.0.2.1"), 56324) + "GET"},
+		{name: "v2 truncated", input: v2Header(1, net.ParseIP("192.0.2.1"), 56324)[:20], err: true},
+	}
+
+	for _, tc := range cases {
+		r := bufio.NewReader(strings.NewReader(tc.input))
+		addr, err := readProxyHeader(r)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.name, err)
+			continue
+		}
+		a := ""
+		if addr != nil {
+			a = addr.String()
+		}
+		if e := tc.expected; e != a {
+			t.Errorf("%v: unexpected address; expected %q, got %q", tc.name, e, a)
+		}
+		if rest, _ := ioutil.ReadAll(r); tc.untouched && string(rest) != tc.input {
+			t.Errorf("%v: expected the input to be left unconsumed, got %q", tc.name, rest)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	trusted, err := ParseTrusted([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	})}
+	go srv.Serve(&Listener{Listener: l, Trusted: trusted})
+	defer srv.Close()
+
+	get := func(header string) (string, error) {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return "", err
+		}
+		defer c.Close()
+		fmt.Fprintf(c, "%sGET / HTTP/1.0\r\n\r\n", header)
+		resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	addr, err := get("PROXY TCP4 192.0.2.1 127.0.0.1 56324 80\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "192.0.2.1:56324", addr; e != a {
+		t.Errorf("Unexpected remote address; expected %v, got %v", e, a)
+	}
+
+	addr, err = get("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("Expected the peer's address without a header, got %v", addr)
+	}
+
+	if _, err := get("PROXY TCP4 192.0.2.1\r\n"); err == nil {
+		t.Error("Expected a connection with an invalid header to be closed")
+	}
+}
+
+func TestListenerUntrustedPeer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := &Listener{Listener: l, Trusted: trusted}
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		fmt.Fprint(c, "PROXY TCP4 192.0.2.1 127.0.0.1 56324 80\r\nGET")
+	}()
+
+	c, err := pl.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected a header from an untrusted peer to be rejected")
+	}
+}
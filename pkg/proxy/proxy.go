@@ -0,0 +1,148 @@
+// Package proxy resolves the address of the client that sent a request
+// forwarded by trusted proxies, such as an ingress controller or an API
+// gateway, from the Forwarded and X-Forwarded-For headers.
+//
+// Only hops added by trusted proxies are believed: the headers are walked
+// from the proxy closest to the broker towards the client, and the first
+// address that isn't a trusted proxy is the client. A client can therefore
+// not spoof its address by sending the headers itself.
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// Trusted is a set of trusted proxy networks.
+type Trusted struct {
+	networks []*net.IPNet
+}
+
+// ParseTrusted returns the Trusted proxies in the given CIDRs or addresses,
+// for example "10.0.0.0/8" or "192.168.1.10".
+func ParseTrusted(cidrs []string) (*Trusted, error) {
+	t := &Trusted{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("proxy: invalid address %q", cidr)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: invalid network %q: %v", cidr, err)
+		}
+		t.networks = append(t.networks, network)
+	}
+	return t, nil
+}
+
+// Contains returns whether ip is a trusted proxy.
+func (t *Trusted) Contains(ip net.IP) bool {
+	for _, network := range t.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent r. The Forwarded
+// header is used if present, X-Forwarded-For otherwise. If the peer isn't
+// a trusted proxy the headers are ignored and the peer's address returned.
+// A malformed hop stops the walk at the last trusted proxy.
+func (t *Trusted) ClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	ip := net.ParseIP(peer)
+	if ip == nil || !t.Contains(ip) {
+		return peer
+	}
+
+	hops := forwardedFor(r.Header)
+	if hops == nil {
+		hops = xForwardedFor(r.Header)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(hops[i])
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !t.Contains(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// Middleware records the client address of requests, which is then returned
+// by broker.ClientIP and RequestContext.ClientIP. Install it before any
+// middleware keying on client addresses, such as a ratelimit.Limiter using
+// ratelimit.ClientIPKey.
+func (t *Trusted) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, broker.WithClientIP(r, t.ClientIP(r)))
+	})
+}
+
+// xForwardedFor returns the addresses of the X-Forwarded-For headers, from
+// the client to the closest proxy.
+func xForwardedFor(h http.Header) []string {
+	var hops []string
+	for _, value := range h["X-Forwarded-For"] {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedFor returns the for parameters of the RFC 7239 Forwarded headers,
+// from the client to the closest proxy, without ports. It returns nil if
+// there is no Forwarded header.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, value := range h["Forwarded"] {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					hop = forwardedNode(kv[1])
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// forwardedNode returns the address of a Forwarded node, which may be
+// quoted and carry a port, e.g. "[2001:db8::1]:4711".
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		expected   string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "203.0.113.7:5000",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "203.0.113.7:5000",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "trusted peer",
+			remoteAddr: "10.1.2.3:5000",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "spoofed hop",
+			remoteAddr: "10.1.2.3:5000",
+			header:     http.Header{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1", "192.168.1.10"}},
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "all trusted",
+			remoteAddr: "10.1.2.3:5000",
+			header:     http.Header{"X-Forwarded-For": {"10.9.9.9"}},
+			expected:   "10.9.9.9",
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.1.2.3:5000",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.1, garbage"}},
+			expected:   "10.1.2.3",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.1.2.3:5000",
+			header: http.Header{
+				"Forwarded":       {`for=198.51.100.1;proto=https, for="[fd00::1]:4711"`},
+				"X-Forwarded-For": {"1.1.1.1"},
+			},
+			expected: "198.51.100.1",
+		},
+		{
+			name:       "forwarded IPv6 client",
+			remoteAddr: "[fd00::2]:5000",
+			header:     http.Header{"Forwarded": {`for="[2001:db8::1]:4711"`}},
+			expected:   "2001:db8::1",
+		},
+		{
+			name:       "forwarded obfuscated",
+			remoteAddr: "10.1.2.3:5000",
+			header:     http.Header{"Forwarded": {"for=_hidden"}},
+			expected:   "10.1.2.3",
+		},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/v2/catalog", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for k, v := range tc.header {
+			r.Header[k] = v
+		}
+		if e, a := tc.expected, trusted.ClientIP(r); e != a {
+			t.Errorf("%v: unexpected client IP; expected %v, got %v", tc.name, e, a)
+		}
+	}
+}
+
+func TestParseTrustedInvalid(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0/33", "proxy.local"} {
+		if _, err := ParseTrusted([]string{cidr}); err == nil {
+			t.Errorf("Expected an error for %q", cidr)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clientIP string
+	h := trusted.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &broker.RequestContext{Request: r}
+		clientIP = c.ClientIP()
+	}))
+
+	r := httptest.NewRequest("GET", "/v2/catalog", nil)
+	r.RemoteAddr = "10.1.2.3:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if e, a := "198.51.100.1", clientIP; e != a {
+		t.Errorf("Unexpected client IP; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,252 @@
+// Package ratelimit provides token bucket rate limiting for the OSB API,
+// keyed by the platform credentials of each request and, optionally, by the
+// originating identity within them, so that a single noisy platform or user
+// cannot starve the others.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+const (
+	// idleBucketTTL is how long a bucket may go unused before it is
+	// forgotten.
+	idleBucketTTL = 10 * time.Minute
+
+	// DefaultMaxKeys is the number of buckets a Limiter keeps when MaxKeys
+	// is not set.
+	DefaultMaxKeys = 10000
+
+	// overflowKey is the bucket shared by new keys once MaxKeys buckets
+	// are in use.
+	overflowKey = "\x00overflow"
+)
+
+// KeyFunc returns the key of the bucket a request is accounted against.
+type KeyFunc func(r *http.Request) string
+
+// Limiter is a set of token buckets, one per key. Each bucket holds up to
+// Burst tokens and is refilled at Rate tokens per second.
+type Limiter struct {
+	// Rate is the number of requests per second allowed for each key.
+	Rate float64
+	// Burst is the maximum number of requests allowed at once for each key.
+	Burst int
+	// KeyFunc selects the bucket for a request. It defaults to
+	// PrincipalKey.
+	KeyFunc KeyFunc
+	// MaxKeys bounds the number of buckets kept. Once it is reached, keys
+	// without a bucket share a single overflow bucket, so a client minting
+	// new keys can't grow the Limiter or escape its limit. It defaults to
+	// DefaultMaxKeys.
+	MaxKeys int
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Limiter allowing rate requests per second with the given
+// burst for each platform principal.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		Rate:    rate,
+		Burst:   burst,
+		KeyFunc: PrincipalKey,
+	}
+}
+
+// SetLimits changes the Rate and Burst of l while it is in use. Buckets
+// keep their tokens, capped at the new burst on their next request.
+func (l *Limiter) SetLimits(rate float64, burst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Rate = rate
+	l.Burst = burst
+}
+
+// Allow consumes a token from the bucket for key. It returns whether the
+// request is allowed and, if not, how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock()
+	if l.buckets == nil {
+		l.buckets = map[string]*bucket{}
+	}
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxKeys() {
+			l.sweepAll(now)
+		}
+		if len(l.buckets) >= l.maxKeys() {
+			key = overflowKey
+			b, ok = l.buckets[key]
+		}
+	}
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	if l.Rate <= 0 {
+		return false, idleBucketTTL
+	}
+	wait := time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	return false, wait
+}
+
+func (l *Limiter) maxKeys() int {
+	if l.MaxKeys <= 0 {
+		return DefaultMaxKeys
+	}
+	// Keep room for the overflow bucket.
+	return l.MaxKeys - 1
+}
+
+// sweep forgets buckets that have not been used recently, at most once per
+// idleBucketTTL.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.sweepAll(now)
+}
+
+// sweepAll forgets buckets that have not been used recently.
+func (l *Limiter) sweepAll(now time.Time) {
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *Limiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// Middleware returns a handler that rejects requests exceeding the limit of
+// their bucket with a 429 and a Retry-After header. It can be installed on a
+// server's router with Router.Use.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyFunc := l.KeyFunc
+		if keyFunc == nil {
+			keyFunc = PrincipalKey
+		}
+
+		allowed, wait := l.Allow(keyFunc(r))
+		if allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writeRateLimited(w, wait)
+	})
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	type e struct {
+		ErrorMessage string `json:"error"`
+		Description  string `json:"description"`
+	}
+	data, err := json.Marshal(&e{
+		ErrorMessage: "RateLimited",
+		Description:  "Too many requests; retry later.",
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write(data)
+}
+
+// PrincipalKey keys requests by the credentials the platform authenticates
+// with: the basic auth username, or a hash of the bearer token. Requests
+// without credentials are keyed by client IP address.
+func PrincipalKey(r *http.Request) string {
+	if username, _, ok := r.BasicAuth(); ok {
+		return "basic/" + username
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		sum := sha256.Sum256([]byte(strings.TrimPrefix(auth, "Bearer ")))
+		return "bearer/" + hex.EncodeToString(sum[:8])
+	}
+	return ClientIPKey(r)
+}
+
+// IdentityKey keys requests by their originating identity within the
+// principal that sent them: the Kubernetes username or Cloud Foundry user ID
+// when the identity header can be parsed. The identity header is supplied by
+// the platform and not verified by the broker, so identities can only
+// divide the limit of their principal; MaxKeys bounds the buckets a
+// misbehaving platform can create.
+func IdentityKey(r *http.Request) string {
+	principal := PrincipalKey(r)
+	if key := identityKey(r); key != "" {
+		return principal + "|" + key
+	}
+	return principal
+}
+
+func identityKey(r *http.Request) string {
+	originatingIdentity, err := broker.OriginatingIdentity(r)
+	if err != nil || originatingIdentity == nil {
+		return ""
+	}
+
+	identity, err := broker.RequestIdentity(r)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case identity.Kubernetes != nil:
+		return identity.Platform + "/" + identity.Kubernetes.Username
+	case identity.CloudFoundry != nil:
+		return identity.Platform + "/" + identity.CloudFoundry.UserID
+	}
+	return identity.Platform + "/" + originatingIdentity.Value
+}
+
+// ClientIPKey keys requests by the IP address of the client, resolved
+// through trusted proxies when the proxy middleware runs before the Limiter.
+func ClientIPKey(r *http.Request) string {
+	return broker.ClientIP(r)
+}
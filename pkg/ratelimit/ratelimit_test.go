@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+	}
+
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("Expected request beyond burst to be rejected")
+	}
+	if e, a := time.Second, wait; e != a {
+		t.Errorf("Unexpected wait; expected %v, got %v", e, a)
+	}
+
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("Expected a different key to have its own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("Expected the bucket to be refilled")
+	}
+}
+
+func TestMiddlewareKeysByIdentity(t *testing.T) {
+	l := New(0.001, 1)
+	l.KeyFunc = IdentityKey
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	identity := func(user string) string {
+		value := base64.StdEncoding.EncodeToString([]byte(`{"username":"` + user + `"}`))
+		return osb.PlatformKubernetes + " " + value
+	}
+
+	do := func(user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+		r.Header.Set(osb.OriginatingIdentityHeader, identity(user))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if e, a := http.StatusOK, do("alice").Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	rec := do("alice")
+	if e, a := http.StatusTooManyRequests, rec.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	if e, a := http.StatusOK, do("bob").Code; e != a {
+		t.Fatalf("Unexpected status code for another identity; expected %v, got %v", e, a)
+	}
+}
+
+func TestIdentityKeyFallsBackToClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+
+	if e, a := "10.0.0.1", IdentityKey(r); e != a {
+		t.Errorf("Unexpected key; expected %v, got %v", e, a)
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	l := New(0.001, 1)
+	l.MaxKeys = 3
+
+	// Two keys get their own bucket; further keys share the overflow
+	// bucket instead of growing the Limiter.
+	for _, key := range []string{"a", "b", "c"} {
+		if ok, _ := l.Allow(key); !ok {
+			t.Fatalf("Expected the first request for %q to be allowed", key)
+		}
+	}
+	if ok, _ := l.Allow("d"); ok {
+		t.Error("Expected a new key to share the exhausted overflow bucket")
+	}
+	if e, a := 3, len(l.buckets); e != a {
+		t.Errorf("Unexpected number of buckets; expected %v, got %v", e, a)
+	}
+}
+
+func TestPrincipalKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+		want  string
+	}{
+		{
+			name:  "basic auth",
+			setup: func(r *http.Request) { r.SetBasicAuth("platform", "secret") },
+			want:  "basic/platform",
+		},
+		{
+			name:  "bearer token",
+			setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+			want:  "bearer/3c469e9d6c5875d3",
+		},
+		{
+			name:  "anonymous",
+			setup: func(r *http.Request) {},
+			want:  "10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+			r.RemoteAddr = "10.0.0.1:5555"
+			tt.setup(r)
+			if e, a := tt.want, PrincipalKey(r); e != a {
+				t.Errorf("Unexpected key; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+// Package record records the OSB interactions of a broker to files and
+// replays them. Recordings are redacted, so they can be attached to bug
+// reports; replaying them reproduces platform-reported problems without
+// access to the original backend.
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+)
+
+// DefaultMaxBodyBytes is the default Recorder.MaxBodyBytes.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Request is a recorded request.
+type Request struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// Truncated is set when the body exceeded the recorder's MaxBodyBytes.
+	// Body is then redact.Placeholder.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int             `json:"status_code"`
+	Header     http.Header     `json:"header,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	// Truncated is set when the body exceeded the recorder's MaxBodyBytes.
+	// Body is then redact.Placeholder.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Exchange is a recorded request/response pair.
+type Exchange struct {
+	Operation string    `json:"operation,omitempty"`
+	Time      time.Time `json:"time"`
+	Request   Request   `json:"request"`
+	Response  Response  `json:"response"`
+}
+
+// Recorder is a middleware that records every exchange it serves to its own
+// JSON file in Dir. Headers and bodies are redacted before being written.
+type Recorder struct {
+	// Dir is the directory recordings are written to.
+	Dir string
+	// MaxBodyBytes bounds the request and response bodies recorded. Only
+	// that much of a body is captured, the rest is passed on as it is read
+	// or written, so the recorder neither reads past the limit a handler
+	// sets on request bodies nor holds streamed responses back. Longer
+	// bodies can't be redacted and are recorded as redact.Placeholder. It
+	// defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int
+
+	seq uint64
+}
+
+// NewRecorder returns a Recorder writing to dir.
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{Dir: dir}
+}
+
+// Middleware returns a handler that records the exchanges served by next.
+// It is meant to be installed on a server's router with Router.Use.
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := rec.MaxBodyBytes
+		if max <= 0 {
+			max = DefaultMaxBodyBytes
+		}
+		body := &limitedBuffer{max: max}
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, body), r.Body}
+		}
+
+		capture := &responseCapture{ResponseWriter: w, statusCode: http.StatusOK, body: limitedBuffer{max: max}}
+		next.ServeHTTP(capture, r)
+
+		exchange := &Exchange{
+			Time: time.Now().UTC(),
+			Request: Request{
+				Method:    r.Method,
+				URL:       r.URL.RequestURI(),
+				Header:    redact.Header(r.Header),
+				Body:      rawJSON(redact.JSON(body.Bytes())),
+				Truncated: body.truncated,
+			},
+			Response: Response{
+				StatusCode: capture.statusCode,
+				Header:     redact.Header(w.Header()),
+				Body:       rawJSON(redact.JSON(capture.body.Bytes())),
+				Truncated:  capture.body.truncated,
+			},
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			exchange.Operation = route.GetName()
+		}
+
+		if err := rec.write(exchange); err != nil {
+			glog.Errorf("record: writing exchange: %v", err)
+		}
+	})
+}
+
+func (rec *Recorder) write(exchange *Exchange) error {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rec.Dir, 0700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%06d", atomic.AddUint64(&rec.seq, 1))
+	if exchange.Operation != "" {
+		name += "-" + exchange.Operation
+	}
+	return ioutil.WriteFile(filepath.Join(rec.Dir, name+".json"), data, 0600)
+}
+
+// rawJSON returns data as a json.RawMessage, or nil if it is empty.
+func rawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// limitedBuffer keeps the first max bytes written to it, and drops the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if room := b.max - b.Len(); len(data) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(data[:room])
+		}
+		return len(data), nil
+	}
+	return b.Buffer.Write(data)
+}
+
+// responseCapture is a ResponseWriter that keeps a copy of the status code
+// and the first bytes of the body written through it.
+type responseCapture struct {
+	http.ResponseWriter
+	statusCode int
+	body       limitedBuffer
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	c.statusCode = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+// Flush forwards to the underlying ResponseWriter so that streamed responses
+// are not held back while being recorded.
+func (c *responseCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Replayer is an http.Handler that serves recorded responses. A request is
+// answered with the next unused exchange recorded for the same method and
+// URL; when all of them have been used, the last one is repeated. Requests
+// without a recording get a 404.
+type Replayer struct {
+	mutex     sync.Mutex
+	exchanges map[string][]*Exchange
+	served    map[string]int
+}
+
+// NewReplayer loads the exchanges recorded in dir, in recording order.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	replayer := &Replayer{
+		exchanges: map[string][]*Exchange{},
+		served:    map[string]int{},
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		exchange := &Exchange{}
+		if err := json.Unmarshal(data, exchange); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		key := replayKey(exchange.Request.Method, exchange.Request.URL)
+		replayer.exchanges[key] = append(replayer.exchanges[key], exchange)
+	}
+
+	return replayer, nil
+}
+
+// ServeHTTP serves the recorded response for r.
+func (p *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := replayKey(r.Method, r.URL.RequestURI())
+
+	p.mutex.Lock()
+	exchanges := p.exchanges[key]
+	if len(exchanges) == 0 {
+		p.mutex.Unlock()
+		http.Error(w, "no recorded exchange for "+key, http.StatusNotFound)
+		return
+	}
+	i := p.served[key]
+	if i >= len(exchanges) {
+		i = len(exchanges) - 1
+	}
+	p.served[key] = i + 1
+	exchange := exchanges[i]
+	p.mutex.Unlock()
+
+	for k, v := range exchange.Response.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(exchange.Response.StatusCode)
+	w.Write(exchange.Response.Body)
+}
+
+func replayKey(method, url string) string {
+	return strings.ToUpper(method) + " " + url
+}
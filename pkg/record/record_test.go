@@ -0,0 +1,135 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"credentials":{"password":"hunter2"}}`))
+	}).Methods("PUT").Name("bind")
+	router.Use(NewRecorder(dir).Middleware)
+
+	r := httptest.NewRequest("PUT", "/v2/service_instances/i1/service_bindings/b1", strings.NewReader(`{"parameters":{"token":"abc"}}`))
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	r.Header.Set("X-Broker-API-Originating-Identity", "kubernetes ZHVkZXI=")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+
+	if e, a := `{"credentials":{"password":"hunter2"}}`, rec.Body.String(); e != a {
+		t.Fatalf("Expected the response to be passed through; expected %v, got %v", e, a)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "000001-bind.json" {
+		t.Fatalf("Unexpected recordings: %v", files)
+	}
+	if e, a := os.FileMode(0600), files[0].Mode().Perm(); e != a {
+		t.Errorf("Unexpected recording mode; expected %v, got %v", e, a)
+	}
+	data, err := ioutil.ReadFile(dir + "/" + files[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "abc", "dXNlcjpwYXNz", "ZHVkZXI="} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Recording contains secret %q:\n%s", secret, data)
+		}
+	}
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	replayer.ServeHTTP(rec, httptest.NewRequest("PUT", "/v2/service_instances/i1/service_bindings/b1", nil))
+	if e, a := http.StatusCreated, rec.Code; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	body := &bytes.Buffer{}
+	if err := json.Compact(body, rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := `{"credentials":"[REDACTED]"}`, body.String(); e != a {
+		t.Errorf("Unexpected body; expected %v, got %v", e, a)
+	}
+
+	rec = httptest.NewRecorder()
+	replayer.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/catalog", nil))
+	if e, a := http.StatusNotFound, rec.Code; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+}
+
+func TestRecorderFlush(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/catalog", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected the recording ResponseWriter to implement http.Flusher")
+		}
+		w.Write([]byte(`{}`))
+		flusher.Flush()
+	}).Name("get_catalog")
+	router.Use(NewRecorder(t.TempDir()).Middleware)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/catalog", nil))
+	if !rec.Flushed {
+		t.Error("Expected the flush to be forwarded")
+	}
+}
+
+func TestRecorderMaxBodyBytes(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("x", 1<<20)
+
+	var read int
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/service_instances/{instance_id}", func(w http.ResponseWriter, r *http.Request) {
+		// A handler limiting the request body, as MaxRequestBodyBytes does.
+		body, _ := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 64))
+		read = len(body)
+		w.Write([]byte(large))
+	}).Methods("PUT").Name("provision")
+	router.Use((&Recorder{Dir: dir, MaxBodyBytes: 16}).Middleware)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("PUT", "/v2/service_instances/i1", strings.NewReader(large)))
+	if e, a := 64, read; e != a {
+		t.Errorf("Unexpected length of the request body read; expected %v, got %v", e, a)
+	}
+	if e, a := len(large), rec.Body.Len(); e != a {
+		t.Errorf("Unexpected length of the response body; expected %v, got %v", e, a)
+	}
+
+	data, err := ioutil.ReadFile(dir + "/000001-provision.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := &Exchange{}
+	if err := json.Unmarshal(data, exchange); err != nil {
+		t.Fatal(err)
+	}
+	if !exchange.Request.Truncated || !exchange.Response.Truncated {
+		t.Errorf("Expected the bodies to be marked truncated, got %+v, %+v", exchange.Request.Truncated, exchange.Response.Truncated)
+	}
+	if e, a := `"[REDACTED]"`, string(exchange.Response.Body); e != a {
+		t.Errorf("Unexpected recorded response body; expected %v, got %v", e, a)
+	}
+}
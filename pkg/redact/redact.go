@@ -0,0 +1,101 @@
+// Package redact removes secrets from the OSB payloads and headers the
+// library logs, records or exposes for debugging.
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+// sensitiveKeys are the JSON keys whose values are redacted, compared case
+// insensitively. "credentials" covers the whole credentials object of bind
+// responses.
+var sensitiveKeys = map[string]bool{
+	"credentials":   true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"private_key":   true,
+	"client_secret": true,
+	"uri":           true,
+}
+
+// sensitiveHeaders are the headers whose values are redacted, in canonical
+// form. The originating identity carries the platform user's identity.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Broker-Api-Originating-Identity",
+}
+
+// IsSensitiveKey returns whether values of the given JSON key are redacted.
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// JSON returns a copy of the given JSON document with the values of
+// sensitive keys replaced by Placeholder, at any depth. Documents that are
+// not valid JSON are replaced entirely, since they can't be inspected.
+func JSON(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(`"` + Placeholder + `"`)
+	}
+
+	redacted, err := json.Marshal(Value(v))
+	if err != nil {
+		return []byte(`"` + Placeholder + `"`)
+	}
+	return redacted
+}
+
+// Value returns a copy of a decoded JSON value with the values of sensitive
+// keys replaced by Placeholder, at any depth.
+func Value(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(typed))
+		for k, val := range typed {
+			if IsSensitiveKey(k) {
+				redacted[k] = Placeholder
+				continue
+			}
+			redacted[k] = Value(val)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, val := range typed {
+			redacted[i] = Value(val)
+		}
+		return redacted
+	}
+	return v
+}
+
+// Header returns a copy of the given header with the values of sensitive
+// headers replaced by Placeholder.
+func Header(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, v := range h {
+		redacted[k] = append([]string(nil), v...)
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := redacted[k]; ok {
+			redacted[k] = []string{Placeholder}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,63 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "credentials",
+			input:    `{"credentials":{"username":"u","password":"p"},"syslog_drain_url":"x"}`,
+			expected: `{"credentials":"[REDACTED]","syslog_drain_url":"x"}`,
+		},
+		{
+			name:     "nested parameters",
+			input:    `{"parameters":{"db":[{"Password":"p","name":"n"}]}}`,
+			expected: `{"parameters":{"db":[{"Password":"[REDACTED]","name":"n"}]}}`,
+		},
+		{
+			name:     "invalid json",
+			input:    `{"password":`,
+			expected: `"[REDACTED]"`,
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			if e, a := tc.expected, string(JSON([]byte(tc.input))); e != a {
+				t.Errorf("Unexpected result; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Basic dXNlcjpwYXNz")
+	h.Set("X-Broker-API-Version", "2.13")
+	h.Set("X-Broker-API-Originating-Identity", "kubernetes ZHVkZXI=")
+
+	redacted := Header(h)
+	if e, a := Placeholder, redacted.Get("X-Broker-API-Originating-Identity"); e != a {
+		t.Errorf("Unexpected originating identity; expected %v, got %v", e, a)
+	}
+	if e, a := Placeholder, redacted.Get("Authorization"); e != a {
+		t.Errorf("Unexpected Authorization; expected %v, got %v", e, a)
+	}
+	if e, a := "2.13", redacted.Get("X-Broker-API-Version"); e != a {
+		t.Errorf("Unexpected version; expected %v, got %v", e, a)
+	}
+	if e, a := "Basic dXNlcjpwYXNz", h.Get("Authorization"); e != a {
+		t.Errorf("Expected the original header to be left untouched, got %v", a)
+	}
+}
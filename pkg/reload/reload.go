@@ -0,0 +1,183 @@
+// Package reload applies a subset of the broker configuration while the
+// broker is running: the log level, the rate limit, maintenance mode and the
+// catalog file. A reload is triggered by SIGHUP or by an authenticated POST to
+// the admin endpoint, and re-reads the configuration with Load. A second
+// admin endpoint reports the configuration in effect and the served catalog.
+package reload
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/config"
+	"github.com/pmorie/osb-broker-lib/pkg/ratelimit"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// Path is the path of the admin endpoint served by Handler.
+const Path = "/admin/reload"
+
+// Reloader reloads the configuration of a running broker.
+type Reloader struct {
+	// Load reads the configuration, typically with config.Load.
+	Load func() (*config.Config, error)
+	// API is put in and out of read-only mode.
+	API *rest.APISurface
+	// Limiter, if set, gets the reloaded rate limit. A zero rate leaves it
+	// unchanged.
+	Limiter *ratelimit.Limiter
+	// Broker is notified of reloads if it implements broker.ReloadAware.
+	Broker broker.Interface
+	// Token is the bearer token required by Handler and StateHandler. They
+	// reject every request while it is empty.
+	Token string
+	// Initial is the configuration the broker started with. StateHandler
+	// reports it until the first successful reload.
+	Initial *config.Config
+	// Caches are invalidated after every successful reload, so they pick
+	// up a reloaded catalog; an offering.Cache, for example.
+	Caches []Invalidator
+
+	mutex      sync.Mutex
+	current    *config.Config
+	reloadedAt time.Time
+}
+
+// Invalidator is a cache derived from the configuration or the catalog.
+type Invalidator interface {
+	// Invalidate drops the cached values.
+	Invalidate()
+}
+
+// Reload reads the configuration and applies it. Reloads are serialized.
+func (r *Reloader) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, err := r.Load()
+	if err != nil {
+		return err
+	}
+
+	var catalog *broker.CatalogResponse
+	if c.CatalogFile != "" {
+		if catalog, err = readCatalog(c.CatalogFile); err != nil {
+			return err
+		}
+	}
+
+	if err := setLogLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if r.Limiter != nil && c.RateLimit > 0 {
+		r.Limiter.SetLimits(c.RateLimit, c.RateBurst)
+	}
+	if r.API != nil {
+		r.API.SetReadOnly(c.Maintenance)
+	}
+
+	if aware, ok := r.Broker.(broker.ReloadAware); ok {
+		err := aware.Reload(&broker.ReloadConfig{
+			LogLevel:    c.LogLevel,
+			Maintenance: c.Maintenance,
+			Catalog:     catalog,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, cache := range r.Caches {
+		cache.Invalidate()
+	}
+	r.current = c
+	r.reloadedAt = time.Now()
+	return nil
+}
+
+// WatchSignals reloads the configuration every time the process receives
+// SIGHUP, until ctx is done. Errors are logged.
+func (r *Reloader) WatchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			glog.Info("Received SIGHUP, reloading configuration")
+			if err := r.Reload(); err != nil {
+				glog.Errorf("Error reloading configuration: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Handler returns the admin endpoint reloading the configuration on POST
+// requests bearing Token. It answers 204 on success and 500 with the error
+// otherwise. Register it on the server's router at Path.
+func (r *Reloader) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !r.authorized(req) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := r.Reload(); err != nil {
+			glog.Errorf("Error reloading configuration: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (r *Reloader) authorized(req *http.Request) bool {
+	auth := req.Header.Get("Authorization")
+	if r.Token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) == 1
+}
+
+// setLogLevel sets the glog verbosity, which glog only exposes as a flag.
+func setLogLevel(level int) error {
+	v := flag.Lookup("v")
+	if v == nil {
+		return fmt.Errorf("reload: glog verbosity flag not registered")
+	}
+	return v.Value.Set(strconv.Itoa(level))
+}
+
+func readCatalog(path string) (*broker.CatalogResponse, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	catalog := &broker.CatalogResponse{}
+	if err := json.Unmarshal(data, catalog); err != nil {
+		return nil, fmt.Errorf("reload: invalid catalog file %s: %v", path, err)
+	}
+	return catalog, nil
+}
@@ -0,0 +1,247 @@
+package reload
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/config"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/ratelimit"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+type reloadAwareBroker struct {
+	brokertest.FakeBroker
+	reloaded *broker.ReloadConfig
+	err      error
+}
+
+func (b *reloadAwareBroker) Reload(c *broker.ReloadConfig) error {
+	b.reloaded = c
+	return b.err
+}
+
+func newTestReloader(t *testing.T, cfg *config.Config) (*Reloader, *reloadAwareBroker) {
+	b := &reloadAwareBroker{}
+	api, err := rest.NewAPISurface(b, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Reloader{
+		Load:    func() (*config.Config, error) { return cfg, nil },
+		API:     api,
+		Limiter: ratelimit.New(1, 1),
+		Broker:  b,
+		Token:   "secret",
+	}, b
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	catalogFile := filepath.Join(dir, "catalog.json")
+	if err := ioutil.WriteFile(catalogFile, []byte(`{"services":[{"id":"svc","name":"db"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := flag.Lookup("v").Value.String()
+	defer flag.Set("v", v)
+
+	r, b := newTestReloader(t, &config.Config{
+		LogLevel:    5,
+		RateLimit:   10,
+		RateBurst:   20,
+		Maintenance: true,
+		CatalogFile: catalogFile,
+	})
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := "5", flag.Lookup("v").Value.String(); e != a {
+		t.Errorf("Unexpected log level; expected %v, got %v", e, a)
+	}
+	if e, a := 10.0, r.Limiter.Rate; e != a {
+		t.Errorf("Unexpected rate; expected %v, got %v", e, a)
+	}
+	if e, a := 20, r.Limiter.Burst; e != a {
+		t.Errorf("Unexpected burst; expected %v, got %v", e, a)
+	}
+	if !r.API.IsReadOnly() {
+		t.Error("Expected maintenance mode to make the API read-only")
+	}
+	if b.reloaded == nil || b.reloaded.Catalog == nil {
+		t.Fatalf("Expected the broker to be notified with the catalog, got %+v", b.reloaded)
+	}
+	if e, a := "db", b.reloaded.Catalog.Services[0].Name; e != a {
+		t.Errorf("Unexpected service name; expected %v, got %v", e, a)
+	}
+}
+
+type fakeCache struct {
+	invalidated int
+}
+
+func (c *fakeCache) Invalidate() {
+	c.invalidated++
+}
+
+func TestReloadInvalidatesCaches(t *testing.T) {
+	v := flag.Lookup("v").Value.String()
+	defer flag.Set("v", v)
+
+	cache := &fakeCache{}
+	r, b := newTestReloader(t, &config.Config{})
+	r.Caches = []Invalidator{cache}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, cache.invalidated; e != a {
+		t.Errorf("Unexpected invalidations; expected %v, got %v", e, a)
+	}
+
+	b.err = errors.New("rejected")
+	if err := r.Reload(); err == nil {
+		t.Fatal("Expected the broker's error")
+	}
+	if e, a := 1, cache.invalidated; e != a {
+		t.Errorf("Unexpected invalidations after a failed reload; expected %v, got %v", e, a)
+	}
+}
+
+func TestReloadErrors(t *testing.T) {
+	r, b := newTestReloader(t, &config.Config{CatalogFile: "/nonexistent/catalog.json"})
+	if err := r.Reload(); err == nil {
+		t.Error("Expected an error for a missing catalog file")
+	}
+	if b.reloaded != nil {
+		t.Error("Expected the broker not to be notified of a failed reload")
+	}
+
+	r, b = newTestReloader(t, &config.Config{})
+	b.err = errors.New("rejected")
+	if err := r.Reload(); err != b.err {
+		t.Errorf("Unexpected error; expected %v, got %v", b.err, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r, b := newTestReloader(t, &config.Config{})
+	h := r.Handler()
+
+	cases := []struct {
+		name   string
+		method string
+		token  string
+		code   int
+	}{
+		{name: "no token", method: "POST", code: http.StatusUnauthorized},
+		{name: "wrong token", method: "POST", token: "guess", code: http.StatusUnauthorized},
+		{name: "GET", method: "GET", token: "secret", code: http.StatusMethodNotAllowed},
+		{name: "authorized", method: "POST", token: "secret", code: http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, Path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if e, a := tc.code, w.Code; e != a {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, e, a)
+		}
+	}
+	if b.reloaded == nil {
+		t.Error("Expected the authorized request to reload the configuration")
+	}
+}
+
+func TestStateHandler(t *testing.T) {
+	v := flag.Lookup("v").Value.String()
+	defer flag.Set("v", v)
+
+	initial := &config.Config{Port: 8443, TLSKey: "private", Features: map[string]bool{"beta": true}}
+	r, b := newTestReloader(t, &config.Config{Port: 8443, LogLevel: 3})
+	r.Initial = initial
+	b.GetCatalogFunc = func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+		return &broker.CatalogResponse{
+			CatalogResponse: osb.CatalogResponse{Services: []osb.Service{{ID: "svc", Name: "db"}}},
+		}, nil
+	}
+	h := r.StateHandler()
+
+	get := func(token string) (*httptest.ResponseRecorder, *State) {
+		req := httptest.NewRequest("GET", StatePath, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		state := &State{}
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), state); err != nil {
+				t.Fatalf("Unexpected body %q: %v", w.Body.String(), err)
+			}
+		}
+		return w, state
+	}
+
+	if w, _ := get("guess"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Unexpected status code with the wrong token; expected %v, got %v", http.StatusUnauthorized, w.Code)
+	}
+
+	w, state := get("secret")
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if strings.Contains(w.Body.String(), "private") {
+		t.Errorf("Expected the TLS key to be redacted from %s", w.Body.String())
+	}
+	if e, a := true, state.Config["features.beta"]; e != a {
+		t.Errorf("Unexpected initial feature flag; expected %v, got %v", e, a)
+	}
+	if state.ReloadedAt != nil {
+		t.Errorf("Unexpected reload time before any reload: %v", state.ReloadedAt)
+	}
+	if state.Catalog == nil || len(state.Catalog.Services) != 1 {
+		t.Fatalf("Unexpected catalog %+v", state.Catalog)
+	}
+	if e, a := "db", state.Catalog.Services[0].Name; e != a {
+		t.Errorf("Unexpected service name; expected %v, got %v", e, a)
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	_, state = get("secret")
+	if e, a := 3.0, state.Config["log.level"]; e != a {
+		t.Errorf("Unexpected reloaded log level; expected %v, got %v", e, a)
+	}
+	if state.ReloadedAt == nil {
+		t.Error("Expected the reload time after a reload")
+	}
+
+	b.GetCatalogFunc = func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+		return nil, errors.New("backend down")
+	}
+	_, state = get("secret")
+	if e, a := "backend down", state.CatalogError; e != a {
+		t.Errorf("Unexpected catalog error; expected %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,84 @@
+package reload
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/config"
+)
+
+// StatePath is the path of the admin endpoint served by StateHandler.
+const StatePath = "/admin/state"
+
+// State is the body of the responses of StateHandler.
+type State struct {
+	// Config is the sanitized configuration in effect, keyed by file key;
+	// see config.Config.Sanitized. It is nil if the Reloader has neither
+	// an Initial configuration nor reloaded one.
+	Config map[string]interface{} `json:"config"`
+	// ReloadedAt is the time of the last successful reload, if any.
+	ReloadedAt *time.Time `json:"reloaded_at,omitempty"`
+	// Catalog is the catalog currently served to platforms.
+	Catalog *broker.CatalogResponse `json:"catalog,omitempty"`
+	// CatalogError is the error the catalog failed with.
+	CatalogError string `json:"catalog_error,omitempty"`
+}
+
+// Current returns the configuration in effect: the configuration of the
+// last successful reload, or Initial before the first one.
+func (r *Reloader) Current() *config.Config {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.current != nil {
+		return r.current
+	}
+	return r.Initial
+}
+
+// StateHandler returns the admin endpoint answering GET requests bearing
+// Token with the State of the broker, so operators can check that a reload
+// took effect and compare the served catalog with the catalog file. Register
+// it on the server's router at StatePath.
+func (r *Reloader) StateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !r.authorized(req) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		state := &State{}
+		if c := r.Current(); c != nil {
+			state.Config = c.Sanitized()
+		}
+		r.mutex.Lock()
+		if !r.reloadedAt.IsZero() {
+			reloadedAt := r.reloadedAt.UTC()
+			state.ReloadedAt = &reloadedAt
+		}
+		r.mutex.Unlock()
+
+		if r.API != nil {
+			catalog, err := r.API.Catalog(&broker.RequestContext{
+				Writer:  w,
+				Request: req,
+			})
+			if err != nil {
+				glog.Errorf("Error getting the catalog for the state endpoint: %v", err)
+				state.CatalogError = err.Error()
+			}
+			state.Catalog = catalog
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	})
+}
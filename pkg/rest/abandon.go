@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// abandoned returns whether the platform gave up on r, by closing the
+// connection or timing out, in which case there is no point in writing a
+// response. Abandoned requests are counted in the Abandoned metric.
+func (s *APISurface) abandoned(r *http.Request) bool {
+	err := r.Context().Err()
+	if err == nil {
+		return false
+	}
+
+	operation := operationName(r)
+	s.Metrics.Abandoned.WithLabelValues(operation).Inc()
+	broker.NewRequestLogger(r).Infof("Client abandoned the request: %v", err)
+	return true
+}
@@ -0,0 +1,68 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestAbandonedRequest(t *testing.T) {
+	started := make(chan struct{})
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			close(started)
+			<-c.Context().Done()
+			return nil, c.Context().Err()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := `{"service_id": "service", "plan_id": "plan", "organization_guid": "org", "space_guid": "space"}`
+	request, err := http.NewRequest(http.MethodPut, s.URL+"/v2/service_instances/instance", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(osb.APIVersionHeader, "2.13")
+	errs := make(chan error, 1)
+	go func() {
+		_, err := http.DefaultClient.Do(request.WithContext(ctx))
+		errs <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-errs; err == nil {
+		t.Fatal("Expected the request to be canceled")
+	}
+
+	// The server notices the closed connection asynchronously.
+	metric := &dto.Metric{}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if err := s.API.Metrics.Abandoned.WithLabelValues(rest.OperationProvision).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		if metric.Counter.GetValue() > 0 {
+			break
+		}
+	}
+	if e, a := 1.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected abandoned count; expected %v, got %v", e, a)
+	}
+
+	errorMetric := &dto.Metric{}
+	if err := s.API.Metrics.Errors.WithLabelValues(rest.OperationProvision, rest.ErrorClassInternal).Write(errorMetric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 0.0, errorMetric.Counter.GetValue(); e != a {
+		t.Errorf("Expected abandoned requests not to count as errors, got %v", a)
+	}
+}
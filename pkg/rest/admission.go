@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// admit runs the admission checks configured on the APISurface before an
+// invocation of the business logic for the given operation. On success, the
+// returned func must be called with the result of the invocation.
+func (s *APISurface) admit(w http.ResponseWriter, r *http.Request, operation string) (func(error), error) {
+	if s.CircuitBreaker != nil {
+		allowed, retryAfter := s.CircuitBreaker.Allow(operation)
+		s.recordBreakerState(operation)
+		if !allowed {
+			setRetryAfter(w, retryAfter)
+			return nil, newCircuitOpenError()
+		}
+	}
+
+	// abandon undoes the admission checks passed so far when a later one
+	// refuses the invocation.
+	abandon := func() {
+		if s.CircuitBreaker != nil {
+			// The invocation never happened; give back the half-open
+			// probe slot without recording a result.
+			s.CircuitBreaker.Release(operation)
+		}
+	}
+
+	dequeue := func() {}
+	if s.BackpressureQueue != nil && s.BackpressureQueue.Queues(operation) {
+		var err error
+		dequeue, err = s.BackpressureQueue.Acquire(r.Context())
+		if err != nil {
+			abandon()
+			if _, ok := osb.IsHTTPError(err); ok {
+				s.Metrics.BackpressureRejected.WithLabelValues(operation).Inc()
+				setRetryAfter(w, s.BackpressureQueue.retryAfter())
+			}
+			return nil, err
+		}
+	}
+
+	release := func() {}
+	if s.ConcurrencyLimiter != nil {
+		var err error
+		release, err = s.ConcurrencyLimiter.Acquire(r.Context(), operation)
+		if err != nil {
+			dequeue()
+			abandon()
+			return nil, err
+		}
+		s.Metrics.ConcurrentOperations.WithLabelValues(operation).Inc()
+		s.Metrics.ConcurrencySaturation.Set(s.ConcurrencyLimiter.Saturation())
+	}
+
+	return func(err error) {
+		dequeue()
+		if s.ConcurrencyLimiter != nil {
+			release()
+			s.Metrics.ConcurrentOperations.WithLabelValues(operation).Dec()
+			s.Metrics.ConcurrencySaturation.Set(s.ConcurrencyLimiter.Saturation())
+		}
+		if s.CircuitBreaker != nil {
+			s.CircuitBreaker.Record(operation, err)
+			s.recordBreakerState(operation)
+		}
+	}, nil
+}
+
+// invoke runs the business logic and reports its result to the done func
+// returned by admit. If the business logic panics, the invocation is
+// reported as failed, releasing its admission, before the panic resumes.
+func invoke(done func(error), logic func() error) error {
+	defer func() {
+		if p := recover(); p != nil {
+			done(fmt.Errorf("business logic panicked: %v", p))
+			panic(p)
+		}
+	}()
+
+	err := logic()
+	done(err)
+	return err
+}
+
+func (s *APISurface) recordBreakerState(operation string) {
+	s.Metrics.CircuitBreakerState.WithLabelValues(operation).Set(float64(s.CircuitBreaker.State(operation)))
+}
+
+// setRetryAfter sets the Retry-After header to the given delay, rounded up
+// to whole seconds.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(d.Seconds()))))
+}
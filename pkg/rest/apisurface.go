@@ -1,11 +1,13 @@
 package rest
 
 import (
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
@@ -13,7 +15,9 @@ import (
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 
 	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
 	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
 )
 
 // APISurface is a type that describes a OSB REST API surface. APISurface is
@@ -27,10 +31,144 @@ type APISurface struct {
 	Broker     broker.Interface
 	Metrics    *metrics.OSBMetricsCollector
 	EnableCORS bool
+	// ReadOnly rejects provision, update, deprovision, bind and unbind
+	// requests with a 503 ReadOnly error while still serving the catalog,
+	// bindings and last operation polls. It is intended for standby replicas
+	// and broker migrations. Use SetReadOnly to change it while serving.
+	ReadOnly bool
+	// DrainRetryAfter is the delay advertised in the Retry-After header of
+	// requests rejected while draining. It defaults to 30 seconds.
+	DrainRetryAfter time.Duration
+	// ConcurrencyLimiter, if set, bounds the number of concurrent
+	// invocations of the business logic.
+	ConcurrencyLimiter *ConcurrencyLimiter
+	// CircuitBreaker, if set, short-circuits operations whose business logic
+	// keeps failing.
+	CircuitBreaker *CircuitBreaker
+	// BackpressureQueue, if set, bounds the number of provision and update
+	// requests running and waiting at once, rejecting the excess with a
+	// Retry-After header. See BackpressureQueue.
+	BackpressureQueue *BackpressureQueue
+	// ResponseInterceptors are run, in order, on every response before it
+	// is written. See ResponseInterceptor.
+	ResponseInterceptors []ResponseInterceptor
+	// CatalogAugmenters are run, in order, on the catalog returned by the
+	// business logic before it is written. See CatalogAugmenter.
+	CatalogAugmenters []CatalogAugmenter
+	// Credentials, if set, issues binding credentials next to the business
+	// logic and revokes them on unbind. See broker.CredentialProvider.
+	Credentials broker.CredentialProvider
+	// ValidateResponses checks the endpoints and volume mounts of the bind
+	// and get binding responses of the business logic against the spec,
+	// answering 500 instead of sending a malformed response to the
+	// platform. Binding metadata is always checked.
+	ValidateResponses bool
+	// EnforceRequires checks bind requests and responses against the
+	// requires field of the service in the catalog, which is fetched from
+	// the business logic for every bind request: services requiring
+	// syslog_drain or volume_mount can only be bound to applications, with
+	// a RequiresApp error otherwise, route services only to routes, and
+	// responses may only use the permissions the service requires.
+	EnforceRequires bool
+	// ErrorMappers translate the errors of the business logic that aren't
+	// osb.HTTPStatusCodeErrors into OSB errors. See ErrorMapper.
+	ErrorMappers []ErrorMapper
+	// ApplySchemaDefaults fills the defaults declared by the plan's
+	// parameter schemas into the parameters of provision, update and bind
+	// requests before they reach the business logic. The catalog is
+	// fetched from the business logic for every such request. Update
+	// requests get the defaults of the update schema, which should only
+	// declare defaults meant to be reapplied on every update.
+	ApplySchemaDefaults bool
+	// ExtensionAPIs are served next to the OSB API and advertised in the
+	// catalog. See ExtensionAPI. Use AddExtension to add extensions once
+	// the server is built.
+	ExtensionAPIs []ExtensionAPI
+	// StreamCatalog encodes the catalog one service at a time directly to
+	// the connection instead of marshaling the whole response in memory
+	// first. It is meant for brokers exposing thousands of plans; business
+	// logic implementing broker.CatalogStreamer is never asked for the
+	// whole catalog. Streaming is disabled while ResponseInterceptors,
+	// CatalogAugmenters or ExtensionAPIs are set, since they need the
+	// whole catalog.
+	StreamCatalog bool
+	// Jobs, if set, runs the Jobs returned by the business logic and
+	// answers last operation requests for them.
+	Jobs *jobs.Manager
+	// Fingerprints, if set, stores a hash of every provision and bind
+	// request accepted by the business logic with its response. A retry
+	// of an identical request is answered with the stored response, which
+	// for bindings holds the generated credentials, without invoking the
+	// business logic; a retry with different attributes gets a 409. The
+	// fingerprint is deleted when the instance is deprovisioned or the
+	// binding unbound. Concurrent first attempts still both reach the
+	// business logic.
+	Fingerprints storage.FingerprintStore
+	// AllowMissingDeleteParameters passes deprovision and unbind requests
+	// without the service_id or plan_id query parameters, which the spec
+	// makes mandatory, to the business logic instead of rejecting them
+	// with a 400. It is meant for brokers serving lenient platforms.
+	AllowMissingDeleteParameters bool
+	// PlanTransitions, if set, rejects update requests making plan
+	// changes it doesn't allow. See PlanTransitionPolicy.
+	PlanTransitions *PlanTransitionPolicy
+	// LifecycleHooks are called, in order, when service instances and
+	// bindings are created, updated or deleted. See LifecycleHook.
+	LifecycleHooks []LifecycleHook
+	// InstanceCaps, if set, limits the number of instances of plans and
+	// services. See InstanceCaps.
+	InstanceCaps *InstanceCaps
+	// Compatibility, if set, adjusts the semantics of requests to the API
+	// version they were sent with. See Compatibility.
+	Compatibility *Compatibility
+	// PollRetryAfter, if set, is the delay suggested in the Retry-After
+	// header of last operation responses for operations in progress whose
+	// business logic doesn't suggest one. See
+	// broker.LastOperationResponse.RetryAfter.
+	PollRetryAfter time.Duration
+	// WarningHeader, if set, is the header the warnings added by the
+	// business logic with RequestContext.AddWarning are sent in, one value
+	// per warning; DefaultWarningHeader is conventional. Warnings are
+	// logged either way.
+	WarningHeader string
+	// BindingParameters controls whether the parameters of GET binding
+	// responses are returned as is, which is the default, redacted or
+	// omitted.
+	BindingParameters ParametersVisibility
+	// MaxRequestBodyBytes, if positive, bounds the size of the bodies of
+	// provision, bind, update and extension requests. Larger bodies are
+	// rejected with 413 Request Entity Too Large.
+	MaxRequestBodyBytes int64
+	// Messages, if set, translates the descriptions of error responses
+	// into the language preferred by the Accept-Language header of the
+	// request.
+	Messages MessageCatalog
+	// PrettyJSON indents JSON responses and sorts their keys, to ease
+	// debugging with curl. It costs a decode and encode of every response
+	// and disables StreamCatalog, so it is meant for development only.
+	PrettyJSON bool
+
+	drain      drainState
+	readOnly   readOnlyState
+	lifecycle  lifecycleState
+	extensions extensionState
 }
 
-// NewAPISurface returns a new, ready-to-go APISurface.
+// NewAPISurface returns a new, ready-to-go APISurface. It returns an error
+// if brokerInterface is nil, including a nil pointer of a type implementing
+// broker.Interface, rather than panicking on the first request. A nil m is
+// replaced by a new collector, which is not registered anywhere.
 func NewAPISurface(brokerInterface broker.Interface, m *metrics.OSBMetricsCollector) (*APISurface, error) {
+	if brokerInterface == nil {
+		return nil, errors.New("rest: NewAPISurface requires a broker.Interface")
+	}
+	if isNil(brokerInterface) {
+		return nil, fmt.Errorf("rest: NewAPISurface got a nil %T as its broker.Interface", brokerInterface)
+	}
+	if m == nil {
+		m = metrics.New()
+	}
+
 	api := &APISurface{
 		Broker:  brokerInterface,
 		Metrics: m,
@@ -41,17 +179,20 @@ func NewAPISurface(brokerInterface broker.Interface, m *metrics.OSBMetricsCollec
 
 // OptionsHandler deals with the OPTIONS type request allowing the client to gather the headers.
 func (s *APISurface) OptionsHandler(w http.ResponseWriter, r *http.Request) {
-	s.writeResponse(w, http.StatusOK, nil)
+	s.writeResponse(w, r, http.StatusOK, nil)
 }
 
 // GetCatalogHandler is the mux handler that dispatches requests to get the
 // broker's catalog to the broker's Interface.
 func (s *APISurface) GetCatalogHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("get_catalog").Inc()
+	// HEAD requests are load balancer probes, not platform actions.
+	if r.Method != http.MethodHead {
+		s.Metrics.Actions.WithLabelValues(OperationGetCatalog).Inc()
+	}
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
 		return
 	}
 
@@ -59,46 +200,148 @@ func (s *APISurface) GetCatalogHandler(w http.ResponseWriter, r *http.Request) {
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
+
+	done, err := s.admit(w, r, OperationGetCatalog)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
 
-	response, err := s.Broker.GetCatalog(c)
+	if streamer, ok := s.Broker.(broker.CatalogStreamer); ok && s.streamsCatalog() {
+		stream := s.newCatalogStream(w, r)
+		err = invoke(done, func() error {
+			return streamer.StreamCatalog(c, stream.emit)
+		})
+		s.finishCatalogStream(stream, err)
+		return
+	}
+
+	var response *broker.CatalogResponse
+	err = invoke(done, func() (err error) {
+		response, err = s.Broker.GetCatalog(c)
+		return err
+	})
+	if err == nil {
+		err = s.completeCatalog(c, response)
+	}
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, response)
+	if s.streamsCatalog() && len(response.ExtensionAPIs) == 0 {
+		stream := s.newCatalogStream(w, r)
+		s.finishCatalogStream(stream, stream.fromResponse(response))
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
 // ProvisionHandler is the mux handler that dispatches ProvisionRequests to the
 // broker's Interface.
 func (s *APISurface) ProvisionHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("provision").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationProvision).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+	r = s.adaptRequest(r, OperationProvision)
+
+	if s.IsReadOnly() {
+		s.writeError(w, r, newReadOnlyError(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.rejectIfDraining(w, r) {
 		return
 	}
 
+	s.limitRequestBody(w, r)
 	request, err := unpackProvisionRequest(r)
 	if err != nil {
-		s.writeError(w, err, http.StatusBadRequest)
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	broker.NewRequestLogger(r).V(4).Infof("Received ProvisionRequest")
+
+	if isValidateOnly(r) {
+		c := &broker.RequestContext{
+			Writer:  w,
+			Request: r,
+		}
+		r = withRequestContext(r, c)
+		response, err := s.validateProvision(request, c)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		s.writeResponse(w, r, http.StatusOK, response)
 		return
 	}
 
-	glog.V(4).Infof("Received ProvisionRequest for instanceID %q", request.InstanceID)
+	var hash string
+	if s.Fingerprints != nil {
+		if hash, err = hashProvisionRequest(request); err != nil {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if s.replayFingerprint(w, r, InstanceOperationKey(request.InstanceID), hash) {
+			return
+		}
+	}
 
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
+
+	if err := s.checkProvisionCap(request); err != nil {
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
 
-	response, err := s.Broker.Provision(request, c)
+	done, err := s.admit(w, r, OperationProvision)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
 		return
 	}
 
+	var response *broker.ProvisionResponse
+	err = invoke(done, func() (err error) {
+		request.Parameters, err = s.applySchemaDefaults(c, request.ServiceID, request.PlanID, instanceCreateSchema, request.Parameters)
+		if err != nil {
+			return err
+		}
+		response, err = s.Broker.Provision(request, c)
+		return err
+	})
+	if err != nil {
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if response.Job != nil {
+		response.OperationKey, err = s.submitJob(w, r, request.AcceptsIncomplete, OperationProvision, request.InstanceID, "", response.Job)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		response.Async = true
+	}
+
+	if response.Async && !request.AcceptsIncomplete {
+		s.writeError(w, r, newAsyncRequiredError(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.recordInstance(request.InstanceID, request.ServiceID, request.PlanID)
+
 	// MUST be returned if the Service Instance was provisioned
 	// as a result of this request and Not async
 	status := http.StatusCreated
@@ -108,14 +351,30 @@ func (s *APISurface) ProvisionHandler(w http.ResponseWriter, r *http.Request) {
 		status = http.StatusAccepted
 	}
 
+	// Operations run by the job manager are tracked by it.
+	if response.Async && response.Job == nil {
+		s.trackOperation(InstanceOperationKey(request.InstanceID))
+	}
+
 	if response.Exists {
 		// MUST be returned if the Service Instance already exists,
 		// is fully provisioned, and the requested parameters
 		// are identical to the existing Service Instance
 		status = http.StatusOK
+	} else {
+		s.emitLifecycleEvent(InstanceOperationKey(request.InstanceID), response.Async, LifecycleEvent{
+			Type:       InstanceProvisioned,
+			InstanceID: request.InstanceID,
+			ServiceID:  request.ServiceID,
+			PlanID:     request.PlanID,
+		})
+	}
+
+	if s.Fingerprints != nil && status != http.StatusOK {
+		s.storeFingerprint(InstanceOperationKey(request.InstanceID), hash, status, response)
 	}
 
-	s.writeResponse(w, status, response)
+	s.writeResponse(w, r, status, response)
 }
 
 // unpackProvisionRequest unpacks an osb request from the given HTTP request.
@@ -133,10 +392,10 @@ func unpackProvisionRequest(r *http.Request) (*osb.ProvisionRequest, error) {
 	vars := mux.Vars(r)
 	osbRequest.InstanceID = vars[osb.VarKeyInstanceID]
 
+	// accepts_incomplete is a query parameter; never trust a value smuggled
+	// in through the request body.
 	asyncQueryParamVal := r.URL.Query().Get(osb.AcceptsIncomplete)
-	if strings.ToLower(asyncQueryParamVal) == "true" {
-		osbRequest.AcceptsIncomplete = true
-	}
+	osbRequest.AcceptsIncomplete = strings.ToLower(asyncQueryParamVal) == "true"
 	identity, err := retrieveOriginatingIdentity(r)
 	// This could be not found because platforms may support the feature
 	// but are not guaranteed to.
@@ -152,39 +411,104 @@ func unpackProvisionRequest(r *http.Request) (*osb.ProvisionRequest, error) {
 // DeprovisionHandler is the mux handler that dispatches deprovision requests to
 // the broker's Interface.
 func (s *APISurface) DeprovisionHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("deprovision").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationDeprovision).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+	r = s.adaptRequest(r, OperationDeprovision)
+
+	if s.IsReadOnly() {
+		s.writeError(w, r, newReadOnlyError(), http.StatusServiceUnavailable)
 		return
 	}
 
 	request, err := unpackDeprovisionRequest(r)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.validateDeleteParameters(request.ServiceID, request.PlanID); err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	glog.V(4).Infof("Received DeprovisionRequest for instanceID %q", request.InstanceID)
+	broker.NewRequestLogger(r).V(4).Infof("Received DeprovisionRequest")
+
+	purger, err := s.purger(r)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusNotImplemented)
+		return
+	}
 
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
 
-	response, err := s.Broker.Deprovision(request, c)
+	done, err := s.admit(w, r, OperationDeprovision)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
 		return
 	}
 
+	var response *broker.DeprovisionResponse
+	err = invoke(done, func() (err error) {
+		if purger != nil {
+			response, err = purger.Purge(request, c)
+		} else {
+			response, err = s.Broker.Deprovision(request, c)
+		}
+		return err
+	})
+	if err != nil {
+		if isGone(err) {
+			s.forgetInstance(request.InstanceID)
+		}
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if response.Job != nil {
+		response.OperationKey, err = s.submitJob(w, r, request.AcceptsIncomplete, OperationDeprovision, request.InstanceID, "", response.Job)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		response.Async = true
+	}
+
+	if response.Async && !request.AcceptsIncomplete {
+		s.writeError(w, r, newAsyncRequiredError(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.forgetInstance(request.InstanceID)
+
 	status := http.StatusOK
 	if response.Async {
 		status = http.StatusAccepted
+		if response.Job == nil {
+			s.trackOperation(InstanceOperationKey(request.InstanceID))
+		}
 	}
 
-	s.writeResponse(w, status, response)
+	s.emitLifecycleEvent(InstanceOperationKey(request.InstanceID), response.Async, LifecycleEvent{
+		Type:       InstanceDeprovisioned,
+		InstanceID: request.InstanceID,
+		ServiceID:  request.ServiceID,
+		PlanID:     request.PlanID,
+	})
+
+	if s.Fingerprints != nil {
+		s.forgetFingerprint(InstanceOperationKey(request.InstanceID))
+	}
+
+	s.writeResponse(w, r, status, response)
 }
 
 // unpackDeprovisionRequest unpacks an osb request from the given HTTP request.
@@ -214,11 +538,11 @@ func unpackDeprovisionRequest(r *http.Request) (*osb.DeprovisionRequest, error)
 // LastOperationHandler is the mux handler that dispatches last-operation
 // requests to the broker's Interface.
 func (s *APISurface) LastOperationHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("last_operation").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationLastOperation).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
 		return
 	}
 
@@ -226,26 +550,50 @@ func (s *APISurface) LastOperationHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		// TODO: This should return a 400 in this case as it is either
 		// malformed or missing mandatory data, as per the OSB spec.
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	glog.V(4).Infof("Received LastOperationRequest for instanceID %q", request.InstanceID)
+	broker.NewRequestLogger(r).V(4).Infof("Received LastOperationRequest")
 
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
+
+	done, err := s.admit(w, r, OperationLastOperation)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
 
-	response, err := s.Broker.LastOperation(request, c)
+	var response *broker.LastOperationResponse
+	err = invoke(done, func() (err error) {
+		response, err = s.jobLastOperation(request.OperationKey, request.InstanceID, "")
+		if err == storage.ErrNotFound {
+			response, err = s.Broker.LastOperation(request, c)
+		}
+		return err
+	})
 	if err != nil {
+		if osb.IsGoneError(err) {
+			s.untrackOperation(InstanceOperationKey(request.InstanceID))
+			s.completeLifecycleEvent(InstanceOperationKey(request.InstanceID), "", true)
+		}
 		// TODO: This should return a 400 in this case as it is either
 		// malformed or missing mandatory data, as per the OSB spec.
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, response)
+	if isTerminalState(response.State) {
+		s.untrackOperation(InstanceOperationKey(request.InstanceID))
+	}
+	s.completeLifecycleEvent(InstanceOperationKey(request.InstanceID), response.State, false)
+	s.setPollRetryAfter(w, response)
+
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
 // unpackLastOperationRequest unpacks an osb request from the given HTTP request.
@@ -254,15 +602,19 @@ func unpackLastOperationRequest(r *http.Request) (*osb.LastOperationRequest, err
 
 	vars := mux.Vars(r)
 	osbRequest.InstanceID = vars[osb.VarKeyInstanceID]
-	serviceID := vars[osb.VarKeyServiceID]
+
+	// The spec passes service_id, plan_id and operation as query parameters;
+	// only the instance ID is part of the route.
+	q := r.URL.Query()
+	serviceID := q.Get(osb.VarKeyServiceID)
 	if serviceID != "" {
 		osbRequest.ServiceID = &serviceID
 	}
-	planID := vars[osb.VarKeyPlanID]
+	planID := q.Get(osb.VarKeyPlanID)
 	if planID != "" {
 		osbRequest.PlanID = &planID
 	}
-	operation := vars[osb.VarKeyOperation]
+	operation := q.Get(osb.VarKeyOperation)
 	if operation != "" {
 		typedOperation := osb.OperationKey(operation)
 		osbRequest.OperationKey = &typedOperation
@@ -273,30 +625,95 @@ func unpackLastOperationRequest(r *http.Request) (*osb.LastOperationRequest, err
 // BindHandler is the mux handler that dispatches bind requests to the broker's
 // Interface.
 func (s *APISurface) BindHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("bind").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationBind).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+	r = s.adaptRequest(r, OperationBind)
+
+	if s.IsReadOnly() {
+		s.writeError(w, r, newReadOnlyError(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.rejectIfDraining(w, r) {
 		return
 	}
 
-	request, err := unpackBindRequest(r)
+	s.limitRequestBody(w, r)
+	request, bindResource, err := unpackBindRequest(r)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	glog.V(4).Infof("Received BindRequest for instanceID %q, bindingID %q", request.InstanceID, request.BindingID)
+	broker.NewRequestLogger(r).V(4).Infof("Received BindRequest")
+
+	var hash string
+	if s.Fingerprints != nil {
+		if hash, err = hashBindRequest(request); err != nil {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if s.replayFingerprint(w, r, BindingOperationKey(request.InstanceID, request.BindingID), hash) {
+			return
+		}
+	}
 
 	c := &broker.RequestContext{
-		Writer:  w,
-		Request: r,
+		Writer:       w,
+		Request:      r,
+		BindResource: bindResource,
 	}
+	r = withRequestContext(r, c)
 
-	response, err := s.Broker.Bind(request, c)
+	done, err := s.admit(w, r, OperationBind)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	var response *broker.BindResponse
+	err = invoke(done, func() (err error) {
+		service, err := s.boundService(request, c)
+		if err != nil {
+			return err
+		}
+		request.Parameters, err = s.applySchemaDefaults(c, request.ServiceID, request.PlanID, bindingCreateSchema, request.Parameters)
+		if err != nil {
+			return err
+		}
+		response, err = s.Broker.Bind(request, c)
+		if err == nil {
+			err = checkPermissions(service, response)
+		}
+		if err == nil {
+			err = s.validateBinding(response.Metadata, response.Endpoints, response.VolumeMounts)
+		}
+		if err == nil {
+			err = s.issueCredentials(request, c, response)
+		}
+		return err
+	})
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if response.Job != nil {
+		response.OperationKey, err = s.submitJob(w, r, request.AcceptsIncomplete, OperationBind, request.InstanceID, request.BindingID, response.Job)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		response.Async = true
+	}
+
+	if response.Async && !request.AcceptsIncomplete {
+		s.writeError(w, r, newAsyncRequiredError(), http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -313,21 +730,64 @@ func (s *APISurface) BindHandler(w http.ResponseWriter, r *http.Request) {
 		// implementation phase" of the OSB spec. See:
 		// https://github.com/openservicebrokerapi/servicebroker/pull/334
 		status = http.StatusAccepted
+		if response.Job == nil {
+			s.trackOperation(BindingOperationKey(request.InstanceID, request.BindingID))
+		}
 	}
 
-	s.writeResponse(w, status, response)
+	if !response.Exists {
+		s.emitLifecycleEvent(BindingOperationKey(request.InstanceID, request.BindingID), response.Async, LifecycleEvent{
+			Type:       BindingCreated,
+			InstanceID: request.InstanceID,
+			BindingID:  request.BindingID,
+			ServiceID:  request.ServiceID,
+			PlanID:     request.PlanID,
+		})
+	}
+
+	if s.Fingerprints != nil && status != http.StatusOK {
+		s.storeFingerprint(BindingOperationKey(request.InstanceID, request.BindingID), hash, status, response)
+	}
+
+	s.writeResponse(w, r, status, response)
+}
+
+// bindBody is the body of a bind request. The client's BindResource
+// decodes app_guid as appGuid, so bind_resource is kept raw, shadowing the
+// client's field, and decoded both ways.
+type bindBody struct {
+	*osb.BindRequest
+	BindResource json.RawMessage `json:"bind_resource"`
 }
 
 // unpackBindRequest unpacks an osb request from the given HTTP request.
-func unpackBindRequest(r *http.Request) (*osb.BindRequest, error) {
+func unpackBindRequest(r *http.Request) (*osb.BindRequest, *broker.BindResource, error) {
 	osbRequest := &osb.BindRequest{}
-	if err := unmarshalRequestBody(r, osbRequest); err != nil {
-		return nil, err
+	body := &bindBody{BindRequest: osbRequest}
+	if err := unmarshalRequestBody(r, body); err != nil {
+		return nil, nil, err
+	}
+
+	var bindResource *broker.BindResource
+	if len(body.BindResource) > 0 {
+		if err := json.Unmarshal(body.BindResource, &osbRequest.BindResource); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(body.BindResource, &bindResource); err != nil {
+			return nil, nil, err
+		}
+	}
+	if bindResource != nil && bindResource.AppGUID != "" && osbRequest.BindResource.AppGUID == nil {
+		osbRequest.BindResource.AppGUID = &bindResource.AppGUID
 	}
 
 	vars := mux.Vars(r)
 	osbRequest.InstanceID = vars[osb.VarKeyInstanceID]
 	osbRequest.BindingID = vars[osb.VarKeyBindingID]
+
+	asyncQueryParamVal := r.URL.Query().Get(osb.AcceptsIncomplete)
+	osbRequest.AcceptsIncomplete = strings.ToLower(asyncQueryParamVal) == "true"
+
 	identity, err := retrieveOriginatingIdentity(r)
 	// This could be not found because platforms may support the feature
 	// but are not guaranteed to.
@@ -337,41 +797,62 @@ func unpackBindRequest(r *http.Request) (*osb.BindRequest, error) {
 
 	osbRequest.OriginatingIdentity = identity
 
-	return osbRequest, nil
+	return osbRequest, bindResource, nil
 }
 
 // GetBindingHandler is the mux handler that dispatches get binding requests to
 // the broker's Interface.
 func (s *APISurface) GetBindingHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("get_binding").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationGetBinding).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
 		return
 	}
 
 	vars := mux.Vars(r)
 	request, err := unpackGetBindingRequest(r, vars)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	glog.Infof("Received GetBinding request for instanceID %q, bindingID %q", request.InstanceID, request.BindingID)
+	broker.NewRequestLogger(r).Infof("Received GetBinding request")
 
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
 
-	response, err := s.Broker.GetBinding(request, c)
+	done, err := s.admit(w, r, OperationGetBinding)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	var response *broker.GetBindingResponse
+	err = invoke(done, func() (err error) {
+		response, err = s.Broker.GetBinding(request, c)
+		if err == nil {
+			err = s.validateBinding(response.Metadata, response.Endpoints, response.VolumeMounts)
+		}
+		return err
+	})
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, response)
+	if s.BindingParameters != ParametersReturned && response.Parameters != nil {
+		// The business logic may keep the response it returned.
+		filtered := *response
+		filtered.Parameters = s.BindingParameters.apply(response.Parameters)
+		response = &filtered
+	}
+
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
 // unpackGetBindingRequest unpacks an osb get binding request from the given
@@ -381,42 +862,87 @@ func unpackGetBindingRequest(r *http.Request, vars map[string]string) (*osb.GetB
 
 	request.InstanceID = vars[osb.VarKeyInstanceID]
 	request.BindingID = vars[osb.VarKeyBindingID]
+	if err := validateBindingIDs(request.InstanceID, request.BindingID); err != nil {
+		return nil, err
+	}
 
 	return request, nil
 }
 
+// validateBindingIDs returns a 400 error if either the instance or the
+// binding ID taken from the route is empty.
+func validateBindingIDs(instanceID, bindingID string) error {
+	var missing string
+	switch {
+	case instanceID == "":
+		missing = osb.VarKeyInstanceID
+	case bindingID == "":
+		missing = osb.VarKeyBindingID
+	default:
+		return nil
+	}
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusBadRequest,
+		Description: strPtr(fmt.Sprintf("%s is required", missing)),
+	}
+}
+
 // GetBindingLastOperation is the mux handler that dispatches binding last
 // operation requests to the broker's Interface.
 func (s *APISurface) BindingLastOperationHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("binding_last_operation").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationBindingLastOperation).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
 		return
 	}
 
 	vars := mux.Vars(r)
 	request, err := unpackBindingLastOperationRequest(r, vars)
 	if err != nil {
-		s.writeError(w, err, http.StatusBadRequest)
+		s.writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	glog.Infof("Received BindingLastOperationRequest for instanceID %q, bindingID %q", request.InstanceID, request.BindingID)
+	broker.NewRequestLogger(r).Infof("Received BindingLastOperationRequest")
 
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
+
+	done, err := s.admit(w, r, OperationBindingLastOperation)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
 
-	response, err := s.Broker.BindingLastOperation(request, c)
+	var response *broker.LastOperationResponse
+	err = invoke(done, func() (err error) {
+		response, err = s.jobLastOperation(request.OperationKey, request.InstanceID, request.BindingID)
+		if err == storage.ErrNotFound {
+			response, err = s.Broker.BindingLastOperation(request, c)
+		}
+		return err
+	})
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		if osb.IsGoneError(err) {
+			s.untrackOperation(BindingOperationKey(request.InstanceID, request.BindingID))
+			s.completeLifecycleEvent(BindingOperationKey(request.InstanceID, request.BindingID), "", true)
+		}
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, response)
+	if isTerminalState(response.State) {
+		s.untrackOperation(BindingOperationKey(request.InstanceID, request.BindingID))
+	}
+	s.completeLifecycleEvent(BindingOperationKey(request.InstanceID, request.BindingID), response.State, false)
+	s.setPollRetryAfter(w, response)
+
+	s.writeResponse(w, r, http.StatusOK, response)
 }
 
 // unpackBindingLastOperationRequest unpacks an osb binding last operation
@@ -427,26 +953,32 @@ func unpackBindingLastOperationRequest(
 	request := &osb.BindingLastOperationRequest{}
 	request.InstanceID = vars[osb.VarKeyInstanceID]
 	request.BindingID = vars[osb.VarKeyBindingID]
+	if err := validateBindingIDs(request.InstanceID, request.BindingID); err != nil {
+		return nil, err
+	}
 
-	serviceID := vars[osb.VarKeyServiceID]
+	q := r.URL.Query()
+	serviceID := q.Get(osb.VarKeyServiceID)
 	if serviceID != "" {
 		request.ServiceID = &serviceID
 	}
 
-	planID := vars[osb.VarKeyPlanID]
+	planID := q.Get(osb.VarKeyPlanID)
 	if planID != "" {
 		request.PlanID = &planID
 	}
 
-	operation := vars[osb.VarKeyOperation]
+	operation := q.Get(osb.VarKeyOperation)
 	if operation != "" {
 		typedOperation := osb.OperationKey(operation)
 		request.OperationKey = &typedOperation
 	}
 
 	identity, err := retrieveOriginatingIdentity(r)
+	// This could be not found because platforms may support the feature
+	// but are not guaranteed to.
 	if err != nil {
-		return nil, err
+		glog.Infof("Unable to retrieve originating identity - %v", err)
 	}
 	request.OriginatingIdentity = identity
 
@@ -456,34 +988,89 @@ func unpackBindingLastOperationRequest(
 // UnbindHandler is the mux handler that dispatches unbind requests to the
 // broker's Interface.
 func (s *APISurface) UnbindHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("unbind").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationUnbind).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+	r = s.adaptRequest(r, OperationUnbind)
+
+	if s.IsReadOnly() {
+		s.writeError(w, r, newReadOnlyError(), http.StatusServiceUnavailable)
 		return
 	}
 
 	v := mux.Vars(r)
 	request, err := unpackUnbindRequest(r, v)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	glog.V(4).Infof("Received UnbindRequest for instanceID %q, bindingID %q", request.InstanceID, request.BindingID)
+	if err := s.validateDeleteParameters(request.ServiceID, request.PlanID); err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	broker.NewRequestLogger(r).V(4).Infof("Received UnbindRequest")
 	c := &broker.RequestContext{
 		Writer:  w,
 		Request: r,
 	}
+	r = withRequestContext(r, c)
 
-	response, err := s.Broker.Unbind(request, c)
+	done, err := s.admit(w, r, OperationUnbind)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
+	}
+
+	var response *broker.UnbindResponse
+	err = invoke(done, func() (err error) {
+		response, err = s.Broker.Unbind(request, c)
+		if err == nil && response.Async && !request.AcceptsIncomplete {
+			return nil
+		}
+		// Credentials are revoked once the binding is gone, which also
+		// covers retries after a revocation failed.
+		if err == nil || isGone(err) {
+			if revokeErr := s.revokeCredentials(request, c); revokeErr != nil {
+				return revokeErr
+			}
+		}
+		return err
+	})
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, response)
+	if response.Async && !request.AcceptsIncomplete {
+		s.writeError(w, r, newAsyncRequiredError(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	status := http.StatusOK
+	if response.Async {
+		status = http.StatusAccepted
+		s.trackOperation(BindingOperationKey(request.InstanceID, request.BindingID))
+	}
+
+	s.emitLifecycleEvent(BindingOperationKey(request.InstanceID, request.BindingID), response.Async, LifecycleEvent{
+		Type:       BindingDeleted,
+		InstanceID: request.InstanceID,
+		BindingID:  request.BindingID,
+		ServiceID:  request.ServiceID,
+		PlanID:     request.PlanID,
+	})
+
+	if s.Fingerprints != nil {
+		s.forgetFingerprint(BindingOperationKey(request.InstanceID, request.BindingID))
+	}
+
+	s.writeResponse(w, r, status, response)
 }
 
 // unpackUnbindRequest unpacks an osb request from the given HTTP request.
@@ -498,6 +1085,10 @@ func unpackUnbindRequest(r *http.Request, vars map[string]string) (*osb.UnbindRe
 	osbRequest.PlanID = r.FormValue(osb.VarKeyPlanID)
 	osbRequest.ServiceID = r.FormValue(osb.VarKeyServiceID)
 
+	// accepts_incomplete is a query parameter as well.
+	asyncQueryParamVal := r.URL.Query().Get(osb.AcceptsIncomplete)
+	osbRequest.AcceptsIncomplete = strings.ToLower(asyncQueryParamVal) == "true"
+
 	identity, err := retrieveOriginatingIdentity(r)
 	// This could be not found because platforms may support the feature
 	// but are not guaranteed to.
@@ -512,54 +1103,160 @@ func unpackUnbindRequest(r *http.Request, vars map[string]string) (*osb.UnbindRe
 // UpdateHandler is the mux handler that dispatches Update requests to the
 // broker's Interface.
 func (s *APISurface) UpdateHandler(w http.ResponseWriter, r *http.Request) {
-	s.Metrics.Actions.WithLabelValues("update").Inc()
+	s.Metrics.Actions.WithLabelValues(OperationUpdate).Inc()
 
 	version := getBrokerAPIVersionFromRequest(r)
 	if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
-		s.writeError(w, err, http.StatusPreconditionFailed)
+		s.writeError(w, r, err, http.StatusPreconditionFailed)
+		return
+	}
+	r = s.adaptRequest(r, OperationUpdate)
+
+	if s.IsReadOnly() {
+		s.writeError(w, r, newReadOnlyError(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.rejectIfDraining(w, r) {
 		return
 	}
 
 	v := mux.Vars(r)
-	request, err := unpackUpdateRequest(r, v)
+	s.limitRequestBody(w, r)
+	request, details, err := unpackUpdateRequest(r, v)
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	glog.V(4).Infof("Received Update Request for instanceID %q", request.InstanceID)
+	broker.NewRequestLogger(r).V(4).Infof("Received Update Request")
+
+	if !s.behavior(r).MaintenanceInfo {
+		details.MaintenanceInfo = nil
+		if details.PreviousValues != nil {
+			details.PreviousValues.MaintenanceInfo = nil
+		}
+	}
 
 	c := &broker.RequestContext{
-		Writer:  w,
-		Request: r,
+		Writer:          w,
+		Request:         r,
+		PreviousValues:  details.PreviousValues,
+		MaintenanceInfo: details.MaintenanceInfo,
+	}
+	r = withRequestContext(r, c)
+
+	if err := s.checkPlanTransition(request, c); err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if request.PlanID != nil {
+		if err := s.checkUpdateCap(request.InstanceID, request.ServiceID, *request.PlanID); err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	done, err := s.admit(w, r, OperationUpdate)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusServiceUnavailable)
+		return
 	}
 
-	response, err := s.Broker.Update(request, c)
+	var response *broker.UpdateInstanceResponse
+	err = invoke(done, func() (err error) {
+		request.Parameters, err = s.applySchemaDefaults(c, request.ServiceID, updatePlanID(request), instanceUpdateSchema, request.Parameters)
+		if err != nil {
+			return err
+		}
+		response, err = s.Broker.Update(request, c)
+		return err
+	})
 	if err != nil {
-		s.writeError(w, err, http.StatusInternalServerError)
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if response.Job != nil {
+		response.OperationKey, err = s.submitJob(w, r, request.AcceptsIncomplete, OperationUpdate, request.InstanceID, "", response.Job)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		response.Async = true
+	}
+
+	if response.Async && !request.AcceptsIncomplete {
+		s.writeError(w, r, newAsyncRequiredError(), http.StatusUnprocessableEntity)
 		return
 	}
 
 	status := http.StatusOK
 	if response.Async {
 		status = http.StatusAccepted
+		if response.Job == nil {
+			s.trackOperation(InstanceOperationKey(request.InstanceID))
+		}
 	}
 
-	s.writeResponse(w, status, response)
+	diff := broker.DiffUpdate(request, c)
+	if diff.PlanChanged {
+		s.recordInstance(request.InstanceID, request.ServiceID, diff.PlanID)
+	}
+	s.emitLifecycleEvent(InstanceOperationKey(request.InstanceID), response.Async, LifecycleEvent{
+		Type:           InstanceUpdated,
+		InstanceID:     request.InstanceID,
+		ServiceID:      request.ServiceID,
+		PlanID:         diff.PlanID,
+		PreviousPlanID: diff.PreviousPlanID,
+	})
+
+	s.writeResponse(w, r, status, response)
+}
+
+// updateDetails are the fields of an update request the client's types
+// don't decode.
+type updateDetails struct {
+	PreviousValues  *broker.PreviousValues  `json:"previous_values"`
+	MaintenanceInfo *broker.MaintenanceInfo `json:"maintenance_info"`
+}
+
+// updateBody is the body of an update request. previous_values is kept raw,
+// shadowing the client's field, and decoded into both the client's
+// PreviousValues and updateDetails.
+type updateBody struct {
+	*osb.UpdateInstanceRequest
+	PreviousValues  json.RawMessage         `json:"previous_values"`
+	MaintenanceInfo *broker.MaintenanceInfo `json:"maintenance_info"`
 }
 
-func unpackUpdateRequest(r *http.Request, vars map[string]string) (*osb.UpdateInstanceRequest, error) {
+// unpackUpdateRequest unpacks an osb update request from the given HTTP
+// request. service_id, plan_id, context, parameters, previous_values and
+// maintenance_info come from the PATCH body; the instance ID comes from the
+// route and accepts_incomplete from the query string.
+func unpackUpdateRequest(r *http.Request, vars map[string]string) (*osb.UpdateInstanceRequest, *updateDetails, error) {
 	osbRequest := &osb.UpdateInstanceRequest{}
-	if err := unmarshalRequestBody(r, osbRequest); err != nil {
-		return nil, err
+	body := &updateBody{UpdateInstanceRequest: osbRequest}
+	if err := unmarshalRequestBody(r, body); err != nil {
+		return nil, nil, err
+	}
+	details := &updateDetails{MaintenanceInfo: body.MaintenanceInfo}
+	if len(body.PreviousValues) > 0 {
+		if err := json.Unmarshal(body.PreviousValues, &osbRequest.PreviousValues); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(body.PreviousValues, &details.PreviousValues); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	osbRequest.InstanceID = vars[osb.VarKeyInstanceID]
 
-	asyncQueryParamVal := r.FormValue(osb.AcceptsIncomplete)
-	if strings.ToLower(asyncQueryParamVal) == "true" {
-		osbRequest.AcceptsIncomplete = true
-	}
+	// accepts_incomplete is a query parameter; never trust a value smuggled
+	// in through the request body.
+	asyncQueryParamVal := r.URL.Query().Get(osb.AcceptsIncomplete)
+	osbRequest.AcceptsIncomplete = strings.ToLower(asyncQueryParamVal) == "true"
 	identity, err := retrieveOriginatingIdentity(r)
 	// This could be not found because platforms may support the feature
 	// but are not guaranteed to.
@@ -568,43 +1265,69 @@ func unpackUpdateRequest(r *http.Request, vars map[string]string) (*osb.UpdateIn
 	}
 	osbRequest.OriginatingIdentity = identity
 
-	return osbRequest, nil
+	return osbRequest, details, nil
+}
+
+// limitRequestBody caps the body of r at MaxRequestBodyBytes, if set.
+func (s *APISurface) limitRequestBody(w http.ResponseWriter, r *http.Request) {
+	if s.MaxRequestBodyBytes > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxRequestBodyBytes)
+	}
 }
 
 // retrieveOriginatingIdentity retrieves the originating identity from
-// the request header.
+// the request header, parsed by broker.ParseOriginatingIdentity if the
+// server installed it.
 func retrieveOriginatingIdentity(r *http.Request) (*osb.OriginatingIdentity, error) {
-	identityHeader := r.Header.Get(osb.OriginatingIdentityHeader)
-
-	if identityHeader != "" {
-		identitySlice := strings.Split(identityHeader, " ")
-		if len(identitySlice) != 2 {
-			glog.Infof("invalid header for originating origin - %v", identityHeader)
-			return nil, fmt.Errorf("invalid originating identity header")
-		}
-		// Base64 decode the value string so the value is passed as valid JSON.
-		val, err := base64.StdEncoding.DecodeString(identitySlice[1])
-		if err != nil {
-			glog.Infof("invalid header for originating origin - %v", identityHeader)
-			return nil, fmt.Errorf("invalid encoding for value of originating identity header")
-		}
-		return &osb.OriginatingIdentity{
-			Platform: identitySlice[0],
-			Value:    string(val),
-		}, nil
+	identity, err := broker.OriginatingIdentity(r)
+	if err != nil {
+		glog.Infof("invalid header for originating origin - %v", r.Header.Get(osb.OriginatingIdentityHeader))
+		return nil, err
+	}
+	if identity == nil {
+		return nil, fmt.Errorf("unable to find originating identity")
 	}
-	return nil, fmt.Errorf("unable to find originating identity")
+	return identity, nil
 }
 
 // writeResponse will serialize 'object' to the HTTP ResponseWriter
 // using the 'code' as the HTTP status code
-func (s *APISurface) writeResponse(w http.ResponseWriter, code int, object interface{}) {
+func (s *APISurface) writeResponse(w http.ResponseWriter, r *http.Request, code int, object interface{}) {
+	if s.abandoned(r) {
+		return
+	}
+
 	data, err := json.Marshal(object)
 	if err != nil {
+		broker.NewRequestLogger(r).Errorf("Error marshaling response: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	s.setResponseHeaders(w, r)
+
+	code, data = s.interceptResponse(r, code, w.Header(), data)
+	if s.PrettyJSON {
+		data = prettyJSON(data)
+	}
+
+	// Responses are small and fully rendered, so send them with a
+	// Content-Length rather than chunked.
+	if !bodyAllowed(code) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		s.WriteFailed(r, err)
+	}
+}
+
+// setResponseHeaders sets the headers common to every response, followed by
+// the headers set by the business logic through the RequestContext.
+func (s *APISurface) setResponseHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if s.EnableCORS {
@@ -614,38 +1337,69 @@ func (s *APISurface) writeResponse(w http.ResponseWriter, code int, object inter
 		w.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, X-Broker-API-Version, X-Broker-API-Originating-Identity, Content-Type, Authorization, Accept")
 	}
 
-	w.WriteHeader(code)
-	w.Write(data)
+	if c := requestContextFrom(r); c != nil {
+		for k, v := range c.ResponseHeader() {
+			w.Header()[k] = v
+		}
+		s.writeWarnings(w, r, c.Warnings())
+	}
 }
 
 // writeError accepts any error and writes it to the given ResponseWriter along
 // with a status code.
 //
-// If the error is an osb.HTTPStatusCodeError, the error's StatusCode field will
-// be used and the response body will contain the error's Description and
-// ErrorMessage fields (if set).
+// If the error is, or wraps, an osb.HTTPStatusCodeError, the error's StatusCode
+// field will be used and the response body will contain the error's
+// Description and ErrorMessage fields (if set). Errors wrapping one of the
+// broker package's sentinel errors, such as broker.ErrInstanceNotFound, are
+// written with the status code the spec requires for the request.
 //
-// Otherwise, the given defaultStatusCode will be used, and the response body
-// will have the result of calling the error's Error method set in the
-// 'description' field.
+// Otherwise, the first OSB error the APISurface's ErrorMappers translate the
+// error into is written the same way. Failing that, the given
+// defaultStatusCode will be used, and the response body will have the result
+// of calling the error's Error method set in the 'description' field.
 //
 // For more information about OSB errors, see:
 //
 // https://github.com/openservicebrokerapi/servicebroker/blob/master/spec.md#service-broker-errors
-func (s *APISurface) writeError(w http.ResponseWriter, err error, defaultStatusCode int) {
-	if httpErr, ok := osb.IsHTTPError(err); ok {
-		s.writeOSBStatusCodeErrorResponse(w, httpErr)
+func (s *APISurface) writeError(w http.ResponseWriter, r *http.Request, err error, defaultStatusCode int) {
+	if s.abandoned(r) {
 		return
 	}
 
-	s.writeErrorResponse(w, defaultStatusCode, err)
+	httpErr, ok := broker.AsHTTPError(err)
+	if !ok {
+		httpErr = sentinelError(r, err)
+	}
+	if httpErr == nil {
+		httpErr = s.mapError(err)
+	}
+
+	if httpErr == nil {
+		s.countError(r, defaultStatusCode, nil)
+		s.writeErrorResponse(w, r, defaultStatusCode, err)
+		return
+	}
+
+	if status, ok := s.missingBindingStatus(r, httpErr); ok {
+		if status < http.StatusBadRequest {
+			s.writeResponse(w, r, status, &struct{}{})
+			return
+		}
+		adjusted := *httpErr
+		adjusted.StatusCode = status
+		httpErr = &adjusted
+	}
+
+	s.countError(r, httpErr.StatusCode, httpErr.ErrorMessage)
+	s.writeOSBStatusCodeErrorResponse(w, r, httpErr)
 }
 
 // writeOSBStatusCodeErrorResponse writes the given HTTPStatusCodeError to the
 // given ResponseWriter. The HTTP response's status code is the error's
 // StatusCode field and the body contains the ErrorMessage and Description
 // fields, if set.
-func (s *APISurface) writeOSBStatusCodeErrorResponse(w http.ResponseWriter, err *osb.HTTPStatusCodeError) {
+func (s *APISurface) writeOSBStatusCodeErrorResponse(w http.ResponseWriter, r *http.Request, err *osb.HTTPStatusCodeError) {
 	type e struct {
 		ErrorMessage *string `json:"error,omitempty"`
 		Description  *string `json:"description,omitempty"`
@@ -653,24 +1407,24 @@ func (s *APISurface) writeOSBStatusCodeErrorResponse(w http.ResponseWriter, err
 
 	body := &e{}
 	if err.Description != nil {
-		body.Description = err.Description
+		body.Description = strPtr(s.localize(w, r, *err.Description))
 	}
 
 	if err.ErrorMessage != nil {
 		body.ErrorMessage = err.ErrorMessage
 	}
 
-	s.writeResponse(w, err.StatusCode, body)
+	s.writeResponse(w, r, err.StatusCode, body)
 }
 
 // writeErrorResponse writes the given status code and error to the given
 // ResponseWriter. The response body will be a json object with the field
 // 'description' set from calling Error() on the passed-in error.
-func (s *APISurface) writeErrorResponse(w http.ResponseWriter, code int, err error) {
+func (s *APISurface) writeErrorResponse(w http.ResponseWriter, r *http.Request, code int, err error) {
 	type e struct {
 		Description string `json:"description"`
 	}
-	s.writeResponse(w, code, &e{
-		Description: err.Error(),
+	s.writeResponse(w, r, code, &e{
+		Description: s.localize(w, r, err.Error()),
 	})
 }
@@ -6,8 +6,37 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
 )
 
+// embeddedBroker implements broker.Interface through its nil embedded
+// field; only pointers to it are ever used.
+type embeddedBroker struct {
+	broker.Interface
+}
+
+func TestNewAPISurface(t *testing.T) {
+	if _, err := NewAPISurface(nil, metrics.New()); err == nil {
+		t.Error("Expected an error for nil business logic")
+	}
+	if _, err := NewAPISurface((*embeddedBroker)(nil), metrics.New()); err == nil {
+		t.Error("Expected an error for a nil pointer as business logic")
+	}
+
+	api, err := NewAPISurface(&embeddedBroker{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if api.Metrics == nil {
+		t.Error("Expected nil metrics to be replaced by a collector")
+	}
+}
+
 func TestUnpackGetBindingRequest(t *testing.T) {
 	instanceID := "i1234"
 	bindingID := "b1234"
@@ -32,6 +61,33 @@ func TestUnpackGetBindingRequest(t *testing.T) {
 	}
 }
 
+func TestUnpackBindingRequestsMissingIDs(t *testing.T) {
+	cases := []struct {
+		name string
+		vars map[string]string
+	}{
+		{name: "no instance", vars: map[string]string{"binding_id": "b1234"}},
+		{name: "no binding", vars: map[string]string{"instance_id": "i1234"}},
+	}
+
+	for _, tc := range cases {
+		req := createFakeGetBindingRequest("i1234", "b1234")
+		req.Header.Set("X-Broker-API-Originating-Identity", "kubernetes ZHVkZXI=")
+
+		if _, err := unpackGetBindingRequest(req, tc.vars); !isBadRequest(err) {
+			t.Errorf("%v: expected a 400 from unpackGetBindingRequest, got %v", tc.name, err)
+		}
+		if _, err := unpackBindingLastOperationRequest(req, tc.vars); !isBadRequest(err) {
+			t.Errorf("%v: expected a 400 from unpackBindingLastOperationRequest, got %v", tc.name, err)
+		}
+	}
+}
+
+func isBadRequest(err error) bool {
+	httpErr, ok := osb.IsHTTPError(err)
+	return ok && httpErr.StatusCode == http.StatusBadRequest
+}
+
 func createFakeGetBindingRequest(i, b string) *http.Request {
 	body := bytes.NewBufferString("")
 	uri := fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", i, b)
@@ -50,7 +106,10 @@ func TestUnpackBindingLastOperationRequest(t *testing.T) {
 	req := createFakeBindingLastOperationRequest(args)
 	req.Header.Set("X-Broker-API-Originating-Identity", "kubernetes ZHVkZXI=")
 
-	bindingLastOpReq, err := unpackBindingLastOperationRequest(req, args)
+	bindingLastOpReq, err := unpackBindingLastOperationRequest(req, map[string]string{
+		"instance_id": args["instance_id"],
+		"binding_id":  args["binding_id"],
+	})
 
 	if err != nil {
 		t.Fatalf("Unpacking binding last operation request: %v", err)
@@ -73,6 +132,29 @@ func TestUnpackBindingLastOperationRequest(t *testing.T) {
 	}
 }
 
+func TestUnpackLastOperationRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v2/service_instances/i1234/last_operation?service_id=s1234&plan_id=p1234&operation=o1234", nil)
+	req = mux.SetURLVars(req, map[string]string{"instance_id": "i1234"})
+
+	lastOpReq, err := unpackLastOperationRequest(req)
+	if err != nil {
+		t.Fatalf("Unpacking last operation request: %v", err)
+	}
+
+	if e, a := "i1234", lastOpReq.InstanceID; e != a {
+		t.Fatalf("InstanceID was unpacked unsuccessfully. Expecting %s got %s", e, a)
+	}
+	if lastOpReq.ServiceID == nil || *lastOpReq.ServiceID != "s1234" {
+		t.Fatalf("ServiceID was unpacked unsuccessfully. Expecting s1234 got %v", lastOpReq.ServiceID)
+	}
+	if lastOpReq.PlanID == nil || *lastOpReq.PlanID != "p1234" {
+		t.Fatalf("PlanID was unpacked unsuccessfully. Expecting p1234 got %v", lastOpReq.PlanID)
+	}
+	if lastOpReq.OperationKey == nil || *lastOpReq.OperationKey != "o1234" {
+		t.Fatalf("OperationKey was unpacked unsuccessfully. Expecting o1234 got %v", lastOpReq.OperationKey)
+	}
+}
+
 func createFakeBindingLastOperationRequest(args map[string]string) *http.Request {
 	body := bytes.NewBufferString("")
 	path := fmt.Sprintf(
@@ -92,7 +174,7 @@ func TestUnpackUpdateRequest(t *testing.T) {
 	acceptsIncomplete := true
 
 	fakeUpdateReq := createFakeUpdateRequest(serviceID, planID, acceptsIncomplete)
-	unpackReq, err := unpackUpdateRequest(fakeUpdateReq, map[string]string{"instance_id": instanceID})
+	unpackReq, _, err := unpackUpdateRequest(fakeUpdateReq, map[string]string{"instance_id": instanceID})
 	if err != nil {
 		t.Fatalf("Unpacking update request: %v", err)
 	}
@@ -112,6 +194,44 @@ func TestUnpackUpdateRequest(t *testing.T) {
 	if unpackReq.AcceptsIncomplete != acceptsIncomplete {
 		t.Fatalf("AcceptsIncomplete was unpacked unsuccessfully. Expecting %t got %t", acceptsIncomplete, unpackReq.AcceptsIncomplete)
 	}
+
+	if e, a := "kubernetes", unpackReq.Context["platform"]; e != a {
+		t.Fatalf("Context was unpacked unsuccessfully. Expecting platform %v got %v", e, a)
+	}
+
+	if e, a := "foo", unpackReq.Parameters["parameter2"]; e != a {
+		t.Fatalf("Parameters were unpacked unsuccessfully. Expecting parameter2 %v got %v", e, a)
+	}
+
+	if unpackReq.PreviousValues == nil || unpackReq.PreviousValues.PlanID != "old-plan-id-here" {
+		t.Fatalf("PreviousValues were unpacked unsuccessfully. Expecting plan old-plan-id-here got %+v", unpackReq.PreviousValues)
+	}
+}
+
+func TestUnpackUpdateRequestIgnoresBodyRouting(t *testing.T) {
+	data := `{"instance_id": "other", "accepts_incomplete": true, "service_id": "s1234"}`
+	req := httptest.NewRequest("PATCH", "/v2/service_instances/i1234", bytes.NewBufferString(data))
+
+	unpackReq, _, err := unpackUpdateRequest(req, map[string]string{"instance_id": "i1234"})
+	if err != nil {
+		t.Fatalf("Unpacking update request: %v", err)
+	}
+
+	if e, a := "i1234", unpackReq.InstanceID; e != a {
+		t.Fatalf("InstanceID must come from the route. Expecting %s got %s", e, a)
+	}
+
+	if unpackReq.AcceptsIncomplete {
+		t.Fatal("AcceptsIncomplete must come from the query string, not the body")
+	}
+}
+
+func TestUnpackUpdateRequestInvalidBody(t *testing.T) {
+	req := httptest.NewRequest("PATCH", "/v2/service_instances/i1234", bytes.NewBufferString("{"))
+
+	if _, _, err := unpackUpdateRequest(req, map[string]string{"instance_id": "i1234"}); err == nil {
+		t.Fatal("Expected an error unpacking a malformed body")
+	}
 }
 
 func createFakeUpdateRequest(s, p string, a bool) *http.Request {
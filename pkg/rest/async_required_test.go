@@ -0,0 +1,82 @@
+package rest_test
+
+import (
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestAsyncRequired(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			response := &broker.ProvisionResponse{}
+			response.Async = true
+			return response, nil
+		},
+		DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+			response := &broker.DeprovisionResponse{}
+			response.Async = true
+			return response, nil
+		},
+		UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+			response := &broker.UpdateInstanceResponse{}
+			response.Async = true
+			return response, nil
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			response := &broker.UnbindResponse{}
+			response.Async = true
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {})
+
+	provision := &osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	}
+	if _, err := s.Client.ProvisionInstance(provision); !osb.IsAsyncRequiredError(err) {
+		t.Errorf("provision: expected AsyncRequired, got %v", err)
+	}
+	provision.AcceptsIncomplete = true
+	if response, err := s.Client.ProvisionInstance(provision); err != nil || !response.Async {
+		t.Errorf("provision: expected an asynchronous response, got %+v, %v", response, err)
+	}
+
+	update := &osb.UpdateInstanceRequest{
+		InstanceID: "instance",
+		ServiceID:  "service",
+	}
+	if _, err := s.Client.UpdateInstance(update); !osb.IsAsyncRequiredError(err) {
+		t.Errorf("update: expected AsyncRequired, got %v", err)
+	}
+
+	deprovision := &osb.DeprovisionRequest{
+		InstanceID: "instance",
+		ServiceID:  "service",
+		PlanID:     "plan",
+	}
+	if _, err := s.Client.DeprovisionInstance(deprovision); !osb.IsAsyncRequiredError(err) {
+		t.Errorf("deprovision: expected AsyncRequired, got %v", err)
+	}
+
+	unbind := &osb.UnbindRequest{
+		InstanceID: "instance",
+		BindingID:  "binding",
+		ServiceID:  "service",
+		PlanID:     "plan",
+	}
+	if _, err := s.Client.Unbind(unbind); !osb.IsAsyncRequiredError(err) {
+		t.Errorf("unbind: expected AsyncRequired, got %v", err)
+	}
+	unbind.AcceptsIncomplete = true
+	if response, err := s.Client.Unbind(unbind); err != nil || !response.Async {
+		t.Errorf("unbind: expected an asynchronous response, got %+v, %v", response, err)
+	}
+}
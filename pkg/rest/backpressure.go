@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+const (
+	backpressureErrorMessage     = "TooManyRequests"
+	backpressureErrorDescription = "The broker has too many provision and update requests waiting; retry later."
+
+	// defaultBackpressureRetryAfter is the Retry-After advertised when the
+	// BackpressureQueue doesn't set one.
+	defaultBackpressureRetryAfter = 30 * time.Second
+)
+
+// BackpressureQueue protects slow backends from bursts of provision and
+// update requests. Workers invocations run at once and up to Depth further
+// requests wait for a free worker; requests beyond that are rejected
+// immediately with StatusCode and a Retry-After header, so platforms back
+// off and retry later instead of piling up connections.
+type BackpressureQueue struct {
+	// Workers is the number of invocations of the queued operations that
+	// run at once. It must be positive.
+	Workers int
+	// Depth is the number of requests that may wait for a worker.
+	Depth int
+	// StatusCode is the status of rejected requests, 429 Too Many
+	// Requests or 503 Service Unavailable. It defaults to 429.
+	StatusCode int
+	// RetryAfter is the delay advertised in the Retry-After header of
+	// rejected requests. It defaults to 30 seconds.
+	RetryAfter time.Duration
+	// Operations are the operations that go through the queue. They
+	// default to OperationProvision and OperationUpdate.
+	Operations []string
+
+	once    sync.Once
+	workers chan struct{}
+
+	mutex   sync.Mutex
+	waiting int
+}
+
+// NewBackpressureQueue returns a BackpressureQueue for provision and update
+// requests with the given number of workers and queue depth.
+func NewBackpressureQueue(workers, depth int) *BackpressureQueue {
+	return &BackpressureQueue{
+		Workers: workers,
+		Depth:   depth,
+	}
+}
+
+func (q *BackpressureQueue) init() {
+	q.once.Do(func() {
+		workers := q.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		q.workers = make(chan struct{}, workers)
+	})
+}
+
+// Queues returns whether requests for operation go through the queue.
+func (q *BackpressureQueue) Queues(operation string) bool {
+	operations := q.Operations
+	if operations == nil {
+		operations = []string{OperationProvision, OperationUpdate}
+	}
+	for _, o := range operations {
+		if o == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// Acquire takes a worker, waiting in the queue if none is free. If the
+// queue is full it fails immediately with an OSB error carrying StatusCode;
+// if ctx is done while waiting it returns the context's error. On success,
+// the returned func must be called to release the worker.
+func (q *BackpressureQueue) Acquire(ctx context.Context) (func(), error) {
+	q.init()
+
+	select {
+	case q.workers <- struct{}{}:
+		return q.release, nil
+	default:
+	}
+
+	q.mutex.Lock()
+	if q.waiting >= q.Depth {
+		q.mutex.Unlock()
+		return nil, q.rejection()
+	}
+	q.waiting++
+	q.mutex.Unlock()
+
+	defer func() {
+		q.mutex.Lock()
+		q.waiting--
+		q.mutex.Unlock()
+	}()
+
+	select {
+	case q.workers <- struct{}{}:
+		return q.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting returns the number of requests waiting for a worker.
+func (q *BackpressureQueue) Waiting() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.waiting
+}
+
+func (q *BackpressureQueue) release() {
+	<-q.workers
+}
+
+func (q *BackpressureQueue) retryAfter() time.Duration {
+	if q.RetryAfter <= 0 {
+		return defaultBackpressureRetryAfter
+	}
+	return q.RetryAfter
+}
+
+func (q *BackpressureQueue) rejection() error {
+	status := q.StatusCode
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+	return osb.HTTPStatusCodeError{
+		StatusCode:   status,
+		ErrorMessage: strPtr(backpressureErrorMessage),
+		Description:  strPtr(backpressureErrorDescription),
+	}
+}
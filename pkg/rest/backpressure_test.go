@@ -0,0 +1,90 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestBackpressureQueue(t *testing.T) {
+	started := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	queue := rest.NewBackpressureQueue(1, 1)
+	queue.RetryAfter = 5 * time.Second
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			started <- struct{}{}
+			<-unblock
+			return &broker.ProvisionResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.BackpressureQueue = queue
+	})
+
+	do := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		req.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		return resp
+	}
+	provision := func(instanceID string) *http.Response {
+		return do(http.MethodPut, "/v2/service_instances/"+instanceID,
+			`{"service_id": "service", "plan_id": "plan", "organization_guid": "org", "space_guid": "space"}`)
+	}
+
+	responses := make(chan *http.Response, 2)
+	go func() { responses <- provision("running") }()
+	<-started
+	go func() { responses <- provision("waiting") }()
+	for deadline := time.Now().Add(5 * time.Second); queue.Waiting() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the second request to queue")
+		}
+	}
+
+	rejected := provision("rejected")
+	brokertest.AssertOSBError(t, rejected, http.StatusTooManyRequests, "TooManyRequests")
+	if e, a := "5", rejected.Header.Get("Retry-After"); e != a {
+		t.Errorf("Unexpected Retry-After; expected %q, got %q", e, a)
+	}
+
+	// Other operations don't go through the queue.
+	catalog := do(http.MethodGet, "/v2/catalog", "")
+	catalog.Body.Close()
+	if e, a := http.StatusOK, catalog.StatusCode; e != a {
+		t.Errorf("Unexpected catalog status while the queue is full; expected %v, got %v", e, a)
+	}
+
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		resp := <-responses
+		resp.Body.Close()
+		if e, a := http.StatusCreated, resp.StatusCode; e != a {
+			t.Errorf("Unexpected status of an admitted request; expected %v, got %v", e, a)
+		}
+	}
+
+	metric := &dto.Metric{}
+	if err := s.API.Metrics.BackpressureRejected.WithLabelValues(rest.OperationProvision).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected rejected count; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,49 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+func TestBindingMetadata(t *testing.T) {
+	metadata := broker.NewBindingMetadata(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), time.Hour, 10*time.Minute)
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			response := &broker.BindResponse{Metadata: metadata}
+			if request.BindingID == "invalid" {
+				response.Metadata = &broker.BindingMetadata{ExpiresAt: "tomorrow"}
+			}
+			return response, nil
+		},
+	})
+
+	bind := &osb.BindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"}
+	if _, err := s.Client.Bind(bind); err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Metadata *broker.BindingMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(s.LastResponse().Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Metadata == nil || *body.Metadata != *metadata {
+		t.Errorf("Unexpected metadata; expected %+v, got %+v", metadata, body.Metadata)
+	}
+
+	bind.BindingID = "invalid"
+	if _, err := s.Client.Bind(bind); err == nil {
+		t.Error("Expected invalid metadata to fail the bind")
+	}
+	if e, a := http.StatusInternalServerError, s.LastResponse().StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,58 @@
+package rest_test
+
+import (
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+func TestBindingRouteVars(t *testing.T) {
+	var getRequest *osb.GetBindingRequest
+	var pollRequest *osb.BindingLastOperationRequest
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetBindingFunc: func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+			getRequest = request
+			return &broker.GetBindingResponse{}, nil
+		},
+		BindingLastOperationFunc: func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			pollRequest = request
+			return &broker.LastOperationResponse{LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress}}, nil
+		},
+	})
+
+	if _, err := s.Client.GetBinding(&osb.GetBindingRequest{InstanceID: "instance", BindingID: "binding"}); err != nil {
+		t.Fatal(err)
+	}
+	if getRequest == nil {
+		t.Fatal("Expected GetBinding to be called")
+	}
+	if e, a := "instance", getRequest.InstanceID; e != a {
+		t.Errorf("Unexpected instance ID; expected %v, got %v", e, a)
+	}
+	if e, a := "binding", getRequest.BindingID; e != a {
+		t.Errorf("Unexpected binding ID; expected %v, got %v", e, a)
+	}
+
+	if _, err := s.Client.PollBindingLastOperation(&osb.BindingLastOperationRequest{
+		InstanceID: "instance",
+		BindingID:  "binding",
+		OriginatingIdentity: &osb.OriginatingIdentity{
+			Platform: "kubernetes",
+			Value:    `{"username": "user"}`,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if pollRequest == nil {
+		t.Fatal("Expected BindingLastOperation to be called")
+	}
+	if e, a := "instance", pollRequest.InstanceID; e != a {
+		t.Errorf("Unexpected instance ID; expected %v, got %v", e, a)
+	}
+	if e, a := "binding", pollRequest.BindingID; e != a {
+		t.Errorf("Unexpected binding ID; expected %v, got %v", e, a)
+	}
+}
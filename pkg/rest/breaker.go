@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+const (
+	circuitOpenErrorMessage     = "CircuitOpen"
+	circuitOpenErrorDescription = "The broker's backend is failing repeatedly; retry later."
+)
+
+// BreakerState is the state of the circuit for one operation.
+type BreakerState int
+
+// The states of a circuit. The numeric values are reported by the circuit
+// breaker state metric.
+const (
+	BreakerClosed   BreakerState = 0
+	BreakerHalfOpen BreakerState = 1
+	BreakerOpen     BreakerState = 2
+)
+
+// CircuitBreaker short-circuits the invocations of an operation with a 503
+// after the business logic failed FailureThreshold consecutive times. The
+// circuit stays open for OpenDuration and then lets up to HalfOpenProbes
+// concurrent invocations through; the circuit closes again if a probe
+// succeeds and reopens if it fails.
+//
+// Invocations failing with a non-OSB error or an OSB error with a 5xx status
+// code count as failures. Other OSB errors, such as 409 Conflict, are
+// considered answers from a healthy backend.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenDuration     time.Duration
+	HalfOpenProbes   int
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+type circuit struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// NewCircuitBreaker returns a CircuitBreaker with the given settings. A
+// HalfOpenProbes smaller than one is treated as one.
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration, halfOpenProbes int) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+		HalfOpenProbes:   halfOpenProbes,
+	}
+}
+
+// Allow returns whether an invocation of the given operation may proceed,
+// and if not, how long until the circuit is half-open. Allowed invocations
+// must report their result with Record, or call Release if they are
+// abandoned.
+func (b *CircuitBreaker) Allow(operation string) (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(operation)
+	switch c.state {
+	case BreakerOpen:
+		remaining := b.OpenDuration - b.clock().Sub(c.openedAt)
+		if remaining > 0 {
+			return false, remaining
+		}
+		c.state = BreakerHalfOpen
+		c.probes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		maxProbes := b.HalfOpenProbes
+		if maxProbes < 1 {
+			maxProbes = 1
+		}
+		if c.probes >= maxProbes {
+			return false, b.OpenDuration
+		}
+		c.probes++
+	}
+
+	return true, 0
+}
+
+// Record reports the result of an allowed invocation of the given
+// operation.
+func (b *CircuitBreaker) Record(operation string, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(operation)
+	if !isBackendFailure(err) {
+		c.state = BreakerClosed
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.state == BreakerHalfOpen || c.failures >= b.FailureThreshold {
+		c.state = BreakerOpen
+		c.openedAt = b.clock()
+	}
+}
+
+// Release gives back the half-open probe slot taken by an allowed invocation
+// of the given operation that was abandoned before reaching the business
+// logic. The circuit's state and failure count are left unchanged.
+func (b *CircuitBreaker) Release(operation string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(operation)
+	if c.state == BreakerHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// State returns the current state of the circuit for the given operation.
+func (b *CircuitBreaker) State(operation string) BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.circuit(operation).state
+}
+
+func (b *CircuitBreaker) circuit(operation string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	c, ok := b.circuits[operation]
+	if !ok {
+		c = &circuit{}
+		b.circuits[operation] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// isBackendFailure returns whether an error returned by the business logic
+// indicates a failing backend.
+func isBackendFailure(err error) bool {
+	// Business logic giving up on a request the platform abandoned says
+	// nothing about the backend.
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if httpErr, ok := broker.AsHTTPError(err); ok {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func newCircuitOpenError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr(circuitOpenErrorMessage),
+		Description:  strPtr(circuitOpenErrorDescription),
+	}
+}
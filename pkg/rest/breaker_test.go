@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(2, time.Minute, 1)
+	b.now = func() time.Time { return now }
+
+	fail := func() {
+		if ok, _ := b.Allow(OperationProvision); !ok {
+			t.Fatal("Expected invocation to be allowed")
+		}
+		b.Record(OperationProvision, errors.New("backend down"))
+	}
+
+	// Client errors don't count as backend failures.
+	b.Allow(OperationProvision)
+	b.Record(OperationProvision, osb.HTTPStatusCodeError{StatusCode: http.StatusConflict})
+
+	fail()
+	if e, a := BreakerClosed, b.State(OperationProvision); e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+	fail()
+	if e, a := BreakerOpen, b.State(OperationProvision); e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+
+	ok, retryAfter := b.Allow(OperationProvision)
+	if ok || retryAfter != time.Minute {
+		t.Fatalf("Expected open circuit to reject with a retry of a minute, got %v, %v", ok, retryAfter)
+	}
+	if ok, _ := b.Allow(OperationBind); !ok {
+		t.Fatal("Expected other operations to be unaffected")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := b.Allow(OperationProvision); !ok {
+		t.Fatal("Expected a half-open probe to be allowed")
+	}
+	if ok, _ := b.Allow(OperationProvision); ok {
+		t.Fatal("Expected only one half-open probe")
+	}
+	b.Record(OperationProvision, nil)
+	if e, a := BreakerClosed, b.State(OperationProvision); e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(1, time.Minute, 1)
+	b.now = func() time.Time { return now }
+
+	b.Allow(OperationBind)
+	b.Record(OperationBind, errors.New("backend down"))
+	now = now.Add(time.Minute)
+
+	if ok, _ := b.Allow(OperationBind); !ok {
+		t.Fatal("Expected a half-open probe to be allowed")
+	}
+	b.Release(OperationBind)
+	if e, a := BreakerHalfOpen, b.State(OperationBind); e != a {
+		t.Fatalf("Expected a released probe not to close the circuit; expected %v, got %v", e, a)
+	}
+	if ok, _ := b.Allow(OperationBind); !ok {
+		t.Fatal("Expected the released probe slot to be available again")
+	}
+}
+
+func TestInvokePanic(t *testing.T) {
+	var reported error
+	done := func(err error) { reported = err }
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the panic to propagate")
+			}
+		}()
+		invoke(done, func() error { panic("boom") })
+	}()
+
+	if reported == nil {
+		t.Fatal("Expected a panicking invocation to be reported as failed")
+	}
+}
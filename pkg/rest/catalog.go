@@ -0,0 +1,69 @@
+package rest
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// CatalogAugmenter is called with the catalog returned by the business logic
+// before it is written, so features layered on the APISurface can advertise
+// themselves, for example by adding metadata to services or plans. The
+// catalog may be modified in place. Returning an error fails the request: an
+// osb.HTTPStatusCodeError is written as is, any other error as a 500.
+type CatalogAugmenter func(c *broker.RequestContext, catalog *broker.CatalogResponse) error
+
+// Catalog returns the catalog served to platforms: the catalog of the
+// business logic advertising the extension APIs, after the
+// CatalogAugmenters ran. Unlike GetCatalogHandler, it doesn't count as a
+// catalog request or go through admission control, so admin tooling can
+// inspect the served catalog without affecting the broker.
+func (s *APISurface) Catalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+	response, err := s.Broker.GetCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		response = &broker.CatalogResponse{}
+	}
+	if err := s.completeCatalog(c, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// completeCatalog advertises the extension APIs in the catalog returned by
+// the business logic and runs the CatalogAugmenters on it.
+func (s *APISurface) completeCatalog(c *broker.RequestContext, catalog *broker.CatalogResponse) error {
+	s.advertiseExtensionAPIs(catalog)
+	return s.augmentCatalog(c, catalog)
+}
+
+// augmentCatalog runs the APISurface's CatalogAugmenters on catalog.
+func (s *APISurface) augmentCatalog(c *broker.RequestContext, catalog *broker.CatalogResponse) error {
+	for _, augment := range s.CatalogAugmenters {
+		if err := augment(c, catalog); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findPlan returns the service and plan with the given IDs in catalog. The
+// plan is nil if the service has no such plan, and both are nil if the
+// catalog has no such service.
+func findPlan(catalog *broker.CatalogResponse, serviceID, planID string) (*osb.Service, *osb.Plan) {
+	for i := range catalog.Services {
+		service := &catalog.Services[i]
+		if service.ID != serviceID {
+			continue
+		}
+		for j := range service.Plans {
+			if service.Plans[j].ID == planID {
+				return service, &service.Plans[j]
+			}
+		}
+		return service, nil
+	}
+	return nil, nil
+}
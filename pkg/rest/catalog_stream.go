@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// streamsCatalog returns whether catalog responses are streamed. Response
+// interceptors, catalog augmenters, extension APIs and pretty-printing need
+// the whole catalog, so streaming is disabled when any are configured.
+func (s *APISurface) streamsCatalog() bool {
+	return s.StreamCatalog && len(s.ResponseInterceptors) == 0 && len(s.CatalogAugmenters) == 0 &&
+		len(s.Extensions()) == 0 && !s.PrettyJSON
+}
+
+// catalogStream writes a catalog response incrementally, encoding one
+// service at a time and flushing it to the connection. The status code and
+// headers are sent with the first service, so errors raised before it can
+// still be answered with an error response.
+type catalogStream struct {
+	s       *APISurface
+	w       http.ResponseWriter
+	r       *http.Request
+	flusher http.Flusher
+	encoder *json.Encoder
+	started bool
+}
+
+func (s *APISurface) newCatalogStream(w http.ResponseWriter, r *http.Request) *catalogStream {
+	flusher, _ := w.(http.Flusher)
+	return &catalogStream{
+		s:       s,
+		w:       w,
+		r:       r,
+		flusher: flusher,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// emit writes one service of the catalog.
+func (cs *catalogStream) emit(service *osb.Service) error {
+	separator := ","
+	if !cs.started {
+		cs.start()
+		separator = `{"services":[`
+	}
+	if _, err := io.WriteString(cs.w, separator); err != nil {
+		cs.s.WriteFailed(cs.r, err)
+		return err
+	}
+	if err := cs.encoder.Encode(service); err != nil {
+		cs.s.WriteFailed(cs.r, err)
+		return err
+	}
+	if cs.flusher != nil {
+		cs.flusher.Flush()
+	}
+	return nil
+}
+
+// close terminates the catalog response.
+func (cs *catalogStream) close() error {
+	end := "]}"
+	if !cs.started {
+		cs.start()
+		end = `{"services":[]}`
+	}
+	_, err := io.WriteString(cs.w, end)
+	if err != nil {
+		cs.s.WriteFailed(cs.r, err)
+	}
+	return err
+}
+
+func (cs *catalogStream) start() {
+	cs.started = true
+	cs.s.setResponseHeaders(cs.w, cs.r)
+	cs.w.WriteHeader(http.StatusOK)
+}
+
+// fromResponse streams an already built catalog response.
+func (cs *catalogStream) fromResponse(response *broker.CatalogResponse) error {
+	for i := range response.Services {
+		if err := cs.emit(&response.Services[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishCatalogStream terminates a streamed catalog response, or answers
+// with an error if streaming failed before anything was written. Later
+// errors can only be logged, leaving a truncated body for the client to
+// reject.
+func (s *APISurface) finishCatalogStream(stream *catalogStream, err error) {
+	if err != nil && !stream.started {
+		s.writeError(stream.w, stream.r, err, http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		err = stream.close()
+	}
+	if err != nil {
+		glog.Errorf("Error streaming catalog: %v", err)
+	}
+}
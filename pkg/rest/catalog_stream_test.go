@@ -0,0 +1,166 @@
+package rest_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestStreamCatalog(t *testing.T) {
+	tests := []struct {
+		name     string
+		services int
+	}{
+		{name: "empty catalog", services: 0},
+		{name: "one service", services: 1},
+		{name: "many services", services: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := &broker.CatalogResponse{}
+			catalog.Services = []osb.Service{}
+			for i := 0; i < tt.services; i++ {
+				catalog.Services = append(catalog.Services, osb.Service{
+					ID:          fmt.Sprintf("service-%d", i),
+					Name:        fmt.Sprintf("service-%d", i),
+					Description: "a service",
+					Plans: []osb.Plan{
+						{ID: fmt.Sprintf("plan-%d", i), Name: "default", Description: "a plan"},
+					},
+				})
+			}
+
+			s := brokertest.NewServer(t, &brokertest.FakeBroker{
+				GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+					return catalog, nil
+				},
+			}, func(api *rest.APISurface) {
+				api.StreamCatalog = true
+			})
+
+			response, err := s.Client.GetCatalog()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e, a := &catalog.CatalogResponse, response; !reflect.DeepEqual(e, a) {
+				t.Errorf("Unexpected catalog; expected %+v, got %+v", e, a)
+			}
+		})
+	}
+}
+
+// streamingBroker is a broker.CatalogStreamer emitting services from a
+// channel.
+type streamingBroker struct {
+	brokertest.FakeBroker
+	stream func(c *broker.RequestContext, emit func(*osb.Service) error) error
+}
+
+func (b *streamingBroker) StreamCatalog(c *broker.RequestContext, emit func(*osb.Service) error) error {
+	return b.stream(c, emit)
+}
+
+func TestStreamCatalogIncrementally(t *testing.T) {
+	read := make(chan struct{})
+	logic := &streamingBroker{
+		stream: func(c *broker.RequestContext, emit func(*osb.Service) error) error {
+			if err := emit(&osb.Service{ID: "first", Name: "first"}); err != nil {
+				return err
+			}
+			// Only continue once the client has read the first service,
+			// which it can't unless it was flushed to the connection.
+			select {
+			case <-read:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected the first service to reach the client before the catalog was complete")
+			}
+			return emit(&osb.Service{ID: "second", Name: "second"})
+		},
+	}
+	s := brokertest.NewServer(t, logic, func(api *rest.APISurface) {
+		api.StreamCatalog = true
+	})
+
+	request, _ := http.NewRequest("GET", s.URL+"/v2/catalog", nil)
+	request.Header.Set(osb.APIVersionHeader, osb.LatestAPIVersion().HeaderValue())
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body := bufio.NewReader(response.Body)
+	first, err := body.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(first, `"first"`) {
+		t.Fatalf("Expected the first chunk to hold the first service, got %q", first)
+	}
+	close(read)
+
+	remainder, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := &osb.CatalogResponse{}
+	if err := json.Unmarshal([]byte(first+string(remainder)), catalog); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(catalog.Services); e != a {
+		t.Fatalf("Unexpected number of services; expected %v, got %v", e, a)
+	}
+}
+
+func TestStreamCatalogError(t *testing.T) {
+	logic := &streamingBroker{
+		stream: func(c *broker.RequestContext, emit func(*osb.Service) error) error {
+			return errors.New("catalog unavailable")
+		},
+	}
+	s := brokertest.NewServer(t, logic, func(api *rest.APISurface) {
+		api.StreamCatalog = true
+	})
+
+	_, err := s.Client.GetCatalog()
+	if httpErr, ok := osb.IsHTTPError(err); !ok || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected an error before the first service to be a 500, got %v", err)
+	}
+}
+
+func TestStreamCatalogWithInterceptors(t *testing.T) {
+	var intercepted []byte
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			return &broker.CatalogResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.StreamCatalog = true
+		api.ResponseInterceptors = []rest.ResponseInterceptor{
+			func(operation string, statusCode int, header http.Header, body []byte) error {
+				intercepted = body
+				return nil
+			},
+		}
+	})
+
+	if _, err := s.Client.GetCatalog(); err != nil {
+		t.Fatal(err)
+	}
+	if intercepted == nil {
+		t.Fatal("Expected interceptors to see the whole catalog body")
+	}
+}
@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// APIVersion is an OSB API version, as sent by platforms in the
+// X-Broker-API-Version header.
+type APIVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseAPIVersion parses a version such as "2.13".
+func ParseAPIVersion(s string) (APIVersion, error) {
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return APIVersion{}, fmt.Errorf("invalid API version %q", s)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return APIVersion{}, fmt.Errorf("invalid API version %q", s)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return APIVersion{}, fmt.Errorf("invalid API version %q", s)
+	}
+	return APIVersion{Major: major, Minor: minor}, nil
+}
+
+// Less returns whether v is older than o.
+func (v APIVersion) Less(o APIVersion) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	return v.Minor < o.Minor
+}
+
+func (v APIVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// VersionBehavior describes how requests of an API version are served.
+type VersionBehavior struct {
+	// OriginatingIdentity passes the originating identity header, added in
+	// 2.13, to the business logic. Without it the header is ignored.
+	OriginatingIdentity bool
+	// AsyncBindings honors accepts_incomplete on bind and unbind requests,
+	// added in 2.14. Without it bindings must complete synchronously, and
+	// asynchronous responses of the business logic get a 422.
+	AsyncBindings bool
+	// MaintenanceInfo passes the maintenance_info of update requests,
+	// added in 2.15, to the business logic. Without it the field is
+	// ignored.
+	MaintenanceInfo bool
+	// MissingBindingStatus is the status of unbind requests for bindings
+	// that don't exist. It defaults to 410 Gone, as the spec requires; a
+	// success status answers with an empty body instead of an error.
+	MissingBindingStatus int
+}
+
+// latestBehavior is the behavior of requests when there is no
+// Compatibility: every feature of the latest version is enabled.
+var latestBehavior = VersionBehavior{
+	OriginatingIdentity: true,
+	AsyncBindings:       true,
+	MaintenanceInfo:     true,
+}
+
+// Compatibility adjusts the semantics of requests to the API version they
+// were sent with, so one broker can serve platforms speaking different
+// versions of the spec.
+type Compatibility struct {
+	// Versions are the behaviors of the versions where behavior changes.
+	// A request gets the behavior of the newest version not newer than its
+	// own; requests older than every version get the oldest behavior and
+	// requests without a parsable version the newest.
+	Versions map[APIVersion]VersionBehavior
+}
+
+// NewCompatibility returns a Compatibility following the spec: 2.12 clients
+// get neither originating identities nor asynchronous bindings, 2.13 clients
+// get originating identities, 2.14 clients asynchronous bindings and 2.15+
+// clients maintenance info.
+func NewCompatibility() *Compatibility {
+	return &Compatibility{
+		Versions: map[APIVersion]VersionBehavior{
+			{Major: 2, Minor: 12}: {},
+			{Major: 2, Minor: 13}: {OriginatingIdentity: true},
+			{Major: 2, Minor: 14}: {OriginatingIdentity: true, AsyncBindings: true},
+			{Major: 2, Minor: 15}: latestBehavior,
+		},
+	}
+}
+
+// Behavior returns the behavior of requests sent with version.
+func (c *Compatibility) Behavior(version string) VersionBehavior {
+	requested, err := ParseAPIVersion(version)
+
+	var best, oldest, newest *APIVersion
+	for v := range c.Versions {
+		v := v
+		if oldest == nil || v.Less(*oldest) {
+			oldest = &v
+		}
+		if newest == nil || newest.Less(v) {
+			newest = &v
+		}
+		if err == nil && !requested.Less(v) && (best == nil || best.Less(v)) {
+			best = &v
+		}
+	}
+	switch {
+	case oldest == nil:
+		return latestBehavior
+	case err != nil:
+		best = newest
+	case best == nil:
+		best = oldest
+	}
+	return c.Versions[*best]
+}
+
+// behavior returns the behavior of r.
+func (s *APISurface) behavior(r *http.Request) VersionBehavior {
+	if s.Compatibility == nil {
+		return latestBehavior
+	}
+	return s.Compatibility.Behavior(getBrokerAPIVersionFromRequest(r))
+}
+
+// adaptRequest returns r without the headers and query parameters its API
+// version doesn't support, so the handler of operation unpacks the request
+// as a platform speaking that version meant it.
+func (s *APISurface) adaptRequest(r *http.Request, operation string) *http.Request {
+	if s.Compatibility == nil {
+		return r
+	}
+	b := s.behavior(r)
+
+	adapted := r.Clone(r.Context())
+	if !b.OriginatingIdentity {
+		adapted.Header.Del(osb.OriginatingIdentityHeader)
+	}
+	if !b.AsyncBindings && (operation == OperationBind || operation == OperationUnbind) {
+		q := adapted.URL.Query()
+		q.Del(osb.AcceptsIncomplete)
+		u := *adapted.URL
+		u.RawQuery = q.Encode()
+		adapted.URL = &u
+		// FormValue reads the parsed form when there is one.
+		adapted.Form = nil
+	}
+	return adapted
+}
+
+// missingBindingStatus returns the status to answer an unbind request for a
+// missing binding with instead of err, if any.
+func (s *APISurface) missingBindingStatus(r *http.Request, err *osb.HTTPStatusCodeError) (int, bool) {
+	if s.Compatibility == nil || err.StatusCode != http.StatusGone {
+		return 0, false
+	}
+	if route := mux.CurrentRoute(r); route == nil || route.GetName() != OperationUnbind {
+		return 0, false
+	}
+	status := s.behavior(r).MissingBindingStatus
+	return status, status != 0 && status != http.StatusGone
+}
@@ -0,0 +1,104 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestCompatibilityBehavior(t *testing.T) {
+	c := rest.NewCompatibility()
+	cases := []struct {
+		version  string
+		expected rest.VersionBehavior
+	}{
+		{version: "2.11", expected: rest.VersionBehavior{}},
+		{version: "2.12", expected: rest.VersionBehavior{}},
+		{version: "2.13", expected: rest.VersionBehavior{OriginatingIdentity: true}},
+		{version: "2.14", expected: rest.VersionBehavior{OriginatingIdentity: true, AsyncBindings: true}},
+		{version: "2.17", expected: rest.VersionBehavior{OriginatingIdentity: true, AsyncBindings: true, MaintenanceInfo: true}},
+		{version: "", expected: rest.VersionBehavior{OriginatingIdentity: true, AsyncBindings: true, MaintenanceInfo: true}},
+	}
+	for _, tc := range cases {
+		if e, a := tc.expected, c.Behavior(tc.version); e != a {
+			t.Errorf("%q: expected %+v, got %+v", tc.version, e, a)
+		}
+	}
+}
+
+func TestCompatibility(t *testing.T) {
+	var identity *osb.OriginatingIdentity
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			identity = request.OriginatingIdentity
+			response := &broker.BindResponse{}
+			response.Async = true
+			return response, nil
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			if request.BindingID == "async" {
+				response := &broker.UnbindResponse{}
+				response.Async = true
+				return response, nil
+			}
+			return nil, broker.ErrBindingGone
+		},
+	}, func(api *rest.APISurface) {
+		api.Compatibility = rest.NewCompatibility()
+		api.Compatibility.Versions[rest.APIVersion{Major: 2, Minor: 12}] = rest.VersionBehavior{MissingBindingStatus: http.StatusOK}
+	})
+
+	do := func(method, path, version string, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(osb.APIVersionHeader, version)
+		req.Header.Set(osb.OriginatingIdentityHeader, "cloudfoundry eyJ1c2VyX2lkIjoiYWxpY2UifQ==")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	bind := `{"service_id": "service", "plan_id": "plan"}`
+	path := "/v2/service_instances/instance/service_bindings/binding?accepts_incomplete=true"
+	if e, a := http.StatusUnprocessableEntity, do(http.MethodPut, path, "2.13", bind).StatusCode; e != a {
+		t.Errorf("Expected asynchronous bindings to be refused to 2.13 clients with %v, got %v", e, a)
+	}
+	if identity == nil {
+		t.Error("Expected 2.13 clients to pass the originating identity")
+	}
+	if e, a := http.StatusAccepted, do(http.MethodPut, path, "2.14", bind).StatusCode; e != a {
+		t.Errorf("Expected asynchronous bindings for 2.14 clients, got %v", a)
+	}
+	do(http.MethodPut, path, "2.12", bind)
+	if identity != nil {
+		t.Error("Expected the originating identity of 2.12 clients to be ignored")
+	}
+
+	path = "/v2/service_instances/instance/service_bindings/binding?service_id=service&plan_id=plan"
+	if e, a := http.StatusOK, do(http.MethodDelete, path, "2.12", "").StatusCode; e != a {
+		t.Errorf("Unexpected status unbinding a missing binding for 2.12 clients; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusGone, do(http.MethodDelete, path, "2.14", "").StatusCode; e != a {
+		t.Errorf("Unexpected status unbinding a missing binding for 2.14 clients; expected %v, got %v", e, a)
+	}
+
+	path = "/v2/service_instances/instance/service_bindings/async?service_id=service&plan_id=plan&accepts_incomplete=true"
+	if e, a := http.StatusUnprocessableEntity, do(http.MethodDelete, path, "2.13", "").StatusCode; e != a {
+		t.Errorf("Expected asynchronous unbinds to be refused to 2.13 clients with %v, got %v", e, a)
+	}
+	if e, a := http.StatusAccepted, do(http.MethodDelete, path, "2.14", "").StatusCode; e != a {
+		t.Errorf("Expected asynchronous unbinds for 2.14 clients, got %v", a)
+	}
+}
@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+const (
+	concurrencyErrorMessage     = "ConcurrencyLimitExceeded"
+	concurrencyErrorDescription = "The broker is handling too many concurrent operations; retry later."
+)
+
+// ConcurrencyLimiter bounds the number of concurrent BusinessLogic
+// invocations, overall and per operation. Requests over the limit wait for a
+// free slot for up to QueueTimeout and then fail with a 503; a zero
+// QueueTimeout fails them immediately.
+type ConcurrencyLimiter struct {
+	// Max is the maximum number of concurrent invocations across all
+	// operations. Zero means unlimited.
+	Max int
+	// PerOperation is the maximum number of concurrent invocations for each
+	// operation, keyed by operation name (see OperationProvision and
+	// friends). Operations without an entry are only bounded by Max.
+	PerOperation map[string]int
+	// QueueTimeout is how long a request waits for a free slot before
+	// failing.
+	QueueTimeout time.Duration
+
+	once       sync.Once
+	all        chan struct{}
+	operations map[string]chan struct{}
+}
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter with the given overall
+// and per-operation limits.
+func NewConcurrencyLimiter(max int, perOperation map[string]int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		Max:          max,
+		PerOperation: perOperation,
+		QueueTimeout: queueTimeout,
+	}
+}
+
+func (l *ConcurrencyLimiter) init() {
+	l.once.Do(func() {
+		if l.Max > 0 {
+			l.all = make(chan struct{}, l.Max)
+		}
+		l.operations = map[string]chan struct{}{}
+		for operation, max := range l.PerOperation {
+			if max > 0 {
+				l.operations[operation] = make(chan struct{}, max)
+			}
+		}
+	})
+}
+
+// Acquire reserves a slot for an invocation of the given operation. On
+// success, the returned func must be called to release the slot.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, operation string) (func(), error) {
+	l.init()
+
+	if l.QueueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.QueueTimeout)
+		defer cancel()
+	}
+
+	operationSem := l.operations[operation]
+	if err := acquireSlot(ctx, operationSem, l.QueueTimeout > 0); err != nil {
+		return nil, err
+	}
+	if err := acquireSlot(ctx, l.all, l.QueueTimeout > 0); err != nil {
+		releaseSlot(operationSem)
+		return nil, err
+	}
+
+	return func() {
+		releaseSlot(l.all)
+		releaseSlot(operationSem)
+	}, nil
+}
+
+// Saturation returns the fraction of the overall limit currently in use, or
+// zero if there is no overall limit.
+func (l *ConcurrencyLimiter) Saturation() float64 {
+	l.init()
+	if l.all == nil {
+		return 0
+	}
+	return float64(len(l.all)) / float64(cap(l.all))
+}
+
+func acquireSlot(ctx context.Context, sem chan struct{}, wait bool) error {
+	if sem == nil {
+		return nil
+	}
+
+	if !wait {
+		select {
+		case sem <- struct{}{}:
+			return nil
+		default:
+			return newConcurrencyLimitError()
+		}
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return newConcurrencyLimitError()
+	}
+}
+
+func releaseSlot(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
+
+func newConcurrencyLimitError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr(concurrencyErrorMessage),
+		Description:  strPtr(concurrencyErrorDescription),
+	}
+}
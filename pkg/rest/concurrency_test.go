@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestConcurrencyLimiterFailFast(t *testing.T) {
+	l := NewConcurrencyLimiter(2, map[string]int{OperationProvision: 1}, 0)
+
+	release, err := l.Acquire(context.Background(), OperationProvision)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = l.Acquire(context.Background(), OperationProvision)
+	if httpErr, ok := osb.IsHTTPError(err); !ok || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 over the per-operation limit, got %v", err)
+	}
+
+	releaseBind, err := l.Acquire(context.Background(), OperationBind)
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring another operation: %v", err)
+	}
+	if e, a := 1.0, l.Saturation(); e != a {
+		t.Errorf("Unexpected saturation; expected %v, got %v", e, a)
+	}
+
+	if _, err := l.Acquire(context.Background(), OperationBind); err == nil {
+		t.Fatal("Expected an error over the overall limit")
+	}
+
+	release()
+	releaseBind()
+	if e, a := 0.0, l.Saturation(); e != a {
+		t.Errorf("Unexpected saturation; expected %v, got %v", e, a)
+	}
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	l := NewConcurrencyLimiter(1, nil, time.Second)
+
+	release, err := l.Acquire(context.Background(), OperationProvision)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+
+	release, err = l.Acquire(context.Background(), OperationProvision)
+	if err != nil {
+		t.Fatalf("Expected queued request to be admitted, got %v", err)
+	}
+	release()
+}
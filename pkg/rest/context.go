@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+type contextKey int
+
+const (
+	requestContextKey contextKey = iota
+	operationKey
+)
+
+// withRequestContext returns a copy of r carrying c, so that writeResponse
+// can apply the response headers set by the business logic.
+func withRequestContext(r *http.Request, c *broker.RequestContext) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestContextKey, c))
+}
+
+// requestContextFrom returns the RequestContext carried by r, or nil.
+func requestContextFrom(r *http.Request) *broker.RequestContext {
+	c, _ := r.Context().Value(requestContextKey).(*broker.RequestContext)
+	return c
+}
+
+// withOperation returns a copy of r served as operation, for handlers whose
+// route isn't named after their operation. See InstrumentHandler.
+func withOperation(r *http.Request, operation string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), operationKey, operation))
+}
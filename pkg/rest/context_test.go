@@ -0,0 +1,97 @@
+package rest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestSetResponseHeader(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			c.SetResponseHeader("Cache-Control", "max-age=60")
+			return &broker.CatalogResponse{}, nil
+		},
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			c.SetResponseHeader("X-Request-Id", "abc")
+			return nil, errors.New("backend down")
+		},
+	})
+
+	if _, err := s.Client.GetCatalog(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "max-age=60", s.LastResponse().Header.Get("Cache-Control"); e != a {
+		t.Errorf("Unexpected Cache-Control header; expected %q, got %q", e, a)
+	}
+
+	s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	})
+	if e, a := "abc", s.LastResponse().Header.Get("X-Request-Id"); e != a {
+		t.Errorf("Expected headers to be applied to error responses; expected %q, got %q", e, a)
+	}
+}
+
+func TestRequestContextVars(t *testing.T) {
+	var vars map[string]string
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			vars = c.Vars()
+			return &broker.BindResponse{}, nil
+		},
+	})
+
+	if _, err := s.Client.Bind(&osb.BindRequest{
+		InstanceID: "instance",
+		BindingID:  "binding",
+		ServiceID:  "service",
+		PlanID:     "plan",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := (map[string]string{"instance_id": "instance", "binding_id": "binding"}), vars; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected vars; expected %v, got %v", e, a)
+	}
+
+	if e, a := 0, len((&broker.RequestContext{}).Vars()); e != a {
+		t.Errorf("Expected no vars without a request, got %v", a)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			c.AddWarning("plan small will be retired on 2019-01-01")
+			c.AddWarning("parameter size is deprecated;\nuse storage")
+			return &broker.ProvisionResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.WarningHeader = rest.DefaultWarningHeader
+	})
+
+	if _, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := []string{"plan small will be retired on 2019-01-01", "parameter size is deprecated; use storage"}
+	if a := s.LastResponse().Header[rest.DefaultWarningHeader]; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected warnings; expected %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"fmt"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// issueCredentials merges the credentials issued by the APISurface's
+// CredentialProvider into the response to a synchronous bind request
+// creating a binding.
+func (s *APISurface) issueCredentials(request *osb.BindRequest, c *broker.RequestContext, response *broker.BindResponse) error {
+	if s.Credentials == nil || response.Job != nil || response.Async || response.Exists {
+		return nil
+	}
+
+	credentials, err := s.Credentials.Issue(request, c)
+	if err != nil {
+		return fmt.Errorf("issuing credentials for binding %q: %v", request.BindingID, err)
+	}
+	if len(credentials) == 0 {
+		return nil
+	}
+
+	if response.Credentials == nil {
+		response.Credentials = make(map[string]interface{}, len(credentials))
+	}
+	for k, v := range credentials {
+		response.Credentials[k] = v
+	}
+	return nil
+}
+
+// revokeCredentials revokes the credentials issued for a binding by the
+// APISurface's CredentialProvider.
+func (s *APISurface) revokeCredentials(request *osb.UnbindRequest, c *broker.RequestContext) error {
+	if s.Credentials == nil {
+		return nil
+	}
+	if err := s.Credentials.Revoke(request, c); err != nil {
+		return fmt.Errorf("revoking credentials of binding %q: %v", request.BindingID, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package rest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+type fakeCredentialProvider struct {
+	revoked  []string
+	issueErr error
+}
+
+func (f *fakeCredentialProvider) Issue(request *osb.BindRequest, c *broker.RequestContext) (map[string]interface{}, error) {
+	if f.issueErr != nil {
+		return nil, f.issueErr
+	}
+	return map[string]interface{}{"password": "issued-" + request.BindingID}, nil
+}
+
+func (f *fakeCredentialProvider) Revoke(request *osb.UnbindRequest, c *broker.RequestContext) error {
+	f.revoked = append(f.revoked, request.BindingID)
+	return nil
+}
+
+func TestCredentialProvider(t *testing.T) {
+	provider := &fakeCredentialProvider{}
+	unbound, exists := false, false
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			response := &broker.BindResponse{Exists: exists}
+			response.Credentials = map[string]interface{}{"host": "db.example.com", "password": "static"}
+			return response, nil
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			if len(provider.revoked) != 0 {
+				t.Error("Expected the credentials to be revoked after the business logic runs")
+			}
+			if request.BindingID == "failing" {
+				return nil, errors.New("backend unavailable")
+			}
+			unbound = true
+			return &broker.UnbindResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Credentials = provider
+	})
+
+	bind := &osb.BindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"}
+	response, err := s.Client.Bind(bind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "issued-binding", response.Credentials["password"]; e != a {
+		t.Errorf("Expected issued credentials to override the business logic's; expected %v, got %v", e, a)
+	}
+	if e, a := "db.example.com", response.Credentials["host"]; e != a {
+		t.Errorf("Expected the business logic's other credentials to be kept; expected %v, got %v", e, a)
+	}
+
+	if _, err := s.Client.Unbind(&osb.UnbindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if !unbound {
+		t.Error("Expected the business logic to unbind")
+	}
+	if e, a := []string{"binding"}, provider.revoked; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected revoked credentials; expected %v, got %v", e, a)
+	}
+
+	provider.revoked = nil
+	if _, err := s.Client.Unbind(&osb.UnbindRequest{InstanceID: "instance", BindingID: "failing", ServiceID: "service", PlanID: "plan"}); err == nil {
+		t.Error("Expected the unbind to fail")
+	}
+	if len(provider.revoked) != 0 {
+		t.Errorf("Expected no credentials to be revoked when the unbind fails, got %v", provider.revoked)
+	}
+
+	// A binding that already exists keeps the credentials it was issued.
+	exists = true
+	provider.issueErr = errors.New("vault sealed")
+	if _, err := s.Client.Bind(bind); err != nil {
+		t.Errorf("Expected no credentials to be issued for an existing binding, got %v", err)
+	}
+
+	exists = false
+	if _, err := s.Client.Bind(bind); err == nil {
+		t.Error("Expected a failure to issue credentials to fail the bind")
+	}
+}
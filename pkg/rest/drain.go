@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+const (
+	drainingErrorMessage     = "Draining"
+	drainingErrorDescription = "The broker is draining and does not accept new operations; retry against another instance of the broker."
+
+	// defaultDrainRetryAfter is the Retry-After advertised to platforms whose
+	// requests are rejected while draining.
+	defaultDrainRetryAfter = 30 * time.Second
+)
+
+// drainState tracks whether an APISurface is draining and which asynchronous
+// operations it has accepted that have not yet been observed to complete.
+type drainState struct {
+	mutex    sync.Mutex
+	draining bool
+	inFlight map[string]struct{}
+}
+
+// Drain puts the APISurface into draining mode: new provision, update and
+// bind requests are rejected with a 503 and a Retry-After header, while
+// deprovision, unbind and last operation requests continue to be served so
+// that in-flight asynchronous operations can complete.
+func (s *APISurface) Drain() {
+	s.drain.mutex.Lock()
+	defer s.drain.mutex.Unlock()
+	s.drain.draining = true
+}
+
+// Draining returns whether the APISurface is in draining mode.
+func (s *APISurface) Draining() bool {
+	s.drain.mutex.Lock()
+	defer s.drain.mutex.Unlock()
+	return s.drain.draining
+}
+
+// InFlightOperations returns the number of asynchronous operations accepted
+// by this APISurface that have not completed. Operations run by the job
+// manager are counted until their job finishes; for other asynchronous
+// operations, the APISurface can only tell they completed once a platform
+// polls a terminal state.
+func (s *APISurface) InFlightOperations() int {
+	s.drain.mutex.Lock()
+	n := len(s.drain.inFlight)
+	s.drain.mutex.Unlock()
+
+	if s.Jobs != nil {
+		n += s.Jobs.ActiveOperations()
+	}
+	return n
+}
+
+// rejectIfDraining writes a 503 response with a Retry-After header and
+// returns true if the APISurface is draining.
+func (s *APISurface) rejectIfDraining(w http.ResponseWriter, r *http.Request) bool {
+	if !s.Draining() {
+		return false
+	}
+
+	retryAfter := s.DrainRetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultDrainRetryAfter
+	}
+	setRetryAfter(w, retryAfter)
+	s.writeError(w, r, osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr(drainingErrorMessage),
+		Description:  strPtr(drainingErrorDescription),
+	}, http.StatusServiceUnavailable)
+
+	return true
+}
+
+// InstanceOperationKey returns the key identifying asynchronous operations on
+// a service instance.
+func InstanceOperationKey(instanceID string) string {
+	return "instance/" + instanceID
+}
+
+// BindingOperationKey returns the key identifying asynchronous operations on
+// a binding.
+func BindingOperationKey(instanceID, bindingID string) string {
+	return "binding/" + instanceID + "/" + bindingID
+}
+
+// trackOperation records an accepted asynchronous operation.
+func (s *APISurface) trackOperation(key string) {
+	s.drain.mutex.Lock()
+	defer s.drain.mutex.Unlock()
+	if s.drain.inFlight == nil {
+		s.drain.inFlight = map[string]struct{}{}
+	}
+	s.drain.inFlight[key] = struct{}{}
+}
+
+// untrackOperation forgets an asynchronous operation once a platform has
+// observed it in a terminal state.
+func (s *APISurface) untrackOperation(key string) {
+	s.drain.mutex.Lock()
+	defer s.drain.mutex.Unlock()
+	delete(s.drain.inFlight, key)
+}
+
+// isTerminalState returns whether the given last operation state ends an
+// asynchronous operation.
+func isTerminalState(state osb.LastOperationState) bool {
+	return state == osb.StateSucceeded || state == osb.StateFailed
+}
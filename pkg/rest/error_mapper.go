@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// ErrorMapper translates errors returned by the business logic, such as
+// sql.ErrNoRows or a cloud SDK's quota error, into the OSB error sent to the
+// platform. The APISurface consults its ErrorMappers, in order, for every
+// error that isn't already an osb.HTTPStatusCodeError; errors no mapper
+// translates are sent with the operation's default status code, usually a
+// 500.
+type ErrorMapper interface {
+	// MapError returns the OSB error for err, or nil if it doesn't
+	// translate err.
+	MapError(err error) *osb.HTTPStatusCodeError
+}
+
+// ErrorMapperFunc adapts a func to an ErrorMapper.
+type ErrorMapperFunc func(err error) *osb.HTTPStatusCodeError
+
+// MapError calls f.
+func (f ErrorMapperFunc) MapError(err error) *osb.HTTPStatusCodeError {
+	return f(err)
+}
+
+// ErrorRegistry is an ErrorMapper translating registered errors, matched
+// with errors.Is, into a status code and OSB error identifier. The error's
+// message is sent as the description.
+type ErrorRegistry struct {
+	entries []errorRegistryEntry
+}
+
+type errorRegistryEntry struct {
+	target       error
+	statusCode   int
+	errorMessage string
+}
+
+// Register maps errors matching target to statusCode and errorMessage, the
+// OSB error identifier, which may be empty. Errors are matched in
+// registration order.
+func (r *ErrorRegistry) Register(target error, statusCode int, errorMessage string) {
+	r.entries = append(r.entries, errorRegistryEntry{
+		target:       target,
+		statusCode:   statusCode,
+		errorMessage: errorMessage,
+	})
+}
+
+// MapError returns the OSB error registered for err, or nil.
+func (r *ErrorRegistry) MapError(err error) *osb.HTTPStatusCodeError {
+	for _, entry := range r.entries {
+		if !errors.Is(err, entry.target) {
+			continue
+		}
+		mapped := &osb.HTTPStatusCodeError{
+			StatusCode:  entry.statusCode,
+			Description: strPtr(err.Error()),
+		}
+		if entry.errorMessage != "" {
+			mapped.ErrorMessage = strPtr(entry.errorMessage)
+		}
+		return mapped
+	}
+	return nil
+}
+
+// mapError returns the OSB error the ErrorMappers translate err into, or nil.
+func (s *APISurface) mapError(err error) *osb.HTTPStatusCodeError {
+	for _, mapper := range s.ErrorMappers {
+		if mapped := mapper.MapError(err); mapped != nil {
+			return mapped
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package rest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+var errQuotaExceeded = errors.New("quota exceeded")
+
+func TestErrorMappers(t *testing.T) {
+	var logicErr error
+	registry := &rest.ErrorRegistry{}
+	registry.Register(sql.ErrNoRows, http.StatusGone, "")
+	registry.Register(errQuotaExceeded, http.StatusUnprocessableEntity, "QuotaExceeded")
+
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			return nil, logicErr
+		},
+	}, func(api *rest.APISurface) {
+		api.ErrorMappers = []rest.ErrorMapper{
+			registry,
+			rest.ErrorMapperFunc(func(err error) *osb.HTTPStatusCodeError {
+				if err.Error() == "throttled" {
+					return &osb.HTTPStatusCodeError{StatusCode: http.StatusTooManyRequests}
+				}
+				return nil
+			}),
+		}
+	})
+
+	cases := []struct {
+		name         string
+		err          error
+		code         int
+		errorMessage string
+	}{
+		{name: "wrapped sentinel", err: fmt.Errorf("loading instance: %w", sql.ErrNoRows), code: http.StatusGone},
+		{name: "identifier", err: errQuotaExceeded, code: http.StatusUnprocessableEntity, errorMessage: "QuotaExceeded"},
+		{name: "func", err: errors.New("throttled"), code: http.StatusTooManyRequests},
+		{name: "unmapped", err: errors.New("boom"), code: http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		logicErr = tc.err
+		request, err := http.NewRequest("GET", s.URL+"/v2/service_instances/instance/last_operation", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := struct {
+			Error string `json:"error"`
+		}{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if e, a := tc.code, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, e, a)
+		}
+		if e, a := tc.errorMessage, body.Error; e != a {
+			t.Errorf("%v: unexpected error identifier; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
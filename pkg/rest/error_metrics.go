@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Error classes of error responses without an OSB error code.
+const (
+	// ErrorClassValidationFailed is the class of 400, 412 and 422 errors.
+	ErrorClassValidationFailed = "validation_failed"
+	// ErrorClassInternal is the class of 5xx errors.
+	ErrorClassInternal = "internal"
+)
+
+// errorClass returns the class an error response is counted under: its OSB
+// error code if it has one, otherwise ErrorClassInternal for 5xx errors,
+// ErrorClassValidationFailed for requests the broker refused to process
+// and the snake-cased status text, such as "not_found", for the others.
+func errorClass(code int, errorMessage *string) string {
+	switch {
+	case errorMessage != nil && *errorMessage != "":
+		return *errorMessage
+	case code >= http.StatusInternalServerError:
+		return ErrorClassInternal
+	case code == http.StatusBadRequest, code == http.StatusPreconditionFailed, code == http.StatusUnprocessableEntity:
+		return ErrorClassValidationFailed
+	}
+	text := http.StatusText(code)
+	if text == "" {
+		return ErrorClassInternal
+	}
+	return strings.ToLower(strings.Replace(text, " ", "_", -1))
+}
+
+// countError counts an error response in the Errors metric.
+func (s *APISurface) countError(r *http.Request, code int, errorMessage *string) {
+	s.Metrics.Errors.WithLabelValues(operationName(r), errorClass(code, errorMessage)).Inc()
+}
@@ -0,0 +1,68 @@
+package rest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestErrorMetrics(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return nil, broker.ErrAsyncRequired
+		},
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			return nil, errors.New("database unavailable")
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			return nil, broker.ErrBindingGone
+		},
+	})
+
+	s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	})
+	s.Client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "instance"})
+	request, err := http.NewRequest(http.MethodDelete, s.URL+"/v2/service_instances/instance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(osb.APIVersionHeader, "2.13")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	s.Client.Unbind(&osb.UnbindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"})
+
+	cases := []struct {
+		operation string
+		class     string
+	}{
+		{operation: rest.OperationProvision, class: osb.AsyncErrorMessage},
+		{operation: rest.OperationLastOperation, class: rest.ErrorClassInternal},
+		{operation: rest.OperationDeprovision, class: rest.ErrorClassValidationFailed},
+		{operation: rest.OperationUnbind, class: "gone"},
+	}
+
+	for _, tc := range cases {
+		metric := &dto.Metric{}
+		if err := s.API.Metrics.Errors.WithLabelValues(tc.operation, tc.class).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		if e, a := 1.0, metric.Counter.GetValue(); e != a {
+			t.Errorf("%v %v: unexpected count; expected %v, got %v", tc.operation, tc.class, e, a)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+const (
+	readOnlyErrorMessage     = "ReadOnly"
+	readOnlyErrorDescription = "The broker is in read-only mode and does not accept changes to service instances or bindings."
+)
+
+// newReadOnlyError returns the error written when a mutating operation is
+// requested while the APISurface is in read-only mode.
+func newReadOnlyError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr(readOnlyErrorMessage),
+		Description:  strPtr(readOnlyErrorDescription),
+	}
+}
+
+// newAsyncRequiredError returns the error written when a request that
+// doesn't accept incomplete operations can only be completed
+// asynchronously.
+func newAsyncRequiredError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		ErrorMessage: strPtr(osb.AsyncErrorMessage),
+		Description:  strPtr(osb.AsyncErrorDescription),
+	}
+}
+
+// newQueueFullError returns the error written when the business logic
+// returned a Job but the job manager's queue is full.
+func newQueueFullError() error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr("QueueFull"),
+		Description:  strPtr("The broker has too many operations in progress; retry later."),
+	}
+}
+
+// sentinelError returns the OSB error for a broker package sentinel error in
+// err's chain, or nil. A missing instance is reported as 410 Gone when the
+// platform is deleting something, as the spec requires for deprovision and
+// unbind, and as 404 Not Found otherwise.
+func sentinelError(r *http.Request, err error) *osb.HTTPStatusCodeError {
+	switch {
+	case errors.Is(err, broker.ErrAsyncRequired):
+		httpErr, _ := broker.AsHTTPError(newAsyncRequiredError())
+		return httpErr
+	case errors.Is(err, broker.ErrBindingGone):
+		return &osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusGone,
+			Description: strPtr(err.Error()),
+		}
+	case errors.Is(err, broker.ErrInstanceNotFound):
+		code := http.StatusNotFound
+		if r.Method == http.MethodDelete {
+			code = http.StatusGone
+		}
+		return &osb.HTTPStatusCodeError{
+			StatusCode:  code,
+			Description: strPtr(err.Error()),
+		}
+	}
+	return nil
+}
+
+// isGone returns whether err reports that the instance or binding a request
+// is for doesn't exist.
+func isGone(err error) bool {
+	if httpErr, ok := broker.AsHTTPError(err); ok {
+		return httpErr.StatusCode == http.StatusGone
+	}
+	return errors.Is(err, broker.ErrInstanceNotFound) || errors.Is(err, broker.ErrBindingGone)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
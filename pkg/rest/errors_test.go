@@ -0,0 +1,104 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestWrappedErrors(t *testing.T) {
+	var logicErr error
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+			return nil, logicErr
+		},
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			return nil, logicErr
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			return nil, logicErr
+		},
+	}, func(api *rest.APISurface) {})
+
+	const (
+		deprovision   = "DELETE /v2/service_instances/instance?service_id=service&plan_id=plan"
+		lastOperation = "GET /v2/service_instances/instance/last_operation"
+		unbind        = "DELETE /v2/service_instances/instance/service_bindings/binding?service_id=service&plan_id=plan"
+	)
+	conflict := "Conflict"
+
+	cases := []struct {
+		name         string
+		request      string
+		err          error
+		code         int
+		errorMessage string
+	}{
+		{
+			name:         "wrapped OSB error",
+			request:      lastOperation,
+			err:          fmt.Errorf("polling: %w", osb.HTTPStatusCodeError{StatusCode: http.StatusConflict, ErrorMessage: &conflict}),
+			code:         http.StatusConflict,
+			errorMessage: conflict,
+		},
+		{
+			name:    "instance not found",
+			request: lastOperation,
+			err:     fmt.Errorf("instance %q: %w", "instance", broker.ErrInstanceNotFound),
+			code:    http.StatusNotFound,
+		},
+		{
+			name:    "instance not found on deprovision",
+			request: deprovision,
+			err:     broker.ErrInstanceNotFound,
+			code:    http.StatusGone,
+		},
+		{
+			name:    "binding gone",
+			request: unbind,
+			err:     fmt.Errorf("binding %q: %w", "binding", broker.ErrBindingGone),
+			code:    http.StatusGone,
+		},
+		{
+			name:         "async required",
+			request:      deprovision,
+			err:          fmt.Errorf("deleting backups: %w", broker.ErrAsyncRequired),
+			code:         http.StatusUnprocessableEntity,
+			errorMessage: osb.AsyncErrorMessage,
+		},
+	}
+
+	for _, tc := range cases {
+		logicErr = tc.err
+		var method, path string
+		fmt.Sscan(tc.request, &method, &path)
+		request, err := http.NewRequest(method, s.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := struct {
+			Error string `json:"error"`
+		}{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if e, a := tc.code, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, e, a)
+		}
+		if e, a := tc.errorMessage, body.Error; e != a {
+			t.Errorf("%v: unexpected error identifier; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
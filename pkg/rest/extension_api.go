@@ -0,0 +1,173 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// ExtensionAPI is an API beyond the OSB API served by the APISurface, such as
+// the extension APIs of OSB 2.15. It is advertised in the extension_apis of
+// the catalog entries of ServiceIDs, and its Operations are routed like the
+// OSB operations: they are named, so middleware installed on the OSB routes
+// (authentication, rate limiting) covers them, and the APISurface validates
+// the API version, counts them in the actions metric, admits them and
+// formats their errors.
+type ExtensionAPI struct {
+	broker.ExtensionAPI
+
+	// ServiceIDs are the IDs of the services advertising the API.
+	ServiceIDs []string
+	// Operations are the operations of the API.
+	Operations []ExtensionOperation
+}
+
+// ExtensionOperation is an operation of an ExtensionAPI.
+type ExtensionOperation struct {
+	// Name identifies the operation in routes, metrics and per-operation
+	// configuration. It must not collide with the OSB operation names.
+	Name string
+	// Method is the HTTP method of the operation.
+	Method string
+	// Path is the mux path template the operation is served at, such as
+	// /v2/service_instances/{instance_id}/backups.
+	Path string
+	// Handler contains the business logic of the operation.
+	Handler ExtensionHandler
+}
+
+// ExtensionRequest is a request to an ExtensionOperation.
+type ExtensionRequest struct {
+	// InstanceID is the instance_id route variable, if any.
+	InstanceID string
+	// Vars are the route variables.
+	Vars map[string]string
+	// Body is the JSON request body. It is nil for an empty body.
+	Body json.RawMessage
+	// OriginatingIdentity is the identity on the platform of the user
+	// making the request, if the platform sent it.
+	OriginatingIdentity *osb.OriginatingIdentity
+}
+
+// ExtensionResponse is the response of an ExtensionOperation.
+type ExtensionResponse struct {
+	// StatusCode is the HTTP status code of the response. It defaults to
+	// 200.
+	StatusCode int
+	// Body is marshaled as the JSON body of the response.
+	Body interface{}
+}
+
+// ExtensionHandler contains the business logic of an ExtensionOperation. An
+// osb.HTTPStatusCodeError is written with its status code; any other error
+// is written as a 500.
+type ExtensionHandler func(request *ExtensionRequest, c *broker.RequestContext) (*ExtensionResponse, error)
+
+// ExtensionOperationHandler returns the mux handler serving op.
+func (s *APISurface) ExtensionOperationHandler(op ExtensionOperation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.Metrics.Actions.WithLabelValues(op.Name).Inc()
+
+		version := getBrokerAPIVersionFromRequest(r)
+		if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
+			s.writeError(w, r, err, http.StatusPreconditionFailed)
+			return
+		}
+
+		s.limitRequestBody(w, r)
+		request, err := unpackExtensionRequest(r)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		broker.NewRequestLogger(r).V(4).Infof("Received %s request", op.Name)
+
+		c := &broker.RequestContext{
+			Writer:  w,
+			Request: r,
+		}
+		r = withRequestContext(r, c)
+
+		done, err := s.admit(w, r, op.Name)
+		if err != nil {
+			s.writeError(w, r, err, http.StatusServiceUnavailable)
+			return
+		}
+
+		var response *ExtensionResponse
+		err = invoke(done, func() (err error) {
+			response, err = op.Handler(request, c)
+			return err
+		})
+		if err != nil {
+			s.writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		var body interface{}
+		if response != nil {
+			body = response.Body
+			if response.StatusCode != 0 {
+				status = response.StatusCode
+			}
+		}
+
+		s.writeResponse(w, r, status, body)
+	}
+}
+
+// unpackExtensionRequest unpacks an extension request from the given HTTP
+// request.
+func unpackExtensionRequest(r *http.Request) (*ExtensionRequest, error) {
+	request := &ExtensionRequest{}
+
+	request.Vars = mux.Vars(r)
+	request.InstanceID = request.Vars[osb.VarKeyInstanceID]
+
+	if r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, bodyTooLarge(err)
+		}
+		if len(body) > 0 {
+			if !json.Valid(body) {
+				return nil, osb.HTTPStatusCodeError{
+					StatusCode:  http.StatusBadRequest,
+					Description: strPtr("The request body is not valid JSON."),
+				}
+			}
+			request.Body = body
+		}
+	}
+
+	identity, err := retrieveOriginatingIdentity(r)
+	// This could be not found because platforms may support the feature
+	// but are not guaranteed to.
+	if err != nil {
+		glog.Infof("Unable to retrieve originating identity - %v", err)
+	}
+	request.OriginatingIdentity = identity
+
+	return request, nil
+}
+
+// advertiseExtensionAPIs adds the APISurface's extension APIs to the
+// catalog entries of their services.
+func (s *APISurface) advertiseExtensionAPIs(catalog *broker.CatalogResponse) {
+	for _, api := range s.Extensions() {
+		for _, serviceID := range api.ServiceIDs {
+			if catalog.ExtensionAPIs == nil {
+				catalog.ExtensionAPIs = map[string][]broker.ExtensionAPI{}
+			}
+			catalog.ExtensionAPIs[serviceID] = append(catalog.ExtensionAPIs[serviceID], api.ExtensionAPI)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestExtensionAPIs(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{ID: "db", Name: "db"}, {ID: "cache", Name: "cache"}}
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.ExtensionAPIs = []rest.ExtensionAPI{{
+			ExtensionAPI: broker.ExtensionAPI{
+				DiscoveryURL: "/v2/extensions/backups/openapi.json",
+				AdheresTo:    "urn:example:backups",
+			},
+			ServiceIDs: []string{"db"},
+			Operations: []rest.ExtensionOperation{{
+				Name:   "create_backup",
+				Method: "POST",
+				Path:   "/v2/service_instances/{instance_id}/backups",
+				Handler: func(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+					if request.InstanceID == "missing" {
+						return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+					}
+					return &rest.ExtensionResponse{
+						StatusCode: http.StatusCreated,
+						Body:       map[string]interface{}{"instance_id": request.InstanceID, "request": request.Body},
+					}, nil
+				},
+			}},
+		}}
+	})
+
+	resp, err := http.Get(s.URL + "/v2/catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var catalog struct {
+		Services []struct {
+			ID            string                `json:"id"`
+			ExtensionAPIs []broker.ExtensionAPI `json:"extension_apis"`
+		} `json:"services"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&catalog)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, len(catalog.Services[0].ExtensionAPIs); e != a {
+		t.Fatalf("Expected db to advertise the extension API; expected %v, got %v", e, a)
+	}
+	if e, a := "urn:example:backups", catalog.Services[0].ExtensionAPIs[0].AdheresTo; e != a {
+		t.Errorf("Unexpected adheres_to; expected %v, got %v", e, a)
+	}
+	if e, a := 0, len(catalog.Services[1].ExtensionAPIs); e != a {
+		t.Errorf("Expected cache not to advertise the extension API; expected %v, got %v", e, a)
+	}
+
+	post := func(instanceID, body string) (int, string) {
+		request, err := http.NewRequest("POST", s.URL+"/v2/service_instances/"+instanceID+"/backups", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.15")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	status, body := post("instance", `{"name":"nightly"}`)
+	if e, a := http.StatusCreated, status; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := `{"instance_id":"instance","request":{"name":"nightly"}}`, body; e != a {
+		t.Errorf("Unexpected body; expected %v, got %v", e, a)
+	}
+
+	if status, _ := post("missing", ""); status != http.StatusNotFound {
+		t.Errorf("Unexpected status code; expected %v, got %v", http.StatusNotFound, status)
+	}
+	if status, _ := post("instance", "{"); status != http.StatusBadRequest {
+		t.Errorf("Unexpected status code for a malformed body; expected %v, got %v", http.StatusBadRequest, status)
+	}
+
+	m := &dto.Metric{}
+	if err := s.API.Metrics.Actions.WithLabelValues("create_backup").Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 3.0, m.GetCounter().GetValue(); e != a {
+		t.Errorf("Unexpected actions count; expected %v, got %v", e, a)
+	}
+}
+
+func TestAddExtension(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{ID: "db", Name: "db"}}
+			return response, nil
+		},
+	})
+
+	restore := func() int {
+		t.Helper()
+		request, err := http.NewRequest("POST", s.URL+"/v2/service_instances/instance/restores", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.15")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if e, a := http.StatusNotFound, restore(); e != a {
+		t.Fatalf("Unexpected status before the extension is added; expected %v, got %v", e, a)
+	}
+
+	extension := rest.ExtensionAPI{
+		ExtensionAPI: broker.ExtensionAPI{DiscoveryURL: "/v2/extensions/restores/openapi.json"},
+		ServiceIDs:   []string{"db"},
+		Operations: []rest.ExtensionOperation{{
+			Name:   "restore",
+			Method: "POST",
+			Path:   "/v2/service_instances/{instance_id}/restores",
+			Handler: func(request *rest.ExtensionRequest, c *broker.RequestContext) (*rest.ExtensionResponse, error) {
+				return &rest.ExtensionResponse{StatusCode: http.StatusAccepted}, nil
+			},
+		}},
+	}
+	if err := s.API.AddExtension(extension); err != nil {
+		t.Fatalf("Unexpected error adding the extension: %v", err)
+	}
+	if e, a := http.StatusAccepted, restore(); e != a {
+		t.Fatalf("Unexpected status once the extension is added; expected %v, got %v", e, a)
+	}
+	metric := &dto.Metric{}
+	if err := s.API.Metrics.Actions.WithLabelValues("restore").Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected action count of the added operation; expected %v, got %v", e, a)
+	}
+
+	if _, err := s.Client.GetCatalog(); err != nil {
+		t.Fatal(err)
+	}
+	if body := s.LastResponse().Body; !strings.Contains(string(body), "/v2/extensions/restores/openapi.json") {
+		t.Errorf("Expected the catalog to advertise the added extension, got %s", body)
+	}
+
+	if err := s.API.AddExtension(extension); err == nil {
+		t.Error("Expected an error adding an operation name twice")
+	}
+	extension.Operations[0].Name = rest.OperationProvision
+	if err := s.API.AddExtension(extension); err == nil {
+		t.Error("Expected an error adding an operation named like an OSB operation")
+	}
+}
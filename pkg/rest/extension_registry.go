@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// osbOperations are the names of the OSB operations, which extension
+// operations must not reuse.
+var osbOperations = map[string]bool{
+	OperationGetCatalog:           true,
+	OperationProvision:            true,
+	OperationDeprovision:          true,
+	OperationLastOperation:        true,
+	OperationBind:                 true,
+	OperationGetBinding:           true,
+	OperationBindingLastOperation: true,
+	OperationUnbind:               true,
+	OperationUpdate:               true,
+}
+
+// extensionState holds the ExtensionAPIs added with AddExtension and the
+// router serving their operations, which is rebuilt on every addition.
+type extensionState struct {
+	mutex  sync.RWMutex
+	added  []ExtensionAPI
+	router *mux.Router
+}
+
+// AddExtension adds an ExtensionAPI after the APISurface is built, for
+// example once a feature flag evaluated at runtime enables it. It may be
+// called while serving: servers built with server.New route requests to the
+// operations of added extensions through MatchExtension, and the catalog
+// advertises them from the next request on. It returns an error if an
+// operation is incomplete or its name is already taken.
+func (s *APISurface) AddExtension(ext ExtensionAPI) error {
+	s.extensions.mutex.Lock()
+	defer s.extensions.mutex.Unlock()
+
+	names := map[string]bool{}
+	for _, api := range append(append([]ExtensionAPI{}, s.ExtensionAPIs...), s.extensions.added...) {
+		for _, op := range api.Operations {
+			names[op.Name] = true
+		}
+	}
+	for _, op := range ext.Operations {
+		switch {
+		case op.Name == "" || op.Method == "" || op.Path == "" || op.Handler == nil:
+			return fmt.Errorf("rest: extension operation %q needs a name, method, path and handler", op.Name)
+		case osbOperations[op.Name]:
+			return fmt.Errorf("rest: extension operation %q collides with an OSB operation", op.Name)
+		case names[op.Name]:
+			return fmt.Errorf("rest: extension operation %q is already registered", op.Name)
+		}
+		names[op.Name] = true
+	}
+
+	s.extensions.added = append(s.extensions.added, ext)
+	router := mux.NewRouter()
+	for _, api := range s.extensions.added {
+		for _, op := range api.Operations {
+			router.HandleFunc(op.Path, s.ExtensionOperationHandler(op)).Methods(op.Method).Name(op.Name)
+		}
+	}
+	s.extensions.router = router
+	return nil
+}
+
+// Extensions returns the ExtensionAPIs served by the APISurface: the
+// ExtensionAPIs field followed by the extensions added with AddExtension.
+func (s *APISurface) Extensions() []ExtensionAPI {
+	s.extensions.mutex.RLock()
+	defer s.extensions.mutex.RUnlock()
+	return append(append([]ExtensionAPI{}, s.ExtensionAPIs...), s.extensions.added...)
+}
+
+// MatchExtension is a mux.MatcherFunc matching requests for the operations
+// of extensions added with AddExtension. On a match, the route and handler
+// of the operation are set on match, so the request is served and named as
+// if the operation had been registered on the router up front.
+func (s *APISurface) MatchExtension(r *http.Request, match *mux.RouteMatch) bool {
+	s.extensions.mutex.RLock()
+	router := s.extensions.router
+	s.extensions.mutex.RUnlock()
+	if router == nil {
+		return false
+	}
+
+	// A failed match leaves errors on the RouteMatch that would confuse
+	// the routes tried after this one.
+	var inner mux.RouteMatch
+	if !router.Match(r, &inner) || inner.MatchErr != nil {
+		return false
+	}
+	*match = inner
+	return true
+}
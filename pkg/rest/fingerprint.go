@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+// provisionFingerprint holds the fields of a provision request that decide
+// whether a retry is identical.
+type provisionFingerprint struct {
+	ServiceID        string                 `json:"service_id"`
+	PlanID           string                 `json:"plan_id"`
+	OrganizationGUID string                 `json:"organization_guid"`
+	SpaceGUID        string                 `json:"space_guid"`
+	Parameters       map[string]interface{} `json:"parameters"`
+	Context          map[string]interface{} `json:"context"`
+}
+
+func hashProvisionRequest(request *osb.ProvisionRequest) (string, error) {
+	return hashRequest(&provisionFingerprint{
+		ServiceID:        request.ServiceID,
+		PlanID:           request.PlanID,
+		OrganizationGUID: request.OrganizationGUID,
+		SpaceGUID:        request.SpaceGUID,
+		Parameters:       request.Parameters,
+		Context:          request.Context,
+	})
+}
+
+// bindFingerprint holds the fields of a bind request that decide whether a
+// retry is identical.
+type bindFingerprint struct {
+	ServiceID    string                 `json:"service_id"`
+	PlanID       string                 `json:"plan_id"`
+	AppGUID      *string                `json:"app_guid"`
+	BindResource *osb.BindResource      `json:"bind_resource"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	Context      map[string]interface{} `json:"context"`
+}
+
+func hashBindRequest(request *osb.BindRequest) (string, error) {
+	return hashRequest(&bindFingerprint{
+		ServiceID:    request.ServiceID,
+		PlanID:       request.PlanID,
+		AppGUID:      request.AppGUID,
+		BindResource: request.BindResource,
+		Parameters:   request.Parameters,
+		Context:      request.Context,
+	})
+}
+
+// hashRequest returns a hash of the JSON encoding of v. Map keys are encoded
+// in sorted order, so equal requests have equal hashes.
+func hashRequest(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayFingerprint answers a retry of a request fingerprinted under key:
+// an identical retry gets the stored response, with a 200 if the original
+// request completed synchronously, and a conflicting one gets a 409. It
+// returns false if no fingerprint was stored, in which case the request must
+// be handled.
+func (s *APISurface) replayFingerprint(w http.ResponseWriter, r *http.Request, key, hash string) bool {
+	fp, err := s.Fingerprints.GetFingerprint(key)
+	if err == storage.ErrNotFound {
+		return false
+	}
+	if err != nil {
+		glog.Errorf("Error reading fingerprint %q: %v", key, err)
+		return false
+	}
+
+	if fp.Hash != hash {
+		s.writeError(w, r, osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusConflict,
+			Description: strPtr("A request with different attributes was already accepted for this resource."),
+		}, http.StatusConflict)
+		return true
+	}
+
+	status := fp.StatusCode
+	if status == http.StatusCreated {
+		status = http.StatusOK
+	}
+	s.writeResponse(w, r, status, json.RawMessage(fp.Response))
+	return true
+}
+
+// storeFingerprint records the response to a request that created the
+// resource identified by key.
+func (s *APISurface) storeFingerprint(key, hash string, status int, response interface{}) {
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = s.Fingerprints.PutFingerprint(&storage.Fingerprint{
+			Key:        key,
+			Hash:       hash,
+			StatusCode: status,
+			Response:   data,
+			Created:    time.Now(),
+		})
+	}
+	if err != nil {
+		glog.Errorf("Error storing fingerprint %q: %v", key, err)
+	}
+}
+
+// forgetFingerprint deletes the fingerprint of a deleted resource, so that it
+// can be created again with different attributes.
+func (s *APISurface) forgetFingerprint(key string) {
+	if err := s.Fingerprints.DeleteFingerprint(key); err != nil {
+		glog.Errorf("Error deleting fingerprint %q: %v", key, err)
+	}
+}
@@ -0,0 +1,125 @@
+package rest_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+func TestProvisionFingerprints(t *testing.T) {
+	calls := 0
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			calls++
+			dashboardURL := "https://dashboard/" + request.InstanceID
+			response := &broker.ProvisionResponse{}
+			response.DashboardURL = &dashboardURL
+			return response, nil
+		},
+		DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+			return &broker.DeprovisionResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Fingerprints = storage.NewMemory()
+	})
+
+	request := &osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+		Parameters:       map[string]interface{}{"size": "small"},
+	}
+	if _, err := s.Client.ProvisionInstance(request); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := http.StatusCreated, s.LastResponse().StatusCode; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	response, err := s.Client.ProvisionInstance(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := http.StatusOK, s.LastResponse().StatusCode; e != a {
+		t.Errorf("Expected an identical retry to get a 200; expected %v, got %v", e, a)
+	}
+	if response.DashboardURL == nil || *response.DashboardURL != "https://dashboard/instance" {
+		t.Errorf("Expected the stored response to be replayed, got %+v", response)
+	}
+	if e, a := 1, calls; e != a {
+		t.Errorf("Expected the business logic to be invoked once; expected %v, got %v", e, a)
+	}
+
+	conflicting := *request
+	conflicting.Parameters = map[string]interface{}{"size": "large"}
+	if _, err := s.Client.ProvisionInstance(&conflicting); !osb.IsConflictError(err) {
+		t.Fatalf("Expected a conflicting retry to get a 409, got %v", err)
+	}
+
+	if _, err := s.Client.DeprovisionInstance(&osb.DeprovisionRequest{InstanceID: "instance", ServiceID: "service", PlanID: "plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.ProvisionInstance(&conflicting); err != nil {
+		t.Fatalf("Expected a deprovisioned instance to be provisioned again, got %v", err)
+	}
+}
+
+func TestBindFingerprints(t *testing.T) {
+	calls := 0
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			calls++
+			response := &broker.BindResponse{}
+			response.Credentials = map[string]interface{}{"password": fmt.Sprintf("generated-%d", calls)}
+			return response, nil
+		},
+		UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+			return &broker.UnbindResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Fingerprints = storage.NewMemory()
+	})
+
+	request := &osb.BindRequest{
+		InstanceID: "instance",
+		BindingID:  "binding",
+		ServiceID:  "service",
+		PlanID:     "plan",
+	}
+	if _, err := s.Client.Bind(request); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := s.Client.Bind(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := http.StatusOK, s.LastResponse().StatusCode; e != a {
+		t.Errorf("Expected an identical retry to get a 200; expected %v, got %v", e, a)
+	}
+	if e, a := "generated-1", response.Credentials["password"]; e != a {
+		t.Errorf("Expected the original credentials to be returned; expected %v, got %v", e, a)
+	}
+
+	conflicting := *request
+	conflicting.PlanID = "other"
+	if _, err := s.Client.Bind(&conflicting); !osb.IsConflictError(err) {
+		t.Fatalf("Expected a conflicting retry to get a 409, got %v", err)
+	}
+
+	if _, err := s.Client.Unbind(&osb.UnbindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.Bind(&conflicting); err != nil {
+		t.Fatalf("Expected an unbound binding to be created again, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func newFuzzRequest(method, uri string, body []byte, identity string, vars map[string]string) *http.Request {
+	r := httptest.NewRequest(method, "/", bytes.NewReader(body))
+	// Set the raw query directly: fuzzed values are not valid URLs.
+	r.URL.RawQuery = uri
+	if identity != "" {
+		r.Header.Set(osb.OriginatingIdentityHeader, identity)
+	}
+	return mux.SetURLVars(r, vars)
+}
+
+func FuzzUnpackProvisionRequest(f *testing.F) {
+	f.Add([]byte(`{"service_id":"s","plan_id":"p"}`), "accepts_incomplete=true", "kubernetes eyJ1c2VybmFtZSI6ImZvbyJ9")
+	f.Add([]byte(`{"accepts_incomplete":true,"instance_id":"other"}`), "", "kubernetes  eyJ1c2VybmFtZSI6ImZvbyJ9")
+	f.Add([]byte(`not json`), "accepts_incomplete=%zz", "cloudfoundry !!!")
+
+	f.Fuzz(func(t *testing.T, body []byte, query, identity string) {
+		r := newFuzzRequest(http.MethodPut, query, body, identity, map[string]string{osb.VarKeyInstanceID: "i1"})
+		request, err := unpackProvisionRequest(r)
+		if err != nil {
+			return
+		}
+		if request.InstanceID != "i1" {
+			t.Errorf("instance ID taken from body: %q", request.InstanceID)
+		}
+		if request.AcceptsIncomplete && r.URL.Query().Get(osb.AcceptsIncomplete) == "" {
+			t.Error("accepts_incomplete taken from body")
+		}
+		if request.OriginatingIdentity != nil && request.OriginatingIdentity.Platform == "" {
+			t.Error("originating identity without a platform")
+		}
+	})
+}
+
+func FuzzUnpackBindRequest(f *testing.F) {
+	f.Add([]byte(`{"service_id":"s","plan_id":"p","bind_resource":{"app_guid":"a"}}`), "", "")
+	f.Add([]byte(`{"binding_id":"other","accepts_incomplete":true}`), "accepts_incomplete=TRUE", "kubernetes e30")
+
+	f.Fuzz(func(t *testing.T, body []byte, query, identity string) {
+		vars := map[string]string{osb.VarKeyInstanceID: "i1", osb.VarKeyBindingID: "b1"}
+		r := newFuzzRequest(http.MethodPut, query, body, identity, vars)
+		request, _, err := unpackBindRequest(r)
+		if err != nil {
+			return
+		}
+		if request.InstanceID != "i1" || request.BindingID != "b1" {
+			t.Errorf("IDs taken from body: %q, %q", request.InstanceID, request.BindingID)
+		}
+		if request.AcceptsIncomplete && r.URL.Query().Get(osb.AcceptsIncomplete) == "" {
+			t.Error("accepts_incomplete taken from body")
+		}
+	})
+}
+
+func FuzzRetrieveOriginatingIdentity(f *testing.F) {
+	f.Add("kubernetes eyJ1c2VybmFtZSI6ImZvbyJ9")
+	f.Add("kubernetes   eyJ1c2VybmFtZSI6ImZvbyJ9  ")
+	f.Add("kubernetes eyJ1c2VybmFtZSI6ImZvbyJ9 extra")
+	f.Add("kubernetes e30")
+	f.Add("kubernetes ZHVkZXI=")
+	f.Add(" ")
+
+	f.Fuzz(func(t *testing.T, header string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header[osb.OriginatingIdentityHeader] = []string{header}
+
+		identity, err := retrieveOriginatingIdentity(r)
+		if err != nil {
+			return
+		}
+		if identity.Platform == "" {
+			t.Errorf("empty platform parsed from %q", header)
+		}
+	})
+}
+
+func TestRetrieveOriginatingIdentity(t *testing.T) {
+	cases := []struct {
+		name      string
+		header    string
+		shouldErr bool
+		platform  string
+		value     string
+	}{
+		{
+			name:     "valid",
+			header:   "kubernetes eyJ1c2VybmFtZSI6ImZvbyJ9",
+			platform: "kubernetes",
+			value:    `{"username":"foo"}`,
+		},
+		{
+			name:     "multiple spaces",
+			header:   " kubernetes   eyJ1c2VybmFtZSI6ImZvbyJ9 ",
+			platform: "kubernetes",
+			value:    `{"username":"foo"}`,
+		},
+		{
+			name:     "unpadded",
+			header:   "kubernetes e30",
+			platform: "kubernetes",
+			value:    `{}`,
+		},
+		{
+			name:      "too many fields",
+			header:    "kubernetes eyJ1c2VybmFtZSI6ImZvbyJ9 extra",
+			shouldErr: true,
+		},
+		{
+			name:      "malformed base64",
+			header:    "kubernetes !!!",
+			shouldErr: true,
+		},
+		{
+			name:      "missing",
+			shouldErr: true,
+		},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				r.Header.Set(osb.OriginatingIdentityHeader, tc.header)
+			}
+
+			identity, err := retrieveOriginatingIdentity(r)
+			if tc.shouldErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if e, a := tc.platform, identity.Platform; e != a {
+				t.Errorf("Unexpected platform; expected %v, got %v", e, a)
+			}
+			if e, a := tc.value, identity.Value; e != a {
+				t.Errorf("Unexpected value; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+// InstanceLimitErrorMessage is the error code of the errors returned when an
+// instance cap is reached.
+const InstanceLimitErrorMessage = "InstanceLimitReached"
+
+// InstanceCaps limits the number of service instances of plans and
+// services. The APISurface records the instances it provisions in Store and
+// rejects provision requests, and updates moving an instance to another
+// plan, that would exceed a cap with a 422 InstanceLimitReached error
+// before invoking the business logic.
+//
+// Instances are recorded when their provisioning is accepted and forgotten
+// when their deprovisioning is, so failed asynchronous provisions count
+// until the platform deletes them. Concurrent requests may exceed a cap by
+// the number of requests in flight.
+type InstanceCaps struct {
+	// Store records the instances of the broker.
+	Store storage.InstanceStore
+	// PerPlan is the maximum number of instances of each plan, by plan ID.
+	PerPlan map[string]int
+	// PerService is the maximum number of instances of each service, by
+	// service ID.
+	PerService map[string]int
+}
+
+// check returns an InstanceLimitReached error if adding an instance of the
+// plan would exceed a cap. Instances moving between plans of the same
+// service only count against the plan cap.
+func (caps *InstanceCaps) check(serviceID, planID string, sameService bool) error {
+	if limit, ok := caps.PerPlan[planID]; ok {
+		n, err := caps.Store.CountInstances(serviceID, planID)
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return newInstanceLimitError(fmt.Sprintf("plan %q of service %q is limited to %d instances", planID, serviceID, limit))
+		}
+	}
+	if limit, ok := caps.PerService[serviceID]; ok && !sameService {
+		n, err := caps.Store.CountInstances(serviceID, "")
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return newInstanceLimitError(fmt.Sprintf("service %q is limited to %d instances", serviceID, limit))
+		}
+	}
+	return nil
+}
+
+func newInstanceLimitError(description string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		ErrorMessage: strPtr(InstanceLimitErrorMessage),
+		Description:  strPtr(description),
+	}
+}
+
+// checkProvisionCap checks a provision request against the InstanceCaps.
+// Retries of a recorded provision are not counted twice.
+func (s *APISurface) checkProvisionCap(request *osb.ProvisionRequest) error {
+	if s.InstanceCaps == nil {
+		return nil
+	}
+	_, err := s.InstanceCaps.Store.GetInstance(request.InstanceID)
+	if err == nil {
+		return nil
+	}
+	if err != storage.ErrNotFound {
+		return err
+	}
+	return s.InstanceCaps.check(request.ServiceID, request.PlanID, false)
+}
+
+// checkUpdateCap checks an update moving an instance to planID against the
+// InstanceCaps.
+func (s *APISurface) checkUpdateCap(instanceID, serviceID, planID string) error {
+	if s.InstanceCaps == nil || planID == "" {
+		return nil
+	}
+	instance, err := s.InstanceCaps.Store.GetInstance(instanceID)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	if instance != nil && instance.PlanID == planID {
+		return nil
+	}
+	return s.InstanceCaps.check(serviceID, planID, instance != nil)
+}
+
+// recordInstance records an instance whose provisioning or plan change was
+// accepted. Errors are logged: the operation already happened.
+func (s *APISurface) recordInstance(instanceID, serviceID, planID string) {
+	if s.InstanceCaps == nil || planID == "" {
+		return
+	}
+	instance := &storage.Instance{
+		InstanceID: instanceID,
+		ServiceID:  serviceID,
+		PlanID:     planID,
+		Created:    time.Now(),
+	}
+	if previous, err := s.InstanceCaps.Store.GetInstance(instanceID); err == nil {
+		instance.Created = previous.Created
+	}
+	if err := s.InstanceCaps.Store.PutInstance(instance); err != nil {
+		glog.Errorf("Error recording instance %q: %v", instanceID, err)
+	}
+}
+
+// forgetInstance forgets an instance whose deprovisioning was accepted.
+func (s *APISurface) forgetInstance(instanceID string) {
+	if s.InstanceCaps == nil {
+		return
+	}
+	if err := s.InstanceCaps.Store.DeleteInstance(instanceID); err != nil {
+		glog.Errorf("Error forgetting instance %q: %v", instanceID, err)
+	}
+}
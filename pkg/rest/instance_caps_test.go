@@ -0,0 +1,67 @@
+package rest_test
+
+import (
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+func TestInstanceCaps(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+		api.InstanceCaps = &rest.InstanceCaps{
+			Store:      storage.NewMemory(),
+			PerPlan:    map[string]int{"small": 1},
+			PerService: map[string]int{"db": 2},
+		}
+	})
+
+	provision := func(instanceID, planID string) error {
+		_, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+			InstanceID:       instanceID,
+			ServiceID:        "db",
+			PlanID:           planID,
+			OrganizationGUID: "org",
+			SpaceGUID:        "space",
+		})
+		return err
+	}
+	isLimitError := func(err error) bool {
+		httpErr, ok := osb.IsHTTPError(err)
+		return ok && httpErr.ErrorMessage != nil && *httpErr.ErrorMessage == rest.InstanceLimitErrorMessage
+	}
+
+	if err := provision("i1", "small"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provision("i1", "small"); err != nil {
+		t.Fatalf("Expected a retried provision not to count against the cap, got %v", err)
+	}
+	if err := provision("i2", "small"); !isLimitError(err) {
+		t.Fatalf("Expected the plan cap to be enforced, got %v", err)
+	}
+	if err := provision("i2", "large"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provision("i3", "large"); !isLimitError(err) {
+		t.Fatalf("Expected the service cap to be enforced, got %v", err)
+	}
+
+	small := "small"
+	if _, err := s.Client.UpdateInstance(&osb.UpdateInstanceRequest{InstanceID: "i2", ServiceID: "db", PlanID: &small}); !isLimitError(err) {
+		t.Fatalf("Expected updates to a full plan to be rejected, got %v", err)
+	}
+
+	if _, err := s.Client.DeprovisionInstance(&osb.DeprovisionRequest{InstanceID: "i1", ServiceID: "db", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.UpdateInstance(&osb.UpdateInstanceRequest{InstanceID: "i2", ServiceID: "db", PlanID: &small}); err != nil {
+		t.Fatalf("Expected the update to succeed once the plan has room, got %v", err)
+	}
+	if err := provision("i3", "large"); err != nil {
+		t.Fatalf("Expected the service to have room after a deprovision, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// InstrumentHandler wraps a handler serving operation outside the OSB API,
+// such as a route of a server.FeatureExtender, so that it is treated like
+// the built-in operations: requests are counted in the actions metric,
+// rejected with a 412 if the business logic doesn't accept their API
+// version and logged, and error responses are counted in the errors
+// metric. server.Server.Extend wraps the routes of extenders with it.
+func (s *APISurface) InstrumentHandler(operation string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Metrics.Actions.WithLabelValues(operation).Inc()
+		r = withOperation(r, operation)
+
+		version := getBrokerAPIVersionFromRequest(r)
+		if err := s.Broker.ValidateBrokerAPIVersion(version); err != nil {
+			s.writeError(w, r, err, http.StatusPreconditionFailed)
+			return
+		}
+
+		broker.NewRequestLogger(r).V(4).Infof("Received %s request", operation)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(recorder, r)
+		if recorder.status >= http.StatusBadRequest {
+			s.Metrics.Errors.WithLabelValues(operation, errorClass(recorder.status, nil)).Inc()
+		}
+	})
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
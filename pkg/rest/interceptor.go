@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// ResponseInterceptor is called with every response the APISurface is about
+// to write, after the body has been marshaled and before anything is sent to
+// the client. The operation is the name of the matched route (one of the
+// Operation constants when the router is built by the server package).
+//
+// Interceptors may modify header. Returning an error vetoes the response: if
+// the error is, or wraps, an osb.HTTPStatusCodeError it is written in place
+// of the response, otherwise a 500 is written with the error as description.
+type ResponseInterceptor func(operation string, statusCode int, header http.Header, body []byte) error
+
+// interceptResponse runs the APISurface's ResponseInterceptors and returns
+// the status code and body to write.
+func (s *APISurface) interceptResponse(r *http.Request, code int, header http.Header, body []byte) (int, []byte) {
+	if len(s.ResponseInterceptors) == 0 {
+		return code, body
+	}
+
+	operation := operationName(r)
+
+	for _, intercept := range s.ResponseInterceptors {
+		if err := intercept(operation, code, header, body); err != nil {
+			glog.Infof("response to %q vetoed: %v", operation, err)
+			return vetoedResponse(err)
+		}
+	}
+
+	return code, body
+}
+
+// vetoedResponse returns the status code and body of the error response that
+// replaces a vetoed response.
+func vetoedResponse(err error) (int, []byte) {
+	type e struct {
+		ErrorMessage *string `json:"error,omitempty"`
+		Description  *string `json:"description,omitempty"`
+	}
+
+	code := http.StatusInternalServerError
+	body := &e{Description: strPtr(err.Error())}
+	if httpErr, ok := broker.AsHTTPError(err); ok {
+		code = httpErr.StatusCode
+		body = &e{ErrorMessage: httpErr.ErrorMessage, Description: httpErr.Description}
+	}
+
+	data, _ := json.Marshal(body)
+	return code, data
+}
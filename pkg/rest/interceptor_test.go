@@ -0,0 +1,69 @@
+package rest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestResponseInterceptors(t *testing.T) {
+	var operations []string
+	signer := func(operation string, statusCode int, header http.Header, body []byte) error {
+		operations = append(operations, operation)
+		header.Set("X-Signature", "signed")
+		return nil
+	}
+
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+		api.ResponseInterceptors = append(api.ResponseInterceptors, signer)
+	})
+
+	if _, err := s.Client.GetCatalog(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "signed", s.LastResponse().Header.Get("X-Signature"); e != a {
+		t.Errorf("Unexpected header; expected %q, got %q", e, a)
+	}
+	if len(operations) != 1 || operations[0] != rest.OperationGetCatalog {
+		t.Errorf("Unexpected operations: %v", operations)
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "plain error",
+			err:        errors.New("vetoed"),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "OSB error",
+			err:        osb.HTTPStatusCodeError{StatusCode: http.StatusForbidden},
+			statusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			veto := func(string, int, http.Header, []byte) error { return tt.err }
+			s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+				api.ResponseInterceptors = []rest.ResponseInterceptor{veto}
+			})
+
+			_, err := s.Client.GetCatalog()
+			httpErr, ok := osb.IsHTTPError(err)
+			if !ok {
+				t.Fatalf("Expected an HTTP error, got %v", err)
+			}
+			if e, a := tt.statusCode, httpErr.StatusCode; e != a {
+				t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+)
+
+// InventoryHook returns a LifecycleHook maintaining the Instances and
+// Bindings gauges of m. The gauges are labeled by service, and by plan if
+// perPlan is set. They only count the changes seen since the broker
+// started: brokers should set them from their own records at startup, for
+// example with m.Instances.WithLabelValues(serviceID, planID).Set(n).
+func InventoryHook(m *metrics.OSBMetricsCollector, perPlan bool) LifecycleHook {
+	plan := func(planID string) string {
+		if perPlan {
+			return planID
+		}
+		return ""
+	}
+
+	return func(event LifecycleEvent) {
+		switch event.Type {
+		case InstanceProvisioned:
+			m.Instances.WithLabelValues(event.ServiceID, plan(event.PlanID)).Inc()
+		case InstanceDeprovisioned:
+			m.Instances.WithLabelValues(event.ServiceID, plan(event.PlanID)).Dec()
+		case InstanceUpdated:
+			if !perPlan || event.PreviousPlanID == "" || event.PlanID == event.PreviousPlanID {
+				return
+			}
+			m.Instances.WithLabelValues(event.ServiceID, event.PreviousPlanID).Dec()
+			m.Instances.WithLabelValues(event.ServiceID, event.PlanID).Inc()
+		case BindingCreated:
+			m.Bindings.WithLabelValues(event.ServiceID, plan(event.PlanID)).Inc()
+		case BindingDeleted:
+			m.Bindings.WithLabelValues(event.ServiceID, plan(event.PlanID)).Dec()
+		}
+	}
+}
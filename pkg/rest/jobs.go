@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+// defaultJobsRetryAfter is the Retry-After advertised when the job queue is
+// full and the job manager doesn't set one.
+const defaultJobsRetryAfter = 10 * time.Second
+
+// submitJob hands a Job returned by the business logic to the job manager
+// and returns the key of the operation running it. Jobs can only answer
+// requests that accept incomplete operations; others get a 422
+// AsyncRequired error. If the job queue is full, a Retry-After header is set
+// and a 503 error is returned.
+func (s *APISurface) submitJob(w http.ResponseWriter, r *http.Request, acceptsIncomplete bool, operation, instanceID, bindingID string, job broker.Job) (*osb.OperationKey, error) {
+	if s.Jobs == nil {
+		return nil, fmt.Errorf("the business logic returned a job for %s but no job manager is configured", operation)
+	}
+	if !acceptsIncomplete {
+		return nil, newAsyncRequiredError()
+	}
+	// A platform that has gone never learns the operation key to poll and
+	// retries the request, so the job would run for nobody.
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	key, err := s.Jobs.Submit(operation, instanceID, bindingID, job)
+	if err == jobs.ErrQueueFull {
+		retryAfter := s.Jobs.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = defaultJobsRetryAfter
+		}
+		setRetryAfter(w, retryAfter)
+		return nil, newQueueFullError()
+	}
+	return key, err
+}
+
+// jobLastOperation returns the state of an operation run by the job manager,
+// or storage.ErrNotFound if the operation isn't one of its jobs.
+func (s *APISurface) jobLastOperation(key *osb.OperationKey, instanceID, bindingID string) (*broker.LastOperationResponse, error) {
+	if s.Jobs == nil {
+		return nil, storage.ErrNotFound
+	}
+	return s.Jobs.LastOperation(key, instanceID, bindingID)
+}
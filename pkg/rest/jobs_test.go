@@ -0,0 +1,140 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+func TestJobs(t *testing.T) {
+	manager := jobs.NewManager(storage.NewMemory(), 1)
+	defer manager.Close()
+
+	release := make(chan struct{})
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return &broker.ProvisionResponse{
+				Job: func(ctx context.Context, progress func(string)) error {
+					progress(broker.ProgressDescription("creating database", 40))
+					<-release
+					return nil
+				},
+			}, nil
+		},
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			t.Error("Expected the job manager to answer last operation requests")
+			return &broker.LastOperationResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Jobs = manager
+	})
+
+	request := &osb.ProvisionRequest{
+		InstanceID:       "instance",
+		ServiceID:        "service",
+		PlanID:           "plan",
+		OrganizationGUID: "org",
+		SpaceGUID:        "space",
+	}
+	if _, err := s.Client.ProvisionInstance(request); !osb.IsAsyncRequiredError(err) {
+		t.Fatalf("Expected AsyncRequired, got %v", err)
+	}
+
+	request.AcceptsIncomplete = true
+	response, err := s.Client.ProvisionInstance(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Async {
+		t.Fatal("Expected an asynchronous response")
+	}
+
+	poll := &osb.LastOperationRequest{InstanceID: "instance"}
+	lastOperation, err := s.Client.PollLastOperation(poll)
+	deadline := time.Now().Add(5 * time.Second)
+	for err == nil && lastOperation.Description == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		lastOperation, err = s.Client.PollLastOperation(poll)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := osb.StateInProgress, lastOperation.State; e != a {
+		t.Fatalf("Unexpected state; expected %v, got %v", e, a)
+	}
+	if lastOperation.Description == nil || *lastOperation.Description != "creating database 40%" {
+		t.Fatalf("Expected the job's progress to be surfaced verbatim, got %v", lastOperation.Description)
+	}
+
+	close(release)
+	deadline = time.Now().Add(5 * time.Second)
+	for lastOperation.State != osb.StateSucceeded {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the job; last state %v", lastOperation.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if lastOperation, err = s.Client.PollLastOperation(poll); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestJobsQueueFull(t *testing.T) {
+	manager := jobs.NewManager(storage.NewMemory(), 1)
+	manager.QueueDepth = 1
+	manager.RetryAfter = 5 * time.Second
+	defer manager.Close()
+
+	running := make(chan struct{}, 3)
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return &broker.ProvisionResponse{
+				Job: func(ctx context.Context, progress func(string)) error {
+					running <- struct{}{}
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Jobs = manager
+	})
+
+	provision := func(id string) error {
+		_, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+			InstanceID:        id,
+			ServiceID:         "service",
+			PlanID:            "plan",
+			OrganizationGUID:  "org",
+			SpaceGUID:         "space",
+			AcceptsIncomplete: true,
+		})
+		return err
+	}
+
+	if err := provision("running"); err != nil {
+		t.Fatal(err)
+	}
+	<-running
+	if err := provision("queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := provision("rejected")
+	httpErr, ok := osb.IsHTTPError(err)
+	if !ok || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a 503, got %v", err)
+	}
+	if e, a := "5", s.LastResponse().Header.Get("Retry-After"); e != a {
+		t.Errorf("Unexpected Retry-After; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,139 @@
+package rest_test
+
+import (
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestLastOperationQueryParameters(t *testing.T) {
+	var instanceRequest *osb.LastOperationRequest
+	var bindingRequest *osb.BindingLastOperationRequest
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			instanceRequest = request
+			return &broker.LastOperationResponse{LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress}}, nil
+		},
+		BindingLastOperationFunc: func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			bindingRequest = request
+			return &broker.LastOperationResponse{LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress}}, nil
+		},
+	})
+
+	serviceID, planID, key := "service", "plan", osb.OperationKey("op-1")
+
+	if _, err := s.Client.PollLastOperation(&osb.LastOperationRequest{
+		InstanceID:   "instance",
+		ServiceID:    &serviceID,
+		PlanID:       &planID,
+		OperationKey: &key,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if instanceRequest == nil {
+		t.Fatal("Expected LastOperation to be called")
+	}
+	if e, a := "instance", instanceRequest.InstanceID; e != a {
+		t.Errorf("Unexpected instance ID; expected %v, got %v", e, a)
+	}
+	if instanceRequest.ServiceID == nil || *instanceRequest.ServiceID != serviceID {
+		t.Errorf("Unexpected service ID; expected %v, got %v", serviceID, instanceRequest.ServiceID)
+	}
+	if instanceRequest.PlanID == nil || *instanceRequest.PlanID != planID {
+		t.Errorf("Unexpected plan ID; expected %v, got %v", planID, instanceRequest.PlanID)
+	}
+	if instanceRequest.OperationKey == nil || *instanceRequest.OperationKey != key {
+		t.Errorf("Unexpected operation key; expected %v, got %v", key, instanceRequest.OperationKey)
+	}
+
+	if _, err := s.Client.PollBindingLastOperation(&osb.BindingLastOperationRequest{
+		InstanceID:   "instance",
+		BindingID:    "binding",
+		ServiceID:    &serviceID,
+		PlanID:       &planID,
+		OperationKey: &key,
+		OriginatingIdentity: &osb.OriginatingIdentity{
+			Platform: "kubernetes",
+			Value:    `{"username": "user"}`,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if bindingRequest == nil {
+		t.Fatal("Expected BindingLastOperation to be called")
+	}
+	if e, a := "binding", bindingRequest.BindingID; e != a {
+		t.Errorf("Unexpected binding ID; expected %v, got %v", e, a)
+	}
+	if bindingRequest.OperationKey == nil || *bindingRequest.OperationKey != key {
+		t.Errorf("Unexpected operation key; expected %v, got %v", key, bindingRequest.OperationKey)
+	}
+}
+
+func TestLastOperationRetryAfter(t *testing.T) {
+	var response *broker.LastOperationResponse
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			return response, nil
+		},
+		BindingLastOperationFunc: func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.PollRetryAfter = 5 * time.Second
+	})
+
+	cases := []struct {
+		name     string
+		response broker.LastOperationResponse
+		expected string
+	}{
+		{
+			name: "suggested by the business logic",
+			response: broker.LastOperationResponse{
+				LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress},
+				RetryAfter:            90 * time.Second,
+			},
+			expected: "90",
+		},
+		{
+			name: "default",
+			response: broker.LastOperationResponse{
+				LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress},
+			},
+			expected: "5",
+		},
+		{
+			name: "completed",
+			response: broker.LastOperationResponse{
+				LastOperationResponse: osb.LastOperationResponse{State: osb.StateSucceeded},
+				RetryAfter:            90 * time.Second,
+			},
+			expected: "",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		response = &tc.response
+
+		if _, err := s.Client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "instance"}); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if e, a := tc.expected, s.LastResponse().Header.Get("Retry-After"); e != a {
+			t.Errorf("%v: unexpected Retry-After of the instance; expected %q, got %q", tc.name, e, a)
+		}
+
+		if _, err := s.Client.PollBindingLastOperation(&osb.BindingLastOperationRequest{InstanceID: "instance", BindingID: "binding"}); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if e, a := tc.expected, s.LastResponse().Header.Get("Retry-After"); e != a {
+			t.Errorf("%v: unexpected Retry-After of the binding; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"sync"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// LifecycleEventType is the type of a LifecycleEvent.
+type LifecycleEventType string
+
+// The types of LifecycleEvents.
+const (
+	InstanceProvisioned   LifecycleEventType = "instance_provisioned"
+	InstanceUpdated       LifecycleEventType = "instance_updated"
+	InstanceDeprovisioned LifecycleEventType = "instance_deprovisioned"
+	BindingCreated        LifecycleEventType = "binding_created"
+	BindingDeleted        LifecycleEventType = "binding_deleted"
+)
+
+// LifecycleEvent reports that a service instance or binding was created,
+// updated or deleted.
+type LifecycleEvent struct {
+	Type       LifecycleEventType
+	InstanceID string
+	BindingID  string
+	ServiceID  string
+	PlanID     string
+	// PreviousPlanID is the plan an updated instance had before the
+	// update, if the platform sent it.
+	PreviousPlanID string
+}
+
+// LifecycleHook is called with the LifecycleEvents of an APISurface.
+//
+// Events of synchronous operations are emitted once the business logic
+// succeeds. Events of asynchronous operations are emitted when a platform
+// polls the operation's last operation and it has succeeded, or for
+// deprovisions, when the instance is gone. Only the APISurface that accepted
+// an asynchronous operation emits its event, so events of operations polled
+// through another replica of the broker are lost. Instances or bindings
+// that already existed are not reported again.
+type LifecycleHook func(event LifecycleEvent)
+
+// lifecycleState holds the events of asynchronous operations until they
+// complete.
+type lifecycleState struct {
+	mutex   sync.Mutex
+	pending map[string]LifecycleEvent
+}
+
+// emitLifecycleEvent calls the LifecycleHooks with event, or if async is
+// set, holds it until the operation identified by key completes.
+func (s *APISurface) emitLifecycleEvent(key string, async bool, event LifecycleEvent) {
+	if len(s.LifecycleHooks) == 0 {
+		return
+	}
+	if async {
+		s.lifecycle.mutex.Lock()
+		if s.lifecycle.pending == nil {
+			s.lifecycle.pending = map[string]LifecycleEvent{}
+		}
+		s.lifecycle.pending[key] = event
+		s.lifecycle.mutex.Unlock()
+		return
+	}
+	for _, hook := range s.LifecycleHooks {
+		hook(event)
+	}
+}
+
+// completeLifecycleEvent emits the held event of the asynchronous operation
+// identified by key if the operation is in state succeeded, and forgets it
+// once the operation is terminal. gone reports that the platform was
+// answered 410 Gone, which completes deprovisions.
+func (s *APISurface) completeLifecycleEvent(key string, state osb.LastOperationState, gone bool) {
+	if !gone && !isTerminalState(state) {
+		return
+	}
+
+	s.lifecycle.mutex.Lock()
+	event, ok := s.lifecycle.pending[key]
+	delete(s.lifecycle.pending, key)
+	s.lifecycle.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+	if state == osb.StateSucceeded || (gone && event.Type == InstanceDeprovisioned) {
+		s.emitLifecycleEvent(key, false, event)
+	}
+}
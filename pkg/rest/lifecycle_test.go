@@ -0,0 +1,112 @@
+package rest_test
+
+import (
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	state := osb.StateInProgress
+	var events []rest.LifecycleEvent
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			response := &broker.ProvisionResponse{}
+			response.Async = request.InstanceID == "async"
+			return response, nil
+		},
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			response := &broker.LastOperationResponse{}
+			response.State = state
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.LifecycleHooks = []rest.LifecycleHook{
+			rest.InventoryHook(api.Metrics, true),
+			func(event rest.LifecycleEvent) {
+				events = append(events, event)
+			},
+		}
+	})
+
+	gauge := func(name string) float64 {
+		metric := &dto.Metric{}
+		g := s.API.Metrics.Instances.WithLabelValues("db", "small")
+		if name == "bindings" {
+			g = s.API.Metrics.Bindings.WithLabelValues("db", "small")
+		}
+		if err := g.Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.Gauge.GetValue()
+	}
+	provision := func(instanceID string) {
+		_, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+			InstanceID:        instanceID,
+			AcceptsIncomplete: true,
+			ServiceID:         "db",
+			PlanID:            "small",
+			OrganizationGUID:  "org",
+			SpaceGUID:         "space",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	poll := func() {
+		if _, err := s.Client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "async"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provision("sync")
+	if e, a := 1.0, gauge("instances"); e != a {
+		t.Fatalf("Unexpected instances after a synchronous provision; expected %v, got %v", e, a)
+	}
+
+	provision("async")
+	poll()
+	if e, a := 1.0, gauge("instances"); e != a {
+		t.Fatalf("Expected an in progress provision not to be counted; got %v instances", a)
+	}
+	state = osb.StateSucceeded
+	poll()
+	poll()
+	if e, a := 2.0, gauge("instances"); e != a {
+		t.Fatalf("Unexpected instances after an asynchronous provision succeeded; expected %v, got %v", e, a)
+	}
+
+	if _, err := s.Client.Bind(&osb.BindRequest{InstanceID: "sync", BindingID: "binding", ServiceID: "db", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, gauge("bindings"); e != a {
+		t.Fatalf("Unexpected bindings; expected %v, got %v", e, a)
+	}
+	if _, err := s.Client.Unbind(&osb.UnbindRequest{InstanceID: "sync", BindingID: "binding", ServiceID: "db", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Client.DeprovisionInstance(&osb.DeprovisionRequest{InstanceID: "sync", ServiceID: "db", PlanID: "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, gauge("instances"); e != a {
+		t.Fatalf("Unexpected instances after a deprovision; expected %v, got %v", e, a)
+	}
+	if e, a := 0.0, gauge("bindings"); e != a {
+		t.Fatalf("Unexpected bindings after an unbind; expected %v, got %v", e, a)
+	}
+
+	expected := []rest.LifecycleEventType{rest.InstanceProvisioned, rest.InstanceProvisioned, rest.BindingCreated, rest.BindingDeleted, rest.InstanceDeprovisioned}
+	if e, a := len(expected), len(events); e != a {
+		t.Fatalf("Unexpected number of events; expected %v, got %v: %+v", e, a, events)
+	}
+	for i, event := range events {
+		if e, a := expected[i], event.Type; e != a {
+			t.Errorf("Unexpected event %v; expected %v, got %v", i, e, a)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestMaxRequestBodyBytes(t *testing.T) {
+	provisioned := 0
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			provisioned++
+			return &broker.ProvisionResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.MaxRequestBodyBytes = 256
+	})
+
+	provision := func(parameter string) *http.Response {
+		body := `{"service_id": "service", "plan_id": "plan", "organization_guid": "org", "space_guid": "space", "parameters": {"p": "` + parameter + `"}}`
+		req, err := http.NewRequest(http.MethodPut, s.URL+"/v2/service_instances/instance", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := provision("small")
+	resp.Body.Close()
+	if e, a := http.StatusCreated, resp.StatusCode; e != a {
+		t.Errorf("Unexpected status of a small request; expected %v, got %v", e, a)
+	}
+
+	osbErr := brokertest.AssertOSBError(t, provision(strings.Repeat("x", 1024)), http.StatusRequestEntityTooLarge, "")
+	if e, a := "The request body exceeds the limit of 256 bytes.", osbErr.Description; e != a {
+		t.Errorf("Unexpected description; expected %q, got %q", e, a)
+	}
+	if e, a := 1, provisioned; e != a {
+		t.Errorf("Unexpected provision count; expected %v, got %v", e, a)
+	}
+}
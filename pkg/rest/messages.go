@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessageCatalog translates the descriptions of the error responses written
+// by the APISurface, such as validation failures and version errors, for
+// platforms serving operators who don't read English. It maps language tags
+// such as "fr" or "pt-BR" to translations keyed by the English description.
+//
+// Descriptions are matched exactly, so descriptions carrying values of the
+// request, like "invalid API version \"3\"", are only translated for the
+// values present in the catalog; descriptions without a translation are
+// written in English.
+type MessageCatalog map[string]map[string]string
+
+// Translate returns the translation of description into the language the
+// Accept-Language header prefers among those of the catalog, and that
+// language. It returns description and an empty language if the catalog has
+// no translation the header accepts, or if the header prefers English.
+func (m MessageCatalog) Translate(acceptLanguage, description string) (string, string) {
+	if len(m) == 0 || acceptLanguage == "" {
+		return description, ""
+	}
+	for _, tag := range acceptedLanguages(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		// "fr-CH" falls back to "fr".
+		base := strings.SplitN(tag, "-", 2)[0]
+		for _, candidate := range []string{tag, base} {
+			for language, translations := range m {
+				if !strings.EqualFold(language, candidate) {
+					continue
+				}
+				if translation, ok := translations[description]; ok {
+					return translation, language
+				}
+			}
+		}
+		// The descriptions are English already.
+		if strings.EqualFold(base, "en") {
+			break
+		}
+	}
+	return description, ""
+}
+
+// acceptedLanguages returns the language tags of an Accept-Language header
+// by decreasing quality, omitting those with a zero quality.
+func acceptedLanguages(header string) []string {
+	type accepted struct {
+		tag     string
+		quality float64
+	}
+	var languages []accepted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		if quality > 0 {
+			languages = append(languages, accepted{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, language := range languages {
+		tags[i] = language.tag
+	}
+	return tags
+}
+
+// localize returns the description of an error response in the language r
+// prefers, setting the Content-Language header of the response when it is
+// translated.
+func (s *APISurface) localize(w http.ResponseWriter, r *http.Request, description string) string {
+	translation, language := s.Messages.Translate(r.Header.Get("Accept-Language"), description)
+	if language != "" {
+		w.Header().Set("Content-Language", language)
+	}
+	return translation
+}
@@ -0,0 +1,184 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+var testMessages = rest.MessageCatalog{
+	"fr": {
+		"unsupported API version": "version de l'API non prise en charge",
+	},
+	"fr-CA": {
+		"unsupported API version": "version d'API non supportée",
+	},
+	"de": {
+		"unsupported API version": "nicht unterstützte API-Version",
+	},
+}
+
+func TestMessageCatalogTranslate(t *testing.T) {
+	cases := []struct {
+		name             string
+		acceptLanguage   string
+		description      string
+		expected         string
+		expectedLanguage string
+	}{
+		{
+			name:        "no header",
+			description: "unsupported API version",
+			expected:    "unsupported API version",
+		},
+		{
+			name:             "exact tag",
+			acceptLanguage:   "fr-CA",
+			description:      "unsupported API version",
+			expected:         "version d'API non supportée",
+			expectedLanguage: "fr-CA",
+		},
+		{
+			name:             "base language",
+			acceptLanguage:   "fr-CH",
+			description:      "unsupported API version",
+			expected:         "version de l'API non prise en charge",
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "quality",
+			acceptLanguage:   "fr;q=0.5, de, en;q=0.8",
+			description:      "unsupported API version",
+			expected:         "nicht unterstützte API-Version",
+			expectedLanguage: "de",
+		},
+		{
+			name:           "English preferred",
+			acceptLanguage: "en, fr;q=0.5",
+			description:    "unsupported API version",
+			expected:       "unsupported API version",
+		},
+		{
+			name:           "refused language",
+			acceptLanguage: "fr;q=0, *",
+			description:    "unsupported API version",
+			expected:       "unsupported API version",
+		},
+		{
+			name:           "no translation",
+			acceptLanguage: "fr",
+			description:    "instance_id is required",
+			expected:       "instance_id is required",
+		},
+	}
+
+	for _, tc := range cases {
+		translation, language := testMessages.Translate(tc.acceptLanguage, tc.description)
+		if e, a := tc.expected, translation; e != a {
+			t.Errorf("%v: unexpected translation; expected %q, got %q", tc.name, e, a)
+		}
+		if e, a := tc.expectedLanguage, language; e != a {
+			t.Errorf("%v: unexpected language; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ValidateBrokerAPIVersionFunc: func(string) error {
+			return errors.New("unsupported API version")
+		},
+	}, func(api *rest.APISurface) {
+		api.Messages = testMessages
+	})
+
+	cases := []struct {
+		name             string
+		acceptLanguage   string
+		expected         string
+		expectedLanguage string
+	}{
+		{
+			name:     "English",
+			expected: "unsupported API version",
+		},
+		{
+			name:             "French",
+			acceptLanguage:   "fr-FR, en;q=0.5",
+			expected:         "version de l'API non prise en charge",
+			expectedLanguage: "fr",
+		},
+	}
+
+	for _, tc := range cases {
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/v2/catalog", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(osb.APIVersionHeader, "2.13")
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if e, a := http.StatusPreconditionFailed, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status; expected %v, got %v", tc.name, e, a)
+		}
+		var description struct {
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(body, &description); err != nil {
+			t.Fatalf("%v: unexpected body %q: %v", tc.name, body, err)
+		}
+		if e, a := tc.expected, description.Description; e != a {
+			t.Errorf("%v: unexpected description; expected %q, got %q", tc.name, e, a)
+		}
+		if e, a := tc.expectedLanguage, resp.Header.Get("Content-Language"); e != a {
+			t.Errorf("%v: unexpected Content-Language; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
+
+func TestLocalizedOSBErrors(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+		api.ReadOnly = true
+		api.Messages = rest.MessageCatalog{
+			"fr": {
+				"The broker is in read-only mode and does not accept changes to service instances or bindings.": "Le broker est en lecture seule.",
+			},
+		}
+	})
+
+	req, err := http.NewRequest(http.MethodDelete, s.URL+"/v2/service_instances/instance?service_id=service&plan_id=plan", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(osb.APIVersionHeader, "2.13")
+	req.Header.Set("Accept-Language", "fr")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	osbErr := brokertest.AssertOSBError(t, resp, http.StatusServiceUnavailable, "ReadOnly")
+	if e, a := "Le broker est en lecture seule.", osbErr.Description; e != a {
+		t.Errorf("Unexpected description; expected %q, got %q", e, a)
+	}
+	if e, a := "fr", resp.Header.Get("Content-Language"); e != a {
+		t.Errorf("Unexpected Content-Language; expected %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,15 @@
+package rest
+
+// The names of the OSB operations served by an APISurface. They are used as
+// metric labels and as keys for per-operation configuration.
+const (
+	OperationGetCatalog           = "get_catalog"
+	OperationProvision            = "provision"
+	OperationDeprovision          = "deprovision"
+	OperationLastOperation        = "last_operation"
+	OperationBind                 = "bind"
+	OperationGetBinding           = "get_binding"
+	OperationBindingLastOperation = "binding_last_operation"
+	OperationUnbind               = "unbind"
+	OperationUpdate               = "update"
+)
@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+)
+
+// ParametersVisibility controls the parameters returned when platforms
+// fetch a resource, since the parameters stored at provision or bind time
+// can hold secrets the broker must not echo back.
+type ParametersVisibility int
+
+const (
+	// ParametersReturned returns the parameters of the business logic as
+	// is. It is the default.
+	ParametersReturned ParametersVisibility = iota
+	// ParametersRedacted replaces the values of sensitive keys, such as
+	// "password" or "token", with redact.Placeholder at any depth; see
+	// redact.IsSensitiveKey.
+	ParametersRedacted
+	// ParametersOmitted removes the parameters from the response.
+	ParametersOmitted
+)
+
+// String returns the name of the visibility.
+func (v ParametersVisibility) String() string {
+	switch v {
+	case ParametersReturned:
+		return "returned"
+	case ParametersRedacted:
+		return "redacted"
+	case ParametersOmitted:
+		return "omitted"
+	}
+	return "unknown"
+}
+
+// apply returns the parameters to send to the platform. The parameters of
+// the business logic are never modified.
+func (v ParametersVisibility) apply(parameters map[string]interface{}) map[string]interface{} {
+	switch {
+	case parameters == nil:
+		return nil
+	case v == ParametersRedacted:
+		redacted, _ := redact.Value(parameters).(map[string]interface{})
+		return redacted
+	case v == ParametersOmitted:
+		return nil
+	}
+	return parameters
+}
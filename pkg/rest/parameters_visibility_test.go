@@ -0,0 +1,72 @@
+package rest_test
+
+import (
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/redact"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestBindingParametersVisibility(t *testing.T) {
+	newParameters := func() map[string]interface{} {
+		return map[string]interface{}{
+			"size": "small",
+			"auth": map[string]interface{}{"user": "admin", "password": "hunter2"},
+		}
+	}
+
+	cases := []struct {
+		visibility rest.ParametersVisibility
+		expected   map[string]interface{}
+	}{
+		{
+			visibility: rest.ParametersReturned,
+			expected:   newParameters(),
+		},
+		{
+			visibility: rest.ParametersRedacted,
+			expected: map[string]interface{}{
+				"size": "small",
+				"auth": map[string]interface{}{"user": "admin", "password": redact.Placeholder},
+			},
+		},
+		{
+			visibility: rest.ParametersOmitted,
+		},
+	}
+
+	for _, tc := range cases {
+		stored := &broker.GetBindingResponse{
+			GetBindingResponse: osb.GetBindingResponse{
+				Credentials: map[string]interface{}{"uri": "db://admin:hunter2@db"},
+				Parameters:  newParameters(),
+			},
+		}
+		s := brokertest.NewServer(t, &brokertest.FakeBroker{
+			GetBindingFunc: func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+				return stored, nil
+			},
+		}, func(api *rest.APISurface) {
+			api.BindingParameters = tc.visibility
+		})
+
+		response, err := s.Client.GetBinding(&osb.GetBindingRequest{InstanceID: "instance", BindingID: "binding"})
+		if err != nil {
+			t.Fatalf("%v: %v", tc.visibility, err)
+		}
+		if e, a := tc.expected, response.Parameters; !reflect.DeepEqual(e, a) {
+			t.Errorf("%v: unexpected parameters; expected %v, got %v", tc.visibility, e, a)
+		}
+		if e, a := "db://admin:hunter2@db", response.Credentials["uri"]; e != a {
+			t.Errorf("%v: unexpected credentials; expected %v, got %v", tc.visibility, e, a)
+		}
+		if e, a := newParameters(), stored.Parameters; !reflect.DeepEqual(e, a) {
+			t.Errorf("%v: the business logic's response was modified; expected %v, got %v", tc.visibility, e, a)
+		}
+	}
+}
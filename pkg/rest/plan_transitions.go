@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"fmt"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// PlanTransitionPolicy declares the plan changes update requests may make.
+// Services without declared transitions may change between any of their
+// plans; once a transition is allowed for a service, every other plan change
+// of that service is rejected with a 400 before the business logic is
+// invoked. Transitions aren't transitive: allowing small to medium and
+// medium to large doesn't allow small to large.
+type PlanTransitionPolicy struct {
+	transitions map[string]map[string]map[string]bool
+}
+
+// NewPlanTransitionPolicy returns a policy allowing every plan change.
+func NewPlanTransitionPolicy() *PlanTransitionPolicy {
+	return &PlanTransitionPolicy{
+		transitions: map[string]map[string]map[string]bool{},
+	}
+}
+
+// Allow allows instances of the service to change from plan from to each of
+// the plans to.
+func (p *PlanTransitionPolicy) Allow(serviceID, from string, to ...string) {
+	plans, ok := p.transitions[serviceID]
+	if !ok {
+		plans = map[string]map[string]bool{}
+		p.transitions[serviceID] = plans
+	}
+	if plans[from] == nil {
+		plans[from] = map[string]bool{}
+	}
+	for _, planID := range to {
+		plans[from][planID] = true
+	}
+}
+
+// Check returns a 400 error if instances of the service may not change from
+// plan from to plan to.
+func (p *PlanTransitionPolicy) Check(serviceID, from, to string) error {
+	plans, ok := p.transitions[serviceID]
+	if !ok || from == to || plans[from][to] {
+		return nil
+	}
+	return newValidationError(fmt.Sprintf("instances of service %q cannot change from plan %q to plan %q", serviceID, from, to))
+}
+
+// checkPlanTransition checks an update request against the
+// PlanTransitionPolicy. Requests whose previous plan is unknown can't be
+// checked and are allowed.
+func (s *APISurface) checkPlanTransition(request *osb.UpdateInstanceRequest, c *broker.RequestContext) error {
+	if s.PlanTransitions == nil {
+		return nil
+	}
+	diff := broker.DiffUpdate(request, c)
+	if !diff.PlanChanged || diff.PreviousPlanID == "" {
+		return nil
+	}
+	return s.PlanTransitions.Check(request.ServiceID, diff.PreviousPlanID, diff.PlanID)
+}
@@ -0,0 +1,63 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestPlanTransitions(t *testing.T) {
+	policy := rest.NewPlanTransitionPolicy()
+	policy.Allow("db", "small", "medium")
+	policy.Allow("db", "medium", "large")
+
+	updated := 0
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+			updated++
+			return &broker.UpdateInstanceResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.PlanTransitions = policy
+	})
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{name: "allowed", body: `{"service_id":"db","plan_id":"medium","previous_values":{"plan_id":"small"}}`, expected: http.StatusOK},
+		{name: "not transitive", body: `{"service_id":"db","plan_id":"large","previous_values":{"plan_id":"small"}}`, expected: http.StatusBadRequest},
+		{name: "downgrade", body: `{"service_id":"db","plan_id":"small","previous_values":{"plan_id":"large"}}`, expected: http.StatusBadRequest},
+		{name: "parameters only", body: `{"service_id":"db","parameters":{"a":1},"previous_values":{"plan_id":"large"}}`, expected: http.StatusOK},
+		{name: "unknown previous plan", body: `{"service_id":"db","plan_id":"small"}`, expected: http.StatusOK},
+		{name: "unrestricted service", body: `{"service_id":"cache","plan_id":"small","previous_values":{"plan_id":"large"}}`, expected: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		before := updated
+		request, err := http.NewRequest(http.MethodPatch, s.URL+"/v2/service_instances/instance", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if e, a := tc.expected, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, e, a)
+		}
+		if invoked := updated > before; invoked != (tc.expected == http.StatusOK) {
+			t.Errorf("%v: unexpected business logic invocation: %v", tc.name, invoked)
+		}
+	}
+}
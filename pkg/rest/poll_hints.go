@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// setPollRetryAfter sets the Retry-After header of a last operation
+// response for an operation in progress to the delay suggested by the
+// business logic, or PollRetryAfter if it didn't suggest one.
+func (s *APISurface) setPollRetryAfter(w http.ResponseWriter, response *broker.LastOperationResponse) {
+	if response.State != osb.StateInProgress {
+		return
+	}
+	retryAfter := response.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = s.PollRetryAfter
+	}
+	if retryAfter > 0 {
+		setRetryAfter(w, retryAfter)
+	}
+}
@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// prettyJSON returns data indented, with the keys of every object sorted.
+// Data that isn't valid JSON is returned as is.
+func prettyJSON(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written instead of rounding them through float64.
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return data
+	}
+
+	// Objects decode to maps, which are marshaled with sorted keys.
+	pretty, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return data
+	}
+	return append(pretty, '\n')
+}
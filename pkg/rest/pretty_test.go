@@ -0,0 +1,64 @@
+package rest_test
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		pretty   bool
+		expected string
+	}{
+		{
+			name:     "compact",
+			expected: `{"error":"ConcurrencyError","description":"Another operation is in progress."}`,
+		},
+		{
+			name:   "pretty",
+			pretty: true,
+			expected: `{
+  "description": "Another operation is in progress.",
+  "error": "ConcurrencyError"
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		s := brokertest.NewServer(t, &brokertest.FakeBroker{
+			ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+				errorMessage, description := "ConcurrencyError", "Another operation is in progress."
+				return nil, osb.HTTPStatusCodeError{
+					StatusCode:   http.StatusUnprocessableEntity,
+					ErrorMessage: &errorMessage,
+					Description:  &description,
+				}
+			},
+		}, func(api *rest.APISurface) {
+			api.PrettyJSON = tc.pretty
+		})
+
+		s.Client.ProvisionInstance(&osb.ProvisionRequest{
+			InstanceID:        "instance",
+			ServiceID:         "service",
+			PlanID:            "plan",
+			OrganizationGUID:  "org",
+			SpaceGUID:         "space",
+			AcceptsIncomplete: true,
+		})
+		responses := s.Responses()
+		if len(responses) != 1 || responses[0].StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("%v: unexpected responses %+v", tc.name, responses)
+		}
+		if e, a := tc.expected, string(responses[0].Body); e != a {
+			t.Errorf("%v: unexpected body; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
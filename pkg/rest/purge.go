@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// PurgeQueryParameter is the extension query parameter asking for a
+// deprovision request to forget the instance without cleaning up its
+// backend. See broker.Purger.
+const PurgeQueryParameter = "purge"
+
+// isPurge returns whether r asks for the instance to be purged.
+func isPurge(r *http.Request) bool {
+	return strings.ToLower(r.URL.Query().Get(PurgeQueryParameter)) == "true"
+}
+
+// purger returns the business logic's Purger if r asks for a purge, or nil
+// for regular deprovision requests. Purge requests to business logic that
+// isn't a Purger get a not supported error.
+func (s *APISurface) purger(r *http.Request) (broker.Purger, error) {
+	if !isPurge(r) {
+		return nil, nil
+	}
+	purger, ok := s.Broker.(broker.Purger)
+	if !ok {
+		return nil, broker.NewNotSupportedError("purging instances")
+	}
+	return purger, nil
+}
@@ -0,0 +1,68 @@
+package rest_test
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+type purgingBroker struct {
+	brokertest.FakeBroker
+	purged []string
+}
+
+func (b *purgingBroker) Purge(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+	b.purged = append(b.purged, request.InstanceID)
+	return &broker.DeprovisionResponse{}, nil
+}
+
+func TestPurge(t *testing.T) {
+	deprovisioned := 0
+	fake := brokertest.FakeBroker{
+		DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+			deprovisioned++
+			return &broker.DeprovisionResponse{}, nil
+		},
+	}
+	purging := &purgingBroker{FakeBroker: fake}
+
+	deprovision := func(s *brokertest.Server, query string) int {
+		request, err := http.NewRequest(http.MethodDelete, s.URL+"/v2/service_instances/instance?service_id=service&plan_id=plan"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	s := brokertest.NewServer(t, purging)
+	if e, a := http.StatusOK, deprovision(s, "&purge=true"); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := 1, len(purging.purged); e != a {
+		t.Fatalf("Expected the instance to be purged; purged %v", purging.purged)
+	}
+	if e, a := 0, deprovisioned; e != a {
+		t.Fatalf("Expected Deprovision not to be called for purge requests; called %v times", a)
+	}
+	if e, a := http.StatusOK, deprovision(s, ""); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := 1, deprovisioned; e != a {
+		t.Fatalf("Expected Deprovision to be called without purge; called %v times", a)
+	}
+
+	s = brokertest.NewServer(t, &fake)
+	if e, a := http.StatusNotImplemented, deprovision(s, "&purge=true"); e != a {
+		t.Fatalf("Unexpected status code for business logic without Purger; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,30 @@
+package rest
+
+import "sync"
+
+// readOnlyState holds the read-only mode set by SetReadOnly, which overrides
+// the ReadOnly field.
+type readOnlyState struct {
+	mutex    sync.Mutex
+	set      bool
+	readOnly bool
+}
+
+// SetReadOnly switches read-only mode on or off while the APISurface is
+// serving, for example for a maintenance window. It overrides ReadOnly.
+func (s *APISurface) SetReadOnly(readOnly bool) {
+	s.readOnly.mutex.Lock()
+	defer s.readOnly.mutex.Unlock()
+	s.readOnly.set = true
+	s.readOnly.readOnly = readOnly
+}
+
+// IsReadOnly returns whether the APISurface rejects mutating operations.
+func (s *APISurface) IsReadOnly() bool {
+	s.readOnly.mutex.Lock()
+	defer s.readOnly.mutex.Unlock()
+	if s.readOnly.set {
+		return s.readOnly.readOnly
+	}
+	return s.ReadOnly
+}
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestUnmarshalRequestBody(t *testing.T) {
+	cases := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{name: "object", body: `{"service_id": "s"}`, valid: true},
+		{name: "trailing whitespace", body: "{\"service_id\": \"s\"}\n", valid: true},
+		{name: "empty", body: ""},
+		{name: "truncated", body: `{"service_id": "s"`},
+		{name: "trailing value", body: `{"service_id": "s"} {}`},
+		{name: "trailing garbage", body: `{"service_id": "s"} x`},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.body))
+		request := &osb.ProvisionRequest{}
+		err := unmarshalRequestBody(r, request)
+		if e, a := tc.valid, err == nil; e != a {
+			t.Errorf("%v: unexpected validity; expected %v, got error %v", tc.name, e, err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Body = nil
+	if err := unmarshalRequestBody(r, &osb.ProvisionRequest{}); err == nil || err.Error() != "request body is empty" {
+		t.Errorf("Unexpected error for an empty body: %v", err)
+	}
+}
+
+func TestUnpackBindRequestBindResource(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		appGUID string
+	}{
+		{name: "spec field", body: `{"bind_resource": {"app_guid": "app", "route": "r"}}`, appGUID: "app"},
+		{name: "client field", body: `{"bind_resource": {"appGuid": "app", "route": "r"}}`, appGUID: "app"},
+		{name: "none", body: `{"service_id": "s"}`},
+		{name: "null", body: `{"bind_resource": null}`},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tc.body))
+		r = mux.SetURLVars(r, map[string]string{osb.VarKeyInstanceID: "i", osb.VarKeyBindingID: "b"})
+		request, bindResource, err := unpackBindRequest(r)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if tc.appGUID == "" {
+			if request.BindResource != nil || bindResource != nil {
+				t.Errorf("%v: unexpected bind resources %+v, %+v", tc.name, request.BindResource, bindResource)
+			}
+			continue
+		}
+		if request.BindResource == nil || request.BindResource.AppGUID == nil {
+			t.Fatalf("%v: expected the client's bind resource to have an app GUID, got %+v", tc.name, request.BindResource)
+		}
+		if e, a := tc.appGUID, *request.BindResource.AppGUID; e != a {
+			t.Errorf("%v: unexpected app GUID; expected %v, got %v", tc.name, e, a)
+		}
+		if request.BindResource.Route == nil || *request.BindResource.Route != "r" {
+			t.Errorf("%v: unexpected route %v", tc.name, request.BindResource.Route)
+		}
+		if bindResource == nil || bindResource.Route != "r" {
+			t.Errorf("%v: unexpected bind resource %+v", tc.name, bindResource)
+		}
+	}
+}
+
+// largeParametersBody returns a provision request body whose parameters
+// hold about size bytes.
+func largeParametersBody(size int) []byte {
+	parameters := map[string]interface{}{}
+	value := strings.Repeat("x", 1000)
+	for i := 0; i*len(value) < size; i++ {
+		parameters[fmt.Sprintf("p%d", i)] = value
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"service_id":        "service",
+		"plan_id":           "plan",
+		"organization_guid": "org",
+		"space_guid":        "space",
+		"parameters":        parameters,
+	})
+	return body
+}
+
+// BenchmarkUnpackProvisionRequest compares the memory used to unpack
+// provision requests with multi-megabyte parameters, decoding their body as
+// it is read, with reading it with ioutil.ReadAll before decoding it.
+func BenchmarkUnpackProvisionRequest(b *testing.B) {
+	for _, size := range []int{1 << 20, 8 << 20} {
+		body := largeParametersBody(size)
+		newRequest := func() *http.Request {
+			r := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(body))
+			return mux.SetURLVars(r, map[string]string{osb.VarKeyInstanceID: "i"})
+		}
+
+		b.Run(fmt.Sprintf("unpack/%dMiB", size>>20), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				if _, err := unpackProvisionRequest(newRequest()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("readall/%dMiB", size>>20), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				data, err := ioutil.ReadAll(newRequest().Body)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := json.Unmarshal(data, &osb.ProvisionRequest{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUnpackUpdateRequest measures the memory used to unpack update
+// requests with multi-megabyte parameters, whose body is decoded once.
+func BenchmarkUnpackUpdateRequest(b *testing.B) {
+	for _, size := range []int{1 << 20, 8 << 20} {
+		body := largeParametersBody(size)
+		b.Run(fmt.Sprintf("%dMiB", size>>20), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodPatch, "/", bytes.NewReader(body))
+				if _, _, err := unpackUpdateRequest(r, map[string]string{osb.VarKeyInstanceID: "i"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"fmt"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// boundService returns the catalog entry of the service a bind request is
+// for and checks the request against the permissions the service requires:
+// bindings of services requiring syslog_drain or volume_mount must be for an
+// application, and bindings of route services for a route. It returns a
+// nil service if EnforceRequires isn't set or the service isn't in the
+// catalog.
+func (s *APISurface) boundService(request *osb.BindRequest, c *broker.RequestContext) (*osb.Service, error) {
+	if !s.EnforceRequires {
+		return nil, nil
+	}
+
+	catalog, err := s.Broker.GetCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	var service *osb.Service
+	for i := range catalog.Services {
+		if catalog.Services[i].ID == request.ServiceID {
+			service = &catalog.Services[i]
+			break
+		}
+	}
+	if service == nil {
+		return nil, nil
+	}
+
+	if err := broker.ValidateRouteBinding(service, request); err != nil {
+		return nil, err
+	}
+	needsApp := broker.Requires(service, broker.RequiresSyslogDrain) || broker.Requires(service, broker.RequiresVolumeMount)
+	if needsApp && appGUID(request, c) == "" {
+		return nil, broker.NewAppRequiredError()
+	}
+	return service, nil
+}
+
+// checkPermissions returns an error if a bind response uses a permission
+// the service doesn't require, which platforms reject.
+func checkPermissions(service *osb.Service, response *broker.BindResponse) error {
+	if service == nil {
+		return nil
+	}
+
+	var permission string
+	switch {
+	case response.SyslogDrainURL != nil && !broker.Requires(service, broker.RequiresSyslogDrain):
+		permission = broker.RequiresSyslogDrain
+	case len(response.VolumeMounts) > 0 && !broker.Requires(service, broker.RequiresVolumeMount):
+		permission = broker.RequiresVolumeMount
+	case response.RouteServiceURL != nil && !broker.Requires(service, broker.RequiresRouteForwarding):
+		permission = broker.RequiresRouteForwarding
+	default:
+		return nil
+	}
+	return fmt.Errorf("invalid binding response: service %q does not require %s", service.ID, permission)
+}
+
+// appGUID returns the GUID of the application a bind request is for, if
+// any.
+func appGUID(request *osb.BindRequest, c *broker.RequestContext) string {
+	if c.BindResource != nil && c.BindResource.AppGUID != "" {
+		return c.BindResource.AppGUID
+	}
+	if request.AppGUID != nil {
+		return *request.AppGUID
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestEnforceRequires(t *testing.T) {
+	var bindResource *broker.BindResource
+	var appGUID *string
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{
+				{ID: "logs", Bindable: true, Requires: []string{broker.RequiresSyslogDrain}},
+				{ID: "db", Bindable: true},
+			}
+			return response, nil
+		},
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			bindResource = c.BindResource
+			if request.BindResource != nil {
+				appGUID = request.BindResource.AppGUID
+			}
+			response := &broker.BindResponse{}
+			drain := "syslog://logs.example.com"
+			response.SyslogDrainURL = &drain
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.EnforceRequires = true
+	})
+
+	bind := func(bindingID, body string) int {
+		request, err := http.NewRequest("PUT", s.URL+"/v2/service_instances/instance/service_bindings/"+bindingID, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if e, a := http.StatusUnprocessableEntity, bind("no-app", `{"service_id":"logs","plan_id":"p"}`); e != a {
+		t.Errorf("Expected RequiresApp for a binding without an application; expected %v, got %v", e, a)
+	}
+
+	body := `{"service_id":"logs","plan_id":"p","bind_resource":{"app_guid":"app","credential_client_id":"client"}}`
+	if e, a := http.StatusCreated, bind("app", body); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if bindResource == nil || bindResource.AppGUID != "app" || bindResource.CredentialClientID != "client" {
+		t.Errorf("Unexpected bind resource in the request context: %+v", bindResource)
+	}
+	if appGUID == nil || *appGUID != "app" {
+		t.Errorf("Expected app_guid to be set on the request's BindResource, got %v", appGUID)
+	}
+
+	body = `{"service_id":"db","plan_id":"p","bind_resource":{"app_guid":"app"}}`
+	if e, a := http.StatusInternalServerError, bind("drain", body); e != a {
+		t.Errorf("Expected a syslog drain for a service not requiring it to be rejected; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// schemaSelector selects the schema of an operation from a plan's schemas.
+type schemaSelector func(schemas *osb.ParameterSchemas) *osb.InputParameters
+
+func instanceCreateSchema(schemas *osb.ParameterSchemas) *osb.InputParameters {
+	if schemas.ServiceInstances == nil {
+		return nil
+	}
+	return schemas.ServiceInstances.Create
+}
+
+func instanceUpdateSchema(schemas *osb.ParameterSchemas) *osb.InputParameters {
+	if schemas.ServiceInstances == nil {
+		return nil
+	}
+	return schemas.ServiceInstances.Update
+}
+
+func bindingCreateSchema(schemas *osb.ParameterSchemas) *osb.InputParameters {
+	if schemas.ServiceBindings == nil {
+		return nil
+	}
+	return schemas.ServiceBindings.Create
+}
+
+// applySchemaDefaults returns parameters with the defaults of the plan's
+// schema selected by schemaOf filled in; see broker.ApplyParameterDefaults.
+// It returns parameters unchanged unless ApplySchemaDefaults is set, or if
+// the plan or its schema isn't in the catalog.
+func (s *APISurface) applySchemaDefaults(c *broker.RequestContext, serviceID, planID string, schemaOf schemaSelector, parameters map[string]interface{}) (map[string]interface{}, error) {
+	if !s.ApplySchemaDefaults {
+		return parameters, nil
+	}
+
+	catalog, err := s.Broker.GetCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	_, plan := findPlan(catalog, serviceID, planID)
+	if plan == nil || plan.ParameterSchemas == nil {
+		return parameters, nil
+	}
+	schema := schemaOf(plan.ParameterSchemas)
+	if schema == nil {
+		return parameters, nil
+	}
+	return broker.ApplyParameterDefaults(schema.Parameters, parameters), nil
+}
+
+// updatePlanID returns the plan an instance has once updated: the requested
+// plan, or else its previous plan.
+func updatePlanID(request *osb.UpdateInstanceRequest) string {
+	if request.PlanID != nil {
+		return *request.PlanID
+	}
+	if request.PreviousValues != nil {
+		return request.PreviousValues.PlanID
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package rest_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestApplySchemaDefaults(t *testing.T) {
+	schema := func(property string) *osb.InputParameters {
+		return &osb.InputParameters{Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				property: map[string]interface{}{"type": "string", "default": "default"},
+			},
+		}}
+	}
+
+	var provisioned, updated, bound map[string]interface{}
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{
+				ID:       "db",
+				Bindable: true,
+				Plans: []osb.Plan{{
+					ID: "small",
+					ParameterSchemas: &osb.ParameterSchemas{
+						ServiceInstances: &osb.ServiceInstanceSchema{
+							Create: schema("size"),
+							Update: schema("maintenance_window"),
+						},
+						ServiceBindings: &osb.ServiceBindingSchema{
+							Create: schema("role"),
+						},
+					},
+				}},
+			}}
+			return response, nil
+		},
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			provisioned = request.Parameters
+			return &broker.ProvisionResponse{}, nil
+		},
+		UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+			updated = request.Parameters
+			return &broker.UpdateInstanceResponse{}, nil
+		},
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			bound = request.Parameters
+			return &broker.BindResponse{}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.ApplySchemaDefaults = true
+	})
+
+	do := func(method, path, body string) {
+		request, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Errorf("Unexpected status code for %v %v: %v", method, path, resp.StatusCode)
+		}
+	}
+
+	do("PUT", "/v2/service_instances/i", `{"service_id":"db","plan_id":"small","parameters":{"name":"x"}}`)
+	do("PATCH", "/v2/service_instances/i", `{"service_id":"db","previous_values":{"plan_id":"small"}}`)
+	do("PUT", "/v2/service_instances/i/service_bindings/b", `{"service_id":"db","plan_id":"small","parameters":{"role":"admin"}}`)
+
+	if e, a := map[string]interface{}{"name": "x", "size": "default"}, provisioned; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected provision parameters; expected %v, got %v", e, a)
+	}
+	if e, a := map[string]interface{}{"maintenance_window": "default"}, updated; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected update parameters; expected %v, got %v", e, a)
+	}
+	if e, a := map[string]interface{}{"role": "admin"}, bound; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected bind parameters; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,54 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+func TestUpdatePreviousValues(t *testing.T) {
+	var diff *broker.UpdateDiff
+	var previous *broker.PreviousValues
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+			previous = c.PreviousValues
+			diff = broker.DiffUpdate(request, c)
+			return &broker.UpdateInstanceResponse{}, nil
+		},
+	})
+
+	body := `{
+		"service_id": "db",
+		"plan_id": "large",
+		"maintenance_info": {"version": "2.0.0"},
+		"previous_values": {"plan_id": "small", "maintenance_info": {"version": "1.0.0"}}
+	}`
+	request, err := http.NewRequest(http.MethodPatch, s.URL+"/v2/service_instances/instance", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(osb.APIVersionHeader, "2.15")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if e, a := http.StatusOK, resp.StatusCode; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if previous == nil || previous.MaintenanceInfo == nil || previous.MaintenanceInfo.Version != "1.0.0" {
+		t.Fatalf("Expected the previous maintenance_info to be passed, got %+v", previous)
+	}
+	if !diff.PlanChanged || diff.PreviousPlanID != "small" || diff.PlanID != "large" {
+		t.Errorf("Expected a plan change from small to large, got %+v", diff)
+	}
+	if !diff.MaintenanceInfoChanged {
+		t.Errorf("Expected a maintenance_info change, got %+v", diff)
+	}
+}
@@ -2,8 +2,13 @@ package rest
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+
+	"github.com/gorilla/mux"
 
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 )
@@ -12,16 +17,73 @@ func getBrokerAPIVersionFromRequest(r *http.Request) string {
 	return r.Header.Get(osb.APIVersionHeader)
 }
 
+// operationName returns the name of the operation r is served as by
+// InstrumentHandler or was routed to, or "" if it wasn't routed by the
+// router built by package server.
+func operationName(r *http.Request) string {
+	if operation, ok := r.Context().Value(operationKey).(string); ok {
+		return operation
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName()
+	}
+	return ""
+}
+
+// bodyAllowed returns whether a response with the status code may have a
+// body. Writing one to a 1xx, 204 or 304 response fails.
+func bodyAllowed(code int) bool {
+	switch {
+	case code >= 100 && code < 200:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// unmarshalRequestBody decodes the JSON body of request into obj as it is
+// read, failing with a 413 error if it exceeds MaxRequestBodyBytes. The
+// body must hold exactly one JSON value.
 func unmarshalRequestBody(request *http.Request, obj interface{}) error {
-	body, err := ioutil.ReadAll(request.Body)
-	if err != nil {
-		return err
+	if request.Body == nil {
+		return fmt.Errorf("request body is empty")
 	}
+	decoder := json.NewDecoder(request.Body)
+	if err := decoder.Decode(obj); err != nil {
+		return bodyTooLarge(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err != nil {
+			return bodyTooLarge(err)
+		}
+		return fmt.Errorf("request body holds more than one JSON value")
+	}
+	return nil
+}
 
-	err = json.Unmarshal(body, obj)
-	if err != nil {
+// bodyTooLarge returns the 413 error for bodies exceeding
+// MaxRequestBodyBytes, or err if it doesn't report one.
+func bodyTooLarge(err error) error {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
 		return err
 	}
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusRequestEntityTooLarge,
+		Description: strPtr(fmt.Sprintf("The request body exceeds the limit of %d bytes.", tooLarge.Limit)),
+	}
+}
 
-	return nil
+// isNil returns whether v is nil or an interface holding a nil pointer, map,
+// slice, channel or func.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
 }
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// ValidateOnlyQueryParameter is the extension query parameter asking for a
+// provision request to be validated without provisioning anything.
+const ValidateOnlyQueryParameter = "validate_only"
+
+// isValidateOnly returns whether r asks for validation only.
+func isValidateOnly(r *http.Request) bool {
+	return strings.ToLower(r.URL.Query().Get(ValidateOnlyQueryParameter)) == "true"
+}
+
+// validateProvision checks request against the catalog and the business
+// logic's ProvisionValidator, if it implements one, without provisioning.
+func (s *APISurface) validateProvision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ValidateProvisionResponse, error) {
+	catalog, err := s.Broker.GetCatalog(c)
+	if err != nil {
+		return nil, err
+	}
+	service, plan := findPlan(catalog, request.ServiceID, request.PlanID)
+	switch {
+	case service == nil:
+		return nil, newValidationError(fmt.Sprintf("service %q is not in the catalog", request.ServiceID))
+	case plan == nil:
+		return nil, newValidationError(fmt.Sprintf("service %q has no plan %q", request.ServiceID, request.PlanID))
+	}
+
+	if s.ApplySchemaDefaults && plan.ParameterSchemas != nil {
+		if schema := instanceCreateSchema(plan.ParameterSchemas); schema != nil {
+			request.Parameters = broker.ApplyParameterDefaults(schema.Parameters, request.Parameters)
+		}
+	}
+
+	response := &broker.ValidateProvisionResponse{}
+	if validator, ok := s.Broker.(broker.ProvisionValidator); ok {
+		if response, err = validator.ValidateProvision(request, c); err != nil {
+			return nil, err
+		}
+	}
+	if response.Async && !request.AcceptsIncomplete {
+		return nil, newAsyncRequiredError()
+	}
+
+	response.Valid = true
+	response.ServiceID = request.ServiceID
+	response.PlanID = request.PlanID
+	return response, nil
+}
+
+// newValidationError returns the error written for a provision request that
+// can't be validated against the catalog.
+func newValidationError(description string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusBadRequest,
+		Description: strPtr(description),
+	}
+}
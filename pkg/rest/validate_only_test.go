@@ -0,0 +1,93 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+)
+
+type validatingBroker struct {
+	brokertest.FakeBroker
+	validated *osb.ProvisionRequest
+}
+
+func (b *validatingBroker) ValidateProvision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ValidateProvisionResponse, error) {
+	b.validated = request
+	if request.Parameters["size"] == "huge" {
+		quotaExceeded := "quota exceeded"
+		return nil, osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusUnprocessableEntity,
+			Description: &quotaExceeded,
+		}
+	}
+	return &broker.ValidateProvisionResponse{
+		Async:   true,
+		Details: map[string]interface{}{"region": "eu"},
+	}, nil
+}
+
+func TestValidateOnlyProvision(t *testing.T) {
+	b := &validatingBroker{FakeBroker: brokertest.FakeBroker{
+		ValidateBrokerAPIVersionFunc: func(string) error { return nil },
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{ID: "db", Plans: []osb.Plan{{ID: "small"}}}}
+			return response, nil
+		},
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			t.Error("Provision must not be called for validate_only requests")
+			return &broker.ProvisionResponse{}, nil
+		},
+	}}
+	s := brokertest.NewServer(t, b)
+
+	provision := func(query, body string) (int, *broker.ValidateProvisionResponse) {
+		request, err := http.NewRequest("PUT", s.URL+"/v2/service_instances/instance?validate_only=true"+query, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		response := &broker.ValidateProvisionResponse{}
+		json.NewDecoder(resp.Body).Decode(response)
+		return resp.StatusCode, response
+	}
+
+	code, response := provision("&accepts_incomplete=true", `{"service_id":"db","plan_id":"small"}`)
+	if e, a := http.StatusOK, code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if !response.Valid || !response.Async || response.ServiceID != "db" || response.PlanID != "small" || response.Details["region"] != "eu" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if b.validated == nil || b.validated.InstanceID != "instance" {
+		t.Errorf("Expected ValidateProvision to be called with the request, got %+v", b.validated)
+	}
+
+	cases := []struct {
+		name  string
+		query string
+		body  string
+		code  int
+	}{
+		{name: "unknown service", query: "&accepts_incomplete=true", body: `{"service_id":"cache","plan_id":"small"}`, code: http.StatusBadRequest},
+		{name: "unknown plan", query: "&accepts_incomplete=true", body: `{"service_id":"db","plan_id":"large"}`, code: http.StatusBadRequest},
+		{name: "quota", query: "&accepts_incomplete=true", body: `{"service_id":"db","plan_id":"small","parameters":{"size":"huge"}}`, code: http.StatusUnprocessableEntity},
+		{name: "async required", body: `{"service_id":"db","plan_id":"small"}`, code: http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		if code, _ := provision(tc.query, tc.body); tc.code != code {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, tc.code, code)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// validateBinding checks the fields of a bind or get binding response that
+// the APISurface validates: the metadata, and the endpoints and volume
+// mounts if ValidateResponses is set.
+func (s *APISurface) validateBinding(metadata *broker.BindingMetadata, endpoints []broker.Endpoint, volumeMounts []interface{}) error {
+	if metadata != nil {
+		if err := metadata.Validate(); err != nil {
+			return err
+		}
+	}
+	if !s.ValidateResponses {
+		return nil
+	}
+	if err := broker.ValidateEndpoints(endpoints); err != nil {
+		return fmt.Errorf("invalid binding response: %v", err)
+	}
+	if err := broker.ValidateVolumeMounts(volumeMounts); err != nil {
+		return fmt.Errorf("invalid binding response: %v", err)
+	}
+	return nil
+}
+
+// validateDeleteParameters returns a 400 error naming the mandatory query
+// parameters missing from a deprovision or unbind request, unless
+// AllowMissingDeleteParameters is set.
+func (s *APISurface) validateDeleteParameters(serviceID, planID string) error {
+	if s.AllowMissingDeleteParameters {
+		return nil
+	}
+	var missing []string
+	if serviceID == "" {
+		missing = append(missing, osb.VarKeyServiceID)
+	}
+	if planID == "" {
+		missing = append(missing, osb.VarKeyPlanID)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusBadRequest,
+		Description: strPtr(fmt.Sprintf("missing mandatory query parameters: %s", strings.Join(missing, ", "))),
+	}
+}
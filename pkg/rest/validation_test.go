@@ -0,0 +1,112 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestValidateResponsesEndpoints(t *testing.T) {
+	endpoints := []broker.Endpoint{{Host: "db.example.com", Ports: []string{"5432"}, Protocol: broker.ProtocolTCP}}
+	logic := &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			return &broker.BindResponse{Endpoints: endpoints}, nil
+		},
+	}
+
+	tests := []struct {
+		name      string
+		validate  bool
+		endpoints []broker.Endpoint
+		expected  int
+	}{
+		{name: "valid", validate: true, endpoints: endpoints, expected: http.StatusCreated},
+		{name: "invalid", validate: true, endpoints: []broker.Endpoint{{Host: "db.example.com"}}, expected: http.StatusInternalServerError},
+		{name: "not validated", endpoints: []broker.Endpoint{{Host: "db.example.com"}}, expected: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoints = tt.endpoints
+			s := brokertest.NewServer(t, logic, func(api *rest.APISurface) {
+				api.ValidateResponses = tt.validate
+			})
+
+			s.Client.Bind(&osb.BindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"})
+			if e, a := tt.expected, s.LastResponse().StatusCode; e != a {
+				t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestValidateResponsesVolumeMounts(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			response := &broker.BindResponse{}
+			response.VolumeMounts = []interface{}{broker.VolumeMount{
+				Driver:       "nfsv3driver",
+				ContainerDir: "/data",
+				Mode:         "rw",
+				DeviceType:   broker.VolumeDeviceTypeShared,
+			}}
+			return response, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.ValidateResponses = true
+	})
+
+	if _, err := s.Client.Bind(&osb.BindRequest{InstanceID: "instance", BindingID: "binding", ServiceID: "service", PlanID: "plan"}); err == nil {
+		t.Fatal("Expected a volume mount without a volume_id to be rejected")
+	}
+	if e, a := http.StatusInternalServerError, s.LastResponse().StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+}
+
+func TestValidateDeleteParameters(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		allow       bool
+		expected    int
+		description string
+	}{
+		{name: "deprovision", path: "/v2/service_instances/instance?service_id=service&plan_id=plan", expected: http.StatusOK},
+		{name: "deprovision without plan", path: "/v2/service_instances/instance?service_id=service", expected: http.StatusBadRequest, description: "missing mandatory query parameters: plan_id"},
+		{name: "unbind without parameters", path: "/v2/service_instances/instance/service_bindings/binding", expected: http.StatusBadRequest, description: "missing mandatory query parameters: service_id, plan_id"},
+		{name: "lenient", path: "/v2/service_instances/instance/service_bindings/binding", allow: true, expected: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		s := brokertest.NewServer(t, &brokertest.FakeBroker{}, func(api *rest.APISurface) {
+			api.AllowMissingDeleteParameters = tc.allow
+		})
+		request, err := http.NewRequest(http.MethodDelete, s.URL+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, "2.13")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := struct {
+			Description string `json:"description"`
+		}{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if e, a := tc.expected, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status code; expected %v, got %v", tc.name, e, a)
+		}
+		if e, a := tc.description, body.Description; e != a {
+			t.Errorf("%v: unexpected description; expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
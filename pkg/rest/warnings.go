@@ -0,0 +1,25 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// DefaultWarningHeader is the conventional WarningHeader.
+const DefaultWarningHeader = "X-Broker-Warning"
+
+// headerUnsafe replaces the characters that can't appear in a header value.
+var headerUnsafe = strings.NewReplacer("\r", " ", "\n", " ")
+
+// writeWarnings logs the warnings of the business logic and adds them to
+// the WarningHeader of the response, if it is set.
+func (s *APISurface) writeWarnings(w http.ResponseWriter, r *http.Request, warnings []string) {
+	for _, warning := range warnings {
+		broker.NewRequestLogger(r).Warningf("Business logic warning: %s", warning)
+		if s.WarningHeader != "" {
+			w.Header().Add(s.WarningHeader, headerUnsafe.Replace(warning))
+		}
+	}
+}
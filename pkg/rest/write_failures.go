@@ -0,0 +1,16 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// WriteFailed logs a failed write of the response to r, which leaves the
+// platform with a truncated or missing response, and counts it in the
+// ResponseWriteFailures metric. Handlers wrapping those of the APISurface
+// and writing their responses report failures through it too.
+func (s *APISurface) WriteFailed(r *http.Request, err error) {
+	s.Metrics.ResponseWriteFailures.WithLabelValues(operationName(r)).Inc()
+	broker.NewRequestLogger(r).Errorf("Error writing response: %v", err)
+}
@@ -0,0 +1,43 @@
+package rest_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// failingWriter is an http.ResponseWriter whose connection is gone.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestResponseWriteFailures(t *testing.T) {
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	request.Header.Set(osb.APIVersionHeader, "2.13")
+	api.GetCatalogHandler(failingWriter{httptest.NewRecorder()}, request)
+
+	metric := &dto.Metric{}
+	if err := api.Metrics.ResponseWriteFailures.WithLabelValues("").Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected write failure count; expected %v, got %v", e, a)
+	}
+}
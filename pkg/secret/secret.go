@@ -0,0 +1,94 @@
+// Package secret projects binding credentials onto the data of a Kubernetes
+// Secret.
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+)
+
+// Separator joins the keys of nested objects in Secret keys.
+const Separator = "_"
+
+// invalidKeyChars matches the characters not allowed in Secret keys.
+var invalidKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// Key returns the Secret key for a credentials key, replacing every
+// character Kubernetes doesn't allow in Secret keys with an underscore.
+func Key(key string) string {
+	return invalidKeyChars.ReplaceAllString(key, "_")
+}
+
+// FromCredentials projects credentials onto Secret data:
+//
+//   - strings are stored as is
+//   - numbers, booleans and arrays are stored JSON encoded
+//   - null values are stored empty
+//   - nested objects are flattened, their keys joined to the parent's with
+//     Separator, so {"db": {"host": "h"}} becomes db_host
+//
+// Keys are passed through Key. It returns an error if two credentials map to
+// the same Secret key.
+func FromCredentials(credentials map[string]interface{}) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	if err := project(data, "", credentials); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// FromBindResponse projects the credentials of a bind response onto Secret
+// data. See FromCredentials.
+func FromBindResponse(response *broker.BindResponse) (map[string][]byte, error) {
+	return FromCredentials(response.Credentials)
+}
+
+func project(data map[string][]byte, prefix string, object map[string]interface{}) error {
+	// Visit keys in order so that collision errors are deterministic.
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := Key(k)
+		if prefix != "" {
+			key = prefix + Separator + key
+		}
+
+		if nested, ok := object[k].(map[string]interface{}); ok {
+			if err := project(data, key, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := encode(object[k])
+		if err != nil {
+			return fmt.Errorf("encoding credential %q: %v", key, err)
+		}
+		if _, exists := data[key]; exists {
+			return fmt.Errorf("more than one credential maps to the Secret key %q", key)
+		}
+		data[key] = value
+	}
+	return nil
+}
+
+func encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromCredentials(t *testing.T) {
+	data, err := FromCredentials(map[string]interface{}{
+		"uri":      "postgres://db.example.com",
+		"port":     5432.0,
+		"tls":      true,
+		"hosts":    []interface{}{"a", "b"},
+		"optional": nil,
+		"admin": map[string]interface{}{
+			"user": "root",
+			"keys": map[string]interface{}{"primary": "k1"},
+		},
+		"api key": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]byte{
+		"uri":                []byte("postgres://db.example.com"),
+		"port":               []byte("5432"),
+		"tls":                []byte("true"),
+		"hosts":              []byte(`["a","b"]`),
+		"optional":           {},
+		"admin_user":         []byte("root"),
+		"admin_keys_primary": []byte("k1"),
+		"api_key":            []byte("secret"),
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("Unexpected data; expected %q, got %q", expected, data)
+	}
+}
+
+func TestFromCredentialsCollision(t *testing.T) {
+	_, err := FromCredentials(map[string]interface{}{
+		"db_host": "a",
+		"db":      map[string]interface{}{"host": "b"},
+	})
+	if err == nil {
+		t.Error("Expected an error for colliding keys")
+	}
+}
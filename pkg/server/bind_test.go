@@ -1,4 +1,4 @@
-package server
+package server_test
 
 import (
 	"errors"
@@ -9,8 +9,10 @@ import (
 	"testing"
 
 	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
 	"github.com/pmorie/osb-broker-lib/pkg/metrics"
 	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
 
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 	prom "github.com/prometheus/client_golang/prometheus"
@@ -100,14 +102,14 @@ func TestBind(t *testing.T) {
 			reg.MustRegister(osbMetrics)
 
 			api := &rest.APISurface{
-				Broker: &FakeBroker{
-					validateAPIVersion: validateFunc,
-					bind:               tc.bindFunc,
+				Broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: validateFunc,
+					BindFunc:                     tc.bindFunc,
 				},
 				Metrics: osbMetrics,
 			}
 
-			s := New(api, reg)
+			s := server.New(api, reg)
 			fs := httptest.NewServer(s.Router)
 			defer fs.Close()
 
@@ -0,0 +1,46 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/deadline"
+)
+
+func TestEnableRequestDeadlines(t *testing.T) {
+	var remaining time.Duration
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			ctx := c.Context()
+			if deadline, ok := ctx.Deadline(); ok {
+				remaining = time.Until(deadline)
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	s.BrokerServer.EnableRequestDeadlines(&deadline.Deadlines{})
+
+	body := `{"service_id": "service", "plan_id": "plan", "organization_guid": "org", "space_guid": "space"}`
+	req, err := http.NewRequest(http.MethodPut, s.URL+"/v2/service_instances/instance", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(osb.APIVersionHeader, "2.13")
+	req.Header.Set(deadline.DefaultHeader, "0.05")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brokertest.AssertOSBError(t, resp, http.StatusGatewayTimeout, "DeadlineExceeded")
+	if remaining <= 0 || remaining > 50*time.Millisecond {
+		t.Errorf("Expected the business logic to get the platform's deadline, got %v remaining", remaining)
+	}
+}
@@ -0,0 +1,164 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/jobs"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDrain(t *testing.T) {
+	reg := prom.NewRegistry()
+	osbMetrics := metrics.New()
+	reg.MustRegister(osbMetrics)
+
+	state := osb.StateInProgress
+	api := &rest.APISurface{
+		Broker: &brokertest.FakeBroker{
+			ValidateBrokerAPIVersionFunc: defaultValidateFunc,
+			ProvisionFunc: func(req *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+				return &broker.ProvisionResponse{
+					ProvisionResponse: osb.ProvisionResponse{Async: true},
+				}, nil
+			},
+			LastOperationFunc: func(req *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+				return &broker.LastOperationResponse{
+					LastOperationResponse: osb.LastOperationResponse{State: state},
+				}, nil
+			},
+		},
+		Metrics: osbMetrics,
+	}
+
+	s := server.New(api, reg)
+	fs := httptest.NewServer(s.Router)
+	defer fs.Close()
+
+	config := defaultClientConfiguration()
+	config.URL = fs.URL
+
+	client, err := osb.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &osb.ProvisionRequest{
+		InstanceID:        "12345",
+		ServiceID:         "12345",
+		PlanID:            "12345",
+		OrganizationGUID:  "12345",
+		SpaceGUID:         "12345",
+		AcceptsIncomplete: true,
+	}
+	if _, err := client.ProvisionInstance(request); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected drain to time out with an in-flight operation, got %v", err)
+	}
+
+	_, err = client.ProvisionInstance(request)
+	httpErr, ok := osb.IsHTTPError(err)
+	if !ok || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while draining, got %v", err)
+	}
+
+	// Polls are still served while draining.
+	if _, err := client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "12345"}); err != nil {
+		t.Fatal(err)
+	}
+
+	state = osb.StateSucceeded
+	if _, err := client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "12345"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Drain(context.Background()); err != nil {
+		t.Fatalf("Unexpected error draining: %v", err)
+	}
+}
+
+func TestDrainRetryAfter(t *testing.T) {
+	api := &rest.APISurface{
+		Broker:          &brokertest.FakeBroker{ValidateBrokerAPIVersionFunc: defaultValidateFunc},
+		Metrics:         metrics.New(),
+		DrainRetryAfter: 10 * time.Second,
+	}
+	api.Drain()
+
+	rec := httptest.NewRecorder()
+	server.NewHTTPHandler(api).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v2/service_instances/12345", nil))
+
+	if e, a := http.StatusServiceUnavailable, rec.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := "10", rec.Header().Get("Retry-After"); e != a {
+		t.Fatalf("Unexpected Retry-After; expected %v, got %v", e, a)
+	}
+}
+
+func TestDrainJobs(t *testing.T) {
+	manager := jobs.NewManager(storage.NewMemory(), 1)
+	defer manager.Close()
+
+	release := make(chan struct{})
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ProvisionFunc: func(req *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			return &broker.ProvisionResponse{
+				Job: func(ctx context.Context, progress func(string)) error {
+					<-release
+					return nil
+				},
+			}, nil
+		},
+	}, func(api *rest.APISurface) {
+		api.Jobs = manager
+	})
+
+	_, err := s.Client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:        "12345",
+		ServiceID:         "12345",
+		PlanID:            "12345",
+		OrganizationGUID:  "12345",
+		SpaceGUID:         "12345",
+		AcceptsIncomplete: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.BrokerServer.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected drain to time out with a running job, got %v", err)
+	}
+
+	// The job finishing completes the drain, without any poll.
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.BrokerServer.Drain(ctx); err != nil {
+		t.Fatalf("Unexpected error draining: %v", err)
+	}
+}
+
+func TestDrainWithoutAPISurface(t *testing.T) {
+	if err := (&server.Server{}).Drain(context.Background()); err == nil {
+		t.Error("Expected an error draining a Server not created with New")
+	}
+}
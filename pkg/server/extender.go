@@ -0,0 +1,275 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// FeatureExtender adds a feature that is not part of the OSB API to a
+// Server, such as a broker-specific endpoint. Extensions are installed with
+// Server.Extend.
+type FeatureExtender interface {
+	// RegisterRoutes attaches the extension's routes to router. Requests
+	// to those routes are rejected with a 412 unless their
+	// X-Broker-API-Version is at least MinAPIVersion.
+	RegisterRoutes(router *mux.Router)
+	// Middleware returns the middleware to install on the OSB API routes,
+	// outermost first. It may return nil.
+	Middleware() []mux.MiddlewareFunc
+	// AugmentCatalog is run on the catalog before it is written; see
+	// rest.CatalogAugmenter. It may be nil.
+	AugmentCatalog() rest.CatalogAugmenter
+	// MinAPIVersion returns the lowest OSB API version, such as "2.14",
+	// the extension's routes serve. An empty version serves every
+	// request.
+	MinAPIVersion() string
+}
+
+// Prioritizer is implemented by feature extenders whose routes may overlap
+// those of other extenders. Requests matching the routes of several
+// extenders are served by the one with the highest priority; extenders
+// without a Priority have priority zero.
+type Prioritizer interface {
+	Priority() int
+}
+
+// extender is an installed FeatureExtender and the router serving its
+// routes.
+type extender struct {
+	name     string
+	priority int
+	router   *mux.Router
+}
+
+// Extend installs the routes, OSB middleware and catalog augmenter of e.
+// Extensions must be installed before the server starts serving requests.
+// The routes are wrapped with rest.APISurface.InstrumentHandler.
+// It returns an error listing the clashing routes, and installs nothing, if
+// a route of e has the same path and method as a route of the server or of
+// an extender of the same priority; see Prioritizer.
+func (s *Server) Extend(e FeatureExtender) error {
+	name := extenderName(e)
+	priority := 0
+	if p, ok := e.(Prioritizer); ok {
+		priority = p.Priority()
+	}
+
+	router := mux.NewRouter()
+	e.RegisterRoutes(router)
+	if conflicts := s.routeConflicts(router, priority); len(conflicts) > 0 {
+		return fmt.Errorf("server: routes of extension %q conflict with %s", name, strings.Join(conflicts, ", "))
+	}
+	if s.api != nil {
+		instrumentRoutes(router, s.api, name)
+	}
+	if min := e.MinAPIVersion(); min != "" {
+		router.Use(requireAPIVersion(min))
+	}
+
+	if s.extenders == nil {
+		s.Router.MatcherFunc(s.matchExtenders)
+	}
+	s.extenders = append(s.extenders, &extender{name: name, priority: priority, router: router})
+	sort.SliceStable(s.extenders, func(i, j int) bool {
+		return s.extenders[i].priority > s.extenders[j].priority
+	})
+	s.extensions = append(s.extensions, name)
+
+	for _, mw := range e.Middleware() {
+		s.UseOSBMiddleware(mw)
+	}
+
+	if augment := e.AugmentCatalog(); augment != nil && s.api != nil {
+		s.api.CatalogAugmenters = append(s.api.CatalogAugmenters, augment)
+	}
+	return nil
+}
+
+// instrumentRoutes wraps the handlers of the routes of router with
+// api.InstrumentHandler, so they are counted, validated and logged like the
+// OSB operations. Routes are counted under their name, or else under the
+// name of their extender.
+func instrumentRoutes(router *mux.Router, api *rest.APISurface, extenderName string) {
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		handler := route.GetHandler()
+		if handler == nil {
+			return nil
+		}
+		operation := route.GetName()
+		if operation == "" {
+			operation = extenderName
+		}
+		route.Handler(api.InstrumentHandler(operation, handler))
+		return nil
+	})
+}
+
+// matchExtenders is a mux.MatcherFunc matching requests for the routes of
+// the installed extenders, in order of priority.
+func (s *Server) matchExtenders(r *http.Request, match *mux.RouteMatch) bool {
+	mismatch := false
+	for _, e := range s.extenders {
+		var inner mux.RouteMatch
+		if e.router.Match(r, &inner) && inner.MatchErr == nil {
+			*match = inner
+			return true
+		}
+		mismatch = mismatch || inner.MatchErr == mux.ErrMethodMismatch
+	}
+	if mismatch {
+		// Let the router answer 405 unless a later route matches.
+		match.MatchErr = mux.ErrMethodMismatch
+	}
+	return false
+}
+
+// routeInfo describes a route for conflict detection.
+type routeInfo struct {
+	template string
+	methods  []string
+	owner    string
+}
+
+func (r routeInfo) String() string {
+	methods := "*"
+	if len(r.methods) > 0 {
+		methods = strings.Join(r.methods, ",")
+	}
+	return fmt.Sprintf("%s %s (%s)", methods, r.template, r.owner)
+}
+
+// overlaps returns whether requests can match both r and o.
+func (r routeInfo) overlaps(o routeInfo) bool {
+	if normalizeTemplate(r.template) != normalizeTemplate(o.template) {
+		return false
+	}
+	if len(r.methods) == 0 || len(o.methods) == 0 {
+		return true
+	}
+	for _, m := range r.methods {
+		for _, n := range o.methods {
+			if strings.EqualFold(m, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templateVariable matches the variables of a mux path template.
+var templateVariable = regexp.MustCompile(`\{[^}]*\}`)
+
+// normalizeTemplate replaces the variables of a path template, whose names
+// don't affect matching, with {}.
+func normalizeTemplate(template string) string {
+	return templateVariable.ReplaceAllString(template, "{}")
+}
+
+// routes returns the routes of router with a path template, owned by owner,
+// or by their name when owner is empty.
+func routes(router *mux.Router, owner string) []routeInfo {
+	var infos []routeInfo
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		info := routeInfo{template: template, methods: methods, owner: owner}
+		if info.owner == "" {
+			info.owner = route.GetName()
+		}
+		if info.owner == "" {
+			info.owner = "server"
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	return infos
+}
+
+// routeConflicts returns the routes of the server and of the extenders of
+// the given priority that the routes of router overlap.
+func (s *Server) routeConflicts(router *mux.Router, priority int) []string {
+	existing := routes(s.Router, "")
+	for _, e := range s.extenders {
+		if e.priority == priority {
+			existing = append(existing, routes(e.router, "extension "+strconv.Quote(e.name))...)
+		}
+	}
+
+	var conflicts []string
+	for _, route := range routes(router, "new") {
+		for _, other := range existing {
+			if route.overlaps(other) {
+				conflicts = append(conflicts, other.String())
+			}
+		}
+	}
+	return conflicts
+}
+
+// requireAPIVersion returns middleware rejecting requests whose
+// X-Broker-API-Version is lower than min, or missing, with a 412.
+func requireAPIVersion(min string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := r.Header.Get(osb.APIVersionHeader)
+			if !apiVersionAtLeast(version, min) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusPreconditionFailed)
+				json.NewEncoder(w).Encode(struct {
+					Description string `json:"description"`
+				}{
+					Description: fmt.Sprintf("This endpoint requires %s %s or later; got %q.", osb.APIVersionHeader, min, version),
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiVersionAtLeast returns whether the OSB API version is at least min.
+// Versions are compared as major.minor; a malformed version is never at
+// least min.
+func apiVersionAtLeast(version, min string) bool {
+	major, minor, ok := parseAPIVersion(version)
+	if !ok {
+		return false
+	}
+	minMajor, minMinor, ok := parseAPIVersion(min)
+	if !ok {
+		return false
+	}
+	if major != minMajor {
+		return major > minMajor
+	}
+	return minor >= minMinor
+}
+
+func parseAPIVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(version, ".", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
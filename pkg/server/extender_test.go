@@ -0,0 +1,214 @@
+package server_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+type backupExtension struct{}
+
+func (backupExtension) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/v2/service_instances/{instance_id}/backups", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+}
+
+func (backupExtension) Middleware() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Backups", "supported")
+			next.ServeHTTP(w, r)
+		})
+	}}
+}
+
+func (backupExtension) AugmentCatalog() rest.CatalogAugmenter {
+	return func(c *broker.RequestContext, catalog *broker.CatalogResponse) error {
+		for i := range catalog.Services {
+			if catalog.Services[i].Metadata == nil {
+				catalog.Services[i].Metadata = map[string]interface{}{}
+			}
+			catalog.Services[i].Metadata["backups"] = true
+		}
+		return nil
+	}
+}
+
+func (backupExtension) MinAPIVersion() string {
+	return "2.14"
+}
+
+func TestExtend(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{ID: "service", Name: "service"}}
+			return response, nil
+		},
+	})
+	s.BrokerServer.Extend(backupExtension{})
+
+	get := func(path, version string) *http.Response {
+		request, err := http.NewRequest("GET", s.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != "" {
+			request.Header.Set(osb.APIVersionHeader, version)
+		}
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	cases := []struct {
+		version  string
+		expected int
+	}{
+		{version: "2.14", expected: http.StatusOK},
+		{version: "2.15", expected: http.StatusOK},
+		{version: "3.0", expected: http.StatusOK},
+		{version: "2.13", expected: http.StatusPreconditionFailed},
+		{version: "", expected: http.StatusPreconditionFailed},
+	}
+	for _, tc := range cases {
+		if e, a := tc.expected, get("/v2/service_instances/instance/backups", tc.version).StatusCode; e != a {
+			t.Errorf("version %q: unexpected status code; expected %v, got %v", tc.version, e, a)
+		}
+	}
+
+	catalog, err := s.Client.GetCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := true, catalog.Services[0].Metadata["backups"]; e != a {
+		t.Errorf("Expected the catalog to be augmented; expected %v, got %v", e, a)
+	}
+	if e, a := "supported", s.LastResponse().Header.Get("X-Backups"); e != a {
+		t.Errorf("Expected the middleware to run on OSB routes; expected %v, got %v", e, a)
+	}
+
+	if e, a := "", get("/healthz", "").Header.Get("X-Backups"); e != a {
+		t.Errorf("Expected the middleware not to run on /healthz; expected %q, got %q", e, a)
+	}
+}
+
+// staticExtender serves a fixed body at a path.
+type staticExtender struct {
+	name     string
+	path     string
+	body     string
+	priority int
+}
+
+func (e staticExtender) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc(e.path, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(e.body))
+	}).Methods("GET")
+}
+func (staticExtender) Middleware() []mux.MiddlewareFunc      { return nil }
+func (staticExtender) AugmentCatalog() rest.CatalogAugmenter { return nil }
+func (staticExtender) MinAPIVersion() string                 { return "" }
+func (e staticExtender) Name() string                        { return e.name }
+func (e staticExtender) Priority() int                       { return e.priority }
+
+func TestExtendConflicts(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	if err := s.BrokerServer.Extend(staticExtender{name: "first", path: "/v2/service_instances/{instance_id}/usage", body: "first"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err := s.BrokerServer.Extend(staticExtender{name: "second", path: "/v2/service_instances/{id}/usage", body: "second"})
+	if err == nil {
+		t.Fatal("Expected an error for routes clashing with an extender of the same priority")
+	}
+	if !strings.Contains(err.Error(), `GET /v2/service_instances/{instance_id}/usage (extension "first")`) {
+		t.Errorf("Expected the error to list the clashing route, got %v", err)
+	}
+
+	err = s.BrokerServer.Extend(staticExtender{name: "catalog", path: "/v2/catalog", priority: 10})
+	if err == nil || !strings.Contains(err.Error(), "GET,HEAD /v2/catalog ("+rest.OperationGetCatalog+")") {
+		t.Errorf("Expected an error for routes clashing with the OSB API whatever their priority, got %v", err)
+	}
+
+	if err := s.BrokerServer.Extend(staticExtender{name: "override", path: "/v2/service_instances/{instance_id}/usage", body: "override", priority: 10}); err != nil {
+		t.Fatalf("Unexpected error for an extender with a higher priority: %v", err)
+	}
+
+	resp, err := http.Get(s.URL + "/v2/service_instances/instance/usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "override", string(body); e != a {
+		t.Errorf("Expected the extender with the highest priority to serve the route; expected %q, got %q", e, a)
+	}
+}
+
+func TestExtendInstrumentsRoutes(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		ValidateBrokerAPIVersionFunc: func(version string) error {
+			if version == "1.0" {
+				return fmt.Errorf("unsupported version %v", version)
+			}
+			return nil
+		},
+	})
+	if err := s.BrokerServer.Extend(staticExtender{name: "usage", path: "/v2/service_instances/{instance_id}/usage"}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, version string) int {
+		request, err := http.NewRequest("GET", s.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set(osb.APIVersionHeader, version)
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if e, a := http.StatusOK, get("/v2/service_instances/instance/usage", "2.14"); e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusPreconditionFailed, get("/v2/service_instances/instance/usage", "1.0"); e != a {
+		t.Errorf("Expected the API version to be validated; expected %v, got %v", e, a)
+	}
+
+	metric := &dto.Metric{}
+	if err := s.API.Metrics.Actions.WithLabelValues("usage").Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected action count; expected %v, got %v", e, a)
+	}
+	metric = &dto.Metric{}
+	if err := s.API.Metrics.Errors.WithLabelValues("usage", rest.ErrorClassValidationFailed).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, metric.Counter.GetValue(); e != a {
+		t.Errorf("Unexpected error count; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,131 @@
+package server_test
+
+import (
+	"flag"
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// goldenBroker returns fixed responses so that the serialized exchanges of
+// every operation can be compared against golden files.
+func goldenBroker() broker.Interface {
+	operation := osb.OperationKey("op-1")
+	return &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{
+				ID:          "service-1",
+				Name:        "example",
+				Description: "An example service",
+				Bindable:    true,
+				Plans:       []osb.Plan{{ID: "plan-1", Name: "default", Description: "The default plan"}},
+			}}
+			return response, nil
+		},
+		ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+			response := &broker.ProvisionResponse{}
+			response.Async = request.AcceptsIncomplete
+			response.DashboardURL = strPtr("https://dashboard/" + request.InstanceID)
+			response.OperationKey = &operation
+			return response, nil
+		},
+		UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+			return &broker.UpdateInstanceResponse{}, nil
+		},
+		DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+			return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusGone}
+		},
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			response := &broker.BindResponse{}
+			response.Credentials = map[string]interface{}{"username": "user", "password": "secret"}
+			return response, nil
+		},
+		LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+			response := &broker.LastOperationResponse{}
+			response.State = osb.StateInProgress
+			response.Description = strPtr("creating")
+			return response, nil
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	api, err := rest.NewAPISurface(goldenBroker(), metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := server.NewHTTPHandler(api)
+
+	golden := &brokertest.Golden{Dir: "testdata/golden", Update: *updateGolden}
+	version := http.Header{osb.APIVersionHeader: []string{"2.13"}}
+
+	cases := []struct {
+		name    string
+		request brokertest.GoldenRequest
+	}{
+		{
+			name:    "get_catalog",
+			request: brokertest.GoldenRequest{Method: "GET", URL: "/v2/catalog", Header: version},
+		},
+		{
+			name: "provision",
+			request: brokertest.GoldenRequest{
+				Method: "PUT",
+				URL:    "/v2/service_instances/i1?accepts_incomplete=true",
+				Header: version,
+				Body:   `{"service_id":"service-1","plan_id":"plan-1","organization_guid":"org","space_guid":"space"}`,
+			},
+		},
+		{
+			name: "update",
+			request: brokertest.GoldenRequest{
+				Method: "PATCH",
+				URL:    "/v2/service_instances/i1",
+				Header: version,
+				Body:   `{"service_id":"service-1","plan_id":"plan-1"}`,
+			},
+		},
+		{
+			name: "deprovision_gone",
+			request: brokertest.GoldenRequest{
+				Method: "DELETE",
+				URL:    "/v2/service_instances/i1?service_id=service-1&plan_id=plan-1",
+				Header: version,
+			},
+		},
+		{
+			name: "bind",
+			request: brokertest.GoldenRequest{
+				Method: "PUT",
+				URL:    "/v2/service_instances/i1/service_bindings/b1",
+				Header: version,
+				Body:   `{"service_id":"service-1","plan_id":"plan-1"}`,
+			},
+		},
+		{
+			name: "last_operation",
+			request: brokertest.GoldenRequest{
+				Method: "GET",
+				URL:    "/v2/service_instances/i1/last_operation?operation=op-1",
+				Header: version,
+			},
+		},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			golden.Assert(t, handler, tc.name, tc.request)
+		})
+	}
+}
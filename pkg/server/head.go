@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etagHandler renders responses of h in memory so that they can be sent with
+// a Content-Length and an ETag of the body. HEAD requests are answered with
+// the headers of the equivalent GET and no body. A successful response whose
+// ETag the request lists in If-None-Match is answered with a 304 Not
+// Modified and no body. A GET response that h flushes, such as a streamed
+// catalog, is passed through from the first flush on and carries no ETag.
+// Failures writing the rendered body are reported to writeFailed.
+func etagHandler(h http.Handler, writeFailed func(*http.Request, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffer := &etagResponseWriter{
+			ResponseWriter: w,
+			request:        r,
+			writeFailed:    writeFailed,
+			head:           r.Method == http.MethodHead,
+			statusCode:     http.StatusOK,
+		}
+		h.ServeHTTP(buffer, r)
+		if buffer.streaming {
+			return
+		}
+
+		if buffer.statusCode == http.StatusNoContent {
+			w.WriteHeader(buffer.statusCode)
+			return
+		}
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(buffer.body.Bytes()))
+		w.Header().Set("ETag", etag)
+		if buffer.statusCode == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(buffer.body.Len()))
+		w.WriteHeader(buffer.statusCode)
+		if !buffer.head {
+			if _, err := w.Write(buffer.body.Bytes()); err != nil {
+				writeFailed(r, err)
+			}
+		}
+	})
+}
+
+// etagMatches returns whether the If-None-Match header value lists etag,
+// comparing weakly as RFC 7232 requires.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagResponseWriter buffers a response in memory until it is flushed.
+// Headers are written to the underlying ResponseWriter's header map directly.
+type etagResponseWriter struct {
+	http.ResponseWriter
+	request     *http.Request
+	writeFailed func(*http.Request, error)
+	head        bool
+	statusCode  int
+	body        bytes.Buffer
+	streaming   bool
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	if w.streaming {
+		return
+	}
+	w.statusCode = code
+}
+
+func (w *etagResponseWriter) Write(data []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+// Flush switches a GET response to streaming: what was buffered so far is
+// written out and later writes go straight to the connection. Flushes are
+// ignored for HEAD requests, which never have a body.
+func (w *etagResponseWriter) Flush() {
+	if w.head {
+		return
+	}
+	if !w.streaming {
+		w.streaming = true
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+			w.writeFailed(w.request, err)
+		}
+		w.body.Reset()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,142 @@
+package server_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestHead(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	for _, path := range []string{"/v2/catalog", "/healthz", "/readiness"} {
+		t.Run(path, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+path, nil)
+			req.Header.Set("X-Broker-API-Version", "2.13")
+			get, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(get.Body)
+			get.Body.Close()
+
+			req, _ = http.NewRequest(http.MethodHead, s.URL+path, nil)
+			req.Header.Set("X-Broker-API-Version", "2.13")
+			head, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			head.Body.Close()
+
+			if e, a := get.StatusCode, head.StatusCode; e != a {
+				t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+			}
+			if e, a := strconv.Itoa(len(body)), head.Header.Get("Content-Length"); e != a {
+				t.Errorf("Unexpected Content-Length; expected %v, got %v", e, a)
+			}
+			if head.Header.Get("ETag") == "" {
+				t.Error("Expected an ETag header")
+			}
+			if e, a := get.Header.Get("ETag"), head.Header.Get("ETag"); e != a {
+				t.Errorf("Expected GET and HEAD to have the same ETag; expected %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestHeadNotCountedAsAction(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	req, _ := http.NewRequest(http.MethodHead, s.URL+"/v2/catalog", nil)
+	req.Header.Set("X-Broker-API-Version", "2.13")
+	head, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.Body.Close()
+
+	m := &dto.Metric{}
+	if err := s.API.Metrics.Actions.WithLabelValues(rest.OperationGetCatalog).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 0.0, m.GetCounter().GetValue(); e != a {
+		t.Errorf("Expected HEAD not to be counted as an action; expected %v, got %v", e, a)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	get := func(ifNoneMatch string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, s.URL+"/v2/catalog", nil)
+		req.Header.Set("X-Broker-API-Version", "2.13")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, body
+	}
+
+	first, body := get("")
+	if e, a := strconv.Itoa(len(body)), first.Header.Get("Content-Length"); e != a {
+		t.Errorf("Unexpected Content-Length; expected %v, got %v", e, a)
+	}
+
+	cases := []struct {
+		ifNoneMatch string
+		expected    int
+	}{
+		{ifNoneMatch: first.Header.Get("ETag"), expected: http.StatusNotModified},
+		{ifNoneMatch: `"other", W/` + first.Header.Get("ETag"), expected: http.StatusNotModified},
+		{ifNoneMatch: "*", expected: http.StatusNotModified},
+		{ifNoneMatch: `"other"`, expected: http.StatusOK},
+	}
+	for _, tc := range cases {
+		resp, body := get(tc.ifNoneMatch)
+		if e, a := tc.expected, resp.StatusCode; e != a {
+			t.Errorf("%v: unexpected status; expected %v, got %v", tc.ifNoneMatch, e, a)
+		}
+		if tc.expected == http.StatusNotModified && len(body) != 0 {
+			t.Errorf("%v: expected no body, got %q", tc.ifNoneMatch, body)
+		}
+	}
+}
+
+// failingWriter is an http.ResponseWriter whose connection is gone.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestETagWriteFailures(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	req.Header.Set("X-Broker-API-Version", "2.13")
+	s.BrokerServer.Router.ServeHTTP(failingWriter{httptest.NewRecorder()}, req)
+
+	m := &dto.Metric{}
+	if err := s.API.Metrics.ResponseWriteFailures.WithLabelValues(rest.OperationGetCatalog).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1.0, m.GetCounter().GetValue(); e != a {
+		t.Errorf("Unexpected write failure count; expected %v, got %v", e, a)
+	}
+}
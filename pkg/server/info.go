@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+const (
+	// InfoPath is the path EnableInfo serves the broker info at.
+	InfoPath = "/v2/info"
+	// OperationInfo names the info route. Like the OSB operations, it is
+	// behind the OSB middleware.
+	OperationInfo = "info"
+)
+
+// knownAPIVersions are the OSB API versions the info endpoint probes the
+// business logic for.
+var knownAPIVersions = []string{"2.11", "2.12", "2.13", "2.14", "2.15", "2.16", "2.17"}
+
+// BrokerInfo describes a broker deployment.
+type BrokerInfo struct {
+	// Name is the name of the broker.
+	Name string `json:"name"`
+	// Version is the version of the broker.
+	Version string `json:"version"`
+	// APIVersions are the OSB API versions accepted by the business logic.
+	// EnableInfo fills them in.
+	APIVersions []string `json:"api_versions"`
+	// Extensions are the feature extenders installed with Extend and the
+	// extension APIs served. EnableInfo fills them in.
+	Extensions []string `json:"extensions"`
+}
+
+// Namer is implemented by feature extenders that name themselves in the
+// broker info. Other extenders are named after their type.
+type Namer interface {
+	Name() string
+}
+
+// EnableInfo serves the broker's name and version, the OSB API versions its
+// business logic accepts and its extensions at InfoPath, so operators and
+// platforms can introspect deployments. Requests need no
+// X-Broker-API-Version header.
+func (s *Server) EnableInfo(name, version string) {
+	s.Router.HandleFunc(InfoPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Info(name, version))
+	}).Methods("GET").Name(OperationInfo)
+}
+
+// Info returns the BrokerInfo served by EnableInfo.
+func (s *Server) Info(name, version string) *BrokerInfo {
+	info := &BrokerInfo{
+		Name:        name,
+		Version:     version,
+		APIVersions: []string{},
+		Extensions:  append([]string{}, s.extensions...),
+	}
+	if s.api == nil {
+		return info
+	}
+	for _, v := range knownAPIVersions {
+		if s.api.Broker.ValidateBrokerAPIVersion(v) == nil {
+			info.APIVersions = append(info.APIVersions, v)
+		}
+	}
+	for _, extension := range s.api.Extensions() {
+		if extension.AdheresTo != "" {
+			info.Extensions = append(info.Extensions, extension.AdheresTo)
+		}
+	}
+	return info
+}
+
+// extenderName returns the name of e in the broker info.
+func extenderName(e FeatureExtender) string {
+	if namer, ok := e.(Namer); ok {
+		return namer.Name()
+	}
+	t := reflect.TypeOf(e)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
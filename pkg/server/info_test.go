@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+type namedExtender struct {
+	routeExtender
+}
+
+func (namedExtender) Name() string { return "snapshots" }
+
+// Priority lets namedExtender serve the routes it shares with
+// routeExtender.
+func (namedExtender) Priority() int { return 1 }
+
+func TestInfo(t *testing.T) {
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{
+		ValidateBrokerAPIVersionFunc: func(version string) error {
+			if version != "2.13" && version != "2.14" {
+				return fmt.Errorf("unsupported version %v", version)
+			}
+			return nil
+		},
+	}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.ExtensionAPIs = []rest.ExtensionAPI{{
+		ExtensionAPI: broker.ExtensionAPI{AdheresTo: "http://example.com/backup"},
+	}}
+
+	s := server.New(api, prom.NewRegistry())
+	if err := s.Extend(namedExtender{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Extend(routeExtender{}); err != nil {
+		t.Fatal(err)
+	}
+	s.EnableInfo("test-broker", "1.2.3")
+
+	r := httptest.NewRequest("GET", server.InfoPath, nil)
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	info := &server.BrokerInfo{}
+	if err := json.Unmarshal(w.Body.Bytes(), info); err != nil {
+		t.Fatal(err)
+	}
+	e := &server.BrokerInfo{
+		Name:        "test-broker",
+		Version:     "1.2.3",
+		APIVersions: []string{"2.13", "2.14"},
+		Extensions:  []string{"snapshots", "server_test.routeExtender", "http://example.com/backup"},
+	}
+	if !reflect.DeepEqual(e, info) {
+		t.Errorf("Unexpected info; expected %+v, got %+v", e, info)
+	}
+}
@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRunOnListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(api, prom.NewRegistry())
+	s.Listener = l
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		// The address is ignored in favor of the listener.
+		errs <- s.Run(ctx, "invalid address")
+	}()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "OK", string(body); e != a {
+		t.Errorf("Unexpected body; expected %v, got %v", e, a)
+	}
+
+	cancel()
+	if e, a := http.ErrServerClosed, <-errs; e != a {
+		t.Errorf("Unexpected error; expected %v, got %v", e, a)
+	}
+}
+
+func TestReusePort(t *testing.T) {
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		s := server.New(api, prom.NewRegistry())
+		s.ReusePort = true
+		go func() {
+			errs <- s.Run(ctx, addr)
+		}()
+	}
+
+	// Both servers are listening on the same address until ctx is done.
+	select {
+	case err := <-errs:
+		t.Fatalf("Unexpected error starting a second server on %v: %v", addr, err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestH2C(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(api, prom.NewRegistry())
+	s.Listener = l
+	s.EnableH2C = true
+	s.IdleTimeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, "")
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	resp, err := client.Get("http://" + l.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if e, a := "HTTP/2.0", resp.Proto; e != a {
+		t.Errorf("Unexpected protocol; expected %v, got %v", e, a)
+	}
+}
+
+func TestRunWithHTTPServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(api, prom.NewRegistry())
+	s.Listener = l
+
+	if err := s.RunWithHTTPServer(context.Background(), &http.Server{Handler: http.NotFoundHandler()}); err == nil {
+		t.Error("Expected an error for an http.Server with a Handler")
+	}
+
+	states := make(chan http.ConnState, 10)
+	srv := &http.Server{
+		ConnState: func(c net.Conn, state http.ConnState) {
+			states <- state
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunWithHTTPServer(ctx, srv)
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if e, a := http.StatusOK, resp.StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := http.StateNew, <-states; e != a {
+		t.Errorf("Expected the ConnState hook to be called; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/openapi"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+// OpenAPIPath is the path EnableOpenAPI serves the OpenAPI document at.
+const OpenAPIPath = "/openapi.json"
+
+// OpenAPIInfo describes the broker in its OpenAPI document.
+type OpenAPIInfo struct {
+	// Title is the name of the broker.
+	Title string
+	// Version is the version of the broker.
+	Version string
+	// BasePath is the path prefix the broker is reachable at, for brokers
+	// mounted behind a gateway route; it is advertised as the server URL.
+	BasePath string
+}
+
+// operationQueryParameters are the query parameters of the OSB operations.
+var operationQueryParameters = map[string][]string{
+	rest.OperationProvision:            {"accepts_incomplete"},
+	rest.OperationDeprovision:          {"accepts_incomplete", "service_id", "plan_id"},
+	rest.OperationUpdate:               {"accepts_incomplete"},
+	rest.OperationLastOperation:        {"service_id", "plan_id", "operation"},
+	rest.OperationBind:                 {"accepts_incomplete"},
+	rest.OperationUnbind:               {"accepts_incomplete", "service_id", "plan_id"},
+	rest.OperationBindingLastOperation: {"service_id", "plan_id", "operation"},
+}
+
+// OpenAPI returns an OpenAPI document describing the routes registered on
+// the Router when it is called, including those of feature extenders and
+// extension APIs. Routes that don't restrict their methods, such as the
+// metrics endpoint, are left out.
+func (s *Server) OpenAPI(info OpenAPIInfo) (*openapi.Document, error) {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    openapi.Info{Title: info.Title, Version: info.Version},
+		Paths:   map[string]openapi.PathItem{},
+	}
+	if info.BasePath != "" {
+		doc.Servers = []openapi.Server{{URL: info.BasePath}}
+	}
+
+	describe := func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil || len(methods) == 0 {
+			return nil
+		}
+
+		path, parameters := pathParameters(path)
+		if name := route.GetName(); name != "" && name != OperationInfo {
+			parameters = append(parameters, openapi.Parameter{
+				Name:     osb.APIVersionHeader,
+				In:       "header",
+				Required: true,
+				Schema:   openapi.Schema{Type: "string"},
+			})
+			for _, name := range operationQueryParameters[route.GetName()] {
+				parameters = append(parameters, openapi.Parameter{
+					Name:   name,
+					In:     "query",
+					Schema: openapi.Schema{Type: "string"},
+				})
+			}
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+		for _, method := range methods {
+			item[strings.ToLower(method)] = &openapi.Operation{
+				OperationID: operationID(route.GetName(), method, len(methods) > 1),
+				Parameters:  parameters,
+				Responses: map[string]openapi.Response{
+					"default": {Description: "See the Open Service Broker API specification."},
+				},
+			}
+		}
+		return nil
+	}
+	if err := s.Router.Walk(describe); err != nil {
+		return nil, err
+	}
+	for _, e := range s.extenders {
+		if err := e.router.Walk(describe); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// EnableOpenAPI serves the OpenAPI document of the server at OpenAPIPath.
+// The document is generated on every request, so it describes routes
+// registered after EnableOpenAPI too.
+func (s *Server) EnableOpenAPI(info OpenAPIInfo) {
+	s.Router.HandleFunc(OpenAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		doc, err := s.OpenAPI(info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	}).Methods("GET")
+}
+
+// pathParameters returns the OpenAPI form of a mux path template, without
+// variable patterns, and its path parameters.
+func pathParameters(template string) (string, []openapi.Parameter) {
+	var parameters []openapi.Parameter
+	var path strings.Builder
+	for {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			path.WriteString(template)
+			break
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			path.WriteString(template)
+			break
+		}
+		name := template[start+1 : start+end]
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[:i]
+		}
+		path.WriteString(template[:start] + "{" + name + "}")
+		template = template[start+end+1:]
+		parameters = append(parameters, openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   openapi.Schema{Type: "string"},
+		})
+	}
+	return path.String(), parameters
+}
+
+// operationID returns the operation ID of a route: its name, suffixed with
+// the method for routes serving several methods.
+func operationID(name, method string, suffix bool) string {
+	if name == "" || !suffix {
+		return name
+	}
+	return name + "_" + strings.ToLower(method)
+}
@@ -0,0 +1,89 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/openapi"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+type routeExtender struct{}
+
+func (routeExtender) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v2/service_instances/{instance_id}/snapshots/{snapshot_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+}
+func (routeExtender) Middleware() []mux.MiddlewareFunc      { return nil }
+func (routeExtender) AugmentCatalog() rest.CatalogAugmenter { return nil }
+func (routeExtender) MinAPIVersion() string                 { return "" }
+
+func TestOpenAPI(t *testing.T) {
+	api, err := rest.NewAPISurface(&brokertest.FakeBroker{}, metrics.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(api, prom.NewRegistry())
+	s.Extend(routeExtender{})
+	s.EnableOpenAPI(server.OpenAPIInfo{Title: "test-broker", Version: "1.0.0", BasePath: "/brokers/test"})
+
+	r := httptest.NewRequest("GET", server.OpenAPIPath, nil)
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+	if e, a := http.StatusOK, w.Code; e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+
+	doc := &openapi.Document{}
+	if err := json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "/brokers/test", doc.Servers[0].URL; e != a {
+		t.Errorf("Unexpected server URL; expected %v, got %v", e, a)
+	}
+
+	binding := doc.Paths["/v2/service_instances/{instance_id}/service_bindings/{binding_id}"]
+	for _, method := range []string{"put", "get", "delete"} {
+		if binding[method] == nil {
+			t.Errorf("Expected a %v operation on the binding path", method)
+		}
+	}
+	if e, a := rest.OperationBind, binding["put"].OperationID; e != a {
+		t.Errorf("Unexpected operation ID; expected %v, got %v", e, a)
+	}
+
+	catalog := doc.Paths["/v2/catalog"]
+	if e, a := rest.OperationGetCatalog+"_get", catalog["get"].OperationID; e != a {
+		t.Errorf("Unexpected operation ID; expected %v, got %v", e, a)
+	}
+	if e, a := "X-Broker-API-Version", catalog["get"].Parameters[0].Name; e != a {
+		t.Errorf("Unexpected parameter; expected %v, got %v", e, a)
+	}
+
+	lastOperation := doc.Paths["/v2/service_instances/{instance_id}/last_operation"]["get"]
+	names := map[string]string{}
+	for _, p := range lastOperation.Parameters {
+		names[p.Name] = p.In
+	}
+	for name, in := range map[string]string{"instance_id": "path", "service_id": "query", "operation": "query"} {
+		if e, a := in, names[name]; e != a {
+			t.Errorf("Unexpected location of parameter %v; expected %q, got %q", name, e, a)
+		}
+	}
+
+	snapshot := doc.Paths["/v2/service_instances/{instance_id}/snapshots/{snapshot_id}"]
+	if snapshot["get"] == nil {
+		t.Errorf("Expected the extender's route to be described, got paths %v", doc.Paths)
+	}
+	if _, ok := doc.Paths["/metrics"]; ok {
+		t.Error("Expected the metrics endpoint to be left out")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build go1.24
+// +build go1.24
+
+package server
+
+import (
+	"net/http"
+)
+
+// setProtocols configures the protocols srv serves for the HTTP/2 settings
+// of the Server.
+func (s *Server) setProtocols(srv *http.Server) error {
+	if !s.DisableHTTP2 && !s.EnableH2C {
+		return nil
+	}
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(!s.DisableHTTP2)
+	srv.Protocols.SetUnencryptedHTTP2(s.EnableH2C && !s.DisableHTTP2)
+	return nil
+}
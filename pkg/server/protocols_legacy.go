@@ -0,0 +1,23 @@
+//go:build !go1.24
+// +build !go1.24
+
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+// setProtocols configures the protocols srv serves for the HTTP/2 settings
+// of the Server. Before Go 1.24, net/http can't serve h2c, and HTTP/2 is
+// disabled by leaving no handler for its TLS protocol.
+func (s *Server) setProtocols(srv *http.Server) error {
+	if s.EnableH2C && !s.DisableHTTP2 {
+		return errors.New("server: EnableH2C requires Go 1.24 or later")
+	}
+	if s.DisableHTTP2 {
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-package server
+package server_test
 
 import (
 	"errors"
@@ -8,8 +8,10 @@ import (
 	"testing"
 
 	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
 	"github.com/pmorie/osb-broker-lib/pkg/metrics"
 	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
 
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 	prom "github.com/prometheus/client_golang/prometheus"
@@ -152,14 +154,14 @@ func TestProvision(t *testing.T) {
 			}
 
 			api := &rest.APISurface{
-				Broker: &FakeBroker{
-					validateAPIVersion: validateFunc,
-					provision:          provisionFunc,
+				Broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: validateFunc,
+					ProvisionFunc:                provisionFunc,
 				},
 				Metrics: osbMetrics,
 			}
 
-			s := New(api, reg)
+			s := server.New(api, reg)
 			fs := httptest.NewServer(s.Router)
 			defer fs.Close()
 
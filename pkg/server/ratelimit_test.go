@@ -0,0 +1,35 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/ratelimit"
+)
+
+func TestEnableRateLimiting(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+	s.BrokerServer.EnableRateLimiting(ratelimit.New(0.001, 1))
+
+	get := func(path string) int {
+		resp, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if e, a := http.StatusOK, get("/v2/catalog"); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if e, a := http.StatusTooManyRequests, get("/v2/catalog"); e != a {
+		t.Fatalf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	for i := 0; i < 3; i++ {
+		if e, a := http.StatusOK, get("/healthz"); e != a {
+			t.Fatalf("Expected health checks not to be rate limited; expected %v, got %v", e, a)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+func TestReadOnly(t *testing.T) {
+	reg := prom.NewRegistry()
+	osbMetrics := metrics.New()
+	reg.MustRegister(osbMetrics)
+
+	api := &rest.APISurface{
+		Broker: &brokertest.FakeBroker{
+			ValidateBrokerAPIVersionFunc: defaultValidateFunc,
+			GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+				return &broker.CatalogResponse{}, nil
+			},
+			LastOperationFunc: func(req *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+				return &broker.LastOperationResponse{
+					LastOperationResponse: osb.LastOperationResponse{State: osb.StateInProgress},
+				}, nil
+			},
+			ProvisionFunc: func(req *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+				t.Error("provision must not be called in read-only mode")
+				return &broker.ProvisionResponse{}, nil
+			},
+			UnbindFunc: func(req *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+				t.Error("unbind must not be called in read-only mode")
+				return &broker.UnbindResponse{}, nil
+			},
+		},
+		Metrics:  osbMetrics,
+		ReadOnly: true,
+	}
+
+	s := server.New(api, reg)
+	fs := httptest.NewServer(s.Router)
+	defer fs.Close()
+
+	config := defaultClientConfiguration()
+	config.URL = fs.URL
+
+	client, err := osb.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetCatalog(); err != nil {
+		t.Errorf("Unexpected error getting catalog: %v", err)
+	}
+
+	if _, err := client.PollLastOperation(&osb.LastOperationRequest{InstanceID: "12345"}); err != nil {
+		t.Errorf("Unexpected error polling last operation: %v", err)
+	}
+
+	expectedErr := osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusServiceUnavailable,
+		ErrorMessage: strPtr("ReadOnly"),
+		Description:  strPtr("The broker is in read-only mode and does not accept changes to service instances or bindings."),
+	}
+
+	_, err = client.ProvisionInstance(&osb.ProvisionRequest{
+		InstanceID:       "12345",
+		ServiceID:        "12345",
+		PlanID:           "12345",
+		OrganizationGUID: "12345",
+		SpaceGUID:        "12345",
+	})
+	if e, a := expectedErr, err; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected provision error; expected %v, got %v", e, a)
+	}
+
+	_, err = client.Unbind(&osb.UnbindRequest{
+		InstanceID: "12345",
+		BindingID:  "12345",
+		ServiceID:  "12345",
+		PlanID:     "12345",
+	})
+	if e, a := expectedErr, err; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected unbind error; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !((linux && (386 || amd64 || arm || arm64 || loong64 || ppc64 || ppc64le || riscv64 || s390x)) || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package server
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePort fails on platforms without SO_REUSEPORT.
+func reusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("server: SO_REUSEPORT is not supported on this platform")
+}
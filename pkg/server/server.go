@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
+	"net"
 	"net/http"
 	"time"
 
@@ -12,6 +14,11 @@ import (
 	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/deadline"
+	"github.com/pmorie/osb-broker-lib/pkg/health"
+	"github.com/pmorie/osb-broker-lib/pkg/proxy"
+	"github.com/pmorie/osb-broker-lib/pkg/ratelimit"
 	"github.com/pmorie/osb-broker-lib/pkg/rest"
 )
 
@@ -23,7 +30,54 @@ type Server struct {
 	//
 	// - OSB API
 	// - metrics API
+	// - readiness API
 	Router *mux.Router
+	// Health aggregates the named dependency checks reported by the
+	// /readiness endpoint. Brokers register their checks on it before
+	// running the server.
+	Health *health.Checker
+	// ReadTimeout and WriteTimeout bound reading a request and writing its
+	// response, ReadHeaderTimeout reading the request headers and
+	// IdleTimeout how long keep-alive connections wait for the next
+	// request. They default to no timeout; see http.Server.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// MaxHeaderBytes bounds the size of request headers. It defaults to
+	// http.DefaultMaxHeaderBytes.
+	MaxHeaderBytes int
+	// DisableHTTP2 serves HTTP/1.1 only. HTTP/2 is otherwise negotiated
+	// with TLS clients.
+	DisableHTTP2 bool
+	// EnableH2C also serves HTTP/2 without TLS (h2c) to clients speaking it
+	// with prior knowledge, as service mesh sidecars do. It requires Go 1.24
+	// or later; Run fails with older toolchains.
+	EnableH2C bool
+	// ShutdownTimeout is how long in-flight requests are given to complete
+	// once the context passed to Run is done. It defaults to 3 seconds.
+	ShutdownTimeout time.Duration
+	// Listener, if set, is the listener the server accepts connections on
+	// instead of listening on the address passed to Run, for example a
+	// socket passed by systemd. See package activation.
+	Listener net.Listener
+	// ReusePort listens with SO_REUSEPORT, so that a replacement broker
+	// process can start accepting connections on the same address before
+	// this one drains and exits, avoiding failed requests during upgrades.
+	// It is not supported on Windows.
+	ReusePort bool
+	// ProxyProtocol reads the PROXY protocol v1 or v2 header that TCP load
+	// balancers, such as AWS NLB or HAProxy in TCP mode, send at the start
+	// of every connection, so requests carry the client's address in
+	// RemoteAddr instead of the load balancer's. See proxy.Listener.
+	ProxyProtocol bool
+	// ProxyProtocolTrusted, if set, only accepts PROXY protocol headers
+	// from these peers.
+	ProxyProtocolTrusted *proxy.Trusted
+
+	api        *rest.APISurface
+	extensions []string
+	extenders  []*extender
 }
 
 // New creates a new Router and registers all the necessary endpoints and handlers.
@@ -34,12 +88,20 @@ func New(api *rest.APISurface, reg prom.Gatherer) *Server {
 		router.Methods("OPTIONS").HandlerFunc(api.OptionsHandler)
 	}
 
+	checker := health.New()
+
 	registerAPIHandlers(router, api)
 	router.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	router.Handle("/readiness", etagHandler(checker, api.WriteFailed)).Methods("GET", "HEAD")
 
-	return &Server{
+	s := &Server{
 		Router: router,
+		Health: checker,
+		api:    api,
 	}
+	s.UseOSBMiddleware(recordSizes(api.Metrics))
+	s.UseOSBMiddleware(broker.ParseOriginatingIdentity)
+	return s
 }
 
 // NewHTTPHandler creates a new Router and registers API handlers
@@ -49,28 +111,103 @@ func NewHTTPHandler(api *rest.APISurface) http.Handler {
 	return router
 }
 
-// registerAPIHandlers registers the APISurface endpoints and handlers.
+// registerAPIHandlers registers the APISurface endpoints and handlers. Each
+// route is named after the operation it serves, so middleware can find the
+// operation of a request with mux.CurrentRoute.
 func registerAPIHandlers(router *mux.Router, api *rest.APISurface) {
-	router.HandleFunc("/v2/catalog", api.GetCatalogHandler).Methods("GET")
-	router.HandleFunc("/v2/service_instances/{instance_id}/last_operation", api.LastOperationHandler).Methods("GET")
-	router.HandleFunc("/v2/service_instances/{instance_id}", api.ProvisionHandler).Methods("PUT")
-	router.HandleFunc("/v2/service_instances/{instance_id}", api.DeprovisionHandler).Methods("DELETE")
-	router.HandleFunc("/v2/service_instances/{instance_id}", api.UpdateHandler).Methods("PATCH")
-	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.BindHandler).Methods("PUT")
-	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.GetBindingHandler).Methods("GET")
-	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}/last_operation", api.BindingLastOperationHandler).Methods("GET")
-	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.UnbindHandler).Methods("DELETE")
-	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+	router.Handle("/v2/catalog", etagHandler(http.HandlerFunc(api.GetCatalogHandler), api.WriteFailed)).Methods("GET", "HEAD").Name(rest.OperationGetCatalog)
+	router.HandleFunc("/v2/service_instances/{instance_id}/last_operation", api.LastOperationHandler).Methods("GET").Name(rest.OperationLastOperation)
+	router.HandleFunc("/v2/service_instances/{instance_id}", api.ProvisionHandler).Methods("PUT").Name(rest.OperationProvision)
+	router.HandleFunc("/v2/service_instances/{instance_id}", api.DeprovisionHandler).Methods("DELETE").Name(rest.OperationDeprovision)
+	router.HandleFunc("/v2/service_instances/{instance_id}", api.UpdateHandler).Methods("PATCH").Name(rest.OperationUpdate)
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.BindHandler).Methods("PUT").Name(rest.OperationBind)
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.GetBindingHandler).Methods("GET").Name(rest.OperationGetBinding)
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}/last_operation", api.BindingLastOperationHandler).Methods("GET").Name(rest.OperationBindingLastOperation)
+	router.HandleFunc("/v2/service_instances/{instance_id}/service_bindings/{binding_id}", api.UnbindHandler).Methods("DELETE").Name(rest.OperationUnbind)
+	for _, extension := range api.ExtensionAPIs {
+		for _, op := range extension.Operations {
+			router.HandleFunc(op.Path, api.ExtensionOperationHandler(op)).Methods(op.Method).Name(op.Name)
+		}
+	}
+	router.MatcherFunc(api.MatchExtension)
+	router.Handle("/healthz", etagHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("OK"))
+	}), api.WriteFailed))
+}
+
+// UseOSBMiddleware installs mw on the routes of the OSB API only, leaving the
+// metrics and health endpoints untouched.
+func (s *Server) UseOSBMiddleware(mw mux.MiddlewareFunc) {
+	s.Router.Use(func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isOSBRoute(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
 	})
 }
 
+// EnableRateLimiting rejects OSB API requests exceeding the limits of l with
+// a 429.
+func (s *Server) EnableRateLimiting(l *ratelimit.Limiter) {
+	s.UseOSBMiddleware(l.Middleware)
+}
+
+// EnableRequestDeadlines runs OSB API requests carrying a deadline header
+// with a context expiring at that deadline. See package deadline.
+func (s *Server) EnableRequestDeadlines(d *deadline.Deadlines) {
+	s.UseOSBMiddleware(d.Middleware)
+}
+
+// isOSBRoute returns whether r was routed to an OSB API handler. Those
+// routes, and only those, are named by registerAPIHandlers.
+func isOSBRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	return route != nil && route.GetName() != ""
+}
+
+// drainPollInterval is how often Drain checks for in-flight operations.
+const drainPollInterval = 100 * time.Millisecond
+
+// Drain stops the server from accepting new provision, update and bind
+// requests, which are answered with 503 and a Retry-After header, while it
+// continues to serve last operation polls, deprovisions and unbinds. Drain
+// blocks until the asynchronous operations accepted by this server have
+// completed or the context is done, allowing rolling upgrades without failed
+// operations. Jobs run by the APISurface's job manager complete when they
+// finish; other asynchronous operations complete when a platform polls a
+// terminal state.
+func (s *Server) Drain(ctx context.Context) error {
+	if s.api == nil {
+		return errors.New("server: Drain requires a Server created with New")
+	}
+	s.api.Drain()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for s.api.InFlightOperations() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// defaultShutdownTimeout is the default ShutdownTimeout.
+const defaultShutdownTimeout = 3 * time.Second
+
 // Run creates the HTTP handler and begins to listen on the specified address.
 func (s *Server) Run(ctx context.Context, addr string) error {
-	listenAndServe := func(srv *http.Server) error {
-		return srv.ListenAndServe()
+	serve := func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
 	}
-	return s.run(ctx, addr, listenAndServe)
+	return s.run(ctx, addr, serve)
 }
 
 // RunTLS creates the HTTPS handler based on the certifications that were passed
@@ -91,36 +228,98 @@ func (s *Server) RunTLS(ctx context.Context, addr string, cert string, key strin
 	if err != nil {
 		return err
 	}
-	listenAndServe := func(srv *http.Server) error {
+	serve := func(srv *http.Server, l net.Listener) error {
 		srv.TLSConfig = new(tls.Config)
 		srv.TLSConfig.Certificates = []tls.Certificate{tlsCert}
-		return srv.ListenAndServeTLS("", "")
+		return srv.ServeTLS(l, "", "")
 	}
-	return s.run(ctx, addr, listenAndServe)
+	return s.run(ctx, addr, serve)
 }
 
 // RunTLSWithTLSFiles creates the HTTPS handler based on the certification
 // files that were passed and begins to listen on the specified address.
 func (s *Server) RunTLSWithTLSFiles(ctx context.Context, addr string, certFilePath string, keyFilePath string) error {
-	listenAndServe := func(srv *http.Server) error {
-		return srv.ListenAndServeTLS(certFilePath, keyFilePath)
+	serve := func(srv *http.Server, l net.Listener) error {
+		return srv.ServeTLS(l, certFilePath, keyFilePath)
 	}
-	return s.run(ctx, addr, listenAndServe)
+	return s.run(ctx, addr, serve)
 }
 
-func (s *Server) run(ctx context.Context, addr string, listenAndServe func(srv *http.Server) error) error {
-	glog.Infof("Starting server on %s\n", addr)
+// listen returns the listener the server accepts connections on.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	l := s.Listener
+	if l == nil {
+		var lc net.ListenConfig
+		if s.ReusePort {
+			lc.Control = reusePort
+		}
+		var err error
+		if l, err = lc.Listen(context.Background(), "tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	if s.ProxyProtocol {
+		l = &proxy.Listener{Listener: l, Trusted: s.ProxyProtocolTrusted}
+	}
+	return l, nil
+}
+
+// RunWithHTTPServer serves the server's Router with srv, for settings the
+// Server doesn't expose, such as ConnState hooks, ErrorLog or TLS curves.
+// srv must not have a Handler: it is set to the Router. srv is served over
+// TLS if its TLSConfig has certificates. The Server's timeout, header and
+// HTTP/2 settings are ignored in favor of srv's, while its listener
+// settings still apply and srv.Addr is listened on unless Listener is set.
+func (s *Server) RunWithHTTPServer(ctx context.Context, srv *http.Server) error {
+	if srv.Handler != nil {
+		return errors.New("server: RunWithHTTPServer requires an http.Server without a Handler")
+	}
+	srv.Handler = s.Router
+
+	serve := func(srv *http.Server, l net.Listener) error {
+		if c := srv.TLSConfig; c != nil && (len(c.Certificates) > 0 || c.GetCertificate != nil) {
+			return srv.ServeTLS(l, "", "")
+		}
+		return srv.Serve(l)
+	}
+	return s.serve(ctx, srv, serve)
+}
+
+func (s *Server) run(ctx context.Context, addr string, serve func(srv *http.Server, l net.Listener) error) error {
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: s.Router,
+		Addr:              addr,
+		Handler:           s.Router,
+		ReadTimeout:       s.ReadTimeout,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+	}
+	if err := s.setProtocols(srv); err != nil {
+		return err
+	}
+	return s.serve(ctx, srv, serve)
+}
+
+// serve listens for srv and serves it with serve until ctx is done.
+func (s *Server) serve(ctx context.Context, srv *http.Server, serve func(srv *http.Server, l net.Listener) error) error {
+	l, err := s.listen(srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Starting server on %s\n", l.Addr())
+	shutdownTimeout := s.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
 	}
 	go func() {
 		<-ctx.Done()
-		c, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		c, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 		if srv.Shutdown(c) != nil {
 			srv.Close()
 		}
 	}()
-	return listenAndServe(srv)
+	return serve(srv, l)
 }
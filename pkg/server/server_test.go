@@ -1,4 +1,4 @@
-package server
+package server_test
 
 import (
 	"bytes"
@@ -10,68 +10,14 @@ import (
 
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
 	"github.com/pmorie/osb-broker-lib/pkg/metrics"
 	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
 )
 
 // TODO: is this more of an integration test?
 
-// FakeBroker provides an implementation of the broker.Interface.
-type FakeBroker struct {
-	validateAPIVersion   func(string) error
-	getCatalog           func(c *broker.RequestContext) (*broker.CatalogResponse, error)
-	provision            func(pr *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error)
-	deprovision          func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error)
-	lastOperation        func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
-	bind                 func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error)
-	unbind               func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error)
-	update               func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error)
-	getBinding           func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error)
-	bindingLastOperation func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
-}
-
-var _ broker.Interface = &FakeBroker{}
-
-func (b *FakeBroker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
-	return b.getCatalog(c)
-}
-
-func (b *FakeBroker) Provision(pr *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
-	return b.provision(pr, c)
-}
-
-func (b *FakeBroker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
-	return b.deprovision(request, c)
-}
-
-func (b *FakeBroker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	return b.lastOperation(request, c)
-}
-
-func (b *FakeBroker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
-	return b.bind(request, c)
-}
-
-func (b *FakeBroker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
-	return b.unbind(request, c)
-}
-
-func (b *FakeBroker) ValidateBrokerAPIVersion(version string) error {
-	return b.validateAPIVersion(version)
-}
-
-func (b *FakeBroker) Update(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
-	return b.update(request, c)
-}
-
-func (b *FakeBroker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
-	return b.getBinding(request, c)
-}
-
-func (b *FakeBroker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	return b.bindingLastOperation(request, c)
-}
-
 func defaultValidateFunc(_ string) error {
 	return nil
 }
@@ -98,6 +44,7 @@ func TestNewHTTPHandler(t *testing.T) {
 	type args struct {
 		broker              broker.Interface
 		servicePath         string
+		serviceQuery        string
 		serviceMethod       string
 		request             []byte
 		originatingIdentity string
@@ -110,9 +57,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface GetCatalog(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					getCatalog: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
 						return &broker.CatalogResponse{}, nil
 					},
 				},
@@ -124,9 +71,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface LastOperation(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					lastOperation: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					LastOperationFunc: func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
 						return &broker.LastOperationResponse{}, nil
 					},
 				},
@@ -138,9 +85,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Provision(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					provision: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					ProvisionFunc: func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
 						return &broker.ProvisionResponse{}, nil
 					},
 				},
@@ -153,13 +100,14 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Deprovision(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					deprovision: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
 						return &broker.DeprovisionResponse{}, nil
 					},
 				},
 				servicePath:   "/v2/service_instances/foo",
+				serviceQuery:  "service_id=service&plan_id=plan",
 				serviceMethod: http.MethodDelete,
 				request:       []byte("{}"),
 			},
@@ -168,9 +116,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Update(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					update: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					UpdateFunc: func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
 						return &broker.UpdateInstanceResponse{}, nil
 					},
 				},
@@ -183,13 +131,14 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Deprovision(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					deprovision: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					DeprovisionFunc: func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
 						return &broker.DeprovisionResponse{}, nil
 					},
 				},
 				servicePath:   "/v2/service_instances/foo",
+				serviceQuery:  "service_id=service&plan_id=plan",
 				serviceMethod: http.MethodDelete,
 				request:       []byte("{}"),
 			},
@@ -198,9 +147,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Bind(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					bind: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
 						return &broker.BindResponse{}, nil
 					},
 				},
@@ -213,9 +162,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface GetBinding(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					getBinding: func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					GetBindingFunc: func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
 						return &broker.GetBindingResponse{}, nil
 					},
 				},
@@ -228,9 +177,9 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface BindingLastOperation(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					bindingLastOperation: func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					BindingLastOperationFunc: func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
 						return &broker.LastOperationResponse{}, nil
 					},
 				},
@@ -244,13 +193,14 @@ func TestNewHTTPHandler(t *testing.T) {
 		{
 			name: "test APISurface Unbind(...)",
 			args: args{
-				broker: &fakeBroker{
-					validateBrokerAPIVersion: func(version string) error { return nil },
-					unbind: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
+				broker: &brokertest.FakeBroker{
+					ValidateBrokerAPIVersionFunc: func(version string) error { return nil },
+					UnbindFunc: func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
 						return &broker.UnbindResponse{}, nil
 					},
 				},
 				servicePath:   "/v2/service_instances/foo/service_bindings/bar",
+				serviceQuery:  "service_id=service&plan_id=plan",
 				serviceMethod: http.MethodDelete,
 				request:       []byte("{}"),
 			},
@@ -260,14 +210,15 @@ func TestNewHTTPHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			api, err := rest.NewAPISurface(tt.args.broker, metrics.New())
-			handler := NewHTTPHandler(api)
-			server := httptest.NewServer(handler)
-			defer server.Close()
-			u, err := url.Parse(server.URL)
+			handler := server.NewHTTPHandler(api)
+			fs := httptest.NewServer(handler)
+			defer fs.Close()
+			u, err := url.Parse(fs.URL)
 			if err != nil {
 				t.Fatal(err)
 			}
 			u.Path = path.Join(u.Path, tt.args.servicePath)
+			u.RawQuery = tt.args.serviceQuery
 			client := http.DefaultClient
 			req, err := http.NewRequest(tt.args.serviceMethod, u.String(), bytes.NewReader(tt.args.request))
 			if tt.args.originatingIdentity != "" {
@@ -286,50 +237,3 @@ func TestNewHTTPHandler(t *testing.T) {
 		})
 	}
 }
-
-// BusinessLogic provides an implementation of the broker.Interface interface.
-type fakeBroker struct {
-	validateBrokerAPIVersion func(version string) error
-	getCatalog               func(c *broker.RequestContext) (*broker.CatalogResponse, error)
-	provision                func(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error)
-	deprovision              func(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error)
-	lastOperation            func(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
-	bind                     func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error)
-	unbind                   func(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error)
-	update                   func(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error)
-	getBinding               func(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error)
-	bindingLastOperation     func(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error)
-}
-
-var _ broker.Interface = &fakeBroker{}
-
-func (b *fakeBroker) ValidateBrokerAPIVersion(version string) error {
-	return b.validateBrokerAPIVersion(version)
-}
-func (b *fakeBroker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
-	return b.getCatalog(c)
-}
-func (b *fakeBroker) Provision(request *osb.ProvisionRequest, c *broker.RequestContext) (*broker.ProvisionResponse, error) {
-	return b.provision(request, c)
-}
-func (b *fakeBroker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (*broker.DeprovisionResponse, error) {
-	return b.deprovision(request, c)
-}
-func (b *fakeBroker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	return b.lastOperation(request, c)
-}
-func (b *fakeBroker) Bind(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
-	return b.bind(request, c)
-}
-func (b *fakeBroker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
-	return b.getBinding(request, c)
-}
-func (b *fakeBroker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	return b.bindingLastOperation(request, c)
-}
-func (b *fakeBroker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
-	return b.unbind(request, c)
-}
-func (b *fakeBroker) Update(request *osb.UpdateInstanceRequest, c *broker.RequestContext) (*broker.UpdateInstanceResponse, error) {
-	return b.update(request, c)
-}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+)
+
+// recordSizes observes the body sizes of OSB API requests and responses in
+// the RequestSize and ResponseSize histograms of m. The request size is the
+// Content-Length, or the bytes the handler read if the request is chunked.
+func recordSizes(m *metrics.OSBMetricsCollector) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			counter := &countingResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(counter, r)
+
+			requestSize := r.ContentLength
+			if requestSize < 0 {
+				requestSize = body.n
+			}
+			operation := mux.CurrentRoute(r).GetName()
+			m.RequestSize.WithLabelValues(operation).Observe(float64(requestSize))
+			m.ResponseSize.WithLabelValues(operation).Observe(float64(counter.n))
+		})
+	}
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingResponseWriter counts the bytes of the response body.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingResponseWriter) Write(data []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(data)
+	c.n += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying ResponseWriter so that streamed responses
+// are not held back.
+func (c *countingResponseWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+)
+
+func TestSizeMetrics(t *testing.T) {
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{})
+
+	body := `{"service_id":"service","plan_id":"plan","organization_guid":"org","space_guid":"space"}`
+	request, err := http.NewRequest(http.MethodPut, s.URL+"/v2/service_instances/instance", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(osb.APIVersionHeader, "2.13")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	histogram := func(h prom.Histogram) *dto.Histogram {
+		metric := &dto.Metric{}
+		if err := h.Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.Histogram
+	}
+
+	requestSize := histogram(s.API.Metrics.RequestSize.WithLabelValues(rest.OperationProvision))
+	if e, a := float64(len(body)), requestSize.GetSampleSum(); e != a {
+		t.Errorf("Unexpected request size; expected %v, got %v", e, a)
+	}
+	responseSize := histogram(s.API.Metrics.ResponseSize.WithLabelValues(rest.OperationProvision))
+	if e, a := float64(len(response)), responseSize.GetSampleSum(); e != a {
+		t.Errorf("Unexpected response size; expected %v, got %v", e, a)
+	}
+	if e, a := uint64(1), responseSize.GetSampleCount(); e != a {
+		t.Errorf("Unexpected response count; expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build linux && (386 || amd64 || arm || arm64 || loong64 || ppc64 || ppc64le || riscv64 || s390x)
+
+package server
+
+// soReusePort is SO_REUSEPORT, which package syscall doesn't define on most
+// Linux architectures. Its value is 0xf on the architectures using the
+// generic socket options, listed in the build constraint; MIPS, SPARC,
+// Alpha and PA-RISC define their own.
+const soReusePort = 0xf
@@ -0,0 +1,231 @@
+// Package sharing implements the OSB service instance sharing proposal as a
+// server.FeatureExtender. Services opt in with the shareable catalog flag;
+// bind requests coming from another space or namespace than the one an
+// instance was provisioned in are recognized as shared and rejected with a
+// 422 unless the service is shareable.
+package sharing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+)
+
+const (
+	// MetadataKey is the service metadata field advertising that instances
+	// of the service can be shared.
+	MetadataKey = "shareable"
+
+	// NotShareableErrorMessage is the error code of the error returned
+	// when a shared binding is requested for an instance that can't be
+	// shared.
+	NotShareableErrorMessage = "InstanceNotShareable"
+	// InvalidContextErrorMessage is the error code of the error returned
+	// when the context of a bind request can't be compared with the
+	// context of its instance.
+	InvalidContextErrorMessage = "InvalidSharingContext"
+)
+
+// IsShareable returns whether the service advertises that its instances can
+// be shared.
+func IsShareable(service *osb.Service) bool {
+	shareable, _ := service.Metadata[MetadataKey].(bool)
+	return shareable
+}
+
+// SetShareable sets the shareable flag in the metadata of the service.
+func SetShareable(service *osb.Service, shareable bool) {
+	if service.Metadata == nil {
+		service.Metadata = map[string]interface{}{}
+	}
+	service.Metadata[MetadataKey] = shareable
+}
+
+// NewNotShareableError returns the error for a shared binding requested for
+// an instance of a service that can't be shared.
+func NewNotShareableError(serviceID string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		ErrorMessage: strPtr(NotShareableErrorMessage),
+		Description:  strPtr(fmt.Sprintf("Instances of service %q can't be shared with other spaces or namespaces.", serviceID)),
+	}
+}
+
+// NewInvalidContextError returns the error for a bind request whose context
+// doesn't identify a space or namespace on the instance's platform.
+func NewInvalidContextError(reason string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusUnprocessableEntity,
+		ErrorMessage: strPtr(InvalidContextErrorMessage),
+		Description:  strPtr(reason),
+	}
+}
+
+// IsSharedContext returns whether a binding created with bindingContext
+// shares an instance provisioned with instanceContext: both contexts are
+// from the same platform but from different Cloud Foundry spaces or
+// Kubernetes namespaces. It returns an error if the contexts can't be
+// compared.
+func IsSharedContext(instanceContext, bindingContext map[string]interface{}) (bool, error) {
+	if len(instanceContext) == 0 || len(bindingContext) == 0 {
+		return false, nil
+	}
+
+	platform, _ := instanceContext["platform"].(string)
+	if bindingPlatform, _ := bindingContext["platform"].(string); bindingPlatform != platform {
+		return false, NewInvalidContextError(fmt.Sprintf("The binding's platform %q differs from the instance's platform %q.", bindingPlatform, platform))
+	}
+
+	var field string
+	switch platform {
+	case "cloudfoundry":
+		field = "space_guid"
+	case "kubernetes":
+		field = "namespace"
+	default:
+		return false, nil
+	}
+
+	bindingScope, _ := bindingContext[field].(string)
+	if bindingScope == "" {
+		return false, NewInvalidContextError(fmt.Sprintf("The binding's context is missing %s.", field))
+	}
+	instanceScope, _ := instanceContext[field].(string)
+	return bindingScope != instanceScope, nil
+}
+
+type sharedKey struct{}
+
+// IsShared returns whether the bind request r was recognized as a shared
+// binding by the Extension. Business logic can call it with the Request of
+// its broker.RequestContext.
+func IsShared(r *http.Request) bool {
+	shared, _ := r.Context().Value(sharedKey{}).(bool)
+	return shared
+}
+
+// Extension is the server.FeatureExtender of instance sharing.
+type Extension struct {
+	// ShareableServices are the IDs of the services whose instances can be
+	// shared. Their catalog entries are flagged shareable.
+	ShareableServices []string
+	// InstanceContext returns the context an instance was provisioned
+	// with, or nil if it is unknown. It is required.
+	InstanceContext func(instanceID string) (map[string]interface{}, error)
+}
+
+var _ server.FeatureExtender = &Extension{}
+
+// RegisterRoutes registers nothing; sharing has no endpoints of its own.
+func (e *Extension) RegisterRoutes(router *mux.Router) {}
+
+// MinAPIVersion returns no minimum version.
+func (e *Extension) MinAPIVersion() string {
+	return ""
+}
+
+// AugmentCatalog flags the ShareableServices shareable.
+func (e *Extension) AugmentCatalog() rest.CatalogAugmenter {
+	return func(c *broker.RequestContext, catalog *broker.CatalogResponse) error {
+		for i := range catalog.Services {
+			if e.shareable(catalog.Services[i].ID) {
+				SetShareable(&catalog.Services[i], true)
+			}
+		}
+		return nil
+	}
+}
+
+// Middleware returns the middleware validating shared bind requests.
+func (e *Extension) Middleware() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{e.validateBind}
+}
+
+func (e *Extension) validateBind(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || route.GetName() != rest.OperationBind || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		request := &osb.BindRequest{}
+		if err := json.Unmarshal(body, request); err != nil {
+			// Let the APISurface report the malformed body.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		instanceContext, err := e.InstanceContext(mux.Vars(r)[osb.VarKeyInstanceID])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		shared, err := IsSharedContext(instanceContext, request.Context)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if shared {
+			if !e.shareable(request.ServiceID) {
+				writeError(w, NewNotShareableError(request.ServiceID))
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), sharedKey{}, true))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (e *Extension) shareable(serviceID string) bool {
+	for _, id := range e.ShareableServices {
+		if id == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes err in the OSB error format, as a 500 unless it is an
+// osb.HTTPStatusCodeError.
+func writeError(w http.ResponseWriter, err error) {
+	type e struct {
+		ErrorMessage *string `json:"error,omitempty"`
+		Description  *string `json:"description,omitempty"`
+	}
+
+	code := http.StatusInternalServerError
+	body := &e{Description: strPtr(err.Error())}
+	if httpErr, ok := osb.IsHTTPError(err); ok {
+		code = httpErr.StatusCode
+		body = &e{ErrorMessage: httpErr.ErrorMessage, Description: httpErr.Description}
+	}
+
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
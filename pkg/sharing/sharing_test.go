@@ -0,0 +1,110 @@
+package sharing_test
+
+import (
+	"net/http"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/brokertest"
+	"github.com/pmorie/osb-broker-lib/pkg/sharing"
+)
+
+func TestIsSharedContext(t *testing.T) {
+	cf := func(space string) map[string]interface{} {
+		return map[string]interface{}{"platform": "cloudfoundry", "space_guid": space}
+	}
+	k8s := func(namespace string) map[string]interface{} {
+		return map[string]interface{}{"platform": "kubernetes", "namespace": namespace}
+	}
+
+	cases := []struct {
+		name     string
+		instance map[string]interface{}
+		binding  map[string]interface{}
+		shared   bool
+		err      bool
+	}{
+		{name: "same space", instance: cf("a"), binding: cf("a")},
+		{name: "other space", instance: cf("a"), binding: cf("b"), shared: true},
+		{name: "other namespace", instance: k8s("a"), binding: k8s("b"), shared: true},
+		{name: "unknown instance", binding: cf("b")},
+		{name: "other platform", instance: cf("a"), binding: k8s("a"), err: true},
+		{name: "missing space", instance: cf("a"), binding: cf(""), err: true},
+	}
+	for _, tc := range cases {
+		shared, err := sharing.IsSharedContext(tc.instance, tc.binding)
+		if e, a := tc.err, err != nil; e != a {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+		if e, a := tc.shared, shared; e != a {
+			t.Errorf("%v: unexpected shared; expected %v, got %v", tc.name, e, a)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	shared := map[string]bool{}
+	s := brokertest.NewServer(t, &brokertest.FakeBroker{
+		GetCatalogFunc: func(c *broker.RequestContext) (*broker.CatalogResponse, error) {
+			response := &broker.CatalogResponse{}
+			response.Services = []osb.Service{{ID: "shareable"}, {ID: "private"}}
+			return response, nil
+		},
+		BindFunc: func(request *osb.BindRequest, c *broker.RequestContext) (*broker.BindResponse, error) {
+			shared[request.BindingID] = sharing.IsShared(c.Request)
+			return &broker.BindResponse{}, nil
+		},
+	})
+	s.BrokerServer.Extend(&sharing.Extension{
+		ShareableServices: []string{"shareable"},
+		InstanceContext: func(instanceID string) (map[string]interface{}, error) {
+			return map[string]interface{}{"platform": "kubernetes", "namespace": "home"}, nil
+		},
+	})
+
+	catalog, err := s.Client.GetCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sharing.IsShareable(&catalog.Services[0]) || sharing.IsShareable(&catalog.Services[1]) {
+		t.Errorf("Expected only the shareable service to be flagged, got %+v", catalog.Services)
+	}
+
+	bind := func(bindingID, serviceID, namespace string) error {
+		_, err := s.Client.Bind(&osb.BindRequest{
+			InstanceID: "instance",
+			BindingID:  bindingID,
+			ServiceID:  serviceID,
+			PlanID:     "plan",
+			Context:    map[string]interface{}{"platform": "kubernetes", "namespace": namespace},
+		})
+		return err
+	}
+
+	if err := bind("local", "private", "home"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bind("shared", "shareable", "away"); err != nil {
+		t.Fatal(err)
+	}
+	if shared["local"] || !shared["shared"] {
+		t.Errorf("Unexpected shared bindings: %v", shared)
+	}
+
+	err = bind("rejected", "private", "away")
+	httpErr, ok := osb.IsHTTPError(err)
+	if !ok {
+		t.Fatalf("Expected an HTTP error, got %v", err)
+	}
+	if e, a := http.StatusUnprocessableEntity, httpErr.StatusCode; e != a {
+		t.Errorf("Unexpected status code; expected %v, got %v", e, a)
+	}
+	if httpErr.ErrorMessage == nil || *httpErr.ErrorMessage != sharing.NotShareableErrorMessage {
+		t.Errorf("Unexpected error message: %v", httpErr.ErrorMessage)
+	}
+	if _, called := shared["rejected"]; called {
+		t.Error("Expected the business logic not to be called for a rejected binding")
+	}
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// configMapLabel marks the ConfigMaps written by a ConfigMapKV.
+	configMapLabel = "app.kubernetes.io/managed-by"
+	// configMapLabelValue is the value of configMapLabel.
+	configMapLabelValue = "osb-broker-lib"
+	// configMapKeyAnnotation holds the KV key of a ConfigMap.
+	configMapKeyAnnotation = "osb-broker-lib/key"
+	// configMapValueKey is the data key holding the KV value.
+	configMapValueKey = "value"
+	// configMapPrefixLabel is the name of the labels indexing a ConfigMap
+	// by the directories of its key: label i holds the hash of the key up
+	// to and including its i+1th "/".
+	configMapPrefixLabel = "osb-broker-lib/prefix-%d"
+	// configMapListLimit is the page size used to list ConfigMaps.
+	configMapListLimit = 500
+)
+
+// ConfigMapKV is a KV keeping each key in its own Kubernetes ConfigMap, so
+// brokers running in-cluster can persist their state without a database.
+// Used with a KVStore, it stores operations as ConfigMaps. It talks to the
+// API server over plain HTTP; the service account needs get, list, patch
+// and delete permissions on ConfigMaps in Namespace.
+//
+// ConfigMaps are labelled with the directories of their key, so listing a
+// prefix ending in "/" only fetches the matching ConfigMaps, a page at a
+// time. Other prefixes fetch every ConfigMap written by a ConfigMapKV.
+//
+// Only operation records are stored: instances and bindings belong to the
+// business logic, which can keep them in the same KV. Every read goes to the
+// API server, so there is no cache to warm up; an informer-backed cache is
+// left for when reads become a bottleneck.
+type ConfigMapKV struct {
+	// URL is the base URL of the Kubernetes API server.
+	URL string
+	// Namespace is the namespace the ConfigMaps are written to.
+	Namespace string
+	// Token is the bearer token used to authenticate to the API server.
+	Token string
+	// Client is the HTTP client used to talk to the API server. It
+	// defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+var _ KV = &ConfigMapKV{}
+
+// NewInClusterConfigMapKV returns a ConfigMapKV using the service account of
+// the pod it runs in. The namespace defaults to the pod's namespace.
+func NewInClusterConfigMapKV(namespace string) (*ConfigMapKV, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	return &ConfigMapKV{
+		URL:       "https://" + net.JoinHostPort(host, port),
+		Namespace: namespace,
+		Token:     strings.TrimSpace(string(token)),
+		Client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// configMap is the subset of a Kubernetes ConfigMap used by ConfigMapKV.
+type configMap struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   configMapMetadata `json:"metadata"`
+	Data       map[string]string `json:"data"`
+}
+
+type configMapMetadata struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type configMapList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []configMap `json:"items"`
+}
+
+// configMapName returns the name of the ConfigMap holding key. Keys aren't
+// valid object names, so they are hashed.
+func configMapName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "osb-" + hex.EncodeToString(sum[:20])
+}
+
+// hashLabelValue returns a label value identifying s. Keys may hold
+// characters not allowed in label values, so they are hashed.
+func hashLabelValue(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:20])
+}
+
+// configMapLabels returns the labels of the ConfigMap holding key.
+func configMapLabels(key string) map[string]string {
+	labels := map[string]string{configMapLabel: configMapLabelValue}
+	for i, depth := 0, 0; i < len(key); i++ {
+		if key[i] == '/' {
+			labels[fmt.Sprintf(configMapPrefixLabel, depth)] = hashLabelValue(key[:i+1])
+			depth++
+		}
+	}
+	return labels
+}
+
+// listSelector returns the label selector of the ConfigMaps whose keys may
+// start with prefix.
+func listSelector(prefix string) string {
+	selector := configMapLabel + "=" + configMapLabelValue
+	if depth := strings.Count(prefix, "/"); depth > 0 && strings.HasSuffix(prefix, "/") {
+		selector += "," + fmt.Sprintf(configMapPrefixLabel, depth-1) + "=" + hashLabelValue(prefix)
+	}
+	return selector
+}
+
+func (kv *ConfigMapKV) configMapsURL() string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/configmaps", strings.TrimSuffix(kv.URL, "/"), url.PathEscape(kv.Namespace))
+}
+
+func (kv *ConfigMapKV) do(ctx context.Context, method, u, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if kv.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+kv.Token)
+	}
+
+	client := kv.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// apiError returns an error describing an unexpected API server response.
+func apiError(method string, resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("%s configmaps: unexpected status %d: %s", method, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// Get implements KV.
+func (kv *ConfigMapKV) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := kv.do(ctx, http.MethodGet, kv.configMapsURL()+"/"+configMapName(key), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, apiError(http.MethodGet, resp)
+	}
+
+	cm := &configMap{}
+	if err := json.NewDecoder(resp.Body).Decode(cm); err != nil {
+		return nil, err
+	}
+	return []byte(cm.Data[configMapValueKey]), nil
+}
+
+// Put implements KV. The ConfigMap is written with a server-side apply, so
+// it is created or replaced in a single request.
+func (kv *ConfigMapKV) Put(ctx context.Context, key string, value []byte) error {
+	name := configMapName(key)
+	body, err := json.Marshal(&configMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: configMapMetadata{
+			Name:        name,
+			Labels:      configMapLabels(key),
+			Annotations: map[string]string{configMapKeyAnnotation: key},
+		},
+		Data: map[string]string{configMapValueKey: string(value)},
+	})
+	if err != nil {
+		return err
+	}
+
+	u := kv.configMapsURL() + "/" + name + "?fieldManager=" + configMapLabelValue + "&force=true"
+	resp, err := kv.do(ctx, http.MethodPatch, u, "application/apply-patch+yaml", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return apiError(http.MethodPatch, resp)
+	}
+	return nil
+}
+
+// Delete implements KV.
+func (kv *ConfigMapKV) Delete(ctx context.Context, key string) error {
+	resp, err := kv.do(ctx, http.MethodDelete, kv.configMapsURL()+"/"+configMapName(key), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return apiError(http.MethodDelete, resp)
+	}
+	return nil
+}
+
+// List implements KV.
+func (kv *ConfigMapKV) List(ctx context.Context, prefix string) ([][]byte, error) {
+	values := [][]byte{}
+	query := url.Values{}
+	query.Set("labelSelector", listSelector(prefix))
+	query.Set("limit", strconv.Itoa(configMapListLimit))
+	for {
+		list, err := kv.listPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, cm := range list.Items {
+			if strings.HasPrefix(cm.Metadata.Annotations[configMapKeyAnnotation], prefix) {
+				values = append(values, []byte(cm.Data[configMapValueKey]))
+			}
+		}
+		if list.Metadata.Continue == "" {
+			return values, nil
+		}
+		query.Set("continue", list.Metadata.Continue)
+	}
+}
+
+// listPage fetches one page of ConfigMaps.
+func (kv *ConfigMapKV) listPage(ctx context.Context, query url.Values) (*configMapList, error) {
+	resp, err := kv.do(ctx, http.MethodGet, kv.configMapsURL()+"?"+query.Encode(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(http.MethodGet, resp)
+	}
+
+	list := &configMapList{}
+	if err := json.NewDecoder(resp.Body).Decode(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// fakeAPIServer serves the ConfigMap endpoints used by ConfigMapKV.
+type fakeAPIServer struct {
+	mutex      sync.Mutex
+	configMaps map[string]configMap
+	lists      int
+}
+
+// matchesSelector returns whether cm has every label=value of selector.
+func matchesSelector(cm configMap, selector string) bool {
+	for _, requirement := range strings.Split(selector, ",") {
+		parts := strings.SplitN(requirement, "=", 2)
+		if len(parts) != 2 || cm.Metadata.Labels[parts[0]] != parts[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const prefix = "/api/v1/namespaces/brokers/configmaps"
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		f.lists++
+		names := []string{}
+		for name, cm := range f.configMaps {
+			if matchesSelector(cm, r.URL.Query().Get("labelSelector")) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		// Pages continue after the name of their last item.
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		list := configMapList{Items: []configMap{}}
+		for _, name := range names {
+			if name <= r.URL.Query().Get("continue") {
+				continue
+			}
+			if limit > 0 && len(list.Items) == limit {
+				list.Metadata.Continue = list.Items[limit-1].Metadata.Name
+				break
+			}
+			list.Items = append(list.Items, f.configMaps[name])
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodGet:
+		cm, ok := f.configMaps[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(cm)
+	case r.Method == http.MethodPatch:
+		cm := configMap{}
+		if err := json.NewDecoder(r.Body).Decode(&cm); err != nil || cm.Metadata.Name != name {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.configMaps[name] = cm
+		json.NewEncoder(w).Encode(cm)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.configMaps[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.configMaps, name)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestConfigMapKVStore(t *testing.T) {
+	api := &fakeAPIServer{configMaps: map[string]configMap{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	s := NewKVStore(&ConfigMapKV{URL: server.URL, Namespace: "brokers", Token: "token"}, "test")
+
+	if _, err := s.GetOperation("missing"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	op := &Operation{
+		Key:        "provision:abc",
+		Type:       "provision",
+		InstanceID: "instance",
+		State:      osb.StateInProgress,
+		Created:    time.Unix(100, 0).UTC(),
+	}
+	if err := s.PutOperation(op); err != nil {
+		t.Fatal(err)
+	}
+	op.State = osb.StateSucceeded
+	if err := s.PutOperation(op); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetOperation("provision:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := osb.StateSucceeded, got.State; e != a {
+		t.Errorf("Unexpected state; expected %v, got %v", e, a)
+	}
+
+	ops, err := s.ListOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 {
+		t.Errorf("Expected one operation, got %+v", ops)
+	}
+
+	if err := s.DeleteOperation("provision:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteOperation("provision:abc"); err != nil {
+		t.Errorf("Expected deleting a missing operation to succeed, got %v", err)
+	}
+	if len(api.configMaps) != 0 {
+		t.Errorf("Expected no ConfigMaps left, got %v", api.configMaps)
+	}
+}
+
+func TestConfigMapKVList(t *testing.T) {
+	api := &fakeAPIServer{configMaps: map[string]configMap{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	kv := &ConfigMapKV{URL: server.URL, Namespace: "brokers", Token: "token"}
+	ctx := context.Background()
+	for i := 0; i < configMapListLimit+1; i++ {
+		if err := kv.Put(ctx, fmt.Sprintf("test/operations/%d", i), []byte("op")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kv.Put(ctx, "test/locks/instance", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Put(ctx, "test/operationsx", []byte("not an operation")); err != nil {
+		t.Fatal(err)
+	}
+
+	api.lists = 0
+	values, err := kv.List(ctx, "test/operations/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := configMapListLimit+1, len(values); e != a {
+		t.Errorf("Unexpected number of values; expected %v, got %v", e, a)
+	}
+	if e, a := 2, api.lists; e != a {
+		t.Errorf("Expected the list to be paginated; expected %v requests, got %v", e, a)
+	}
+
+	values, err = kv.List(ctx, "test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := configMapListLimit+3, len(values); e != a {
+		t.Errorf("Expected a parent directory to list its descendants; expected %v values, got %v", e, a)
+	}
+
+	values, err = kv.List(ctx, "test/operations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := configMapListLimit+2, len(values); e != a {
+		t.Errorf("Expected a partial prefix to match by key; expected %v values, got %v", e, a)
+	}
+}
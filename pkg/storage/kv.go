@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultKVTimeout bounds each call a KVStore makes to its backend when
+// Timeout is not set.
+const DefaultKVTimeout = 10 * time.Second
+
+// KV is a key-value backend for a KVStore. It is the subset of an etcd v3
+// client needed by the store, so adapting a clientv3.Client takes a few
+// lines; any other consistent key-value store works as well.
+type KV interface {
+	// Get returns the value stored at key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores value at key.
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete deletes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the values of every key with the given prefix.
+	List(ctx context.Context, prefix string) ([][]byte, error)
+}
+
+// KVStore is a store keeping its records as JSON documents in a KV backend,
+// such as etcd, under Prefix. Operations are indexed by instance and binding
+// under a separate prefix holding their keys; the record and its index entry
+// are not written atomically, so an index entry may briefly point to a
+// missing record, which lookups skip.
+type KVStore struct {
+	// KV is the backend the records are kept in.
+	KV KV
+	// Prefix is prepended to every key written by the store, so that
+	// several brokers can share a backend.
+	Prefix string
+	// Timeout bounds each call to KV. It defaults to DefaultKVTimeout.
+	Timeout time.Duration
+}
+
+var _ OperationStore = &KVStore{}
+var _ FingerprintStore = &KVStore{}
+var _ InstanceStore = &KVStore{}
+
+// NewKVStore returns a KVStore writing to kv under prefix.
+func NewKVStore(kv KV, prefix string) *KVStore {
+	return &KVStore{
+		KV:     kv,
+		Prefix: prefix,
+	}
+}
+
+// context returns the context of one call to KV.
+func (s *KVStore) context() (context.Context, context.CancelFunc) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultKVTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func (s *KVStore) operationsPrefix() string {
+	return strings.TrimSuffix(s.Prefix, "/") + "/operations/"
+}
+
+// targetPrefix returns the prefix of the index entries of the operations on
+// an instance, or on a binding if bindingID is set.
+func (s *KVStore) targetPrefix(instanceID, bindingID string) string {
+	return strings.TrimSuffix(s.Prefix, "/") + "/targets/" + url.PathEscape(instanceID) + "/" + url.PathEscape(bindingID) + "/"
+}
+
+// PutOperation implements OperationStore.
+func (s *KVStore) PutOperation(op *Operation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.KV.Put(ctx, s.targetPrefix(op.InstanceID, op.BindingID)+op.Key, []byte(op.Key)); err != nil {
+		return err
+	}
+	return s.KV.Put(ctx, s.operationsPrefix()+op.Key, data)
+}
+
+// GetOperation implements OperationStore.
+func (s *KVStore) GetOperation(key string) (*Operation, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	data, err := s.KV.Get(ctx, s.operationsPrefix()+key)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &Operation{}
+	if err := json.Unmarshal(data, op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// LatestOperation implements OperationStore.
+func (s *KVStore) LatestOperation(instanceID, bindingID string) (*Operation, error) {
+	ctx, cancel := s.context()
+	keys, err := s.KV.List(ctx, s.targetPrefix(instanceID, bindingID))
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *Operation
+	for _, key := range keys {
+		op, err := s.GetOperation(string(key))
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || newer(op, latest) {
+			latest = op
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+// ListOperations implements OperationStore.
+func (s *KVStore) ListOperations() ([]*Operation, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	values, err := s.KV.List(ctx, s.operationsPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]*Operation, 0, len(values))
+	for _, data := range values {
+		op := &Operation{}
+		if err := json.Unmarshal(data, op); err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Created.Equal(ops[j].Created) {
+			return ops[i].Key < ops[j].Key
+		}
+		return ops[i].Created.Before(ops[j].Created)
+	})
+	return ops, nil
+}
+
+// DeleteOperation implements OperationStore.
+func (s *KVStore) DeleteOperation(key string) error {
+	op, err := s.GetOperation(key)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.KV.Delete(ctx, s.operationsPrefix()+key); err != nil {
+		return err
+	}
+	return s.KV.Delete(ctx, s.targetPrefix(op.InstanceID, op.BindingID)+key)
+}
+
+func (s *KVStore) fingerprintKey(key string) string {
+	return strings.TrimSuffix(s.Prefix, "/") + "/fingerprints/" + url.PathEscape(key)
+}
+
+// PutFingerprint implements FingerprintStore.
+func (s *KVStore) PutFingerprint(fp *Fingerprint) error {
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.KV.Put(ctx, s.fingerprintKey(fp.Key), data)
+}
+
+// GetFingerprint implements FingerprintStore.
+func (s *KVStore) GetFingerprint(key string) (*Fingerprint, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	data, err := s.KV.Get(ctx, s.fingerprintKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	fp := &Fingerprint{}
+	if err := json.Unmarshal(data, fp); err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
+// DeleteFingerprint implements FingerprintStore.
+func (s *KVStore) DeleteFingerprint(key string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.KV.Delete(ctx, s.fingerprintKey(key))
+}
+
+func (s *KVStore) instanceKey(instanceID string) string {
+	return strings.TrimSuffix(s.Prefix, "/") + "/instances/" + url.PathEscape(instanceID)
+}
+
+// planPrefix returns the prefix of the index entries of the instances of a
+// plan, or of every plan of the service if planID is empty.
+func (s *KVStore) planPrefix(serviceID, planID string) string {
+	prefix := strings.TrimSuffix(s.Prefix, "/") + "/plans/" + url.PathEscape(serviceID) + "/"
+	if planID == "" {
+		return prefix
+	}
+	return prefix + url.PathEscape(planID) + "/"
+}
+
+// PutInstance implements InstanceStore. The index entry of the instance's
+// previous plan, if it changed, is deleted first.
+func (s *KVStore) PutInstance(instance *Instance) error {
+	previous, err := s.GetInstance(instance.InstanceID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if previous != nil && (previous.ServiceID != instance.ServiceID || previous.PlanID != instance.PlanID) {
+		if err := s.KV.Delete(ctx, s.planPrefix(previous.ServiceID, previous.PlanID)+url.PathEscape(instance.InstanceID)); err != nil {
+			return err
+		}
+	}
+	if err := s.KV.Put(ctx, s.instanceKey(instance.InstanceID), data); err != nil {
+		return err
+	}
+	return s.KV.Put(ctx, s.planPrefix(instance.ServiceID, instance.PlanID)+url.PathEscape(instance.InstanceID), []byte(instance.InstanceID))
+}
+
+// GetInstance implements InstanceStore.
+func (s *KVStore) GetInstance(instanceID string) (*Instance, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	data, err := s.KV.Get(ctx, s.instanceKey(instanceID))
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &Instance{}
+	if err := json.Unmarshal(data, instance); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// DeleteInstance implements InstanceStore.
+func (s *KVStore) DeleteInstance(instanceID string) error {
+	instance, err := s.GetInstance(instanceID)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.KV.Delete(ctx, s.instanceKey(instanceID)); err != nil {
+		return err
+	}
+	return s.KV.Delete(ctx, s.planPrefix(instance.ServiceID, instance.PlanID)+url.PathEscape(instanceID))
+}
+
+// CountInstances implements InstanceStore by listing the plan's index
+// entries.
+func (s *KVStore) CountInstances(serviceID, planID string) (int, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	entries, err := s.KV.List(ctx, s.planPrefix(serviceID, planID))
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// mapKV is a KV backed by a map.
+type mapKV struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+func (m *mapKV) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	value, ok := m.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *mapKV) Put(ctx context.Context, key string, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *mapKV) Delete(ctx context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapKV) List(ctx context.Context, prefix string) ([][]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	values := [][]byte{}
+	for key, value := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func TestKVStoreOperations(t *testing.T) {
+	kv := &mapKV{data: map[string][]byte{}}
+	s := NewKVStore(kv, "/brokers/test")
+
+	if _, err := s.GetOperation("missing"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	now := time.Unix(100, 0).UTC()
+	for i, key := range []string{"b", "a"} {
+		err := s.PutOperation(&Operation{
+			Key:        key,
+			Type:       "bind",
+			InstanceID: "instance",
+			BindingID:  "binding",
+			State:      osb.StateInProgress,
+			Created:    now.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := kv.data["/brokers/test/operations/a"]; !ok {
+		t.Errorf("Expected operations to be stored under the prefix, got keys %v", kv.data)
+	}
+
+	op, err := s.GetOperation("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "binding", op.BindingID; e != a {
+		t.Errorf("Unexpected binding ID; expected %v, got %v", e, a)
+	}
+
+	ops, err := s.ListOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Key != "b" || ops[1].Key != "a" {
+		t.Errorf("Expected operations oldest first, got %+v", ops)
+	}
+
+	latest, err := s.LatestOperation("instance", "binding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "a", latest.Key; e != a {
+		t.Errorf("Unexpected latest operation; expected %v, got %v", e, a)
+	}
+	if _, err := s.LatestOperation("other", ""); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an instance without operations, got %v", err)
+	}
+
+	if err := s.DeleteOperation("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetOperation("a"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if latest, err := s.LatestOperation("instance", "binding"); err != nil || latest.Key != "b" {
+		t.Errorf("Expected the index to follow deletes, got %+v, %v", latest, err)
+	}
+}
+
+func TestKVStoreInstances(t *testing.T) {
+	testInstanceStore(t, NewKVStore(&mapKV{data: map[string][]byte{}}, "/brokers/test"))
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultLockRetryInterval is how often a LeaseLocker retries to take a held
+// lock when RetryInterval is not set.
+const DefaultLockRetryInterval = 100 * time.Millisecond
+
+// ErrLockLost is returned by the unlock func of a lock whose lease expired
+// before it was released, meaning another process may have taken the lock
+// meanwhile.
+var ErrLockLost = errors.New("storage: lock lease expired before unlock")
+
+// Locker provides mutual exclusion between the processes of a highly
+// available broker deployment.
+type Locker interface {
+	// Lock blocks until it holds the lock named key or ctx is done. The
+	// lock is released by calling the returned unlock func, or
+	// automatically once ttl has elapsed, so that a crashed holder can't
+	// keep it forever; holders must finish their critical section within
+	// ttl.
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, err error)
+}
+
+// LeaseKV is a KV with leases, such as etcd v3. Keys attached to a lease are
+// deleted when the lease expires or is revoked. As with KV, it is the subset
+// of an etcd v3 client needed by a LeaseLocker:
+//
+//	Grant:       clientv3.Lease.Grant with the ttl in seconds
+//	Revoke:      clientv3.Lease.Revoke
+//	PutIfAbsent: a Txn comparing CreateRevision(key) to 0, then putting
+//	             the value with clientv3.WithLease
+type LeaseKV interface {
+	KV
+	// Grant creates a lease expiring after ttl and returns its ID.
+	Grant(ctx context.Context, ttl time.Duration) (int64, error)
+	// Revoke ends a lease, deleting the keys attached to it. Revoking an
+	// expired lease returns ErrNotFound.
+	Revoke(ctx context.Context, lease int64) error
+	// PutIfAbsent atomically stores value at key, attached to lease, if
+	// key doesn't exist, and returns whether it did.
+	PutIfAbsent(ctx context.Context, key string, value []byte, lease int64) (bool, error)
+}
+
+// LeaseLocker is a Locker keeping its locks as keys attached to leases in a
+// LeaseKV, under Prefix.
+type LeaseLocker struct {
+	// KV is the backend holding the locks.
+	KV LeaseKV
+	// Prefix is prepended to the key of every lock.
+	Prefix string
+	// RetryInterval is how often a held lock is retried. It defaults to
+	// DefaultLockRetryInterval.
+	RetryInterval time.Duration
+}
+
+var _ Locker = &LeaseLocker{}
+
+// NewLeaseLocker returns a LeaseLocker keeping locks in kv under prefix.
+func NewLeaseLocker(kv LeaseKV, prefix string) *LeaseLocker {
+	return &LeaseLocker{
+		KV:     kv,
+		Prefix: prefix,
+	}
+}
+
+// Lock implements Locker.
+func (l *LeaseLocker) Lock(ctx context.Context, key string, ttl time.Duration) (func() error, error) {
+	lease, err := l.KV.Grant(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(l.Prefix, "/") + "/locks/" + key
+	retry := l.RetryInterval
+	if retry <= 0 {
+		retry = DefaultLockRetryInterval
+	}
+	for {
+		ok, err := l.KV.PutIfAbsent(ctx, name, nil, lease)
+		if err != nil {
+			l.revoke(lease)
+			return nil, err
+		}
+		if ok {
+			return func() error {
+				err := l.revoke(lease)
+				if err == ErrNotFound {
+					return ErrLockLost
+				}
+				return err
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			l.revoke(lease)
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// revoke ends a lease with a context of its own, since it is also used to
+// clean up after the caller's context is done.
+func (l *LeaseLocker) revoke(lease int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultKVTimeout)
+	defer cancel()
+	return l.KV.Revoke(ctx, lease)
+}
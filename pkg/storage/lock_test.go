@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// leaseMapKV is a LeaseKV backed by a map. Leases never expire on their own;
+// tests expire them with Revoke.
+type leaseMapKV struct {
+	mapKV
+	nextLease int64
+	leases    map[int64][]string
+}
+
+func (m *leaseMapKV) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nextLease++
+	m.leases[m.nextLease] = nil
+	return m.nextLease, nil
+}
+
+func (m *leaseMapKV) Revoke(ctx context.Context, lease int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	keys, ok := m.leases[lease]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, key := range keys {
+		delete(m.data, key)
+	}
+	delete(m.leases, lease)
+	return nil
+}
+
+func (m *leaseMapKV) PutIfAbsent(ctx context.Context, key string, value []byte, lease int64) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.data[key]; ok {
+		return false, nil
+	}
+	m.data[key] = value
+	m.leases[lease] = append(m.leases[lease], key)
+	return true, nil
+}
+
+func TestLeaseLocker(t *testing.T) {
+	kv := &leaseMapKV{mapKV: mapKV{data: map[string][]byte{}}, leases: map[int64][]string{}}
+	l := NewLeaseLocker(kv, "/brokers/test")
+	l.RetryInterval = time.Millisecond
+
+	unlock, err := l.Lock(context.Background(), "instance", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := kv.data["/brokers/test/locks/instance"]; !ok {
+		t.Errorf("Expected the lock to be stored under the prefix, got keys %v", kv.data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Lock(ctx, "instance", time.Minute); err != context.DeadlineExceeded {
+		t.Fatalf("Expected a held lock to time out, got %v", err)
+	}
+	if e, a := 1, len(kv.leases); e != a {
+		t.Errorf("Expected the lease of the failed attempt to be revoked; expected %v leases, got %v", e, a)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = l.Lock(context.Background(), "instance", time.Minute)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be available, got %v", err)
+	}
+
+	// Expire the lease behind the holder's back.
+	for lease := range kv.leases {
+		kv.Revoke(context.Background(), lease)
+	}
+	if err := unlock(); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost unlocking an expired lock, got %v", err)
+	}
+}
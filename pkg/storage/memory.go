@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// Memory is an in-memory store. Its contents are lost when the process
+// exits, so it is suited to tests and brokers whose state can be rebuilt.
+//
+// The zero value is ready to use.
+type Memory struct {
+	mutex      sync.Mutex
+	operations map[string]Operation
+	// targets indexes the keys of operations by instance and binding.
+	targets map[target]map[string]bool
+
+	fingerprints map[string]Fingerprint
+
+	instances map[string]Instance
+	// plans counts the instances by service and plan.
+	plans map[plan]int
+}
+
+// plan is a plan of a service.
+type plan struct {
+	serviceID string
+	planID    string
+}
+
+// target is the instance or binding an operation acts on.
+type target struct {
+	instanceID string
+	bindingID  string
+}
+
+func targetOf(op *Operation) target {
+	return target{instanceID: op.InstanceID, bindingID: op.BindingID}
+}
+
+var _ OperationStore = &Memory{}
+var _ FingerprintStore = &Memory{}
+var _ InstanceStore = &Memory{}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// PutOperation implements OperationStore.
+func (m *Memory) PutOperation(op *Operation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.operations == nil {
+		m.operations = map[string]Operation{}
+		m.targets = map[target]map[string]bool{}
+	}
+	m.unindex(op.Key)
+	m.operations[op.Key] = *op
+	keys := m.targets[targetOf(op)]
+	if keys == nil {
+		keys = map[string]bool{}
+		m.targets[targetOf(op)] = keys
+	}
+	keys[op.Key] = true
+	return nil
+}
+
+// GetOperation implements OperationStore.
+func (m *Memory) GetOperation(key string) (*Operation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	op, ok := m.operations[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &op, nil
+}
+
+// LatestOperation implements OperationStore.
+func (m *Memory) LatestOperation(instanceID, bindingID string) (*Operation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var latest *Operation
+	for key := range m.targets[target{instanceID: instanceID, bindingID: bindingID}] {
+		op := m.operations[key]
+		if latest == nil || newer(&op, latest) {
+			latest = &op
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+// ListOperations implements OperationStore.
+func (m *Memory) ListOperations() ([]*Operation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ops := make([]*Operation, 0, len(m.operations))
+	for key := range m.operations {
+		op := m.operations[key]
+		ops = append(ops, &op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Created.Equal(ops[j].Created) {
+			return ops[i].Key < ops[j].Key
+		}
+		return ops[i].Created.Before(ops[j].Created)
+	})
+	return ops, nil
+}
+
+// DeleteOperation implements OperationStore.
+func (m *Memory) DeleteOperation(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.unindex(key)
+	delete(m.operations, key)
+	return nil
+}
+
+// unindex removes a stored operation from the target index.
+func (m *Memory) unindex(key string) {
+	op, ok := m.operations[key]
+	if !ok {
+		return
+	}
+	t := targetOf(&op)
+	delete(m.targets[t], key)
+	if len(m.targets[t]) == 0 {
+		delete(m.targets, t)
+	}
+}
+
+// PutFingerprint implements FingerprintStore.
+func (m *Memory) PutFingerprint(fp *Fingerprint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.fingerprints == nil {
+		m.fingerprints = map[string]Fingerprint{}
+	}
+	m.fingerprints[fp.Key] = *fp
+	return nil
+}
+
+// GetFingerprint implements FingerprintStore.
+func (m *Memory) GetFingerprint(key string) (*Fingerprint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	fp, ok := m.fingerprints[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &fp, nil
+}
+
+// DeleteFingerprint implements FingerprintStore.
+func (m *Memory) DeleteFingerprint(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.fingerprints, key)
+	return nil
+}
+
+// PutInstance implements InstanceStore.
+func (m *Memory) PutInstance(instance *Instance) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.instances == nil {
+		m.instances = map[string]Instance{}
+		m.plans = map[plan]int{}
+	}
+	m.deleteInstance(instance.InstanceID)
+	m.instances[instance.InstanceID] = *instance
+	m.plans[plan{instance.ServiceID, instance.PlanID}]++
+	return nil
+}
+
+// GetInstance implements InstanceStore.
+func (m *Memory) GetInstance(instanceID string) (*Instance, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	instance, ok := m.instances[instanceID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &instance, nil
+}
+
+// DeleteInstance implements InstanceStore.
+func (m *Memory) DeleteInstance(instanceID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.deleteInstance(instanceID)
+	return nil
+}
+
+// deleteInstance deletes an instance and its count. m.mutex must be held.
+func (m *Memory) deleteInstance(instanceID string) {
+	instance, ok := m.instances[instanceID]
+	if !ok {
+		return
+	}
+	delete(m.instances, instanceID)
+	p := plan{instance.ServiceID, instance.PlanID}
+	m.plans[p]--
+	if m.plans[p] == 0 {
+		delete(m.plans, p)
+	}
+}
+
+// CountInstances implements InstanceStore.
+func (m *Memory) CountInstances(serviceID, planID string) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if planID != "" {
+		return m.plans[plan{serviceID, planID}], nil
+	}
+	n := 0
+	for p, count := range m.plans {
+		if p.serviceID == serviceID {
+			n += count
+		}
+	}
+	return n, nil
+}
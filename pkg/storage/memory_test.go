@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestMemoryOperations(t *testing.T) {
+	m := NewMemory()
+
+	if _, err := m.GetOperation("missing"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	now := time.Unix(100, 0)
+	for i, key := range []string{"b", "a"} {
+		err := m.PutOperation(&Operation{
+			Key:     key,
+			Type:    "provision",
+			State:   osb.StateInProgress,
+			Created: now.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	op, err := m.GetOperation("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op.State = osb.StateSucceeded
+	if stored, _ := m.GetOperation("a"); stored.State != osb.StateInProgress {
+		t.Error("Expected the store to return copies of operations")
+	}
+
+	ops, err := m.ListOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Key != "b" || ops[1].Key != "a" {
+		t.Errorf("Expected operations oldest first, got %+v", ops)
+	}
+
+	latest, err := m.LatestOperation("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "a", latest.Key; e != a {
+		t.Errorf("Unexpected latest operation; expected %v, got %v", e, a)
+	}
+	if _, err := m.LatestOperation("other", ""); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for an instance without operations, got %v", err)
+	}
+
+	if err := m.DeleteOperation("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetOperation("b"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if latest, err := m.LatestOperation("", ""); err != nil || latest.Key != "a" {
+		t.Errorf("Expected the index to follow deletes, got %+v, %v", latest, err)
+	}
+}
+
+func TestMemoryFingerprints(t *testing.T) {
+	m := NewMemory()
+
+	if _, err := m.GetFingerprint("instance/i1"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := m.PutFingerprint(&Fingerprint{Key: "instance/i1", Hash: "abc", StatusCode: 201, Response: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := m.GetFingerprint("instance/i1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "abc", fp.Hash; e != a {
+		t.Errorf("Unexpected hash; expected %v, got %v", e, a)
+	}
+	if err := m.DeleteFingerprint("instance/i1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetFingerprint("instance/i1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+// testInstanceStore exercises an empty InstanceStore.
+func testInstanceStore(t *testing.T, s InstanceStore) {
+	t.Helper()
+
+	if _, err := s.GetInstance("i1"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	for _, instance := range []*Instance{
+		{InstanceID: "i1", ServiceID: "db", PlanID: "small"},
+		{InstanceID: "i2", ServiceID: "db", PlanID: "small"},
+		{InstanceID: "i3", ServiceID: "db", PlanID: "large"},
+		{InstanceID: "i4", ServiceID: "cache", PlanID: "small"},
+	} {
+		if err := s.PutInstance(instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := func(serviceID, planID string) int {
+		t.Helper()
+		n, err := s.CountInstances(serviceID, planID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if e, a := 2, count("db", "small"); e != a {
+		t.Errorf("Unexpected plan count; expected %v, got %v", e, a)
+	}
+	if e, a := 3, count("db", ""); e != a {
+		t.Errorf("Unexpected service count; expected %v, got %v", e, a)
+	}
+
+	if err := s.PutInstance(&Instance{InstanceID: "i2", ServiceID: "db", PlanID: "large"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, count("db", "small"); e != a {
+		t.Errorf("Expected a plan change to move the instance; got %v instances of the old plan", a)
+	}
+	if e, a := 2, count("db", "large"); e != a {
+		t.Errorf("Expected a plan change to move the instance; got %v instances of the new plan", a)
+	}
+
+	if err := s.DeleteInstance("i3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteInstance("missing"); err != nil {
+		t.Fatalf("Expected deleting a missing instance to succeed, got %v", err)
+	}
+	if _, err := s.GetInstance("i3"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if e, a := 2, count("db", ""); e != a {
+		t.Errorf("Unexpected service count after delete; expected %v, got %v", e, a)
+	}
+}
+
+func TestMemoryInstances(t *testing.T) {
+	testInstanceStore(t, NewMemory())
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// Dialect is the flavour of SQL spoken by the database behind a SQL store.
+type Dialect int
+
+const (
+	// Postgres uses $1, $2... placeholders.
+	Postgres Dialect = iota
+	// SQLite uses ? placeholders. SQLite 3.24 or later is required.
+	SQLite
+)
+
+// migrations are the statements creating the schema of a SQL store, in
+// order. Migration n is recorded as version n+1 in osb_schema_migrations;
+// new migrations must only ever be appended.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS osb_operations (
+		operation_key VARCHAR(255) PRIMARY KEY,
+		type VARCHAR(64) NOT NULL,
+		instance_id VARCHAR(255) NOT NULL,
+		binding_id VARCHAR(255) NOT NULL,
+		state VARCHAR(32) NOT NULL,
+		description TEXT NOT NULL,
+		created BIGINT NOT NULL,
+		updated BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS osb_operations_instance ON osb_operations (instance_id, binding_id)`,
+	`ALTER TABLE osb_operations ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE osb_operations ADD COLUMN observed BIGINT NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS osb_fingerprints (
+		fingerprint_key VARCHAR(512) PRIMARY KEY,
+		hash VARCHAR(128) NOT NULL,
+		status_code INTEGER NOT NULL,
+		response TEXT NOT NULL,
+		created BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS osb_instances (
+		instance_id VARCHAR(255) PRIMARY KEY,
+		service_id VARCHAR(255) NOT NULL,
+		plan_id VARCHAR(255) NOT NULL,
+		created BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS osb_instances_plan ON osb_instances (service_id, plan_id)`,
+}
+
+// SQL is a store backed by a database/sql database. The driver must be
+// registered by the program; the store has been written for the lib/pq and
+// mattn/go-sqlite3 drivers. Call Migrate before using the store.
+type SQL struct {
+	// DB is the database holding the store's tables.
+	DB *sql.DB
+	// Dialect is the flavour of SQL spoken by DB.
+	Dialect Dialect
+}
+
+var _ OperationStore = &SQL{}
+var _ FingerprintStore = &SQL{}
+var _ InstanceStore = &SQL{}
+
+// NewSQL returns a SQL store using db.
+func NewSQL(db *sql.DB, dialect Dialect) *SQL {
+	return &SQL{
+		DB:      db,
+		Dialect: dialect,
+	}
+}
+
+// migrationLockID is the key of the Postgres advisory lock held while
+// migrating, so that brokers starting together don't race to apply the same
+// migration.
+const migrationLockID = 0x6f73622d6d6967
+
+// Migrate creates or upgrades the store's schema. Each migration is applied
+// in its own transaction, so an interrupted Migrate can be run again. Several
+// processes may run Migrate concurrently: on Postgres they are serialized by
+// an advisory lock, and on SQLite by the database lock; in both cases the
+// schema version is read again before each migration and a migration that
+// was applied meanwhile is skipped.
+func (s *SQL) Migrate() error {
+	_, err := s.DB.Exec(`CREATE TABLE IF NOT EXISTS osb_schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("creating migrations table: %v", err)
+	}
+
+	for {
+		applied, err := s.migrateOnce()
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return nil
+		}
+	}
+}
+
+// migrateOnce applies the next pending migration, if any, and returns
+// whether it did.
+func (s *SQL) migrateOnce() (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if s.Dialect == Postgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+			return false, fmt.Errorf("locking schema: %v", err)
+		}
+	}
+
+	var version int
+	row := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM osb_schema_migrations`)
+	if err := row.Scan(&version); err != nil {
+		return false, fmt.Errorf("reading schema version: %v", err)
+	}
+	if version >= len(migrations) {
+		return false, tx.Commit()
+	}
+
+	if _, err := tx.Exec(migrations[version]); err != nil {
+		return false, fmt.Errorf("applying migration %d: %v", version+1, err)
+	}
+	// The version is the primary key, so a concurrent Migrate that applied
+	// the same migration makes this insert, and the transaction, fail.
+	if _, err := tx.Exec(s.rebind(`INSERT INTO osb_schema_migrations (version) VALUES (?)`), version+1); err != nil {
+		return false, fmt.Errorf("recording migration %d: %v", version+1, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("applying migration %d: %v", version+1, err)
+	}
+	return true, nil
+}
+
+// rebind rewrites the ? placeholders of query for the store's Dialect.
+func (s *SQL) rebind(query string) string {
+	if s.Dialect != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+const operationColumns = `operation_key, type, instance_id, binding_id, state, description, attempts, created, updated, observed`
+
+// PutOperation implements OperationStore.
+func (s *SQL) PutOperation(op *Operation) error {
+	_, err := s.DB.Exec(s.rebind(`INSERT INTO osb_operations (`+operationColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (operation_key) DO UPDATE SET
+			type = excluded.type,
+			instance_id = excluded.instance_id,
+			binding_id = excluded.binding_id,
+			state = excluded.state,
+			description = excluded.description,
+			attempts = excluded.attempts,
+			created = excluded.created,
+			updated = excluded.updated,
+			observed = excluded.observed`),
+		op.Key, op.Type, op.InstanceID, op.BindingID, string(op.State), op.Description, op.Attempts,
+		op.Created.UnixNano(), op.Updated.UnixNano(), unixNano(op.Observed))
+	return err
+}
+
+// GetOperation implements OperationStore.
+func (s *SQL) GetOperation(key string) (*Operation, error) {
+	row := s.DB.QueryRow(s.rebind(`SELECT `+operationColumns+` FROM osb_operations WHERE operation_key = ?`), key)
+	op, err := scanOperation(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return op, err
+}
+
+// LatestOperation implements OperationStore. It is served by the
+// osb_operations_instance index.
+func (s *SQL) LatestOperation(instanceID, bindingID string) (*Operation, error) {
+	row := s.DB.QueryRow(s.rebind(`SELECT `+operationColumns+` FROM osb_operations
+		WHERE instance_id = ? AND binding_id = ?
+		ORDER BY created DESC, operation_key DESC LIMIT 1`), instanceID, bindingID)
+	op, err := scanOperation(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return op, err
+}
+
+// ListOperations implements OperationStore.
+func (s *SQL) ListOperations() ([]*Operation, error) {
+	rows, err := s.DB.Query(`SELECT ` + operationColumns + ` FROM osb_operations ORDER BY created, operation_key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ops := []*Operation{}
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+// DeleteOperation implements OperationStore.
+func (s *SQL) DeleteOperation(key string) error {
+	_, err := s.DB.Exec(s.rebind(`DELETE FROM osb_operations WHERE operation_key = ?`), key)
+	return err
+}
+
+// PutFingerprint implements FingerprintStore.
+func (s *SQL) PutFingerprint(fp *Fingerprint) error {
+	_, err := s.DB.Exec(s.rebind(`INSERT INTO osb_fingerprints (fingerprint_key, hash, status_code, response, created)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (fingerprint_key) DO UPDATE SET
+			hash = excluded.hash,
+			status_code = excluded.status_code,
+			response = excluded.response,
+			created = excluded.created`),
+		fp.Key, fp.Hash, fp.StatusCode, string(fp.Response), fp.Created.UnixNano())
+	return err
+}
+
+// GetFingerprint implements FingerprintStore.
+func (s *SQL) GetFingerprint(key string) (*Fingerprint, error) {
+	fp := &Fingerprint{}
+	var response string
+	var created int64
+	err := s.DB.QueryRow(s.rebind(`SELECT fingerprint_key, hash, status_code, response, created FROM osb_fingerprints WHERE fingerprint_key = ?`), key).
+		Scan(&fp.Key, &fp.Hash, &fp.StatusCode, &response, &created)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	fp.Response = []byte(response)
+	fp.Created = time.Unix(0, created)
+	return fp, nil
+}
+
+// DeleteFingerprint implements FingerprintStore.
+func (s *SQL) DeleteFingerprint(key string) error {
+	_, err := s.DB.Exec(s.rebind(`DELETE FROM osb_fingerprints WHERE fingerprint_key = ?`), key)
+	return err
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOperation(row scanner) (*Operation, error) {
+	op := &Operation{}
+	var state string
+	var created, updated, observed int64
+	err := row.Scan(&op.Key, &op.Type, &op.InstanceID, &op.BindingID, &state, &op.Description, &op.Attempts, &created, &updated, &observed)
+	if err != nil {
+		return nil, err
+	}
+	op.State = osb.LastOperationState(state)
+	op.Created = time.Unix(0, created)
+	op.Updated = time.Unix(0, updated)
+	if observed != 0 {
+		op.Observed = time.Unix(0, observed)
+	}
+	return op, nil
+}
+
+// unixNano returns t in nanoseconds since the epoch, or 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// PutInstance implements InstanceStore.
+func (s *SQL) PutInstance(instance *Instance) error {
+	_, err := s.DB.Exec(s.rebind(`INSERT INTO osb_instances (instance_id, service_id, plan_id, created)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (instance_id) DO UPDATE SET
+			service_id = excluded.service_id,
+			plan_id = excluded.plan_id,
+			created = excluded.created`),
+		instance.InstanceID, instance.ServiceID, instance.PlanID, instance.Created.UnixNano())
+	return err
+}
+
+// GetInstance implements InstanceStore.
+func (s *SQL) GetInstance(instanceID string) (*Instance, error) {
+	instance := &Instance{}
+	var created int64
+	err := s.DB.QueryRow(s.rebind(`SELECT instance_id, service_id, plan_id, created FROM osb_instances WHERE instance_id = ?`), instanceID).
+		Scan(&instance.InstanceID, &instance.ServiceID, &instance.PlanID, &created)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	instance.Created = time.Unix(0, created)
+	return instance, nil
+}
+
+// DeleteInstance implements InstanceStore.
+func (s *SQL) DeleteInstance(instanceID string) error {
+	_, err := s.DB.Exec(s.rebind(`DELETE FROM osb_instances WHERE instance_id = ?`), instanceID)
+	return err
+}
+
+// CountInstances implements InstanceStore.
+func (s *SQL) CountInstances(serviceID, planID string) (int, error) {
+	var n int
+	var err error
+	if planID == "" {
+		err = s.DB.QueryRow(s.rebind(`SELECT COUNT(*) FROM osb_instances WHERE service_id = ?`), serviceID).Scan(&n)
+	} else {
+		err = s.DB.QueryRow(s.rebind(`SELECT COUNT(*) FROM osb_instances WHERE service_id = ? AND plan_id = ?`), serviceID, planID).Scan(&n)
+	}
+	return n, err
+}
@@ -0,0 +1,104 @@
+//go:build sqlite
+// +build sqlite
+
+// This test runs the SQL store against a real SQLite database. It needs the
+// mattn/go-sqlite3 driver, which is not vendored, in the GOPATH and cgo; CI
+// runs it as .travis.yml does:
+//
+//	git clone --branch v1.14.22 https://github.com/mattn/go-sqlite3 $GOPATH/src/github.com/mattn/go-sqlite3
+//	go test -tags sqlite ./pkg/storage/
+
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+func TestSQLiteOperations(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "osb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := NewSQL(db, SQLite)
+	for i := 0; i < 2; i++ {
+		if err := s.Migrate(); err != nil {
+			t.Fatalf("Migrate run %d: %v", i+1, err)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT MAX(version) FROM osb_schema_migrations`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := len(migrations), version; e != a {
+		t.Fatalf("Unexpected schema version; expected %v, got %v", e, a)
+	}
+
+	if _, err := s.GetOperation("missing"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	now := time.Unix(100, 0).UTC()
+	for i, key := range []string{"b", "a"} {
+		err := s.PutOperation(&Operation{
+			Key:        key,
+			Type:       "provision",
+			InstanceID: "instance",
+			State:      osb.StateInProgress,
+			Attempts:   1,
+			Created:    now.Add(time.Duration(i) * time.Second),
+			Updated:    now,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	op, err := s.GetOperation("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op.State = osb.StateSucceeded
+	op.Attempts = 2
+	if err := s.PutOperation(op); err != nil {
+		t.Fatal(err)
+	}
+	op, err = s.GetOperation("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.State != osb.StateSucceeded || op.Attempts != 2 || !op.Created.Equal(now.Add(time.Second)) {
+		t.Errorf("Unexpected operation after update: %+v", op)
+	}
+
+	ops, err := s.ListOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Key != "b" || ops[1].Key != "a" {
+		t.Errorf("Expected operations oldest first, got %+v", ops)
+	}
+
+	latest, err := s.LatestOperation("instance", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "a", latest.Key; e != a {
+		t.Errorf("Unexpected latest operation; expected %v, got %v", e, a)
+	}
+
+	if err := s.DeleteOperation("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetOperation("a"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a database/sql driver that records the statements it
+// is asked to execute. Queries return a single row holding version, which
+// recording a migration increments.
+type recordingDriver struct {
+	mutex   sync.Mutex
+	version int64
+	execs   []string
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) { return &recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recordingConn) Commit() error             { return nil }
+func (c *recordingConn) Rollback() error           { return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+	s.d.execs = append(s.d.execs, s.query)
+	if strings.HasPrefix(s.query, "INSERT INTO osb_schema_migrations") {
+		s.d.version++
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mutex.Lock()
+	defer s.d.mutex.Unlock()
+	return &recordingRows{version: s.d.version}, nil
+}
+
+type recordingRows struct {
+	version int64
+	done    bool
+}
+
+func (r *recordingRows) Columns() []string { return []string{"version"} }
+func (r *recordingRows) Close() error      { return nil }
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.version
+	return nil
+}
+
+var registerOnce sync.Once
+var testDriver = &recordingDriver{}
+
+func TestSQLMigrate(t *testing.T) {
+	registerOnce.Do(func() { sql.Register("osb-recording", testDriver) })
+	db, err := sql.Open("osb-recording", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name       string
+		version    int64
+		migrations int
+	}{
+		{name: "empty database", version: 0, migrations: len(migrations)},
+		{name: "partially migrated", version: 1, migrations: len(migrations) - 1},
+		{name: "up to date", version: int64(len(migrations)), migrations: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDriver.version = tt.version
+			testDriver.execs = nil
+
+			s := NewSQL(db, Postgres)
+			if err := s.Migrate(); err != nil {
+				t.Fatal(err)
+			}
+
+			// One statement creates the migrations table, then each
+			// migration takes the lock, is applied and recorded, and a
+			// last pass takes the lock to find nothing left to do.
+			if e, a := 1+3*tt.migrations+1, len(testDriver.execs); e != a {
+				t.Fatalf("Unexpected number of statements; expected %v, got %v: %v", e, a, testDriver.execs)
+			}
+			for _, stmt := range testDriver.execs {
+				if strings.Contains(stmt, "?") {
+					t.Errorf("Expected Postgres placeholders, got %q", stmt)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLRebind(t *testing.T) {
+	query := `SELECT a FROM t WHERE b = ? AND c = ?`
+	if e, a := `SELECT a FROM t WHERE b = $1 AND c = $2`, (&SQL{Dialect: Postgres}).rebind(query); e != a {
+		t.Errorf("Unexpected Postgres query; expected %q, got %q", e, a)
+	}
+	if e, a := query, (&SQL{Dialect: SQLite}).rebind(query); e != a {
+		t.Errorf("Unexpected SQLite query; expected %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,127 @@
+// Package storage defines the persistence interfaces used by the optional
+// subsystems of this library, such as the asynchronous job manager, and an
+// in-memory implementation of them.
+package storage
+
+import (
+	"errors"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// ErrNotFound is returned by stores when the requested record doesn't exist.
+var ErrNotFound = errors.New("storage: not found")
+
+// Operation is the persisted state of an asynchronous operation.
+type Operation struct {
+	// Key is the operation key returned to the platform. It identifies the
+	// operation in the store.
+	Key string
+	// Type is the OSB operation, for example "provision" or "bind".
+	Type string
+	// InstanceID is the service instance the operation acts on.
+	InstanceID string
+	// BindingID is the binding the operation acts on, if any.
+	BindingID string
+	// State is the last known state of the operation.
+	State osb.LastOperationState
+	// Description is surfaced in last operation responses.
+	Description string
+	// Attempts is the number of times the operation has been started.
+	Attempts int
+	// Created is when the operation was accepted.
+	Created time.Time
+	// Updated is when the operation last changed.
+	Updated time.Time
+	// Observed is when the platform first polled the operation in a
+	// terminal state. It is zero while the platform hasn't.
+	Observed time.Time
+}
+
+// OperationStore persists asynchronous operations. Implementations must be
+// safe for concurrent use.
+type OperationStore interface {
+	// PutOperation creates or replaces the operation with the same key.
+	PutOperation(op *Operation) error
+	// GetOperation returns the operation with the given key, or
+	// ErrNotFound.
+	GetOperation(key string) (*Operation, error)
+	// LatestOperation returns the most recently created operation on an
+	// instance, or on a binding if bindingID is set, or ErrNotFound.
+	// Implementations must not scan every stored operation.
+	LatestOperation(instanceID, bindingID string) (*Operation, error)
+	// ListOperations returns every stored operation, oldest first.
+	ListOperations() ([]*Operation, error)
+	// DeleteOperation deletes the operation with the given key. Deleting an
+	// operation that doesn't exist is not an error.
+	DeleteOperation(key string) error
+}
+
+// Fingerprint is the stored outcome of a request that created an instance or
+// a binding, used to answer retries of the request.
+type Fingerprint struct {
+	// Key identifies the instance or binding the request created.
+	Key string
+	// Hash is a hash of the request.
+	Hash string
+	// StatusCode is the status code of the response to the request.
+	StatusCode int
+	// Response is the body of the response to the request. For bindings,
+	// it holds the credentials.
+	Response []byte
+	// Created is when the request was answered.
+	Created time.Time
+}
+
+// FingerprintStore persists request fingerprints. Implementations must be
+// safe for concurrent use.
+type FingerprintStore interface {
+	// PutFingerprint creates or replaces the fingerprint with the same
+	// key.
+	PutFingerprint(fp *Fingerprint) error
+	// GetFingerprint returns the fingerprint with the given key, or
+	// ErrNotFound.
+	GetFingerprint(key string) (*Fingerprint, error)
+	// DeleteFingerprint deletes the fingerprint with the given key.
+	// Deleting a fingerprint that doesn't exist is not an error.
+	DeleteFingerprint(key string) error
+}
+
+// Instance is the record of a provisioned service instance, kept to enforce
+// instance caps.
+type Instance struct {
+	// InstanceID identifies the instance in the store.
+	InstanceID string
+	// ServiceID is the instance's service.
+	ServiceID string
+	// PlanID is the instance's plan.
+	PlanID string
+	// Created is when the instance was provisioned.
+	Created time.Time
+}
+
+// InstanceStore persists the service instances of a broker. Implementations
+// must be safe for concurrent use.
+type InstanceStore interface {
+	// PutInstance creates or replaces the instance with the same ID.
+	PutInstance(instance *Instance) error
+	// GetInstance returns the instance with the given ID, or ErrNotFound.
+	GetInstance(instanceID string) (*Instance, error)
+	// DeleteInstance deletes the instance with the given ID. Deleting an
+	// instance that doesn't exist is not an error.
+	DeleteInstance(instanceID string) error
+	// CountInstances returns the number of instances of a plan, or of
+	// every plan of the service if planID is empty. Implementations must
+	// not scan every stored instance.
+	CountInstances(serviceID, planID string) (int, error)
+}
+
+// newer returns whether a was created after b. Operations created at the same
+// time are ordered by key, as in ListOperations.
+func newer(a, b *Operation) bool {
+	if a.Created.Equal(b.Created) {
+		return a.Key > b.Key
+	}
+	return a.Created.After(b.Created)
+}
@@ -0,0 +1,144 @@
+// Package vault issues binding credentials from HashiCorp Vault dynamic
+// secrets through a broker.CredentialProvider. Every binding gets its own
+// lease, which is revoked when the binding is deleted.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/broker"
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+// Secret is a dynamic secret read from Vault.
+type Secret struct {
+	// LeaseID identifies the lease of the secret.
+	LeaseID string
+	// LeaseDuration is how long the secret is valid for.
+	LeaseDuration time.Duration
+	// Data holds the credentials.
+	Data map[string]interface{}
+}
+
+// Client is the subset of the Vault API used to mint and revoke dynamic
+// secrets. Implementations typically wrap the Vault client library's
+// Logical().Read and Sys().Revoke.
+type Client interface {
+	// Read mints a dynamic secret by reading path.
+	Read(path string) (*Secret, error)
+	// Revoke revokes the lease with the given ID.
+	Revoke(leaseID string) error
+}
+
+// lease is the record kept for the credentials of a binding.
+type lease struct {
+	LeaseID string    `json:"lease_id"`
+	Expires time.Time `json:"expires"`
+}
+
+// Provider is a broker.CredentialProvider minting binding credentials from
+// Vault. The lease of each binding is recorded in Leases so it can be
+// revoked on unbind, including by another replica.
+type Provider struct {
+	// Client talks to Vault.
+	Client Client
+	// Path returns the Vault path the credentials of a binding are minted
+	// from, such as database/creds/readonly.
+	Path func(request *osb.BindRequest) string
+	// Leases records the lease of each binding.
+	Leases storage.KV
+	// Prefix is prepended to the keys written to Leases.
+	Prefix string
+	// Timeout bounds each call to Leases. It defaults to
+	// storage.DefaultKVTimeout.
+	Timeout time.Duration
+
+	now func() time.Time
+}
+
+var _ broker.CredentialProvider = &Provider{}
+
+// Issue mints new credentials for the binding and records their lease. The
+// lease of credentials previously issued for the binding, by an earlier
+// attempt of the request, is revoked.
+func (p *Provider) Issue(request *osb.BindRequest, c *broker.RequestContext) (map[string]interface{}, error) {
+	secret, err := p.Client.Read(p.Path(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.revoke(request.InstanceID, request.BindingID); err != nil {
+		p.Client.Revoke(secret.LeaseID)
+		return nil, err
+	}
+
+	data, err := json.Marshal(&lease{
+		LeaseID: secret.LeaseID,
+		Expires: p.clock().Add(secret.LeaseDuration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := p.context()
+	defer cancel()
+	if err := p.Leases.Put(ctx, p.key(request.InstanceID, request.BindingID), data); err != nil {
+		p.Client.Revoke(secret.LeaseID)
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
+
+// Revoke revokes the lease of the binding's credentials.
+func (p *Provider) Revoke(request *osb.UnbindRequest, c *broker.RequestContext) error {
+	return p.revoke(request.InstanceID, request.BindingID)
+}
+
+func (p *Provider) revoke(instanceID, bindingID string) error {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	key := p.key(instanceID, bindingID)
+	data, err := p.Leases.Get(ctx, key)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l := &lease{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return fmt.Errorf("decoding lease of binding %q: %v", bindingID, err)
+	}
+	if err := p.Client.Revoke(l.LeaseID); err != nil {
+		return err
+	}
+	return p.Leases.Delete(ctx, key)
+}
+
+func (p *Provider) key(instanceID, bindingID string) string {
+	return strings.TrimSuffix(p.Prefix, "/") + "/leases/" + url.PathEscape(instanceID) + "/" + url.PathEscape(bindingID)
+}
+
+func (p *Provider) context() (context.Context, context.CancelFunc) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = storage.DefaultKVTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func (p *Provider) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/pmorie/osb-broker-lib/pkg/storage"
+)
+
+type fakeClient struct {
+	issued  int
+	revoked []string
+}
+
+func (f *fakeClient) Read(path string) (*Secret, error) {
+	f.issued++
+	return &Secret{
+		LeaseID:       fmt.Sprintf("%s/%d", path, f.issued),
+		LeaseDuration: time.Hour,
+		Data:          map[string]interface{}{"username": fmt.Sprintf("user-%d", f.issued)},
+	}, nil
+}
+
+func (f *fakeClient) Revoke(leaseID string) error {
+	f.revoked = append(f.revoked, leaseID)
+	return nil
+}
+
+type mapKV struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+func (m *mapKV) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	value, ok := m.data[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *mapKV) Put(ctx context.Context, key string, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *mapKV) Delete(ctx context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapKV) List(ctx context.Context, prefix string) ([][]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var values [][]byte
+	for key, value := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func TestProvider(t *testing.T) {
+	client := &fakeClient{}
+	leases := &mapKV{data: map[string][]byte{}}
+	p := &Provider{
+		Client: client,
+		Path: func(request *osb.BindRequest) string {
+			return "database/creds/" + request.PlanID
+		},
+		Leases: leases,
+		Prefix: "broker",
+	}
+
+	bind := &osb.BindRequest{InstanceID: "instance", BindingID: "binding", PlanID: "small"}
+	credentials, err := p.Issue(bind, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := map[string]interface{}{"username": "user-1"}, credentials; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected credentials; expected %v, got %v", e, a)
+	}
+
+	if _, err := p.Issue(bind, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []string{"database/creds/small/1"}, client.revoked; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected a retry to revoke the previous lease; expected %v, got %v", e, a)
+	}
+
+	unbind := &osb.UnbindRequest{InstanceID: "instance", BindingID: "binding"}
+	if err := p.Revoke(unbind, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []string{"database/creds/small/1", "database/creds/small/2"}, client.revoked; !reflect.DeepEqual(e, a) {
+		t.Errorf("Unexpected revoked leases; expected %v, got %v", e, a)
+	}
+	if e, a := 0, len(leases.data); e != a {
+		t.Errorf("Expected the lease record to be deleted; expected %v records, got %v", e, a)
+	}
+
+	if err := p.Revoke(unbind, nil); err != nil {
+		t.Errorf("Expected revoking twice to succeed, got %v", err)
+	}
+}